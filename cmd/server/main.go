@@ -29,13 +29,18 @@ import (
 	"os/signal"
 	"syscall"
 	"time"
+	_ "time/tzdata" // time zones for reports, even on hosts without zoneinfo
 
 	"github.com/pavelc4/auriya-todolist-go/internal/cache"
 	"github.com/pavelc4/auriya-todolist-go/internal/config"
 	"github.com/pavelc4/auriya-todolist-go/internal/database"
+	"github.com/pavelc4/auriya-todolist-go/internal/http/handler"
 	"github.com/pavelc4/auriya-todolist-go/internal/http/repository"
 	"github.com/pavelc4/auriya-todolist-go/internal/http/router"
 	"github.com/pavelc4/auriya-todolist-go/internal/http/service"
+	"github.com/pavelc4/auriya-todolist-go/internal/jobs"
+	"github.com/pavelc4/auriya-todolist-go/internal/outbox"
+	"github.com/pavelc4/auriya-todolist-go/internal/realtime"
 )
 
 func main() {
@@ -57,12 +62,36 @@ func main() {
 	// Initialize cache service
 	cacheSvc := cache.NewService(5*time.Minute, 10*time.Minute)
 
-	googleConf := cfg.GoogleOAuthConfig
-	githubConf := cfg.GitHubOAuthConfig
 	userRepo := repository.NewUserRepository(db, cacheSvc)
 	jwtService := service.NewJWTService(os.Getenv("JWT_SECRET"))
 
-	r := router.New(db, googleConf, githubConf, userRepo, jwtService, cacheSvc)
+	store := repository.NewStore(db)
+
+	// The hub pushes task and project changes to live connections
+	hub := realtime.NewHub(db, handler.ChangeLoader(store))
+	// Every instance hears every change, so each drops the tasks it has
+	// cached when they change anywhere, including rebalances and other
+	// writes that touch more tasks than the request names.
+	hub.Observe(func(ch realtime.Change) {
+		if ch.Type == "task" {
+			cacheSvc.Delete(fmt.Sprintf("task:%d", ch.ID))
+		}
+	}, cacheSvc.Flush)
+
+	r := router.New(cfg, db, userRepo, jwtService, cacheSvc, hub)
+
+	// Background jobs stop when jobsCtx is cancelled during shutdown
+	jobsCtx, stopJobs := context.WithCancel(ctx)
+	defer stopJobs()
+
+	go hub.Run(jobsCtx)
+	go jobs.NewTrashPurger(store, cfg.TrashRetention, cfg.TrashPurgeInterval).Run(jobsCtx)
+	go jobs.NewTimerStopper(store, cacheSvc, cfg.TimerAutoStop, cfg.TimerAutoStopInterval).Run(jobsCtx)
+	go jobs.NewIdempotencyPurger(store, cfg.IdempotencyRetention, cfg.IdempotencyPurgeInterval).Run(jobsCtx)
+	go jobs.NewTombstonePurger(store, cfg.SyncTombstoneRetention, cfg.SyncTombstonePurgeInterval).Run(jobsCtx)
+	go jobs.NewOutboxRelay(store, outboxSinks(cfg, store), cfg.OutboxRetention, cfg.OutboxRelayInterval).Run(jobsCtx)
+	go jobs.NewWebhookDispatcher(store, handler.WebhookRenderer, cfg.WebhookTimeout, cfg.WebhookLogRetention, cfg.WebhookDispatchInterval, cfg.WebhookAllowPrivate).Run(jobsCtx)
+	go jobs.NewExportWorker(store, handler.ExportWriter(store), cfg.ExportDir, cfg.ExportRetention, cfg.ExportWorkerInterval).Run(jobsCtx)
 
 	srv := &http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.AppPort),
@@ -71,6 +100,9 @@ func main() {
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
+	// Event streams never finish on their own; end them so Shutdown does
+	// not wait for its whole timeout.
+	srv.RegisterOnShutdown(hub.Close)
 
 	errCh := make(chan error, 1)
 	go func() {
@@ -91,6 +123,8 @@ func main() {
 		log.Printf("shutdown signal: %v", sig)
 	}
 
+	stopJobs()
+
 	ctxShutdown, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 	if err := srv.Shutdown(ctxShutdown); err != nil {
@@ -98,3 +132,15 @@ func main() {
 	}
 	log.Println("server exited")
 }
+
+// outboxSinks returns where domain events from the outbox are published.
+// Only sinks shared by every instance belong here, since an event is
+// published to each sink once; per-instance work such as dropping cached
+// tasks is done by the hub's observer.
+func outboxSinks(cfg *config.Config, store *repository.Store) []outbox.Sink {
+	sinks := []outbox.Sink{outbox.NewWebhookSink(store.Queries)}
+	if cfg.OutboxNATSURL != "" {
+		sinks = append(sinks, outbox.NewNATSSink(cfg.OutboxNATSURL, cfg.OutboxNATSPrefix))
+	}
+	return sinks
+}
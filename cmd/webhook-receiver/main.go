@@ -0,0 +1,76 @@
+// Command webhook-receiver is a local endpoint for trying out webhooks. It
+// verifies each delivery's signature, prints it, and answers with a
+// configurable status so that retries and auto-disabling can be watched.
+//
+//	go run ./cmd/webhook-receiver -addr :9090 -secret whsec_...
+//
+// Then create a webhook with the URL http://localhost:9090/ and the same
+// secret.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pavelc4/auriya-todolist-go/internal/webhooks"
+)
+
+func main() {
+	addr := flag.String("addr", ":9090", "address to listen on")
+	secret := flag.String("secret", "", "webhook secret; signatures are not checked when empty")
+	status := flag.Int("status", http.StatusNoContent, "status to answer deliveries with")
+	failFirst := flag.Int("fail-first", 0, "answer the first n attempts of each delivery with 503")
+	tolerance := flag.Duration("tolerance", 5*time.Minute, "maximum age of a signature")
+	flag.Parse()
+
+	var mu sync.Mutex
+	seen := make(map[string]int)
+
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "POST only", http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		id := r.Header.Get(webhooks.HeaderDelivery)
+		event := r.Header.Get(webhooks.HeaderEvent)
+		if *secret != "" {
+			if err := webhooks.Verify(*secret, r.Header.Get(webhooks.HeaderSignature), body, *tolerance); err != nil {
+				log.Printf("delivery %s (%s): rejected: %v", id, event, err)
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+		}
+
+		mu.Lock()
+		seen[id]++
+		attempt := seen[id]
+		mu.Unlock()
+
+		var pretty bytes.Buffer
+		if json.Indent(&pretty, body, "", "  ") != nil {
+			pretty.Write(body)
+		}
+		log.Printf("delivery %s (%s), attempt %d:\n%s", id, event, attempt, pretty.String())
+
+		if attempt <= *failFirst {
+			http.Error(w, "failing on purpose", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(*status)
+	})
+
+	log.Printf("webhook receiver listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
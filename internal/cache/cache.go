@@ -31,3 +31,8 @@ func (s *Service) Get(key string) (interface{}, bool) {
 func (s *Service) Delete(key string) {
 	s.client.Delete(key)
 }
+
+// Flush removes every item from the cache.
+func (s *Service) Flush() {
+	s.client.Flush()
+}
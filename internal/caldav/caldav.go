@@ -0,0 +1,356 @@
+// Package caldav handles the XML side of WebDAV (RFC 4918), CalDAV
+// (RFC 4791) and WebDAV sync (RFC 6578): it parses PROPFIND and REPORT
+// bodies, evaluates calendar-query filters and writes multistatus
+// responses. What the resources are and where they are stored is left to
+// the caller.
+package caldav
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Namespaces of the properties and reports this package knows.
+const (
+	NSDAV            = "DAV:"
+	NSCalDAV         = "urn:ietf:params:xml:ns:caldav"
+	NSCalendarServer = "http://calendarserver.org/ns/"
+)
+
+// prefixes are the namespace prefixes multistatus and error bodies declare
+// on their root element. Prop values written by callers use them.
+var prefixes = map[string]string{
+	NSDAV:            "d",
+	NSCalDAV:         "c",
+	NSCalendarServer: "cs",
+}
+
+// DAV returns the name of an element in the DAV: namespace.
+func DAV(local string) xml.Name { return xml.Name{Space: NSDAV, Local: local} }
+
+// Cal returns the name of an element in the CalDAV namespace.
+func Cal(local string) xml.Name { return xml.Name{Space: NSCalDAV, Local: local} }
+
+// CS returns the name of an element in the CalendarServer namespace.
+func CS(local string) xml.Name { return xml.Name{Space: NSCalendarServer, Local: local} }
+
+// ErrBadRequest is returned for request bodies that are not valid XML or
+// miss required elements. The wrapped message says why.
+var ErrBadRequest = errors.New("bad request body")
+
+// Element is a parsed XML element. Text holds its character data with
+// surrounding white space removed.
+type Element struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr
+	Children []*Element
+	Text     string
+}
+
+// ParseXML reads an XML document. An empty body gives a nil element.
+func ParseXML(r io.Reader) (*Element, error) {
+	dec := xml.NewDecoder(r)
+	var root *Element
+	var stack []*Element
+	var text []strings.Builder
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrBadRequest, err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			e := &Element{XMLName: t.Name, Attrs: t.Attr}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, e)
+			} else if root == nil {
+				root = e
+			}
+			stack = append(stack, e)
+			text = append(text, strings.Builder{})
+		case xml.EndElement:
+			e := stack[len(stack)-1]
+			e.Text = strings.TrimSpace(text[len(text)-1].String())
+			stack, text = stack[:len(stack)-1], text[:len(text)-1]
+		case xml.CharData:
+			if len(text) > 0 {
+				text[len(text)-1].Write(t)
+			}
+		}
+	}
+	return root, nil
+}
+
+// Child returns the first child with the given name, or nil.
+func (e *Element) Child(name xml.Name) *Element {
+	if e == nil {
+		return nil
+	}
+	for _, c := range e.Children {
+		if c.XMLName == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// ChildrenNamed returns the children with the given name.
+func (e *Element) ChildrenNamed(name xml.Name) []*Element {
+	if e == nil {
+		return nil
+	}
+	var out []*Element
+	for _, c := range e.Children {
+		if c.XMLName == name {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// Attr returns the value of an attribute without a namespace, or "".
+func (e *Element) Attr(local string) string {
+	for _, a := range e.Attrs {
+		if a.Name.Space == "" && a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// PropRequest says which properties a PROPFIND or REPORT asks for: all of
+// them, only their names, or the ones listed in Names.
+type PropRequest struct {
+	AllProp  bool
+	PropName bool
+	Names    []xml.Name
+}
+
+// ParsePropFind reads a PROPFIND body. An empty body asks for all
+// properties.
+func ParsePropFind(root *Element) (PropRequest, error) {
+	if root == nil {
+		return PropRequest{AllProp: true}, nil
+	}
+	if root.XMLName != DAV("propfind") {
+		return PropRequest{}, fmt.Errorf("%w: expected propfind", ErrBadRequest)
+	}
+	return parsePropRequest(root), nil
+}
+
+func parsePropRequest(parent *Element) PropRequest {
+	if parent.Child(DAV("propname")) != nil {
+		return PropRequest{PropName: true}
+	}
+	prop := parent.Child(DAV("prop"))
+	if prop == nil {
+		return PropRequest{AllProp: true}
+	}
+	req := PropRequest{}
+	for _, c := range prop.Children {
+		req.Names = append(req.Names, c.XMLName)
+	}
+	return req
+}
+
+// Report is a parsed REPORT body. Kind is the name of its root element and
+// says which of the other fields are set: Filter for calendar-query, Hrefs
+// for calendar-multiget and the Sync fields for sync-collection. Limit is
+// the number of results the client asked for at most, or 0.
+type Report struct {
+	Kind      xml.Name
+	Props     PropRequest
+	Filter    CompFilter
+	Hrefs     []string
+	SyncToken string
+	SyncLevel string
+	Limit     int
+}
+
+// ParseReport reads a REPORT body.
+func ParseReport(root *Element) (Report, error) {
+	if root == nil {
+		return Report{}, fmt.Errorf("%w: empty report", ErrBadRequest)
+	}
+	r := Report{Kind: root.XMLName, Props: parsePropRequest(root)}
+	switch root.XMLName {
+	case Cal("calendar-query"):
+		filter := root.Child(Cal("filter")).Child(Cal("comp-filter"))
+		if filter == nil {
+			return Report{}, fmt.Errorf("%w: calendar-query needs a comp-filter", ErrBadRequest)
+		}
+		f, err := parseCompFilter(filter)
+		if err != nil {
+			return Report{}, err
+		}
+		r.Filter = f
+	case Cal("calendar-multiget"):
+		for _, h := range root.ChildrenNamed(DAV("href")) {
+			r.Hrefs = append(r.Hrefs, h.Text)
+		}
+	case DAV("sync-collection"):
+		if t := root.Child(DAV("sync-token")); t != nil {
+			r.SyncToken = t.Text
+		}
+		r.SyncLevel = "1"
+		if l := root.Child(DAV("sync-level")); l != nil {
+			r.SyncLevel = l.Text
+		}
+		if n := root.Child(DAV("limit")).Child(DAV("nresults")); n != nil {
+			limit, err := strconv.Atoi(n.Text)
+			if err != nil || limit < 1 {
+				return Report{}, fmt.Errorf("%w: bad nresults", ErrBadRequest)
+			}
+			r.Limit = limit
+		}
+	}
+	return r, nil
+}
+
+// Prop is a property of a resource. Value is its content as XML, already
+// escaped; it may use the d, c and cs prefixes for the DAV, CalDAV and
+// CalendarServer namespaces.
+type Prop struct {
+	Name  xml.Name
+	Value string
+}
+
+// Text returns s escaped for use as a Prop value.
+func Text(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// Href returns a DAV:href element for a Prop value.
+func Href(href string) string {
+	return "<d:href>" + Text(href) + "</d:href>"
+}
+
+// Select picks the properties req asks for from all of a resource's
+// properties, and lists the asked-for ones the resource does not have.
+// With PropName set the values are left empty.
+func Select(all []Prop, req PropRequest) (found []Prop, missing []xml.Name) {
+	switch {
+	case req.AllProp:
+		return all, nil
+	case req.PropName:
+		for _, p := range all {
+			found = append(found, Prop{Name: p.Name})
+		}
+		return found, nil
+	}
+	for _, name := range req.Names {
+		ok := false
+		for _, p := range all {
+			if p.Name == name {
+				found = append(found, p)
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			missing = append(missing, name)
+		}
+	}
+	return found, missing
+}
+
+// Response is one resource in a multistatus body. A non-zero Status
+// reports the resource as a whole, such as 404 for a deleted one, and
+// leaves out the properties.
+type Response struct {
+	Href    string
+	Status  int
+	Props   []Prop
+	Missing []xml.Name
+}
+
+// Multistatus is a 207 Multi-Status response body. SyncToken is only set
+// for sync-collection reports.
+type Multistatus struct {
+	Responses []Response
+	SyncToken string
+}
+
+// Encode returns the body as XML.
+func (m Multistatus) Encode() []byte {
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	b.WriteString("<d:multistatus" + nsDecls() + ">")
+	for _, r := range m.Responses {
+		b.WriteString("<d:response>")
+		b.WriteString(Href(r.Href))
+		if r.Status != 0 {
+			b.WriteString("<d:status>" + statusLine(r.Status) + "</d:status>")
+			b.WriteString("</d:response>")
+			continue
+		}
+		if len(r.Props) > 0 || len(r.Missing) == 0 {
+			b.WriteString("<d:propstat><d:prop>")
+			for _, p := range r.Props {
+				writeElement(&b, p.Name, p.Value)
+			}
+			b.WriteString("</d:prop><d:status>" + statusLine(http.StatusOK) + "</d:status></d:propstat>")
+		}
+		if len(r.Missing) > 0 {
+			b.WriteString("<d:propstat><d:prop>")
+			for _, name := range r.Missing {
+				writeElement(&b, name, "")
+			}
+			b.WriteString("</d:prop><d:status>" + statusLine(http.StatusNotFound) + "</d:status></d:propstat>")
+		}
+		b.WriteString("</d:response>")
+	}
+	if m.SyncToken != "" {
+		b.WriteString("<d:sync-token>" + Text(m.SyncToken) + "</d:sync-token>")
+	}
+	b.WriteString("</d:multistatus>")
+	return b.Bytes()
+}
+
+// ErrorBody returns a DAV:error body naming the precondition a request
+// failed, such as DAV:valid-sync-token.
+func ErrorBody(condition xml.Name) []byte {
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	b.WriteString("<d:error" + nsDecls() + ">")
+	writeElement(&b, condition, "")
+	b.WriteString("</d:error>")
+	return b.Bytes()
+}
+
+func nsDecls() string {
+	return ` xmlns:d="` + NSDAV + `" xmlns:c="` + NSCalDAV + `" xmlns:cs="` + NSCalendarServer + `"`
+}
+
+// writeElement writes an element with the given content. Names from other
+// namespaces declare theirs on the element itself.
+func writeElement(b *bytes.Buffer, name xml.Name, value string) {
+	tag, decl := name.Local, ""
+	if prefix, ok := prefixes[name.Space]; ok {
+		tag = prefix + ":" + name.Local
+	} else if name.Space != "" {
+		tag = "x:" + name.Local
+		decl = ` xmlns:x="` + Text(name.Space) + `"`
+	}
+	if value == "" {
+		b.WriteString("<" + tag + decl + "/>")
+		return
+	}
+	b.WriteString("<" + tag + decl + ">" + value + "</" + tag + ">")
+}
+
+func statusLine(code int) string {
+	return "HTTP/1.1 " + strconv.Itoa(code) + " " + http.StatusText(code)
+}
@@ -0,0 +1,258 @@
+package caldav
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pavelc4/auriya-todolist-go/internal/ical"
+)
+
+// CompFilter is a CALDAV:comp-filter. It matches a component with its
+// name that passes the time range and all property and child filters, or,
+// with IsNotDefined, the absence of such a component.
+type CompFilter struct {
+	Name         string
+	IsNotDefined bool
+	TimeRange    *TimeRange
+	Props        []PropFilter
+	Comps        []CompFilter
+}
+
+// PropFilter is a CALDAV:prop-filter. Parameter filters are not
+// supported and always match.
+type PropFilter struct {
+	Name         string
+	IsNotDefined bool
+	TimeRange    *TimeRange
+	TextMatch    *TextMatch
+}
+
+// TextMatch is a CALDAV:text-match: a substring test, case-insensitive for
+// ASCII unless the collation is i;octet.
+type TextMatch struct {
+	Text      string
+	Negate    bool
+	Collation string
+}
+
+// TimeRange is a CALDAV:time-range. A zero Start or End leaves that side
+// open.
+type TimeRange struct {
+	Start time.Time
+	End   time.Time
+}
+
+func parseCompFilter(e *Element) (CompFilter, error) {
+	f := CompFilter{Name: strings.ToUpper(e.Attr("name"))}
+	if f.Name == "" {
+		return f, fmt.Errorf("%w: comp-filter needs a name", ErrBadRequest)
+	}
+	for _, c := range e.Children {
+		switch c.XMLName {
+		case Cal("is-not-defined"):
+			f.IsNotDefined = true
+		case Cal("time-range"):
+			tr, err := parseTimeRange(c)
+			if err != nil {
+				return f, err
+			}
+			f.TimeRange = tr
+		case Cal("prop-filter"):
+			pf, err := parsePropFilter(c)
+			if err != nil {
+				return f, err
+			}
+			f.Props = append(f.Props, pf)
+		case Cal("comp-filter"):
+			cf, err := parseCompFilter(c)
+			if err != nil {
+				return f, err
+			}
+			f.Comps = append(f.Comps, cf)
+		}
+	}
+	return f, nil
+}
+
+func parsePropFilter(e *Element) (PropFilter, error) {
+	f := PropFilter{Name: strings.ToUpper(e.Attr("name"))}
+	if f.Name == "" {
+		return f, fmt.Errorf("%w: prop-filter needs a name", ErrBadRequest)
+	}
+	for _, c := range e.Children {
+		switch c.XMLName {
+		case Cal("is-not-defined"):
+			f.IsNotDefined = true
+		case Cal("time-range"):
+			tr, err := parseTimeRange(c)
+			if err != nil {
+				return f, err
+			}
+			f.TimeRange = tr
+		case Cal("text-match"):
+			f.TextMatch = &TextMatch{
+				Text:      c.Text,
+				Negate:    c.Attr("negate-condition") == "yes",
+				Collation: c.Attr("collation"),
+			}
+		}
+	}
+	return f, nil
+}
+
+func parseTimeRange(e *Element) (*TimeRange, error) {
+	var tr TimeRange
+	for attr, t := range map[string]*time.Time{"start": &tr.Start, "end": &tr.End} {
+		v := e.Attr(attr)
+		if v == "" {
+			continue
+		}
+		parsed, err := time.Parse("20060102T150405Z", v)
+		if err != nil {
+			return nil, fmt.Errorf("%w: bad time-range %s", ErrBadRequest, attr)
+		}
+		*t = parsed
+	}
+	if tr.Start.IsZero() && tr.End.IsZero() {
+		return nil, fmt.Errorf("%w: time-range needs a start or an end", ErrBadRequest)
+	}
+	return &tr, nil
+}
+
+// Match reports whether the filter matches c, which must be the component
+// the filter names; for a calendar-query that is the VCALENDAR.
+//
+// Time ranges are tested against the component's own dates. Recurrence
+// rules are not expanded, so a repeating to-do only matches a range that
+// covers its first occurrence.
+func (f CompFilter) Match(c *ical.Component) bool {
+	if c.Name != f.Name {
+		return false
+	}
+	return f.matchSelf(c)
+}
+
+func (f CompFilter) matchSelf(c *ical.Component) bool {
+	if f.TimeRange != nil && !f.TimeRange.overlaps(c) {
+		return false
+	}
+	for _, pf := range f.Props {
+		if !pf.match(c) {
+			return false
+		}
+	}
+	for _, cf := range f.Comps {
+		children := c.Children(cf.Name)
+		if cf.IsNotDefined {
+			if len(children) > 0 {
+				return false
+			}
+			continue
+		}
+		ok := false
+		for _, child := range children {
+			if cf.matchSelf(child) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (f PropFilter) match(c *ical.Component) bool {
+	props := c.PropsNamed(f.Name)
+	if f.IsNotDefined {
+		return len(props) == 0
+	}
+	for _, p := range props {
+		if f.TimeRange != nil {
+			t, _, err := ical.ParseDateTime(p)
+			if err != nil || !f.TimeRange.contains(t) {
+				continue
+			}
+		}
+		if f.TextMatch != nil && !f.TextMatch.match(ical.ParseText(p.Value)) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+func (m TextMatch) match(s string) bool {
+	text := m.Text
+	if m.Collation != "i;octet" {
+		s, text = asciiLower(s), asciiLower(text)
+	}
+	return strings.Contains(s, text) != m.Negate
+}
+
+func asciiLower(s string) string {
+	return strings.Map(func(r rune) rune {
+		if 'A' <= r && r <= 'Z' {
+			return r + 'a' - 'A'
+		}
+		return r
+	}, s)
+}
+
+// contains reports whether the range holds the instant t.
+func (tr TimeRange) contains(t time.Time) bool {
+	return tr.startsBy(t) && tr.endsAfter(t)
+}
+
+// The comparisons below are the ones RFC 4791 uses, with start and end
+// the bounds of the range; an open side always passes.
+
+// startsBy reports start <= t.
+func (tr TimeRange) startsBy(t time.Time) bool { return tr.Start.IsZero() || !tr.Start.After(t) }
+
+// startsBefore reports start < t.
+func (tr TimeRange) startsBefore(t time.Time) bool { return tr.Start.IsZero() || tr.Start.Before(t) }
+
+// endsAfter reports end > t.
+func (tr TimeRange) endsAfter(t time.Time) bool { return tr.End.IsZero() || tr.End.After(t) }
+
+// endsBy reports end >= t.
+func (tr TimeRange) endsBy(t time.Time) bool { return tr.End.IsZero() || !tr.End.Before(t) }
+
+// overlaps applies the rules of RFC 4791 section 9.9 for VTODO components.
+// Other components have no dates of their own that matter here and always
+// overlap.
+func (tr TimeRange) overlaps(c *ical.Component) bool {
+	if c.Name != "VTODO" {
+		return true
+	}
+	date := func(name string) (time.Time, bool) {
+		p := c.Prop(name)
+		if p == nil {
+			return time.Time{}, false
+		}
+		t, _, err := ical.ParseDateTime(*p)
+		return t, err == nil
+	}
+	start, hasStart := date("DTSTART")
+	due, hasDue := date("DUE")
+	completed, hasCompleted := date("COMPLETED")
+	created, hasCreated := date("CREATED")
+	switch {
+	case hasStart && hasDue:
+		return (tr.startsBefore(due) || tr.startsBy(start)) && (tr.endsAfter(start) || tr.endsBy(due))
+	case hasStart:
+		return tr.startsBy(start) && tr.endsAfter(start)
+	case hasDue:
+		return tr.startsBefore(due) && tr.endsBy(due)
+	case hasCompleted && hasCreated:
+		return (tr.startsBy(created) || tr.startsBy(completed)) && (tr.endsBy(created) || tr.endsBy(completed))
+	case hasCompleted:
+		return tr.startsBy(completed) && tr.endsBy(completed)
+	case hasCreated:
+		return tr.endsAfter(created)
+	}
+	return true
+}
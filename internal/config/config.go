@@ -2,7 +2,9 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 	"golang.org/x/oauth2"
@@ -16,6 +18,59 @@ type Config struct {
 	DatabaseURL       string
 	AppPort           int
 	AppEnv            string
+
+	// TrashRetention is how long soft-deleted tasks and projects are kept
+	// before the purge job removes them for good.
+	TrashRetention     time.Duration
+	TrashPurgeInterval time.Duration
+
+	// UndoWindow is how long after a task change it can still be undone.
+	UndoWindow time.Duration
+
+	// TimerAutoStop is how long a timer may run before the auto-stop job
+	// ends it.
+	TimerAutoStop         time.Duration
+	TimerAutoStopInterval time.Duration
+
+	// IdempotencyRetention is how long responses to requests made with an
+	// Idempotency-Key are kept for replay.
+	IdempotencyRetention     time.Duration
+	IdempotencyPurgeInterval time.Duration
+
+	// SyncTombstoneRetention is how long deletes are kept for incremental
+	// sync. Clients whose sync token is older must resync from scratch.
+	SyncTombstoneRetention     time.Duration
+	SyncTombstonePurgeInterval time.Duration
+
+	// WebhookTimeout is how long a webhook receiver has to answer a
+	// delivery. Finished deliveries are kept in the delivery log for
+	// WebhookLogRetention.
+	WebhookTimeout          time.Duration
+	WebhookDispatchInterval time.Duration
+	WebhookLogRetention     time.Duration
+	// WebhookAllowPrivate lets webhooks reach loopback, private and
+	// link-local addresses, for testing against a local receiver. It must
+	// stay off wherever users are not trusted with the server's network.
+	WebhookAllowPrivate bool
+
+	// OutboxRelayInterval is how often the outbox relay looks for new
+	// events. Published events, and those given up on, are kept for
+	// OutboxRetention.
+	OutboxRelayInterval time.Duration
+	OutboxRetention     time.Duration
+	// OutboxNATSURL is a NATS-compatible broker that events are published
+	// to, on subjects under OutboxNATSPrefix. Empty leaves it out.
+	OutboxNATSURL    string
+	OutboxNATSPrefix string
+
+	// ExportAsyncThreshold is the number of tasks above which data exports
+	// are written by the export worker instead of in the request. The files
+	// go to ExportDir, which instances must share, and are deleted after
+	// ExportRetention.
+	ExportAsyncThreshold int64
+	ExportDir            string
+	ExportRetention      time.Duration
+	ExportWorkerInterval time.Duration
 }
 
 func Load() (*Config, error) {
@@ -32,6 +87,35 @@ func Load() (*Config, error) {
 		DatabaseURL: os.Getenv("DATABASE_URL"),
 		AppPort:     port,
 		AppEnv:      os.Getenv("APP_ENV"),
+
+		TrashRetention:     durationEnv("TRASH_RETENTION", 30*24*time.Hour),
+		TrashPurgeInterval: durationEnv("TRASH_PURGE_INTERVAL", time.Hour),
+		UndoWindow:         durationEnv("UNDO_WINDOW", 10*time.Minute),
+
+		TimerAutoStop:         durationEnv("TIMER_AUTO_STOP", 12*time.Hour),
+		TimerAutoStopInterval: durationEnv("TIMER_AUTO_STOP_INTERVAL", 5*time.Minute),
+
+		IdempotencyRetention:     durationEnv("IDEMPOTENCY_RETENTION", 24*time.Hour),
+		IdempotencyPurgeInterval: durationEnv("IDEMPOTENCY_PURGE_INTERVAL", time.Hour),
+
+		SyncTombstoneRetention:     durationEnv("SYNC_TOMBSTONE_RETENTION", 90*24*time.Hour),
+		SyncTombstonePurgeInterval: durationEnv("SYNC_TOMBSTONE_PURGE_INTERVAL", time.Hour),
+
+		WebhookTimeout:          durationEnv("WEBHOOK_TIMEOUT", 10*time.Second),
+		WebhookDispatchInterval: durationEnv("WEBHOOK_DISPATCH_INTERVAL", 2*time.Second),
+		WebhookLogRetention:     durationEnv("WEBHOOK_LOG_RETENTION", 30*24*time.Hour),
+		WebhookAllowPrivate:     boolEnv("WEBHOOK_ALLOW_PRIVATE", false),
+
+		OutboxRelayInterval: durationEnv("OUTBOX_RELAY_INTERVAL", time.Second),
+		OutboxRetention:     durationEnv("OUTBOX_RETENTION", 7*24*time.Hour),
+		OutboxNATSURL:       os.Getenv("OUTBOX_NATS_URL"),
+		OutboxNATSPrefix:    stringEnv("OUTBOX_NATS_PREFIX", "auriya.events"),
+
+		ExportAsyncThreshold: int64Env("EXPORT_ASYNC_THRESHOLD", 5000),
+		ExportDir:            stringEnv("EXPORT_DIR", filepath.Join(os.TempDir(), "auriya-exports")),
+		ExportRetention:      durationEnv("EXPORT_RETENTION", 24*time.Hour),
+		ExportWorkerInterval: durationEnv("EXPORT_WORKER_INTERVAL", 5*time.Second),
+
 		GoogleOAuthConfig: &oauth2.Config{
 			ClientID:     os.Getenv("GOOGLE_CLIENT_ID"),
 			ClientSecret: os.Getenv("GOOGLE_CLIENT_SECRET"),
@@ -50,3 +134,45 @@ func Load() (*Config, error) {
 
 	return cfg, nil
 }
+
+// stringEnv reads a string from the environment, falling back to def when
+// it is unset or empty.
+func stringEnv(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// int64Env reads a non-negative integer from the environment, falling back
+// to def when it is unset or invalid.
+func int64Env(key string, def int64) int64 {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n >= 0 {
+			return n
+		}
+	}
+	return def
+}
+
+// boolEnv reads a strconv.ParseBool value from the environment, falling
+// back to def when it is unset or invalid.
+func boolEnv(key string, def bool) bool {
+	if v := os.Getenv(key); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return def
+}
+
+// durationEnv reads a time.ParseDuration value (e.g. "720h") from the
+// environment, falling back to def when it is unset or invalid.
+func durationEnv(key string, def time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+	}
+	return def
+}
@@ -8,25 +8,198 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type CaldavObject struct {
+	TaskID    int64              `json:"task_id"`
+	UserID    int64              `json:"user_id"`
+	Name      string             `json:"name"`
+	Uid       string             `json:"uid"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type CalendarFeed struct {
+	UserID     int64              `json:"user_id"`
+	TokenHash  []byte             `json:"token_hash"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	LastUsedAt pgtype.Timestamptz `json:"last_used_at"`
+}
+
+type ChecklistItem struct {
+	ID        int64              `json:"id"`
+	TaskID    int64              `json:"task_id"`
+	UserID    int64              `json:"user_id"`
+	Text      string             `json:"text"`
+	Checked   bool               `json:"checked"`
+	Position  string             `json:"position"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+}
+
+type Export struct {
+	ID          int64              `json:"id"`
+	UserID      int64              `json:"user_id"`
+	Format      string             `json:"format"`
+	ProjectIds  []int64            `json:"project_ids"`
+	Inbox       bool               `json:"inbox"`
+	Status      string             `json:"status"`
+	FilePath    pgtype.Text        `json:"file_path"`
+	SizeBytes   pgtype.Int8        `json:"size_bytes"`
+	Error       pgtype.Text        `json:"error"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	StartedAt   pgtype.Timestamptz `json:"started_at"`
+	LeaseUntil  pgtype.Timestamptz `json:"lease_until"`
+	CompletedAt pgtype.Timestamptz `json:"completed_at"`
+	ExpiresAt   pgtype.Timestamptz `json:"expires_at"`
+}
+
+type IdempotencyKey struct {
+	UserID          int64              `json:"user_id"`
+	Key             string             `json:"key"`
+	RequestHash     []byte             `json:"request_hash"`
+	StatusCode      pgtype.Int4        `json:"status_code"`
+	ContentType     pgtype.Text        `json:"content_type"`
+	ResponseBody    []byte             `json:"response_body"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	CompletedAt     pgtype.Timestamptz `json:"completed_at"`
+	ResponseHeaders []byte             `json:"response_headers"`
+}
+
+type ImportRecord struct {
+	UserID     int64              `json:"user_id"`
+	Source     string             `json:"source"`
+	ExternalID string             `json:"external_id"`
+	ProjectID  pgtype.Int8        `json:"project_id"`
+	TaskID     pgtype.Int8        `json:"task_id"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+}
+
+type Outbox struct {
+	ID             int64              `json:"id"`
+	EventID        pgtype.UUID        `json:"event_id"`
+	EventType      string             `json:"event_type"`
+	AggregateType  string             `json:"aggregate_type"`
+	AggregateID    int64              `json:"aggregate_id"`
+	UserID         int64              `json:"user_id"`
+	ProjectID      pgtype.Int8        `json:"project_id"`
+	Payload        []byte             `json:"payload"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+	PublishedAt    pgtype.Timestamptz `json:"published_at"`
+	PublishedSinks []string           `json:"published_sinks"`
+	Attempts       int32              `json:"attempts"`
+	NextAttemptAt  pgtype.Timestamptz `json:"next_attempt_at"`
+	LastError      pgtype.Text        `json:"last_error"`
+	DeadAt         pgtype.Timestamptz `json:"dead_at"`
+}
+
+type PersonalToken struct {
+	ID         int64              `json:"id"`
+	UserID     int64              `json:"user_id"`
+	Name       string             `json:"name"`
+	TokenHash  []byte             `json:"token_hash"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	LastUsedAt pgtype.Timestamptz `json:"last_used_at"`
+}
+
 type Project struct {
 	ID        int64              `json:"id"`
 	UserID    int64              `json:"user_id"`
 	Name      string             `json:"name"`
 	CreatedAt pgtype.Timestamptz `json:"created_at"`
 	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+	DeletedAt pgtype.Timestamptz `json:"deleted_at"`
+	Version   int64              `json:"version"`
+	ChangeSeq int64              `json:"change_seq"`
+}
+
+type ProjectSection struct {
+	ID        int64              `json:"id"`
+	ProjectID int64              `json:"project_id"`
+	UserID    int64              `json:"user_id"`
+	Name      string             `json:"name"`
+	Position  string             `json:"position"`
+	Status    pgtype.Text        `json:"status"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+}
+
+type ProjectStatus struct {
+	ProjectID int64  `json:"project_id"`
+	Key       string `json:"key"`
+	Name      string `json:"name"`
+	Category  string `json:"category"`
+	Position  int32  `json:"position"`
+	IsDefault bool   `json:"is_default"`
+}
+
+type ProjectStatusTransition struct {
+	ProjectID  int64  `json:"project_id"`
+	FromStatus string `json:"from_status"`
+	ToStatus   string `json:"to_status"`
+}
+
+type SavedFilter struct {
+	ID         int64              `json:"id"`
+	UserID     int64              `json:"user_id"`
+	Name       string             `json:"name"`
+	Definition []byte             `json:"definition"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt  pgtype.Timestamptz `json:"updated_at"`
+}
+
+type SyncTombstone struct {
+	ChangeSeq  int64              `json:"change_seq"`
+	UserID     int64              `json:"user_id"`
+	EntityType string             `json:"entity_type"`
+	EntityID   int64              `json:"entity_id"`
+	DeletedAt  pgtype.Timestamptz `json:"deleted_at"`
 }
 
 type Task struct {
+	ID               int64              `json:"id"`
+	UserID           int64              `json:"user_id"`
+	Title            string             `json:"title"`
+	Description      *string            `json:"description"`
+	Status           string             `json:"status"`
+	Priority         int32              `json:"priority"`
+	DueDate          pgtype.Timestamptz `json:"due_date"`
+	CreatedAt        pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt        pgtype.Timestamptz `json:"updated_at"`
+	ProjectID        pgtype.Int8        `json:"project_id"`
+	DeletedAt        pgtype.Timestamptz `json:"deleted_at"`
+	Position         string             `json:"position"`
+	SectionID        pgtype.Int8        `json:"section_id"`
+	StatusCategory   string             `json:"status_category"`
+	EstimateSeconds  pgtype.Int4        `json:"estimate_seconds"`
+	TimeSpentSeconds int64              `json:"time_spent_seconds"`
+	ChecklistTotal   int32              `json:"checklist_total"`
+	ChecklistChecked int32              `json:"checklist_checked"`
+	Tags             []string           `json:"tags"`
+	Recurrence       *string            `json:"recurrence"`
+	Version          int64              `json:"version"`
+	ChangeSeq        int64              `json:"change_seq"`
+}
+
+type TaskEvent struct {
+	ID             int64              `json:"id"`
+	TaskID         int64              `json:"task_id"`
+	UserID         int64              `json:"user_id"`
+	ActorID        pgtype.Int8        `json:"actor_id"`
+	Action         string             `json:"action"`
+	Changes        []byte             `json:"changes"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+	UndoneAt       pgtype.Timestamptz `json:"undone_at"`
+	RevertsEventID pgtype.Int8        `json:"reverts_event_id"`
+}
+
+type TimeEntry struct {
 	ID          int64              `json:"id"`
+	TaskID      int64              `json:"task_id"`
 	UserID      int64              `json:"user_id"`
-	Title       string             `json:"title"`
-	Description *string            `json:"description"`
-	Status      string             `json:"status"`
-	Priority    int32              `json:"priority"`
-	DueDate     pgtype.Timestamptz `json:"due_date"`
+	StartedAt   pgtype.Timestamptz `json:"started_at"`
+	EndedAt     pgtype.Timestamptz `json:"ended_at"`
+	Note        *string            `json:"note"`
+	AutoStopped bool               `json:"auto_stopped"`
 	CreatedAt   pgtype.Timestamptz `json:"created_at"`
 	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
-	ProjectID   pgtype.Int8        `json:"project_id"`
 }
 
 type User struct {
@@ -42,3 +215,42 @@ type User struct {
 	UpdatedAt      pgtype.Timestamptz `json:"updated_at"`
 	LastLogin      pgtype.Timestamptz `json:"last_login"`
 }
+
+type Webhook struct {
+	ID           int64              `json:"id"`
+	UserID       int64              `json:"user_id"`
+	ProjectID    pgtype.Int8        `json:"project_id"`
+	Url          string             `json:"url"`
+	Secret       string             `json:"secret"`
+	EventTypes   []string           `json:"event_types"`
+	Active       bool               `json:"active"`
+	FailureCount int32              `json:"failure_count"`
+	DisabledAt   pgtype.Timestamptz `json:"disabled_at"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt    pgtype.Timestamptz `json:"updated_at"`
+}
+
+type WebhookAttempt struct {
+	ID          int64              `json:"id"`
+	DeliveryID  int64              `json:"delivery_id"`
+	StatusCode  pgtype.Int4        `json:"status_code"`
+	Error       pgtype.Text        `json:"error"`
+	DurationMs  int32              `json:"duration_ms"`
+	AttemptedAt pgtype.Timestamptz `json:"attempted_at"`
+}
+
+type WebhookDelivery struct {
+	ID             int64              `json:"id"`
+	WebhookID      int64              `json:"webhook_id"`
+	EventType      string             `json:"event_type"`
+	ResourceType   string             `json:"resource_type"`
+	Payload        []byte             `json:"payload"`
+	Status         string             `json:"status"`
+	Attempts       int32              `json:"attempts"`
+	NextAttemptAt  pgtype.Timestamptz `json:"next_attempt_at"`
+	LastStatusCode pgtype.Int4        `json:"last_status_code"`
+	LastError      pgtype.Text        `json:"last_error"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+	CompletedAt    pgtype.Timestamptz `json:"completed_at"`
+	EventID        pgtype.UUID        `json:"event_id"`
+}
@@ -7,6 +7,8 @@ package db
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const createProject = `-- name: CreateProject :one
@@ -15,7 +17,7 @@ INSERT INTO projects (
   name
 ) VALUES (
   $1, $2
-) RETURNING id, user_id, name, created_at, updated_at
+) RETURNING id, user_id, name, created_at, updated_at, deleted_at, version, change_seq
 `
 
 type CreateProjectParams struct {
@@ -32,13 +34,18 @@ func (q *Queries) CreateProject(ctx context.Context, arg CreateProjectParams) (P
 		&i.Name,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.Version,
+		&i.ChangeSeq,
 	)
 	return i, err
 }
 
-const deleteProject = `-- name: DeleteProject :exec
-DELETE FROM projects
-WHERE id = $1 AND user_id = $2
+const deleteProject = `-- name: DeleteProject :one
+UPDATE projects
+SET deleted_at = now()
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+RETURNING id, user_id, name, created_at, updated_at, deleted_at, version, change_seq
 `
 
 type DeleteProjectParams struct {
@@ -46,14 +53,37 @@ type DeleteProjectParams struct {
 	UserID int64 `json:"user_id"`
 }
 
-func (q *Queries) DeleteProject(ctx context.Context, arg DeleteProjectParams) error {
-	_, err := q.db.Exec(ctx, deleteProject, arg.ID, arg.UserID)
-	return err
+func (q *Queries) DeleteProject(ctx context.Context, arg DeleteProjectParams) (Project, error) {
+	row := q.db.QueryRow(ctx, deleteProject, arg.ID, arg.UserID)
+	var i Project
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.Version,
+		&i.ChangeSeq,
+	)
+	return i, err
+}
+
+const emptyProjectTrash = `-- name: EmptyProjectTrash :execrows
+DELETE FROM projects WHERE user_id = $1 AND deleted_at IS NOT NULL
+`
+
+func (q *Queries) EmptyProjectTrash(ctx context.Context, userID int64) (int64, error) {
+	result, err := q.db.Exec(ctx, emptyProjectTrash, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
 const getProject = `-- name: GetProject :one
-SELECT id, user_id, name, created_at, updated_at FROM projects
-WHERE id = $1 AND user_id = $2
+SELECT id, user_id, name, created_at, updated_at, deleted_at, version, change_seq FROM projects
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
 LIMIT 1
 `
 
@@ -71,13 +101,70 @@ func (q *Queries) GetProject(ctx context.Context, arg GetProjectParams) (Project
 		&i.Name,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.Version,
+		&i.ChangeSeq,
+	)
+	return i, err
+}
+
+const getProjectForUpdate = `-- name: GetProjectForUpdate :one
+SELECT id, user_id, name, created_at, updated_at, deleted_at, version, change_seq FROM projects
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+FOR UPDATE
+`
+
+type GetProjectForUpdateParams struct {
+	ID     int64 `json:"id"`
+	UserID int64 `json:"user_id"`
+}
+
+func (q *Queries) GetProjectForUpdate(ctx context.Context, arg GetProjectForUpdateParams) (Project, error) {
+	row := q.db.QueryRow(ctx, getProjectForUpdate, arg.ID, arg.UserID)
+	var i Project
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.Version,
+		&i.ChangeSeq,
+	)
+	return i, err
+}
+
+const getTrashedProject = `-- name: GetTrashedProject :one
+SELECT id, user_id, name, created_at, updated_at, deleted_at, version, change_seq FROM projects
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL
+LIMIT 1
+`
+
+type GetTrashedProjectParams struct {
+	ID     int64 `json:"id"`
+	UserID int64 `json:"user_id"`
+}
+
+func (q *Queries) GetTrashedProject(ctx context.Context, arg GetTrashedProjectParams) (Project, error) {
+	row := q.db.QueryRow(ctx, getTrashedProject, arg.ID, arg.UserID)
+	var i Project
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.Version,
+		&i.ChangeSeq,
 	)
 	return i, err
 }
 
 const listProjects = `-- name: ListProjects :many
-SELECT id, user_id, name, created_at, updated_at FROM projects
-WHERE user_id = $1
+SELECT id, user_id, name, created_at, updated_at, deleted_at, version, change_seq FROM projects
+WHERE user_id = $1 AND deleted_at IS NULL
 ORDER BY created_at DESC
 `
 
@@ -96,6 +183,44 @@ func (q *Queries) ListProjects(ctx context.Context, userID int64) ([]Project, er
 			&i.Name,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.Version,
+			&i.ChangeSeq,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTrashedProjects = `-- name: ListTrashedProjects :many
+SELECT id, user_id, name, created_at, updated_at, deleted_at, version, change_seq FROM projects
+WHERE user_id = $1 AND deleted_at IS NOT NULL
+ORDER BY deleted_at DESC
+`
+
+func (q *Queries) ListTrashedProjects(ctx context.Context, userID int64) ([]Project, error) {
+	rows, err := q.db.Query(ctx, listTrashedProjects, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Project
+	for rows.Next() {
+		var i Project
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.Version,
+			&i.ChangeSeq,
 		); err != nil {
 			return nil, err
 		}
@@ -107,11 +232,74 @@ func (q *Queries) ListProjects(ctx context.Context, userID int64) ([]Project, er
 	return items, nil
 }
 
+const purgeExpiredProjects = `-- name: PurgeExpiredProjects :execrows
+DELETE FROM projects WHERE user_id = $1 AND deleted_at IS NOT NULL AND deleted_at < $2
+`
+
+type PurgeExpiredProjectsParams struct {
+	UserID    int64              `json:"user_id"`
+	DeletedAt pgtype.Timestamptz `json:"deleted_at"`
+}
+
+func (q *Queries) PurgeExpiredProjects(ctx context.Context, arg PurgeExpiredProjectsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, purgeExpiredProjects, arg.UserID, arg.DeletedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const purgeProject = `-- name: PurgeProject :execrows
+DELETE FROM projects
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL
+`
+
+type PurgeProjectParams struct {
+	ID     int64 `json:"id"`
+	UserID int64 `json:"user_id"`
+}
+
+func (q *Queries) PurgeProject(ctx context.Context, arg PurgeProjectParams) (int64, error) {
+	result, err := q.db.Exec(ctx, purgeProject, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const restoreProject = `-- name: RestoreProject :one
+UPDATE projects
+SET deleted_at = NULL, updated_at = now()
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL
+RETURNING id, user_id, name, created_at, updated_at, deleted_at, version, change_seq
+`
+
+type RestoreProjectParams struct {
+	ID     int64 `json:"id"`
+	UserID int64 `json:"user_id"`
+}
+
+func (q *Queries) RestoreProject(ctx context.Context, arg RestoreProjectParams) (Project, error) {
+	row := q.db.QueryRow(ctx, restoreProject, arg.ID, arg.UserID)
+	var i Project
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.Version,
+		&i.ChangeSeq,
+	)
+	return i, err
+}
+
 const updateProject = `-- name: UpdateProject :one
 UPDATE projects
 SET name = $3, updated_at = now()
-WHERE id = $1 AND user_id = $2
-RETURNING id, user_id, name, created_at, updated_at
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+RETURNING id, user_id, name, created_at, updated_at, deleted_at, version, change_seq
 `
 
 type UpdateProjectParams struct {
@@ -129,6 +317,9 @@ func (q *Queries) UpdateProject(ctx context.Context, arg UpdateProjectParams) (P
 		&i.Name,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.Version,
+		&i.ChangeSeq,
 	)
 	return i, err
 }
@@ -6,17 +6,159 @@ package db
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type Querier interface {
+	ClaimExport(ctx context.Context, leaseUntil pgtype.Timestamptz) (Export, error)
+	ClaimIdempotencyKey(ctx context.Context, arg ClaimIdempotencyKeyParams) (IdempotencyKey, error)
+	ClaimOutboxEvents(ctx context.Context, arg ClaimOutboxEventsParams) ([]Outbox, error)
+	ClaimWebhookDeliveries(ctx context.Context, arg ClaimWebhookDeliveriesParams) ([]WebhookDelivery, error)
+	CompleteIdempotencyKey(ctx context.Context, arg CompleteIdempotencyKeyParams) error
+	CountExportTasks(ctx context.Context, arg CountExportTasksParams) (int64, error)
+	CountWebhookFailure(ctx context.Context, arg CountWebhookFailureParams) (Webhook, error)
+	CreateChecklistItem(ctx context.Context, arg CreateChecklistItemParams) (ChecklistItem, error)
+	CreateExport(ctx context.Context, arg CreateExportParams) (Export, error)
+	CreateImportRecord(ctx context.Context, arg CreateImportRecordParams) (int64, error)
+	CreatePersonalToken(ctx context.Context, arg CreatePersonalTokenParams) (PersonalToken, error)
+	CreateProject(ctx context.Context, arg CreateProjectParams) (Project, error)
+	CreateProjectStatus(ctx context.Context, arg CreateProjectStatusParams) error
+	CreateProjectTransition(ctx context.Context, arg CreateProjectTransitionParams) error
+	CreateSavedFilter(ctx context.Context, arg CreateSavedFilterParams) (SavedFilter, error)
+	CreateSection(ctx context.Context, arg CreateSectionParams) (ProjectSection, error)
 	CreateTask(ctx context.Context, arg CreateTaskParams) (Task, error)
+	CreateTaskEvent(ctx context.Context, arg CreateTaskEventParams) (TaskEvent, error)
+	CreateTimeEntry(ctx context.Context, arg CreateTimeEntryParams) (TimeEntry, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
-	DeleteTask(ctx context.Context, arg DeleteTaskParams) error
+	CreateWebhook(ctx context.Context, arg CreateWebhookParams) (Webhook, error)
+	CreateWebhookDelivery(ctx context.Context, arg CreateWebhookDeliveryParams) (WebhookDelivery, error)
+	DeleteCaldavObjectByName(ctx context.Context, arg DeleteCaldavObjectByNameParams) error
+	DeleteCalendarFeed(ctx context.Context, userID int64) (int64, error)
+	DeleteChecklistItem(ctx context.Context, arg DeleteChecklistItemParams) (int64, error)
+	DeleteExpiredIdempotencyKeys(ctx context.Context, createdAt pgtype.Timestamptz) (int64, error)
+	DeleteExpiredOutboxEvents(ctx context.Context, before pgtype.Timestamptz) (int64, error)
+	DeleteExpiredSyncTombstones(ctx context.Context, before pgtype.Timestamptz) (int64, error)
+	DeleteExpiredWebhookDeliveries(ctx context.Context, completedAt pgtype.Timestamptz) (int64, error)
+	DeleteExport(ctx context.Context, id int64) error
+	DeleteOrphanedCaldavObjects(ctx context.Context) (int64, error)
+	DeletePersonalToken(ctx context.Context, arg DeletePersonalTokenParams) (int64, error)
+	DeleteProject(ctx context.Context, arg DeleteProjectParams) (Project, error)
+	DeleteProjectStatuses(ctx context.Context, projectID int64) error
+	DeleteSavedFilter(ctx context.Context, arg DeleteSavedFilterParams) (int64, error)
+	DeleteSection(ctx context.Context, arg DeleteSectionParams) (int64, error)
+	DeleteTask(ctx context.Context, arg DeleteTaskParams) (Task, error)
+	DeleteTimeEntry(ctx context.Context, arg DeleteTimeEntryParams) (TimeEntry, error)
+	DeleteWebhook(ctx context.Context, arg DeleteWebhookParams) (int64, error)
+	EmptyProjectTrash(ctx context.Context, userID int64) (int64, error)
+	EmptyTaskTrash(ctx context.Context, userID int64) (int64, error)
+	EnqueueWebhookEvent(ctx context.Context, arg EnqueueWebhookEventParams) (int64, error)
+	FilterTasks(ctx context.Context, arg FilterTasksParams) ([]Task, error)
+	FinishExport(ctx context.Context, arg FinishExportParams) error
+	FinishWebhookAttempt(ctx context.Context, arg FinishWebhookAttemptParams) (WebhookDelivery, error)
+	GetCaldavObjectByName(ctx context.Context, arg GetCaldavObjectByNameParams) (CaldavObject, error)
+	GetCalendarFeed(ctx context.Context, userID int64) (CalendarFeed, error)
+	GetChecklistItem(ctx context.Context, arg GetChecklistItemParams) (ChecklistItem, error)
+	GetExport(ctx context.Context, arg GetExportParams) (Export, error)
+	GetIdempotencyKey(ctx context.Context, arg GetIdempotencyKeyParams) (IdempotencyKey, error)
+	GetImportRecord(ctx context.Context, arg GetImportRecordParams) (ImportRecord, error)
+	GetLastTaskPosition(ctx context.Context, arg GetLastTaskPositionParams) (string, error)
+	GetLatestChangeSeq(ctx context.Context, userID int64) (int64, error)
+	GetLatestUndoableTaskEvent(ctx context.Context, arg GetLatestUndoableTaskEventParams) (TaskEvent, error)
+	GetNextTaskPosition(ctx context.Context, arg GetNextTaskPositionParams) (string, error)
+	GetPrevTaskPosition(ctx context.Context, arg GetPrevTaskPositionParams) (string, error)
+	GetProject(ctx context.Context, arg GetProjectParams) (Project, error)
+	GetProjectForUpdate(ctx context.Context, arg GetProjectForUpdateParams) (Project, error)
+	GetRunningTimeEntry(ctx context.Context, userID int64) (TimeEntry, error)
+	GetSavedFilter(ctx context.Context, arg GetSavedFilterParams) (SavedFilter, error)
+	GetSection(ctx context.Context, arg GetSectionParams) (ProjectSection, error)
 	GetTask(ctx context.Context, arg GetTaskParams) (Task, error)
+	GetTaskEvent(ctx context.Context, arg GetTaskEventParams) (TaskEvent, error)
+	GetTaskForUpdate(ctx context.Context, arg GetTaskForUpdateParams) (Task, error)
+	GetTimeEntry(ctx context.Context, arg GetTimeEntryParams) (TimeEntry, error)
+	GetTrashedProject(ctx context.Context, arg GetTrashedProjectParams) (Project, error)
+	GetTrashedTask(ctx context.Context, arg GetTrashedTaskParams) (Task, error)
 	GetUserByEmail(ctx context.Context, email string) (User, error)
 	GetUserByID(ctx context.Context, id int64) (User, error)
+	GetWebhook(ctx context.Context, arg GetWebhookParams) (Webhook, error)
+	GetWebhookByID(ctx context.Context, id int64) (Webhook, error)
+	GetWebhookDelivery(ctx context.Context, arg GetWebhookDeliveryParams) (WebhookDelivery, error)
+	ListCaldavObjects(ctx context.Context, arg ListCaldavObjectsParams) ([]CaldavObject, error)
+	ListChecklistItems(ctx context.Context, arg ListChecklistItemsParams) ([]ChecklistItem, error)
+	ListChecklistItemsByTasks(ctx context.Context, arg ListChecklistItemsByTasksParams) ([]ChecklistItem, error)
+	ListCollectionTasks(ctx context.Context, arg ListCollectionTasksParams) ([]Task, error)
+	ListExpiredExports(ctx context.Context, limit int32) ([]Export, error)
+	ListExpiredTrashUsers(ctx context.Context, before pgtype.Timestamptz) ([]int64, error)
+	ListExportTasks(ctx context.Context, arg ListExportTasksParams) ([]Task, error)
+	ListPersonalTokens(ctx context.Context, userID int64) ([]PersonalToken, error)
+	ListProjectChanges(ctx context.Context, arg ListProjectChangesParams) ([]Project, error)
+	ListProjectStatuses(ctx context.Context, projectID int64) ([]ProjectStatus, error)
+	ListProjectTaskStatuses(ctx context.Context, projectID pgtype.Int8) ([]string, error)
+	ListProjectTransitions(ctx context.Context, projectID int64) ([]ProjectStatusTransition, error)
+	ListProjects(ctx context.Context, userID int64) ([]Project, error)
+	ListSavedFilters(ctx context.Context, userID int64) ([]SavedFilter, error)
+	ListSections(ctx context.Context, arg ListSectionsParams) ([]ProjectSection, error)
+	ListStaleTimerUsers(ctx context.Context, maxSeconds int64) ([]int64, error)
+	ListSyncTombstones(ctx context.Context, arg ListSyncTombstonesParams) ([]SyncTombstone, error)
+	ListTaskChanges(ctx context.Context, arg ListTaskChangesParams) ([]Task, error)
+	ListTaskEvents(ctx context.Context, arg ListTaskEventsParams) ([]TaskEvent, error)
+	ListTaskIDsByPosition(ctx context.Context, arg ListTaskIDsByPositionParams) ([]int64, error)
 	ListTasks(ctx context.Context, arg ListTasksParams) ([]Task, error)
+	ListTasksByProject(ctx context.Context, arg ListTasksByProjectParams) ([]Task, error)
+	ListTimeEntries(ctx context.Context, arg ListTimeEntriesParams) ([]TimeEntry, error)
+	ListTrashedProjects(ctx context.Context, userID int64) ([]Project, error)
+	ListTrashedTasks(ctx context.Context, userID int64) ([]Task, error)
+	ListWebhookAttempts(ctx context.Context, deliveryID int64) ([]WebhookAttempt, error)
+	ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]WebhookDelivery, error)
+	ListWebhooks(ctx context.Context, userID int64) ([]Webhook, error)
+	LockSyncChanges(ctx context.Context, userID int64) error
+	LockTask(ctx context.Context, arg LockTaskParams) (Task, error)
+	LockTaskPositions(ctx context.Context, arg LockTaskPositionsParams) error
+	MarkOutboxDead(ctx context.Context, arg MarkOutboxDeadParams) error
+	MarkOutboxFailed(ctx context.Context, arg MarkOutboxFailedParams) error
+	MarkOutboxPublished(ctx context.Context, arg MarkOutboxPublishedParams) error
+	MarkTaskEventUndone(ctx context.Context, id int64) error
+	MoveTask(ctx context.Context, arg MoveTaskParams) (Task, error)
+	OutboxSinkBehind(ctx context.Context, arg OutboxSinkBehindParams) (bool, error)
+	PurgeExpiredProjects(ctx context.Context, arg PurgeExpiredProjectsParams) (int64, error)
+	PurgeExpiredTasks(ctx context.Context, arg PurgeExpiredTasksParams) (int64, error)
+	PurgeProject(ctx context.Context, arg PurgeProjectParams) (int64, error)
+	PurgeProjectTasks(ctx context.Context, arg PurgeProjectTasksParams) error
+	PurgeTask(ctx context.Context, arg PurgeTaskParams) (int64, error)
+	RecordWebhookAttempt(ctx context.Context, arg RecordWebhookAttemptParams) error
+	RedeliverWebhookDelivery(ctx context.Context, arg RedeliverWebhookDeliveryParams) (WebhookDelivery, error)
+	RefreshTaskChecklist(ctx context.Context, id int64) (Task, error)
+	RefreshTaskTimeSpent(ctx context.Context, id int64) error
+	ReleaseIdempotencyKey(ctx context.Context, arg ReleaseIdempotencyKeyParams) error
+	RemapProjectTaskStatus(ctx context.Context, arg RemapProjectTaskStatusParams) ([]Task, error)
+	ResetWebhookFailures(ctx context.Context, id int64) error
+	RestoreProject(ctx context.Context, arg RestoreProjectParams) (Project, error)
+	RestoreProjectTasks(ctx context.Context, arg RestoreProjectTasksParams) ([]Task, error)
+	RestoreTask(ctx context.Context, arg RestoreTaskParams) (Task, error)
+	SetCaldavObject(ctx context.Context, arg SetCaldavObjectParams) error
+	SetCalendarFeedToken(ctx context.Context, arg SetCalendarFeedTokenParams) (CalendarFeed, error)
+	SetChecklistItemPosition(ctx context.Context, arg SetChecklistItemPositionParams) error
+	SetSectionPosition(ctx context.Context, arg SetSectionPositionParams) error
+	SetTaskFields(ctx context.Context, arg SetTaskFieldsParams) (Task, error)
+	SetTaskPosition(ctx context.Context, arg SetTaskPositionParams) error
+	SetWebhookFields(ctx context.Context, arg SetWebhookFieldsParams) (Webhook, error)
+	SetWebhookSecret(ctx context.Context, arg SetWebhookSecretParams) (Webhook, error)
+	StartTimeEntry(ctx context.Context, arg StartTimeEntryParams) (TimeEntry, error)
+	StopStaleTimeEntries(ctx context.Context, arg StopStaleTimeEntriesParams) ([]TimeEntry, error)
+	StopTaskTimeEntries(ctx context.Context, taskID int64) (int64, error)
+	StopTimeEntry(ctx context.Context, id int64) (TimeEntry, error)
+	SyncProjectTaskCategories(ctx context.Context, projectID pgtype.Int8) ([]int64, error)
+	TimeReport(ctx context.Context, arg TimeReportParams) ([]TimeReportRow, error)
+	ToggleChecklistItem(ctx context.Context, arg ToggleChecklistItemParams) (ChecklistItem, error)
+	TrashProjectTasks(ctx context.Context, arg TrashProjectTasksParams) ([]Task, error)
+	UpdateChecklistItem(ctx context.Context, arg UpdateChecklistItemParams) (ChecklistItem, error)
+	UpdateProject(ctx context.Context, arg UpdateProjectParams) (Project, error)
+	UpdateSavedFilter(ctx context.Context, arg UpdateSavedFilterParams) (SavedFilter, error)
+	UpdateSection(ctx context.Context, arg UpdateSectionParams) (ProjectSection, error)
 	UpdateTask(ctx context.Context, arg UpdateTaskParams) (Task, error)
+	UpdateTimeEntry(ctx context.Context, arg UpdateTimeEntryParams) (TimeEntry, error)
+	UseCalendarFeedToken(ctx context.Context, tokenHash []byte) (CalendarFeed, error)
+	UsePersonalToken(ctx context.Context, tokenHash []byte) (PersonalToken, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -11,8 +11,34 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const countExportTasks = `-- name: CountExportTasks :one
+SELECT count(*) FROM tasks
+WHERE user_id = $1
+  AND deleted_at IS NULL
+  AND (
+    ($2::bigint[] IS NULL AND NOT $3::bool)
+    OR project_id = ANY($2::bigint[])
+    OR ($3::bool AND project_id IS NULL)
+  )
+`
+
+type CountExportTasksParams struct {
+	UserID     int64   `json:"user_id"`
+	ProjectIds []int64 `json:"project_ids"`
+	Inbox      bool    `json:"inbox"`
+}
+
+// CountExportTasks counts the live tasks an export with these filters would
+// hold. With no project_ids and inbox false every task is counted.
+func (q *Queries) CountExportTasks(ctx context.Context, arg CountExportTasksParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countExportTasks, arg.UserID, arg.ProjectIds, arg.Inbox)
+	var i int64
+	err := row.Scan(&i)
+	return i, err
+}
+
 const createTask = `-- name: CreateTask :one
-INSERT INTO tasks (title, description, status, priority, due_date, user_id, project_id)
+INSERT INTO tasks (title, description, status, priority, due_date, user_id, project_id, position, status_category, estimate_seconds, tags, recurrence)
 VALUES (
   $1,
   $2,
@@ -20,19 +46,29 @@ VALUES (
   COALESCE($4, 1),
   $5,
   $6,
-  $7
+  $7,
+  $8,
+  $9,
+  $10,
+  COALESCE($11::text[], '{}'),
+  $12
 )
-RETURNING id, user_id, title, description, status, priority, due_date, created_at, updated_at, project_id
+RETURNING id, user_id, title, description, status, priority, due_date, created_at, updated_at, project_id, deleted_at, position, section_id, status_category, estimate_seconds, time_spent_seconds, checklist_total, checklist_checked, tags, recurrence, version, change_seq
 `
 
 type CreateTaskParams struct {
-	Title       string             `json:"title"`
-	Description *string            `json:"description"`
-	Status      interface{}        `json:"status"`
-	Priority    interface{}        `json:"priority"`
-	DueDate     pgtype.Timestamptz `json:"due_date"`
-	UserID      int64              `json:"user_id"`
-	ProjectID   pgtype.Int8        `json:"project_id"`
+	Title           string             `json:"title"`
+	Description     *string            `json:"description"`
+	Status          interface{}        `json:"status"`
+	Priority        interface{}        `json:"priority"`
+	DueDate         pgtype.Timestamptz `json:"due_date"`
+	UserID          int64              `json:"user_id"`
+	ProjectID       pgtype.Int8        `json:"project_id"`
+	Position        string             `json:"position"`
+	StatusCategory  string             `json:"status_category"`
+	EstimateSeconds pgtype.Int4        `json:"estimate_seconds"`
+	Tags            []string           `json:"tags"`
+	Recurrence      *string            `json:"recurrence"`
 }
 
 func (q *Queries) CreateTask(ctx context.Context, arg CreateTaskParams) (Task, error) {
@@ -44,6 +80,11 @@ func (q *Queries) CreateTask(ctx context.Context, arg CreateTaskParams) (Task, e
 		arg.DueDate,
 		arg.UserID,
 		arg.ProjectID,
+		arg.Position,
+		arg.StatusCategory,
+		arg.EstimateSeconds,
+		arg.Tags,
+		arg.Recurrence,
 	)
 	var i Task
 	err := row.Scan(
@@ -57,12 +98,26 @@ func (q *Queries) CreateTask(ctx context.Context, arg CreateTaskParams) (Task, e
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.ProjectID,
+		&i.DeletedAt,
+		&i.Position,
+		&i.SectionID,
+		&i.StatusCategory,
+		&i.EstimateSeconds,
+		&i.TimeSpentSeconds,
+		&i.ChecklistTotal,
+		&i.ChecklistChecked,
+		&i.Tags,
+		&i.Recurrence,
+		&i.Version,
+		&i.ChangeSeq,
 	)
 	return i, err
 }
 
-const deleteTask = `-- name: DeleteTask :exec
-DELETE FROM tasks WHERE id = $1 AND user_id = $2
+const deleteTask = `-- name: DeleteTask :one
+UPDATE tasks SET deleted_at = now()
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+RETURNING id, user_id, title, description, status, priority, due_date, created_at, updated_at, project_id, deleted_at, position, section_id, status_category, estimate_seconds, time_spent_seconds, checklist_total, checklist_checked, tags, recurrence, version, change_seq
 `
 
 type DeleteTaskParams struct {
@@ -70,13 +125,232 @@ type DeleteTaskParams struct {
 	UserID int64 `json:"user_id"`
 }
 
-func (q *Queries) DeleteTask(ctx context.Context, arg DeleteTaskParams) error {
-	_, err := q.db.Exec(ctx, deleteTask, arg.ID, arg.UserID)
-	return err
+func (q *Queries) DeleteTask(ctx context.Context, arg DeleteTaskParams) (Task, error) {
+	row := q.db.QueryRow(ctx, deleteTask, arg.ID, arg.UserID)
+	var i Task
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Title,
+		&i.Description,
+		&i.Status,
+		&i.Priority,
+		&i.DueDate,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ProjectID,
+		&i.DeletedAt,
+		&i.Position,
+		&i.SectionID,
+		&i.StatusCategory,
+		&i.EstimateSeconds,
+		&i.TimeSpentSeconds,
+		&i.ChecklistTotal,
+		&i.ChecklistChecked,
+		&i.Tags,
+		&i.Recurrence,
+		&i.Version,
+		&i.ChangeSeq,
+	)
+	return i, err
+}
+
+const emptyTaskTrash = `-- name: EmptyTaskTrash :execrows
+DELETE FROM tasks WHERE user_id = $1 AND deleted_at IS NOT NULL
+`
+
+func (q *Queries) EmptyTaskTrash(ctx context.Context, userID int64) (int64, error) {
+	result, err := q.db.Exec(ctx, emptyTaskTrash, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const filterTasks = `-- name: FilterTasks :many
+SELECT id, user_id, title, description, status, priority, due_date, created_at, updated_at, project_id, deleted_at, position, section_id, status_category, estimate_seconds, time_spent_seconds, checklist_total, checklist_checked, tags, recurrence, version, change_seq FROM tasks
+WHERE user_id = $1
+  AND deleted_at IS NULL
+  AND ($2::text[] IS NULL OR status = ANY($2::text[]))
+  AND ($3::text[] IS NULL OR status_category = ANY($3::text[]))
+  AND ($4::int IS NULL OR priority >= $4::int)
+  AND ($5::int IS NULL OR priority <= $5::int)
+  AND ($6::bigint[] IS NULL OR project_id = ANY($6::bigint[]))
+  AND (NOT $7::bool OR project_id IS NULL)
+  AND ($8::text[] IS NULL OR tags @> $8::text[])
+  AND ($9::timestamptz IS NULL OR due_date >= $9::timestamptz)
+  AND ($10::timestamptz IS NULL OR due_date < $10::timestamptz)
+  AND ($11::bool IS NULL OR (due_date IS NOT NULL) = $11::bool)
+ORDER BY
+  CASE WHEN $12::text = 'due_date' THEN due_date END,
+  CASE WHEN $12::text = 'priority' THEN priority END DESC,
+  CASE WHEN $12::text = 'position' THEN position END,
+  created_at DESC
+LIMIT $13 OFFSET $14
+`
+
+type FilterTasksParams struct {
+	UserID      int64              `json:"user_id"`
+	Statuses    []string           `json:"statuses"`
+	Categories  []string           `json:"categories"`
+	PriorityMin pgtype.Int4        `json:"priority_min"`
+	PriorityMax pgtype.Int4        `json:"priority_max"`
+	ProjectIds  []int64            `json:"project_ids"`
+	Inbox       bool               `json:"inbox"`
+	Tags        []string           `json:"tags"`
+	DueAfter    pgtype.Timestamptz `json:"due_after"`
+	DueBefore   pgtype.Timestamptz `json:"due_before"`
+	HasDueDate  pgtype.Bool        `json:"has_due_date"`
+	Sort        string             `json:"sort"`
+	Limit       int32              `json:"limit"`
+	Offset      int32              `json:"offset"`
+}
+
+// Every condition is optional: a NULL argument (or a false inbox) skips it.
+func (q *Queries) FilterTasks(ctx context.Context, arg FilterTasksParams) ([]Task, error) {
+	rows, err := q.db.Query(ctx, filterTasks,
+		arg.UserID,
+		arg.Statuses,
+		arg.Categories,
+		arg.PriorityMin,
+		arg.PriorityMax,
+		arg.ProjectIds,
+		arg.Inbox,
+		arg.Tags,
+		arg.DueAfter,
+		arg.DueBefore,
+		arg.HasDueDate,
+		arg.Sort,
+		arg.Limit,
+		arg.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Task
+	for rows.Next() {
+		var i Task
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Title,
+			&i.Description,
+			&i.Status,
+			&i.Priority,
+			&i.DueDate,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ProjectID,
+			&i.DeletedAt,
+			&i.Position,
+			&i.SectionID,
+			&i.StatusCategory,
+			&i.EstimateSeconds,
+			&i.TimeSpentSeconds,
+			&i.ChecklistTotal,
+			&i.ChecklistChecked,
+			&i.Tags,
+			&i.Recurrence,
+			&i.Version,
+			&i.ChangeSeq,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLastTaskPosition = `-- name: GetLastTaskPosition :one
+SELECT position FROM tasks
+WHERE user_id = $1
+  AND project_id IS NOT DISTINCT FROM $2
+  AND deleted_at IS NULL
+  AND id <> $3
+ORDER BY position DESC
+LIMIT 1
+`
+
+type GetLastTaskPositionParams struct {
+	UserID    int64       `json:"user_id"`
+	ProjectID pgtype.Int8 `json:"project_id"`
+	ExcludeID int64       `json:"exclude_id"`
+}
+
+func (q *Queries) GetLastTaskPosition(ctx context.Context, arg GetLastTaskPositionParams) (string, error) {
+	row := q.db.QueryRow(ctx, getLastTaskPosition, arg.UserID, arg.ProjectID, arg.ExcludeID)
+	var i string
+	err := row.Scan(&i)
+	return i, err
+}
+
+const getNextTaskPosition = `-- name: GetNextTaskPosition :one
+SELECT position FROM tasks
+WHERE user_id = $1
+  AND project_id IS NOT DISTINCT FROM $2
+  AND deleted_at IS NULL
+  AND id <> $3
+  AND position > $4
+ORDER BY position
+LIMIT 1
+`
+
+type GetNextTaskPositionParams struct {
+	UserID    int64       `json:"user_id"`
+	ProjectID pgtype.Int8 `json:"project_id"`
+	ExcludeID int64       `json:"exclude_id"`
+	Position  string      `json:"position"`
+}
+
+func (q *Queries) GetNextTaskPosition(ctx context.Context, arg GetNextTaskPositionParams) (string, error) {
+	row := q.db.QueryRow(ctx, getNextTaskPosition,
+		arg.UserID,
+		arg.ProjectID,
+		arg.ExcludeID,
+		arg.Position,
+	)
+	var i string
+	err := row.Scan(&i)
+	return i, err
+}
+
+const getPrevTaskPosition = `-- name: GetPrevTaskPosition :one
+SELECT position FROM tasks
+WHERE user_id = $1
+  AND project_id IS NOT DISTINCT FROM $2
+  AND deleted_at IS NULL
+  AND id <> $3
+  AND position < $4
+ORDER BY position DESC
+LIMIT 1
+`
+
+type GetPrevTaskPositionParams struct {
+	UserID    int64       `json:"user_id"`
+	ProjectID pgtype.Int8 `json:"project_id"`
+	ExcludeID int64       `json:"exclude_id"`
+	Position  string      `json:"position"`
+}
+
+func (q *Queries) GetPrevTaskPosition(ctx context.Context, arg GetPrevTaskPositionParams) (string, error) {
+	row := q.db.QueryRow(ctx, getPrevTaskPosition,
+		arg.UserID,
+		arg.ProjectID,
+		arg.ExcludeID,
+		arg.Position,
+	)
+	var i string
+	err := row.Scan(&i)
+	return i, err
 }
 
 const getTask = `-- name: GetTask :one
-SELECT id, user_id, title, description, status, priority, due_date, created_at, updated_at, project_id FROM tasks WHERE id = $1 AND user_id = $2
+SELECT id, user_id, title, description, status, priority, due_date, created_at, updated_at, project_id, deleted_at, position, section_id, status_category, estimate_seconds, time_spent_seconds, checklist_total, checklist_checked, tags, recurrence, version, change_seq FROM tasks
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
 `
 
 type GetTaskParams struct {
@@ -98,35 +372,118 @@ func (q *Queries) GetTask(ctx context.Context, arg GetTaskParams) (Task, error)
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.ProjectID,
+		&i.DeletedAt,
+		&i.Position,
+		&i.SectionID,
+		&i.StatusCategory,
+		&i.EstimateSeconds,
+		&i.TimeSpentSeconds,
+		&i.ChecklistTotal,
+		&i.ChecklistChecked,
+		&i.Tags,
+		&i.Recurrence,
+		&i.Version,
+		&i.ChangeSeq,
 	)
 	return i, err
 }
 
-const listTasks = `-- name: ListTasks :many
-SELECT id, user_id, title, description, status, priority, due_date, created_at, updated_at, project_id FROM tasks
-WHERE user_id = $1
-  AND ($2::text IS NULL OR status = $2::text)
-  AND ($3::timestamptz IS NULL OR due_date <= $3::timestamptz)
-ORDER BY created_at DESC
-LIMIT $5 OFFSET $4
+const getTaskForUpdate = `-- name: GetTaskForUpdate :one
+SELECT id, user_id, title, description, status, priority, due_date, created_at, updated_at, project_id, deleted_at, position, section_id, status_category, estimate_seconds, time_spent_seconds, checklist_total, checklist_checked, tags, recurrence, version, change_seq FROM tasks
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+FOR UPDATE
 `
 
-type ListTasksParams struct {
-	UserID    int64              `json:"user_id"`
-	Status    *string            `json:"status"`
-	DueBefore pgtype.Timestamptz `json:"due_before"`
-	Offset    int32              `json:"offset"`
-	Limit     int32              `json:"limit"`
+type GetTaskForUpdateParams struct {
+	ID     int64 `json:"id"`
+	UserID int64 `json:"user_id"`
 }
 
-func (q *Queries) ListTasks(ctx context.Context, arg ListTasksParams) ([]Task, error) {
-	rows, err := q.db.Query(ctx, listTasks,
-		arg.UserID,
-		arg.Status,
-		arg.DueBefore,
-		arg.Offset,
-		arg.Limit,
+func (q *Queries) GetTaskForUpdate(ctx context.Context, arg GetTaskForUpdateParams) (Task, error) {
+	row := q.db.QueryRow(ctx, getTaskForUpdate, arg.ID, arg.UserID)
+	var i Task
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Title,
+		&i.Description,
+		&i.Status,
+		&i.Priority,
+		&i.DueDate,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ProjectID,
+		&i.DeletedAt,
+		&i.Position,
+		&i.SectionID,
+		&i.StatusCategory,
+		&i.EstimateSeconds,
+		&i.TimeSpentSeconds,
+		&i.ChecklistTotal,
+		&i.ChecklistChecked,
+		&i.Tags,
+		&i.Recurrence,
+		&i.Version,
+		&i.ChangeSeq,
+	)
+	return i, err
+}
+
+const getTrashedTask = `-- name: GetTrashedTask :one
+SELECT id, user_id, title, description, status, priority, due_date, created_at, updated_at, project_id, deleted_at, position, section_id, status_category, estimate_seconds, time_spent_seconds, checklist_total, checklist_checked, tags, recurrence, version, change_seq FROM tasks
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL
+`
+
+type GetTrashedTaskParams struct {
+	ID     int64 `json:"id"`
+	UserID int64 `json:"user_id"`
+}
+
+func (q *Queries) GetTrashedTask(ctx context.Context, arg GetTrashedTaskParams) (Task, error) {
+	row := q.db.QueryRow(ctx, getTrashedTask, arg.ID, arg.UserID)
+	var i Task
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Title,
+		&i.Description,
+		&i.Status,
+		&i.Priority,
+		&i.DueDate,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ProjectID,
+		&i.DeletedAt,
+		&i.Position,
+		&i.SectionID,
+		&i.StatusCategory,
+		&i.EstimateSeconds,
+		&i.TimeSpentSeconds,
+		&i.ChecklistTotal,
+		&i.ChecklistChecked,
+		&i.Tags,
+		&i.Recurrence,
+		&i.Version,
+		&i.ChangeSeq,
 	)
+	return i, err
+}
+
+const listCollectionTasks = `-- name: ListCollectionTasks :many
+SELECT id, user_id, title, description, status, priority, due_date, created_at, updated_at, project_id, deleted_at, position, section_id, status_category, estimate_seconds, time_spent_seconds, checklist_total, checklist_checked, tags, recurrence, version, change_seq FROM tasks
+WHERE user_id = $1 AND project_id IS NOT DISTINCT FROM $2 AND deleted_at IS NULL
+ORDER BY id
+`
+
+type ListCollectionTasksParams struct {
+	UserID    int64       `json:"user_id"`
+	ProjectID pgtype.Int8 `json:"project_id"`
+}
+
+// ListCollectionTasks returns the live tasks of a project, or of the inbox
+// when project_id is NULL, in the order they were created.
+func (q *Queries) ListCollectionTasks(ctx context.Context, arg ListCollectionTasksParams) ([]Task, error) {
+	rows, err := q.db.Query(ctx, listCollectionTasks, arg.UserID, arg.ProjectID)
 	if err != nil {
 		return nil, err
 	}
@@ -145,6 +502,18 @@ func (q *Queries) ListTasks(ctx context.Context, arg ListTasksParams) ([]Task, e
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.ProjectID,
+			&i.DeletedAt,
+			&i.Position,
+			&i.SectionID,
+			&i.StatusCategory,
+			&i.EstimateSeconds,
+			&i.TimeSpentSeconds,
+			&i.ChecklistTotal,
+			&i.ChecklistChecked,
+			&i.Tags,
+			&i.Recurrence,
+			&i.Version,
+			&i.ChangeSeq,
 		); err != nil {
 			return nil, err
 		}
@@ -156,19 +525,69 @@ func (q *Queries) ListTasks(ctx context.Context, arg ListTasksParams) ([]Task, e
 	return items, nil
 }
 
-const listTasksByProject = `-- name: ListTasksByProject :many
-SELECT id, user_id, title, description, status, priority, due_date, created_at, updated_at, project_id FROM tasks
-WHERE user_id = $1 AND project_id = $2
-ORDER BY created_at DESC
+const listExpiredTrashUsers = `-- name: ListExpiredTrashUsers :many
+SELECT user_id FROM tasks WHERE deleted_at IS NOT NULL AND deleted_at < $1
+UNION
+SELECT user_id FROM projects WHERE deleted_at IS NOT NULL AND deleted_at < $1
 `
 
-type ListTasksByProjectParams struct {
-	UserID    int64       `json:"user_id"`
-	ProjectID pgtype.Int8 `json:"project_id"`
+// ListExpiredTrashUsers returns the users with tasks or projects trashed
+// before a time, for purging them one user at a time.
+func (q *Queries) ListExpiredTrashUsers(ctx context.Context, before pgtype.Timestamptz) ([]int64, error) {
+	rows, err := q.db.Query(ctx, listExpiredTrashUsers, before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int64
+	for rows.Next() {
+		var i int64
+		if err := rows.Scan(&i); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-func (q *Queries) ListTasksByProject(ctx context.Context, arg ListTasksByProjectParams) ([]Task, error) {
-	rows, err := q.db.Query(ctx, listTasksByProject, arg.UserID, arg.ProjectID)
+const listExportTasks = `-- name: ListExportTasks :many
+SELECT id, user_id, title, description, status, priority, due_date, created_at, updated_at, project_id, deleted_at, position, section_id, status_category, estimate_seconds, time_spent_seconds, checklist_total, checklist_checked, tags, recurrence, version, change_seq FROM tasks
+WHERE user_id = $1
+  AND deleted_at IS NULL
+  AND (
+    ($2::bigint[] IS NULL AND NOT $3::bool)
+    OR project_id = ANY($2::bigint[])
+    OR ($3::bool AND project_id IS NULL)
+  )
+  AND (COALESCE(project_id, 0), id) > ($4::bigint, $5::bigint)
+ORDER BY COALESCE(project_id, 0), id
+LIMIT $6
+`
+
+type ListExportTasksParams struct {
+	UserID       int64   `json:"user_id"`
+	ProjectIds   []int64 `json:"project_ids"`
+	Inbox        bool    `json:"inbox"`
+	AfterProject int64   `json:"after_project"`
+	AfterID      int64   `json:"after_id"`
+	Limit        int32   `json:"limit"`
+}
+
+// ListExportTasks pages through the live tasks of an export, inbox first
+// and then by project, each in the order they were created. Pass the
+// project (0 for the inbox) and id of the last task of the previous page.
+func (q *Queries) ListExportTasks(ctx context.Context, arg ListExportTasksParams) ([]Task, error) {
+	rows, err := q.db.Query(ctx, listExportTasks,
+		arg.UserID,
+		arg.ProjectIds,
+		arg.Inbox,
+		arg.AfterProject,
+		arg.AfterID,
+		arg.Limit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -187,6 +606,18 @@ func (q *Queries) ListTasksByProject(ctx context.Context, arg ListTasksByProject
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.ProjectID,
+			&i.DeletedAt,
+			&i.Position,
+			&i.SectionID,
+			&i.StatusCategory,
+			&i.EstimateSeconds,
+			&i.TimeSpentSeconds,
+			&i.ChecklistTotal,
+			&i.ChecklistChecked,
+			&i.Tags,
+			&i.Recurrence,
+			&i.Version,
+			&i.ChangeSeq,
 		); err != nil {
 			return nil, err
 		}
@@ -198,37 +629,871 @@ func (q *Queries) ListTasksByProject(ctx context.Context, arg ListTasksByProject
 	return items, nil
 }
 
-const updateTask = `-- name: UpdateTask :one
-UPDATE tasks
-SET
-  title       = COALESCE($1, title),
-  description = COALESCE($2, description),
-  status      = COALESCE($3, status),
-  priority    = COALESCE($4, priority),
-  due_date    = COALESCE($5, due_date),
-  project_id  = COALESCE($6, project_id)
-WHERE id = $7 AND user_id = $8
-RETURNING id, user_id, title, description, status, priority, due_date, created_at, updated_at, project_id
+const listProjectTaskStatuses = `-- name: ListProjectTaskStatuses :many
+SELECT DISTINCT status FROM tasks
+WHERE project_id = $1
+ORDER BY status
 `
 
-type UpdateTaskParams struct {
-	Title       pgtype.Text        `json:"title"`
-	Description *string            `json:"description"`
-	Status      pgtype.Text        `json:"status"`
-	Priority    pgtype.Int4        `json:"priority"`
-	DueDate     pgtype.Timestamptz `json:"due_date"`
-	ProjectID   pgtype.Int8        `json:"project_id"`
-	ID          int64              `json:"id"`
-	UserID      int64              `json:"user_id"`
+// Includes trashed tasks, which keep their status when restored.
+func (q *Queries) ListProjectTaskStatuses(ctx context.Context, projectID pgtype.Int8) ([]string, error) {
+	rows, err := q.db.Query(ctx, listProjectTaskStatuses, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var i string
+		if err := rows.Scan(&i); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTaskIDsByPosition = `-- name: ListTaskIDsByPosition :many
+SELECT id FROM tasks
+WHERE user_id = $1
+  AND project_id IS NOT DISTINCT FROM $2
+  AND deleted_at IS NULL
+  AND id <> $3
+ORDER BY position, id
+`
+
+type ListTaskIDsByPositionParams struct {
+	UserID    int64       `json:"user_id"`
+	ProjectID pgtype.Int8 `json:"project_id"`
+	ExcludeID int64       `json:"exclude_id"`
+}
+
+func (q *Queries) ListTaskIDsByPosition(ctx context.Context, arg ListTaskIDsByPositionParams) ([]int64, error) {
+	rows, err := q.db.Query(ctx, listTaskIDsByPosition, arg.UserID, arg.ProjectID, arg.ExcludeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int64
+	for rows.Next() {
+		var i int64
+		if err := rows.Scan(&i); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTasks = `-- name: ListTasks :many
+SELECT id, user_id, title, description, status, priority, due_date, created_at, updated_at, project_id, deleted_at, position, section_id, status_category, estimate_seconds, time_spent_seconds, checklist_total, checklist_checked, tags, recurrence, version, change_seq FROM tasks
+WHERE user_id = $1
+  AND deleted_at IS NULL
+  AND ($2::text IS NULL OR status = $2::text)
+  AND ($3::timestamptz IS NULL OR due_date <= $3::timestamptz)
+ORDER BY
+  CASE WHEN $4::text = 'position' THEN position END,
+  created_at DESC
+LIMIT $6 OFFSET $5
+`
+
+type ListTasksParams struct {
+	UserID    int64              `json:"user_id"`
+	Status    *string            `json:"status"`
+	DueBefore pgtype.Timestamptz `json:"due_before"`
+	Sort      string             `json:"sort"`
+	Offset    int32              `json:"offset"`
+	Limit     int32              `json:"limit"`
+}
+
+func (q *Queries) ListTasks(ctx context.Context, arg ListTasksParams) ([]Task, error) {
+	rows, err := q.db.Query(ctx, listTasks,
+		arg.UserID,
+		arg.Status,
+		arg.DueBefore,
+		arg.Sort,
+		arg.Offset,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Task
+	for rows.Next() {
+		var i Task
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Title,
+			&i.Description,
+			&i.Status,
+			&i.Priority,
+			&i.DueDate,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ProjectID,
+			&i.DeletedAt,
+			&i.Position,
+			&i.SectionID,
+			&i.StatusCategory,
+			&i.EstimateSeconds,
+			&i.TimeSpentSeconds,
+			&i.ChecklistTotal,
+			&i.ChecklistChecked,
+			&i.Tags,
+			&i.Recurrence,
+			&i.Version,
+			&i.ChangeSeq,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTasksByProject = `-- name: ListTasksByProject :many
+SELECT id, user_id, title, description, status, priority, due_date, created_at, updated_at, project_id, deleted_at, position, section_id, status_category, estimate_seconds, time_spent_seconds, checklist_total, checklist_checked, tags, recurrence, version, change_seq FROM tasks
+WHERE user_id = $1 AND project_id = $2 AND deleted_at IS NULL
+ORDER BY
+  CASE WHEN $3::text = 'position' THEN position END,
+  created_at DESC
+`
+
+type ListTasksByProjectParams struct {
+	UserID    int64       `json:"user_id"`
+	ProjectID pgtype.Int8 `json:"project_id"`
+	Sort      string      `json:"sort"`
+}
+
+func (q *Queries) ListTasksByProject(ctx context.Context, arg ListTasksByProjectParams) ([]Task, error) {
+	rows, err := q.db.Query(ctx, listTasksByProject, arg.UserID, arg.ProjectID, arg.Sort)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Task
+	for rows.Next() {
+		var i Task
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Title,
+			&i.Description,
+			&i.Status,
+			&i.Priority,
+			&i.DueDate,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ProjectID,
+			&i.DeletedAt,
+			&i.Position,
+			&i.SectionID,
+			&i.StatusCategory,
+			&i.EstimateSeconds,
+			&i.TimeSpentSeconds,
+			&i.ChecklistTotal,
+			&i.ChecklistChecked,
+			&i.Tags,
+			&i.Recurrence,
+			&i.Version,
+			&i.ChangeSeq,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTrashedTasks = `-- name: ListTrashedTasks :many
+SELECT id, user_id, title, description, status, priority, due_date, created_at, updated_at, project_id, deleted_at, position, section_id, status_category, estimate_seconds, time_spent_seconds, checklist_total, checklist_checked, tags, recurrence, version, change_seq FROM tasks
+WHERE user_id = $1 AND deleted_at IS NOT NULL
+ORDER BY deleted_at DESC
+`
+
+func (q *Queries) ListTrashedTasks(ctx context.Context, userID int64) ([]Task, error) {
+	rows, err := q.db.Query(ctx, listTrashedTasks, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Task
+	for rows.Next() {
+		var i Task
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Title,
+			&i.Description,
+			&i.Status,
+			&i.Priority,
+			&i.DueDate,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ProjectID,
+			&i.DeletedAt,
+			&i.Position,
+			&i.SectionID,
+			&i.StatusCategory,
+			&i.EstimateSeconds,
+			&i.TimeSpentSeconds,
+			&i.ChecklistTotal,
+			&i.ChecklistChecked,
+			&i.Tags,
+			&i.Recurrence,
+			&i.Version,
+			&i.ChangeSeq,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockTask = `-- name: LockTask :one
+SELECT id, user_id, title, description, status, priority, due_date, created_at, updated_at, project_id, deleted_at, position, section_id, status_category, estimate_seconds, time_spent_seconds, checklist_total, checklist_checked, tags, recurrence, version, change_seq FROM tasks
+WHERE id = $1 AND user_id = $2
+FOR UPDATE
+`
+
+type LockTaskParams struct {
+	ID     int64 `json:"id"`
+	UserID int64 `json:"user_id"`
+}
+
+func (q *Queries) LockTask(ctx context.Context, arg LockTaskParams) (Task, error) {
+	row := q.db.QueryRow(ctx, lockTask, arg.ID, arg.UserID)
+	var i Task
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Title,
+		&i.Description,
+		&i.Status,
+		&i.Priority,
+		&i.DueDate,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ProjectID,
+		&i.DeletedAt,
+		&i.Position,
+		&i.SectionID,
+		&i.StatusCategory,
+		&i.EstimateSeconds,
+		&i.TimeSpentSeconds,
+		&i.ChecklistTotal,
+		&i.ChecklistChecked,
+		&i.Tags,
+		&i.Recurrence,
+		&i.Version,
+		&i.ChangeSeq,
+	)
+	return i, err
+}
+
+const lockTaskPositions = `-- name: LockTaskPositions :exec
+SELECT pg_advisory_xact_lock(hashtextextended(
+  'task_positions:' || $1::bigint || ':' || COALESCE($2::bigint, 0), 0
+))
+`
+
+type LockTaskPositionsParams struct {
+	UserID    int64       `json:"user_id"`
+	ProjectID pgtype.Int8 `json:"project_id"`
+}
+
+func (q *Queries) LockTaskPositions(ctx context.Context, arg LockTaskPositionsParams) error {
+	_, err := q.db.Exec(ctx, lockTaskPositions, arg.UserID, arg.ProjectID)
+	return err
+}
+
+const moveTask = `-- name: MoveTask :one
+UPDATE tasks
+SET
+  project_id = $1,
+  section_id = $2,
+  position   = $3,
+  status     = $4,
+  status_category = $5
+WHERE id = $6 AND user_id = $7 AND deleted_at IS NULL
+RETURNING id, user_id, title, description, status, priority, due_date, created_at, updated_at, project_id, deleted_at, position, section_id, status_category, estimate_seconds, time_spent_seconds, checklist_total, checklist_checked, tags, recurrence, version, change_seq
+`
+
+type MoveTaskParams struct {
+	ProjectID      pgtype.Int8 `json:"project_id"`
+	SectionID      pgtype.Int8 `json:"section_id"`
+	Position       string      `json:"position"`
+	Status         string      `json:"status"`
+	StatusCategory string      `json:"status_category"`
+	ID             int64       `json:"id"`
+	UserID         int64       `json:"user_id"`
+}
+
+func (q *Queries) MoveTask(ctx context.Context, arg MoveTaskParams) (Task, error) {
+	row := q.db.QueryRow(ctx, moveTask,
+		arg.ProjectID,
+		arg.SectionID,
+		arg.Position,
+		arg.Status,
+		arg.StatusCategory,
+		arg.ID,
+		arg.UserID,
+	)
+	var i Task
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Title,
+		&i.Description,
+		&i.Status,
+		&i.Priority,
+		&i.DueDate,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ProjectID,
+		&i.DeletedAt,
+		&i.Position,
+		&i.SectionID,
+		&i.StatusCategory,
+		&i.EstimateSeconds,
+		&i.TimeSpentSeconds,
+		&i.ChecklistTotal,
+		&i.ChecklistChecked,
+		&i.Tags,
+		&i.Recurrence,
+		&i.Version,
+		&i.ChangeSeq,
+	)
+	return i, err
+}
+
+const purgeExpiredTasks = `-- name: PurgeExpiredTasks :execrows
+DELETE FROM tasks
+WHERE user_id = $1 AND deleted_at IS NOT NULL AND deleted_at < $2
+`
+
+type PurgeExpiredTasksParams struct {
+	UserID int64              `json:"user_id"`
+	Before pgtype.Timestamptz `json:"before"`
+}
+
+func (q *Queries) PurgeExpiredTasks(ctx context.Context, arg PurgeExpiredTasksParams) (int64, error) {
+	result, err := q.db.Exec(ctx, purgeExpiredTasks, arg.UserID, arg.Before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const purgeProjectTasks = `-- name: PurgeProjectTasks :exec
+DELETE FROM tasks
+WHERE project_id = $1 AND user_id = $2 AND deleted_at IS NOT NULL
+`
+
+type PurgeProjectTasksParams struct {
+	ProjectID pgtype.Int8 `json:"project_id"`
+	UserID    int64       `json:"user_id"`
+}
+
+func (q *Queries) PurgeProjectTasks(ctx context.Context, arg PurgeProjectTasksParams) error {
+	_, err := q.db.Exec(ctx, purgeProjectTasks, arg.ProjectID, arg.UserID)
+	return err
+}
+
+const purgeTask = `-- name: PurgeTask :execrows
+DELETE FROM tasks
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL
+`
+
+type PurgeTaskParams struct {
+	ID     int64 `json:"id"`
+	UserID int64 `json:"user_id"`
+}
+
+func (q *Queries) PurgeTask(ctx context.Context, arg PurgeTaskParams) (int64, error) {
+	result, err := q.db.Exec(ctx, purgeTask, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const refreshTaskChecklist = `-- name: RefreshTaskChecklist :one
+UPDATE tasks
+SET
+  checklist_total   = (SELECT count(*) FROM checklist_items c WHERE c.task_id = tasks.id),
+  checklist_checked = (SELECT count(*) FROM checklist_items c WHERE c.task_id = tasks.id AND c.checked)
+WHERE id = $1
+RETURNING id, user_id, title, description, status, priority, due_date, created_at, updated_at, project_id, deleted_at, position, section_id, status_category, estimate_seconds, time_spent_seconds, checklist_total, checklist_checked, tags, recurrence, version, change_seq
+`
+
+// Also bumps updated_at through the tasks trigger, so checklist edits show
+// up as task changes.
+func (q *Queries) RefreshTaskChecklist(ctx context.Context, id int64) (Task, error) {
+	row := q.db.QueryRow(ctx, refreshTaskChecklist, id)
+	var i Task
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Title,
+		&i.Description,
+		&i.Status,
+		&i.Priority,
+		&i.DueDate,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ProjectID,
+		&i.DeletedAt,
+		&i.Position,
+		&i.SectionID,
+		&i.StatusCategory,
+		&i.EstimateSeconds,
+		&i.TimeSpentSeconds,
+		&i.ChecklistTotal,
+		&i.ChecklistChecked,
+		&i.Tags,
+		&i.Recurrence,
+		&i.Version,
+		&i.ChangeSeq,
+	)
+	return i, err
+}
+
+const refreshTaskTimeSpent = `-- name: RefreshTaskTimeSpent :exec
+UPDATE tasks
+SET time_spent_seconds = (
+  SELECT COALESCE(SUM(EXTRACT(EPOCH FROM e.ended_at - e.started_at)), 0)::bigint
+  FROM time_entries e
+  WHERE e.task_id = tasks.id AND e.ended_at IS NOT NULL
+)
+WHERE id = $1
+`
+
+func (q *Queries) RefreshTaskTimeSpent(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, refreshTaskTimeSpent, id)
+	return err
+}
+
+const remapProjectTaskStatus = `-- name: RemapProjectTaskStatus :many
+UPDATE tasks
+SET status = $1, status_category = $2
+WHERE project_id = $3 AND status = $4
+RETURNING id, user_id, title, description, status, priority, due_date, created_at, updated_at, project_id, deleted_at, position, section_id, status_category, estimate_seconds, time_spent_seconds, checklist_total, checklist_checked, tags, recurrence, version, change_seq
+`
+
+type RemapProjectTaskStatusParams struct {
+	ToStatus       string      `json:"to_status"`
+	StatusCategory string      `json:"status_category"`
+	ProjectID      pgtype.Int8 `json:"project_id"`
+	FromStatus     string      `json:"from_status"`
+}
+
+func (q *Queries) RemapProjectTaskStatus(ctx context.Context, arg RemapProjectTaskStatusParams) ([]Task, error) {
+	rows, err := q.db.Query(ctx, remapProjectTaskStatus,
+		arg.ToStatus,
+		arg.StatusCategory,
+		arg.ProjectID,
+		arg.FromStatus,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Task
+	for rows.Next() {
+		var i Task
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Title,
+			&i.Description,
+			&i.Status,
+			&i.Priority,
+			&i.DueDate,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ProjectID,
+			&i.DeletedAt,
+			&i.Position,
+			&i.SectionID,
+			&i.StatusCategory,
+			&i.EstimateSeconds,
+			&i.TimeSpentSeconds,
+			&i.ChecklistTotal,
+			&i.ChecklistChecked,
+			&i.Tags,
+			&i.Recurrence,
+			&i.Version,
+			&i.ChangeSeq,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const restoreProjectTasks = `-- name: RestoreProjectTasks :many
+UPDATE tasks SET deleted_at = NULL
+WHERE project_id = $1 AND user_id = $2 AND deleted_at = $3
+RETURNING id, user_id, title, description, status, priority, due_date, created_at, updated_at, project_id, deleted_at, position, section_id, status_category, estimate_seconds, time_spent_seconds, checklist_total, checklist_checked, tags, recurrence, version, change_seq
+`
+
+type RestoreProjectTasksParams struct {
+	ProjectID pgtype.Int8        `json:"project_id"`
+	UserID    int64              `json:"user_id"`
+	DeletedAt pgtype.Timestamptz `json:"deleted_at"`
+}
+
+func (q *Queries) RestoreProjectTasks(ctx context.Context, arg RestoreProjectTasksParams) ([]Task, error) {
+	rows, err := q.db.Query(ctx, restoreProjectTasks, arg.ProjectID, arg.UserID, arg.DeletedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Task
+	for rows.Next() {
+		var i Task
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Title,
+			&i.Description,
+			&i.Status,
+			&i.Priority,
+			&i.DueDate,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ProjectID,
+			&i.DeletedAt,
+			&i.Position,
+			&i.SectionID,
+			&i.StatusCategory,
+			&i.EstimateSeconds,
+			&i.TimeSpentSeconds,
+			&i.ChecklistTotal,
+			&i.ChecklistChecked,
+			&i.Tags,
+			&i.Recurrence,
+			&i.Version,
+			&i.ChangeSeq,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const restoreTask = `-- name: RestoreTask :one
+UPDATE tasks SET deleted_at = NULL
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL
+RETURNING id, user_id, title, description, status, priority, due_date, created_at, updated_at, project_id, deleted_at, position, section_id, status_category, estimate_seconds, time_spent_seconds, checklist_total, checklist_checked, tags, recurrence, version, change_seq
+`
+
+type RestoreTaskParams struct {
+	ID     int64 `json:"id"`
+	UserID int64 `json:"user_id"`
+}
+
+func (q *Queries) RestoreTask(ctx context.Context, arg RestoreTaskParams) (Task, error) {
+	row := q.db.QueryRow(ctx, restoreTask, arg.ID, arg.UserID)
+	var i Task
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Title,
+		&i.Description,
+		&i.Status,
+		&i.Priority,
+		&i.DueDate,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ProjectID,
+		&i.DeletedAt,
+		&i.Position,
+		&i.SectionID,
+		&i.StatusCategory,
+		&i.EstimateSeconds,
+		&i.TimeSpentSeconds,
+		&i.ChecklistTotal,
+		&i.ChecklistChecked,
+		&i.Tags,
+		&i.Recurrence,
+		&i.Version,
+		&i.ChangeSeq,
+	)
+	return i, err
+}
+
+const setTaskFields = `-- name: SetTaskFields :one
+UPDATE tasks
+SET
+  title       = $1,
+  description = $2,
+  status      = $3,
+  status_category = $4,
+  priority    = $5,
+  due_date    = $6,
+  estimate_seconds = $7,
+  tags        = $8,
+  recurrence  = $9,
+  project_id  = $10,
+  section_id  = $11
+WHERE id = $12 AND user_id = $13 AND deleted_at IS NULL
+RETURNING id, user_id, title, description, status, priority, due_date, created_at, updated_at, project_id, deleted_at, position, section_id, status_category, estimate_seconds, time_spent_seconds, checklist_total, checklist_checked, tags, recurrence, version, change_seq
+`
+
+type SetTaskFieldsParams struct {
+	Title           string             `json:"title"`
+	Description     *string            `json:"description"`
+	Status          string             `json:"status"`
+	StatusCategory  string             `json:"status_category"`
+	Priority        int32              `json:"priority"`
+	DueDate         pgtype.Timestamptz `json:"due_date"`
+	EstimateSeconds pgtype.Int4        `json:"estimate_seconds"`
+	Tags            []string           `json:"tags"`
+	Recurrence      *string            `json:"recurrence"`
+	ProjectID       pgtype.Int8        `json:"project_id"`
+	SectionID       pgtype.Int8        `json:"section_id"`
+	ID              int64              `json:"id"`
+	UserID          int64              `json:"user_id"`
+}
+
+func (q *Queries) SetTaskFields(ctx context.Context, arg SetTaskFieldsParams) (Task, error) {
+	row := q.db.QueryRow(ctx, setTaskFields,
+		arg.Title,
+		arg.Description,
+		arg.Status,
+		arg.StatusCategory,
+		arg.Priority,
+		arg.DueDate,
+		arg.EstimateSeconds,
+		arg.Tags,
+		arg.Recurrence,
+		arg.ProjectID,
+		arg.SectionID,
+		arg.ID,
+		arg.UserID,
+	)
+	var i Task
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Title,
+		&i.Description,
+		&i.Status,
+		&i.Priority,
+		&i.DueDate,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ProjectID,
+		&i.DeletedAt,
+		&i.Position,
+		&i.SectionID,
+		&i.StatusCategory,
+		&i.EstimateSeconds,
+		&i.TimeSpentSeconds,
+		&i.ChecklistTotal,
+		&i.ChecklistChecked,
+		&i.Tags,
+		&i.Recurrence,
+		&i.Version,
+		&i.ChangeSeq,
+	)
+	return i, err
+}
+
+const setTaskPosition = `-- name: SetTaskPosition :exec
+UPDATE tasks SET position = $1
+WHERE id = $2 AND user_id = $3
+`
+
+type SetTaskPositionParams struct {
+	Position string `json:"position"`
+	ID       int64  `json:"id"`
+	UserID   int64  `json:"user_id"`
+}
+
+func (q *Queries) SetTaskPosition(ctx context.Context, arg SetTaskPositionParams) error {
+	_, err := q.db.Exec(ctx, setTaskPosition, arg.Position, arg.ID, arg.UserID)
+	return err
+}
+
+const syncProjectTaskCategories = `-- name: SyncProjectTaskCategories :many
+UPDATE tasks t
+SET status_category = s.category
+FROM project_statuses s
+WHERE t.project_id = $1
+  AND s.project_id = t.project_id
+  AND s.key = t.status
+  AND t.status_category <> s.category
+RETURNING t.id
+`
+
+func (q *Queries) SyncProjectTaskCategories(ctx context.Context, projectID pgtype.Int8) ([]int64, error) {
+	rows, err := q.db.Query(ctx, syncProjectTaskCategories, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int64
+	for rows.Next() {
+		var i int64
+		if err := rows.Scan(&i); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const trashProjectTasks = `-- name: TrashProjectTasks :many
+UPDATE tasks SET deleted_at = $1
+WHERE project_id = $2 AND user_id = $3 AND deleted_at IS NULL
+RETURNING id, user_id, title, description, status, priority, due_date, created_at, updated_at, project_id, deleted_at, position, section_id, status_category, estimate_seconds, time_spent_seconds, checklist_total, checklist_checked, tags, recurrence, version, change_seq
+`
+
+type TrashProjectTasksParams struct {
+	DeletedAt pgtype.Timestamptz `json:"deleted_at"`
+	ProjectID pgtype.Int8        `json:"project_id"`
+	UserID    int64              `json:"user_id"`
+}
+
+func (q *Queries) TrashProjectTasks(ctx context.Context, arg TrashProjectTasksParams) ([]Task, error) {
+	rows, err := q.db.Query(ctx, trashProjectTasks, arg.DeletedAt, arg.ProjectID, arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Task
+	for rows.Next() {
+		var i Task
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Title,
+			&i.Description,
+			&i.Status,
+			&i.Priority,
+			&i.DueDate,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ProjectID,
+			&i.DeletedAt,
+			&i.Position,
+			&i.SectionID,
+			&i.StatusCategory,
+			&i.EstimateSeconds,
+			&i.TimeSpentSeconds,
+			&i.ChecklistTotal,
+			&i.ChecklistChecked,
+			&i.Tags,
+			&i.Recurrence,
+			&i.Version,
+			&i.ChangeSeq,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateTask = `-- name: UpdateTask :one
+UPDATE tasks
+SET
+  title       = COALESCE($1, title),
+  description = CASE WHEN $2::bool THEN NULL
+                     ELSE COALESCE($3, description) END,
+  status      = COALESCE($4, status),
+  status_category = COALESCE($5, status_category),
+  priority    = COALESCE($6, priority),
+  due_date    = CASE WHEN $7::bool THEN NULL
+                     ELSE COALESCE($8, due_date) END,
+  estimate_seconds = CASE WHEN $9::bool THEN NULL
+                          ELSE COALESCE($10, estimate_seconds) END,
+  tags        = COALESCE($11::text[], tags),
+  -- An empty recurrence clears it
+  recurrence  = NULLIF(COALESCE($12, recurrence), ''),
+  project_id  = CASE WHEN $13::bool THEN NULL
+                     ELSE COALESCE($14, project_id) END,
+  -- Sections belong to a project, so moving to another project clears it
+  section_id  = CASE
+    WHEN $13::bool THEN NULL
+    WHEN project_id IS DISTINCT FROM COALESCE($14, project_id) THEN NULL
+    ELSE section_id
+  END
+WHERE id = $15 AND user_id = $16 AND deleted_at IS NULL
+RETURNING id, user_id, title, description, status, priority, due_date, created_at, updated_at, project_id, deleted_at, position, section_id, status_category, estimate_seconds, time_spent_seconds, checklist_total, checklist_checked, tags, recurrence, version, change_seq
+`
+
+type UpdateTaskParams struct {
+	Title            pgtype.Text        `json:"title"`
+	ClearDescription bool               `json:"clear_description"`
+	Description      *string            `json:"description"`
+	Status           pgtype.Text        `json:"status"`
+	StatusCategory   pgtype.Text        `json:"status_category"`
+	Priority         pgtype.Int4        `json:"priority"`
+	ClearDueDate     bool               `json:"clear_due_date"`
+	DueDate          pgtype.Timestamptz `json:"due_date"`
+	ClearEstimate    bool               `json:"clear_estimate"`
+	EstimateSeconds  pgtype.Int4        `json:"estimate_seconds"`
+	Tags             []string           `json:"tags"`
+	Recurrence       *string            `json:"recurrence"`
+	ClearProject     bool               `json:"clear_project"`
+	ProjectID        pgtype.Int8        `json:"project_id"`
+	ID               int64              `json:"id"`
+	UserID           int64              `json:"user_id"`
 }
 
+// UpdateTask leaves a nullable field alone when NULL is passed for it; the
+// clear_* flags set it to NULL instead.
 func (q *Queries) UpdateTask(ctx context.Context, arg UpdateTaskParams) (Task, error) {
 	row := q.db.QueryRow(ctx, updateTask,
 		arg.Title,
+		arg.ClearDescription,
 		arg.Description,
 		arg.Status,
+		arg.StatusCategory,
 		arg.Priority,
+		arg.ClearDueDate,
 		arg.DueDate,
+		arg.ClearEstimate,
+		arg.EstimateSeconds,
+		arg.Tags,
+		arg.Recurrence,
+		arg.ClearProject,
 		arg.ProjectID,
 		arg.ID,
 		arg.UserID,
@@ -245,6 +1510,18 @@ func (q *Queries) UpdateTask(ctx context.Context, arg UpdateTaskParams) (Task, e
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.ProjectID,
+		&i.DeletedAt,
+		&i.Position,
+		&i.SectionID,
+		&i.StatusCategory,
+		&i.EstimateSeconds,
+		&i.TimeSpentSeconds,
+		&i.ChecklistTotal,
+		&i.ChecklistChecked,
+		&i.Tags,
+		&i.Recurrence,
+		&i.Version,
+		&i.ChangeSeq,
 	)
 	return i, err
 }
@@ -0,0 +1,352 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/pavelc4/auriya-todolist-go/internal/cache"
+	db "github.com/pavelc4/auriya-todolist-go/internal/db/sqlc"
+	"github.com/pavelc4/auriya-todolist-go/internal/http/repository"
+)
+
+var (
+	// errBatchRolledBack ends an atomic batch after an operation failed.
+	errBatchRolledBack = errors.New("batch rolled back")
+	// errUnresolvedRef is returned for a temp_id whose create failed.
+	errUnresolvedRef = errors.New("unresolved reference")
+	// errUnknownProject is returned for a project_id the user does not own.
+	errUnknownProject = errors.New("unknown project")
+)
+
+type BatchHandler struct {
+	Store *repository.Store
+	cache *cache.Service
+}
+
+func NewBatchHandler(store *repository.Store, cache *cache.Service) *BatchHandler {
+	return &BatchHandler{Store: store, cache: cache}
+}
+
+// batchOp is an operation whose shape has been checked, with Data decoded
+// into the request type for its op and type.
+type batchOp struct {
+	BatchOperation
+	data any
+}
+
+// Run executes operations in order inside one transaction, as replayed by
+// offline clients. The whole batch is checked before anything runs, so a
+// malformed operation rejects it with 400. In atomic mode the first failing
+// operation rolls everything back and the response is 422; in best-effort
+// mode every operation runs in its own savepoint and the ones that succeed
+// are committed. Operations that use the temp_id of a failed create fail
+// with 424.
+func (h *BatchHandler) Run(c *gin.Context) {
+	var req BatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "detail": err.Error()})
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = BatchAtomic
+	}
+	ops, err := prepareBatch(req.Operations)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "detail": err.Error()})
+		return
+	}
+
+	userID := c.GetInt64("userID")
+	ctx := c.Request.Context()
+	atomic := req.Mode == BatchAtomic
+
+	results := make([]BatchResult, len(ops))
+	failedAt := -1
+	var touched []int64
+	err = h.Store.ExecTxSteps(ctx, func(tx *repository.Tx) error {
+		temps := make(map[string]int64)
+		for i, op := range ops {
+			var res BatchResult
+			var tasks []int64
+			run := func(q *db.Queries) error {
+				var err error
+				res, tasks, err = runBatchOp(ctx, q, userID, op, temps)
+				return err
+			}
+
+			var err error
+			if atomic {
+				err = run(tx.Queries)
+			} else {
+				err = tx.Savepoint(ctx, run)
+			}
+			if err != nil {
+				status, code := batchErrorStatus(err)
+				if status == 0 {
+					return err
+				}
+				results[i] = BatchResult{Index: i, Status: status, Error: code, Detail: err.Error(), TempID: op.TempID}
+				if atomic {
+					failedAt = i
+					return errBatchRolledBack
+				}
+				continue
+			}
+
+			res.Index = i
+			res.TempID = op.TempID
+			results[i] = res
+			touched = append(touched, tasks...)
+			if op.TempID != "" {
+				temps[op.TempID] = res.ID
+			}
+		}
+		return nil
+	})
+
+	switch {
+	case errors.Is(err, errBatchRolledBack):
+		for i, op := range ops {
+			switch {
+			case i < failedAt:
+				results[i] = BatchResult{Index: i, Status: http.StatusFailedDependency, Error: "rolled_back", TempID: op.TempID}
+			case i > failedAt:
+				results[i] = BatchResult{Index: i, Status: http.StatusFailedDependency, Error: "not_run", TempID: op.TempID}
+			}
+		}
+		c.JSON(http.StatusUnprocessableEntity, newBatchResponse(req.Mode, false, results))
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		return
+	}
+
+	for _, id := range touched {
+		h.cache.Delete(fmt.Sprintf("task:%d", id))
+	}
+
+	c.JSON(http.StatusOK, newBatchResponse(req.Mode, true, results))
+}
+
+func newBatchResponse(mode string, committed bool, results []BatchResult) BatchResponse {
+	resp := BatchResponse{Mode: mode, Committed: committed, Results: results}
+	for _, r := range results {
+		if r.Error == "" {
+			resp.Succeeded++
+		} else {
+			resp.Failed++
+		}
+	}
+	return resp
+}
+
+// prepareBatch checks that every operation has the fields its op needs, that
+// temp_ids are unique and only used after the create that defines them and
+// for the right type, and decodes and validates each operation's data.
+func prepareBatch(in []BatchOperation) ([]batchOp, error) {
+	temps := make(map[string]string)
+	checkRef := func(r *BatchRef, typ string) error {
+		if r == nil || r.TempID == "" {
+			return nil
+		}
+		t, ok := temps[r.TempID]
+		if !ok {
+			return fmt.Errorf("temp_id %q is not created by an earlier operation", r.TempID)
+		}
+		if t != typ {
+			return fmt.Errorf("temp_id %q is a %s, not a %s", r.TempID, t, typ)
+		}
+		return nil
+	}
+
+	ops := make([]batchOp, len(in))
+	for i, op := range in {
+		err := func() error {
+			switch {
+			case op.Op == "create" && op.ID != nil:
+				return errors.New("id is not allowed for create")
+			case op.Op != "create" && op.ID == nil:
+				return fmt.Errorf("id is required for %s", op.Op)
+			case op.Op != "create" && op.TempID != "":
+				return errors.New("temp_id is only allowed for create")
+			case op.Op != "delete" && len(op.Data) == 0:
+				return fmt.Errorf("data is required for %s", op.Op)
+			}
+			if err := checkRef(op.ID, op.Type); err != nil {
+				return err
+			}
+
+			var data any
+			var projectRef **BatchRef
+			switch {
+			case op.Type == "task" && op.Op == "create":
+				d := &batchTaskCreate{}
+				data, projectRef = d, &d.ProjectID
+			case op.Type == "task" && op.Op == "update":
+				d := &batchTaskUpdate{}
+				data, projectRef = d, &d.ProjectID
+			case op.Type == "project" && op.Op == "create":
+				data = &CreateProjectRequest{}
+			case op.Type == "project" && op.Op == "update":
+				data = &UpdateProjectRequest{}
+			}
+			if data != nil {
+				if err := json.Unmarshal(op.Data, data); err != nil {
+					return fmt.Errorf("data: %w", err)
+				}
+				if err := binding.Validator.ValidateStruct(data); err != nil {
+					return fmt.Errorf("data: %w", err)
+				}
+			}
+			if projectRef != nil {
+				if err := checkRef(*projectRef, "project"); err != nil {
+					return fmt.Errorf("project_id: %w", err)
+				}
+			}
+			ops[i] = batchOp{BatchOperation: op, data: data}
+
+			if op.TempID != "" {
+				if _, dup := temps[op.TempID]; dup {
+					return fmt.Errorf("temp_id %q is used twice", op.TempID)
+				}
+				temps[op.TempID] = op.Type
+			}
+			return nil
+		}()
+		if err != nil {
+			return nil, fmt.Errorf("operations[%d]: %w", i, err)
+		}
+	}
+	return ops, nil
+}
+
+// runBatchOp performs one operation, resolving temp_ids through temps. It
+// returns the result and the tasks whose cache entries it invalidates.
+func runBatchOp(ctx context.Context, q *db.Queries, userID int64, op batchOp, temps map[string]int64) (BatchResult, []int64, error) {
+	resolve := func(r *BatchRef) (int64, error) {
+		if r.TempID == "" {
+			return r.ID, nil
+		}
+		id, ok := temps[r.TempID]
+		if !ok {
+			return 0, fmt.Errorf("%w: the create for temp_id %q failed", errUnresolvedRef, r.TempID)
+		}
+		return id, nil
+	}
+	project := func(r *BatchRef) (pgtype.Int8, error) {
+		if r == nil {
+			return pgtype.Int8{}, nil
+		}
+		id, err := resolve(r)
+		if err != nil {
+			return pgtype.Int8{}, err
+		}
+		_, err = q.GetProject(ctx, db.GetProjectParams{ID: id, UserID: userID})
+		if errors.Is(err, pgx.ErrNoRows) {
+			return pgtype.Int8{}, fmt.Errorf("%w: project %d not found", errUnknownProject, id)
+		}
+		return pgtype.Int8{Int64: id, Valid: true}, err
+	}
+
+	var id int64
+	if op.ID != nil {
+		var err error
+		if id, err = resolve(op.ID); err != nil {
+			return BatchResult{}, nil, err
+		}
+	}
+
+	taskResult := func(status int, task db.Task) BatchResult {
+		resp := newTaskResponse(task)
+		return BatchResult{Status: status, ID: task.ID, Task: &resp}
+	}
+	projectResult := func(status int, p db.Project) BatchResult {
+		resp := newProjectResponse(p)
+		return BatchResult{Status: status, ID: p.ID, Project: &resp}
+	}
+
+	switch op.Type + "." + op.Op {
+	case "task.create":
+		d := op.data.(*batchTaskCreate)
+		arg := d.CreateTaskRequest.params(userID)
+		var err error
+		if arg.ProjectID, err = project(d.ProjectID); err != nil {
+			return BatchResult{}, nil, err
+		}
+		task, err := repository.CreateTask(ctx, q, userID, arg)
+		if err != nil {
+			return BatchResult{}, nil, err
+		}
+		return taskResult(http.StatusCreated, task), nil, nil
+
+	case "task.update":
+		d := op.data.(*batchTaskUpdate)
+		arg := d.UpdateTaskRequest.params(id, userID)
+		var err error
+		if arg.ProjectID, err = project(d.ProjectID); err != nil {
+			return BatchResult{}, nil, err
+		}
+		task, err := repository.UpdateTask(ctx, q, userID, arg)
+		if err != nil {
+			return BatchResult{}, nil, err
+		}
+		return taskResult(http.StatusOK, task), []int64{id}, nil
+
+	case "task.delete":
+		if _, err := repository.DeleteTask(ctx, q, userID, db.DeleteTaskParams{ID: id, UserID: userID}); err != nil {
+			return BatchResult{}, nil, err
+		}
+		return BatchResult{Status: http.StatusNoContent, ID: id}, []int64{id}, nil
+
+	case "project.create":
+		d := op.data.(*CreateProjectRequest)
+		p, err := q.CreateProject(ctx, db.CreateProjectParams{UserID: userID, Name: d.Name})
+		if err != nil {
+			return BatchResult{}, nil, err
+		}
+		return projectResult(http.StatusCreated, p), nil, nil
+
+	case "project.update":
+		d := op.data.(*UpdateProjectRequest)
+		p, err := q.UpdateProject(ctx, db.UpdateProjectParams{ID: id, UserID: userID, Name: d.Name})
+		if err != nil {
+			return BatchResult{}, nil, err
+		}
+		return projectResult(http.StatusOK, p), nil, nil
+
+	default: // project.delete
+		trashed, err := repository.TrashProject(ctx, q, userID, db.DeleteProjectParams{ID: id, UserID: userID})
+		if err != nil {
+			return BatchResult{}, nil, err
+		}
+		tasks := make([]int64, len(trashed))
+		for i, t := range trashed {
+			tasks[i] = t.ID
+		}
+		return BatchResult{Status: http.StatusNoContent, ID: id}, tasks, nil
+	}
+}
+
+// batchErrorStatus returns the status and error code reported for a failed
+// operation, or 0 for errors that should fail the whole batch.
+func batchErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, errUnresolvedRef):
+		return http.StatusFailedDependency, "unresolved_reference"
+	case errors.Is(err, errUnknownProject):
+		return http.StatusUnprocessableEntity, "unknown_project"
+	case errors.Is(err, pgx.ErrNoRows):
+		return http.StatusNotFound, "not_found"
+	}
+	if code := taskFieldErrorCode(err); code != "" {
+		return http.StatusUnprocessableEntity, code
+	}
+	return 0, ""
+}
@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// Batch modes. An atomic batch is rolled back as a whole when any operation
+// fails; a best-effort batch keeps the operations that succeeded.
+const (
+	BatchAtomic     = "atomic"
+	BatchBestEffort = "best_effort"
+)
+
+// BatchRequest defines the request body for running a list of operations in
+// order. Mode defaults to atomic.
+type BatchRequest struct {
+	Mode       string           `json:"mode" binding:"omitempty,oneof=atomic best_effort"`
+	Operations []BatchOperation `json:"operations" binding:"required,min=1,max=200,dive"`
+}
+
+// BatchOperation is one create, update or delete of a task or project. Data
+// is the body the matching single-item route takes. A create may set TempID
+// so that later operations can use it wherever they take an ID, including
+// a task's project_id.
+type BatchOperation struct {
+	Op     string          `json:"op" binding:"required,oneof=create update delete"`
+	Type   string          `json:"type" binding:"required,oneof=task project"`
+	TempID string          `json:"temp_id" binding:"omitempty,max=64"`
+	ID     *BatchRef       `json:"id"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// BatchRef is either a numeric ID or a temp_id given by an earlier create in
+// the same batch.
+type BatchRef struct {
+	ID     int64
+	TempID string
+}
+
+// UnmarshalJSON accepts a positive number or a non-empty string.
+func (r *BatchRef) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '"' {
+		if err := json.Unmarshal(b, &r.TempID); err != nil {
+			return err
+		}
+		if r.TempID == "" {
+			return errors.New("temp_id reference must not be empty")
+		}
+		return nil
+	}
+	if err := json.Unmarshal(b, &r.ID); err != nil {
+		return err
+	}
+	if r.ID < 1 {
+		return errors.New("id must be positive")
+	}
+	return nil
+}
+
+// batchTaskCreate and batchTaskUpdate are the task bodies with a project_id
+// that may be a temp_id.
+type batchTaskCreate struct {
+	CreateTaskRequest
+	ProjectID *BatchRef `json:"project_id"`
+}
+
+type batchTaskUpdate struct {
+	UpdateTaskRequest
+	ProjectID *BatchRef `json:"project_id"`
+}
+
+// BatchResult is the outcome of one operation. Status is the HTTP status the
+// operation would have had as a single request; operations that were rolled
+// back or never ran because of another one's failure get 424. ID is the
+// real ID of the task or project, which for creates resolves TempID.
+type BatchResult struct {
+	Index   int              `json:"index"`
+	Status  int              `json:"status"`
+	Error   string           `json:"error,omitempty"`
+	Detail  string           `json:"detail,omitempty"`
+	ID      int64            `json:"id,omitempty"`
+	TempID  string           `json:"temp_id,omitempty"`
+	Task    *TaskResponse    `json:"task,omitempty"`
+	Project *ProjectResponse `json:"project,omitempty"`
+}
+
+// BatchResponse lists the outcome of every operation in request order.
+// Committed is false when an atomic batch was rolled back.
+type BatchResponse struct {
+	Mode      string        `json:"mode"`
+	Committed bool          `json:"committed"`
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+	Results   []BatchResult `json:"results"`
+}
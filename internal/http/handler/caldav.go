@@ -0,0 +1,995 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/pavelc4/auriya-todolist-go/internal/cache"
+	"github.com/pavelc4/auriya-todolist-go/internal/caldav"
+	db "github.com/pavelc4/auriya-todolist-go/internal/db/sqlc"
+	"github.com/pavelc4/auriya-todolist-go/internal/http/repository"
+	"github.com/pavelc4/auriya-todolist-go/internal/ical"
+)
+
+// The CalDAV server lives under /dav/. The principal of the signed-in user
+// has one calendar home, which holds a calendar collection for the inbox
+// and one for each project. Tasks are VTODO resources in the collection of
+// their project:
+//
+//	/dav/principal/
+//	/dav/calendars/
+//	/dav/calendars/inbox/
+//	/dav/calendars/<project id>/<name>.ics
+//
+// Tasks made elsewhere are named task-<id>.ics; tasks that clients create
+// keep the name and UID the client gave them.
+const (
+	davRoot          = "/dav/"
+	davPrincipalPath = davRoot + "principal/"
+	davHomePath      = davRoot + "calendars/"
+	davInbox         = "inbox"
+
+	davContentType = "text/calendar; charset=utf-8; component=VTODO"
+	// davSyncTokenPrefix turns sync tokens into the URIs RFC 6578 asks for.
+	davSyncTokenPrefix = "urn:auriya:sync:"
+	// davSyncLimit is the most changes one sync-collection report returns;
+	// the client asks again for the rest.
+	davSyncLimit = 500
+	// maxDAVBodySize caps request bodies.
+	maxDAVBodySize = 1 << 20
+	// davAllow lists the methods the server implements.
+	davAllow = "OPTIONS, GET, HEAD, PUT, DELETE, PROPFIND, REPORT"
+)
+
+// DAVMethods are the methods routed to CalDAVHandler.Serve. The ones it
+// does not implement are answered with 405.
+var DAVMethods = []string{
+	http.MethodOptions, http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete,
+	"PROPFIND", "REPORT", "PROPPATCH", "MKCOL", "MKCALENDAR", "COPY", "MOVE", "LOCK", "UNLOCK",
+}
+
+type CalDAVHandler struct {
+	Store     *repository.Store
+	cache     *cache.Service
+	retention time.Duration
+}
+
+// NewCalDAVHandler returns the CalDAV server. retention is how long sync
+// tombstones are kept; older sync tokens are refused so clients start over.
+func NewCalDAVHandler(store *repository.Store, cache *cache.Service, retention time.Duration) *CalDAVHandler {
+	return &CalDAVHandler{Store: store, cache: cache, retention: retention}
+}
+
+type davKind int
+
+const (
+	davRootKind davKind = iota
+	davPrincipalKind
+	davHomeKind
+	davCollectionKind
+	davObjectKind
+)
+
+// davPath is a parsed request path. Project is unset for the inbox.
+type davPath struct {
+	kind    davKind
+	project pgtype.Int8
+	name    string
+}
+
+// parseDAVPath parses a path below /dav/, such as "calendars/12/x.ics".
+func parseDAVPath(p string) (davPath, bool) {
+	p = strings.Trim(p, "/")
+	if p == "" {
+		return davPath{kind: davRootKind}, true
+	}
+	parts := strings.Split(p, "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "principal":
+		return davPath{kind: davPrincipalKind}, true
+	case parts[0] != "calendars" || len(parts) > 3:
+		return davPath{}, false
+	case len(parts) == 1:
+		return davPath{kind: davHomeKind}, true
+	}
+	path := davPath{kind: davCollectionKind}
+	if parts[1] != davInbox {
+		id, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil || id < 1 {
+			return davPath{}, false
+		}
+		path.project = pgtype.Int8{Int64: id, Valid: true}
+	}
+	if len(parts) == 3 {
+		path.kind, path.name = davObjectKind, parts[2]
+	}
+	return path, true
+}
+
+func collectionHref(project pgtype.Int8) string {
+	if !project.Valid {
+		return davHomePath + davInbox + "/"
+	}
+	return davHomePath + strconv.FormatInt(project.Int64, 10) + "/"
+}
+
+func objectHref(project pgtype.Int8, name string) string {
+	return collectionHref(project) + url.PathEscape(name)
+}
+
+// davCollection is a calendar collection: the inbox or a project.
+type davCollection struct {
+	project pgtype.Int8
+	name    string
+}
+
+// davObject is a task together with the name and UID it has in CalDAV.
+type davObject struct {
+	task db.Task
+	name string
+	uid  string
+}
+
+func defaultObjectName(taskID int64) string {
+	return "task-" + strconv.FormatInt(taskID, 10) + ".ics"
+}
+
+// davObjects pairs tasks with their names and UIDs.
+func davObjects(ctx context.Context, q *db.Queries, userID int64, tasks []db.Task) ([]davObject, error) {
+	names, err := davNames(ctx, q, userID, taskIDs(tasks))
+	if err != nil {
+		return nil, err
+	}
+	objects := make([]davObject, 0, len(tasks))
+	for _, t := range tasks {
+		o := davObject{task: t, name: defaultObjectName(t.ID), uid: taskUID(t.ID)}
+		if row, ok := names[t.ID]; ok {
+			o.name, o.uid = row.Name, row.Uid
+		}
+		objects = append(objects, o)
+	}
+	return objects, nil
+}
+
+// davNames returns the names clients gave the tasks that have one.
+func davNames(ctx context.Context, q *db.Queries, userID int64, ids []int64) (map[int64]db.CaldavObject, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	rows, err := q.ListCaldavObjects(ctx, db.ListCaldavObjectsParams{UserID: userID, TaskIds: ids})
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[int64]db.CaldavObject, len(rows))
+	for _, row := range rows {
+		names[row.TaskID] = row
+	}
+	return names, nil
+}
+
+func taskIDs(tasks []db.Task) []int64 {
+	ids := make([]int64, len(tasks))
+	for i, t := range tasks {
+		ids[i] = t.ID
+	}
+	return ids
+}
+
+// findDAVObject returns the live task served under name, in whichever
+// collection it is. It fails with pgx.ErrNoRows when there is none. With
+// lock set the task row is locked for a write.
+func findDAVObject(ctx context.Context, q *db.Queries, userID int64, name string, lock bool) (davObject, error) {
+	get := q.GetTask
+	if lock {
+		get = func(ctx context.Context, arg db.GetTaskParams) (db.Task, error) {
+			return q.GetTaskForUpdate(ctx, db.GetTaskForUpdateParams(arg))
+		}
+	}
+
+	row, err := q.GetCaldavObjectByName(ctx, db.GetCaldavObjectByNameParams{UserID: userID, Name: name})
+	if err == nil {
+		task, err := get(ctx, db.GetTaskParams{ID: row.TaskID, UserID: userID})
+		if err != nil {
+			return davObject{}, err
+		}
+		return davObject{task: task, name: row.Name, uid: row.Uid}, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return davObject{}, err
+	}
+
+	// Not a client's name; it may be the default name of a task.
+	idText, ok := strings.CutPrefix(name, "task-")
+	if !ok {
+		return davObject{}, pgx.ErrNoRows
+	}
+	idText, ok = strings.CutSuffix(idText, ".ics")
+	id, err := strconv.ParseInt(idText, 10, 64)
+	if !ok || err != nil || id < 1 {
+		return davObject{}, pgx.ErrNoRows
+	}
+	task, err := get(ctx, db.GetTaskParams{ID: id, UserID: userID})
+	if err != nil {
+		return davObject{}, err
+	}
+	names, err := davNames(ctx, q, userID, []int64{id})
+	if err != nil {
+		return davObject{}, err
+	}
+	if _, renamed := names[id]; renamed {
+		return davObject{}, pgx.ErrNoRows
+	}
+	return davObject{task: task, name: name, uid: taskUID(id)}, nil
+}
+
+// davCalendar renders a task as the calendar object resource clients get.
+func davCalendar(o davObject) *ical.Component {
+	cal := ical.NewComponent("VCALENDAR")
+	cal.Add("VERSION", "2.0")
+	cal.Add("PRODID", "-//Auriya//Todolist//EN")
+	todo := taskTodo(o.task)
+	for i := range todo.Props {
+		if todo.Props[i].Name == "UID" {
+			todo.Props[i].Value = o.uid
+		}
+	}
+	cal.Append(todo)
+	return cal
+}
+
+func encodeCalendar(cal *ical.Component) string {
+	var b bytes.Buffer
+	ical.Encode(&b, cal)
+	return b.String()
+}
+
+// Serve answers every request below /dav/.
+func (h *CalDAVHandler) Serve(c *gin.Context) {
+	path, ok := parseDAVPath(c.Param("path"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxDAVBodySize)
+
+	switch c.Request.Method {
+	case http.MethodOptions:
+		c.Header("DAV", "1, calendar-access")
+		c.Header("Allow", davAllow)
+		c.Status(http.StatusOK)
+	case "PROPFIND":
+		h.propfind(c, path)
+	case "REPORT":
+		h.report(c, path)
+	case http.MethodGet, http.MethodHead:
+		h.get(c, path)
+	case http.MethodPut:
+		h.put(c, path)
+	case http.MethodDelete:
+		h.delete(c, path)
+	default:
+		c.Header("Allow", davAllow)
+		c.JSON(http.StatusMethodNotAllowed, gin.H{"error": "method_not_allowed"})
+	}
+}
+
+// WellKnown points clients that look up /.well-known/caldav at the server.
+func (h *CalDAVHandler) WellKnown(c *gin.Context) {
+	c.Redirect(http.StatusMovedPermanently, davRoot)
+}
+
+// collection loads the collection of a path; it fails with pgx.ErrNoRows
+// for a project that does not exist.
+func (h *CalDAVHandler) collection(ctx context.Context, userID int64, project pgtype.Int8) (davCollection, error) {
+	if !project.Valid {
+		return davCollection{name: "Inbox"}, nil
+	}
+	p, err := h.Store.Queries.GetProject(ctx, db.GetProjectParams{ID: project.Int64, UserID: userID})
+	if err != nil {
+		return davCollection{}, err
+	}
+	return davCollection{project: project, name: p.Name}, nil
+}
+
+// syncToken returns a token for the user's latest change. It serves as
+// both the collections' sync-token and their ctag, so a change to any task
+// or project marks every collection as changed.
+func (h *CalDAVHandler) syncToken(ctx context.Context, userID int64) (string, error) {
+	issued := time.Now()
+	seq, err := h.Store.Queries.GetLatestChangeSeq(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	return davSyncTokenPrefix + encodeSyncToken(seq, issued), nil
+}
+
+// Properties every resource has.
+func davCommonProps() []caldav.Prop {
+	return []caldav.Prop{
+		{Name: caldav.DAV("current-user-principal"), Value: caldav.Href(davPrincipalPath)},
+		{Name: caldav.DAV("current-user-privilege-set"), Value: "<d:privilege><d:read/></d:privilege><d:privilege><d:write/></d:privilege>"},
+	}
+}
+
+func (h *CalDAVHandler) principalProps(ctx context.Context, userID int64) ([]caldav.Prop, error) {
+	user, err := h.Store.Queries.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	name := user.Email
+	if user.FullName.Valid && user.FullName.String != "" {
+		name = user.FullName.String
+	}
+	return append(davCommonProps(),
+		caldav.Prop{Name: caldav.DAV("resourcetype"), Value: "<d:principal/>"},
+		caldav.Prop{Name: caldav.DAV("displayname"), Value: caldav.Text(name)},
+		caldav.Prop{Name: caldav.DAV("principal-URL"), Value: caldav.Href(davPrincipalPath)},
+		caldav.Prop{Name: caldav.Cal("calendar-home-set"), Value: caldav.Href(davHomePath)},
+		caldav.Prop{Name: caldav.Cal("calendar-user-address-set"), Value: caldav.Href("mailto:" + user.Email)},
+	), nil
+}
+
+func collectionProps(col davCollection, syncToken string) []caldav.Prop {
+	return append(davCommonProps(),
+		caldav.Prop{Name: caldav.DAV("resourcetype"), Value: "<d:collection/><c:calendar/>"},
+		caldav.Prop{Name: caldav.DAV("displayname"), Value: caldav.Text(col.name)},
+		caldav.Prop{Name: caldav.DAV("owner"), Value: caldav.Href(davPrincipalPath)},
+		caldav.Prop{Name: caldav.DAV("sync-token"), Value: caldav.Text(syncToken)},
+		caldav.Prop{Name: caldav.CS("getctag"), Value: caldav.Text(syncToken)},
+		caldav.Prop{Name: caldav.DAV("supported-report-set"), Value: "<d:supported-report><d:report><c:calendar-query/></d:report></d:supported-report>" +
+			"<d:supported-report><d:report><c:calendar-multiget/></d:report></d:supported-report>" +
+			"<d:supported-report><d:report><d:sync-collection/></d:report></d:supported-report>"},
+		caldav.Prop{Name: caldav.Cal("supported-calendar-component-set"), Value: `<c:comp name="VTODO"/>`},
+		caldav.Prop{Name: caldav.Cal("supported-calendar-data"), Value: `<c:calendar-data content-type="text/calendar" version="2.0"/>`},
+		caldav.Prop{Name: caldav.Cal("max-resource-size"), Value: strconv.Itoa(maxDAVBodySize)},
+	)
+}
+
+// objectProps returns the properties of a task resource; calendar data is
+// only included when withData is set.
+func objectProps(o davObject, withData bool) []caldav.Prop {
+	props := append(davCommonProps(),
+		caldav.Prop{Name: caldav.DAV("resourcetype")},
+		caldav.Prop{Name: caldav.DAV("getetag"), Value: caldav.Text(versionETag(o.task.Version))},
+		caldav.Prop{Name: caldav.DAV("getcontenttype"), Value: caldav.Text(davContentType)},
+		caldav.Prop{Name: caldav.DAV("getlastmodified"), Value: o.task.UpdatedAt.Time.UTC().Format(http.TimeFormat)},
+	)
+	if withData {
+		props = append(props, caldav.Prop{Name: caldav.Cal("calendar-data"), Value: caldav.Text(encodeCalendar(davCalendar(o)))})
+	}
+	return props
+}
+
+func davResponse(href string, props []caldav.Prop, req caldav.PropRequest) caldav.Response {
+	found, missing := caldav.Select(props, req)
+	return caldav.Response{Href: href, Props: found, Missing: missing}
+}
+
+func writeMultistatus(c *gin.Context, ms caldav.Multistatus) {
+	c.Data(http.StatusMultiStatus, "application/xml; charset=utf-8", ms.Encode())
+}
+
+// writeDAVError answers with the precondition or postcondition a request
+// failed.
+func writeDAVError(c *gin.Context, status int, condition xml.Name) {
+	c.Data(status, "application/xml; charset=utf-8", caldav.ErrorBody(condition))
+}
+
+// writeDAVDBError answers err, which is pgx.ErrNoRows for resources that
+// do not exist.
+func writeDAVDBError(c *gin.Context, err error) {
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+}
+
+// propfind lists properties of a resource and, with Depth: 1, of its
+// members. Depth: infinity is treated as 1.
+func (h *CalDAVHandler) propfind(c *gin.Context, path davPath) {
+	root, err := caldav.ParseXML(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "detail": err.Error()})
+		return
+	}
+	req, err := caldav.ParsePropFind(root)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "detail": err.Error()})
+		return
+	}
+	depth := c.GetHeader("Depth") != "0"
+
+	ctx := c.Request.Context()
+	userID := c.GetInt64("userID")
+	var ms caldav.Multistatus
+	add := func(href string, props []caldav.Prop) {
+		ms.Responses = append(ms.Responses, davResponse(href, props, req))
+	}
+	homeProps := append(davCommonProps(),
+		caldav.Prop{Name: caldav.DAV("resourcetype"), Value: "<d:collection/>"},
+		caldav.Prop{Name: caldav.DAV("displayname"), Value: "Calendars"},
+	)
+
+	switch path.kind {
+	case davRootKind:
+		add(davRoot, append(davCommonProps(), caldav.Prop{Name: caldav.DAV("resourcetype"), Value: "<d:collection/>"}))
+		if depth {
+			props, err := h.principalProps(ctx, userID)
+			if err != nil {
+				writeDAVDBError(c, err)
+				return
+			}
+			add(davPrincipalPath, props)
+			add(davHomePath, homeProps)
+		}
+	case davPrincipalKind:
+		props, err := h.principalProps(ctx, userID)
+		if err != nil {
+			writeDAVDBError(c, err)
+			return
+		}
+		add(davPrincipalPath, props)
+	case davHomeKind:
+		add(davHomePath, homeProps)
+		if depth {
+			token, err := h.syncToken(ctx, userID)
+			if err != nil {
+				writeDAVDBError(c, err)
+				return
+			}
+			projects, err := h.Store.Queries.ListProjects(ctx, userID)
+			if err != nil {
+				writeDAVDBError(c, err)
+				return
+			}
+			add(collectionHref(pgtype.Int8{}), collectionProps(davCollection{name: "Inbox"}, token))
+			for _, p := range projects {
+				project := pgtype.Int8{Int64: p.ID, Valid: true}
+				add(collectionHref(project), collectionProps(davCollection{project: project, name: p.Name}, token))
+			}
+		}
+	case davCollectionKind:
+		col, err := h.collection(ctx, userID, path.project)
+		if err != nil {
+			writeDAVDBError(c, err)
+			return
+		}
+		token, err := h.syncToken(ctx, userID)
+		if err != nil {
+			writeDAVDBError(c, err)
+			return
+		}
+		add(collectionHref(col.project), collectionProps(col, token))
+		if depth {
+			objects, err := h.collectionObjects(ctx, userID, col.project)
+			if err != nil {
+				writeDAVDBError(c, err)
+				return
+			}
+			for _, o := range objects {
+				add(objectHref(col.project, o.name), objectProps(o, false))
+			}
+		}
+	case davObjectKind:
+		o, err := h.object(ctx, userID, path)
+		if err != nil {
+			writeDAVDBError(c, err)
+			return
+		}
+		add(objectHref(path.project, o.name), objectProps(o, false))
+	}
+	writeMultistatus(c, ms)
+}
+
+func (h *CalDAVHandler) collectionObjects(ctx context.Context, userID int64, project pgtype.Int8) ([]davObject, error) {
+	tasks, err := h.Store.Queries.ListCollectionTasks(ctx, db.ListCollectionTasksParams{UserID: userID, ProjectID: project})
+	if err != nil {
+		return nil, err
+	}
+	return davObjects(ctx, h.Store.Queries, userID, tasks)
+}
+
+// object loads the task a path names. Tasks are only found in the
+// collection of their project.
+func (h *CalDAVHandler) object(ctx context.Context, userID int64, path davPath) (davObject, error) {
+	o, err := findDAVObject(ctx, h.Store.Queries, userID, path.name, false)
+	if err != nil {
+		return davObject{}, err
+	}
+	if o.task.ProjectID != path.project {
+		return davObject{}, pgx.ErrNoRows
+	}
+	return o, nil
+}
+
+// report answers the calendar-query, calendar-multiget and sync-collection
+// reports on a collection.
+func (h *CalDAVHandler) report(c *gin.Context, path davPath) {
+	root, err := caldav.ParseXML(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "detail": err.Error()})
+		return
+	}
+	r, err := caldav.ParseReport(root)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "detail": err.Error()})
+		return
+	}
+	if path.kind != davCollectionKind {
+		writeDAVError(c, http.StatusForbidden, caldav.DAV("supported-report"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetInt64("userID")
+	col, err := h.collection(ctx, userID, path.project)
+	if err != nil {
+		writeDAVDBError(c, err)
+		return
+	}
+
+	switch r.Kind {
+	case caldav.Cal("calendar-query"):
+		objects, err := h.collectionObjects(ctx, userID, col.project)
+		if err != nil {
+			writeDAVDBError(c, err)
+			return
+		}
+		var ms caldav.Multistatus
+		for _, o := range objects {
+			if r.Filter.Match(davCalendar(o)) {
+				ms.Responses = append(ms.Responses, davResponse(objectHref(col.project, o.name), objectProps(o, true), r.Props))
+			}
+		}
+		writeMultistatus(c, ms)
+	case caldav.Cal("calendar-multiget"):
+		objects, err := h.collectionObjects(ctx, userID, col.project)
+		if err != nil {
+			writeDAVDBError(c, err)
+			return
+		}
+		byName := make(map[string]davObject, len(objects))
+		for _, o := range objects {
+			byName[o.name] = o
+		}
+		var ms caldav.Multistatus
+		for _, href := range r.Hrefs {
+			u, err := url.Parse(href)
+			name, ok := "", err == nil
+			if ok {
+				name, ok = strings.CutPrefix(u.Path, collectionHref(col.project))
+			}
+			if o, found := byName[name]; ok && found {
+				ms.Responses = append(ms.Responses, davResponse(href, objectProps(o, true), r.Props))
+			} else {
+				ms.Responses = append(ms.Responses, caldav.Response{Href: href, Status: http.StatusNotFound})
+			}
+		}
+		writeMultistatus(c, ms)
+	case caldav.DAV("sync-collection"):
+		h.syncCollection(c, col, r)
+	default:
+		writeDAVError(c, http.StatusForbidden, caldav.DAV("supported-report"))
+	}
+}
+
+// syncCollection answers a sync-collection report from the change numbers
+// that also drive GET /api/sync. Without a token it lists every task in
+// the collection. With one it lists the tasks changed since, and reports
+// tasks that were deleted or changed in another collection as gone; the
+// latter may include tasks the client never had, which clients ignore.
+func (h *CalDAVHandler) syncCollection(c *gin.Context, col davCollection, r caldav.Report) {
+	if r.SyncLevel != "1" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "detail": "only sync-level 1 is supported"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetInt64("userID")
+	// Taken before the snapshot, so the next token is never newer than
+	// what it covers.
+	issued := time.Now()
+	var ms caldav.Multistatus
+
+	if r.SyncToken == "" {
+		var seq int64
+		var tasks []db.Task
+		err := h.Store.ExecSnapshot(ctx, func(q *db.Queries) error {
+			var err error
+			if seq, err = q.GetLatestChangeSeq(ctx, userID); err != nil {
+				return err
+			}
+			tasks, err = q.ListCollectionTasks(ctx, db.ListCollectionTasksParams{UserID: userID, ProjectID: col.project})
+			return err
+		})
+		if err != nil {
+			writeDAVDBError(c, err)
+			return
+		}
+		objects, err := davObjects(ctx, h.Store.Queries, userID, tasks)
+		if err != nil {
+			writeDAVDBError(c, err)
+			return
+		}
+		for _, o := range objects {
+			ms.Responses = append(ms.Responses, davResponse(objectHref(col.project, o.name), objectProps(o, true), r.Props))
+		}
+		ms.SyncToken = davSyncTokenPrefix + encodeSyncToken(seq, issued)
+		writeMultistatus(c, ms)
+		return
+	}
+
+	raw, ok := strings.CutPrefix(r.SyncToken, davSyncTokenPrefix)
+	after, since, err := decodeSyncToken(raw)
+	if !ok || err != nil || since.Before(issued.Add(-h.retention+syncTokenSlack)) {
+		writeDAVError(c, http.StatusForbidden, caldav.DAV("valid-sync-token"))
+		return
+	}
+	limit := davSyncLimit
+	if r.Limit > 0 && r.Limit < limit {
+		limit = r.Limit
+	}
+	changes, more, err := listChanges(ctx, h.Store, userID, after, int32(limit))
+	if err != nil {
+		writeDAVDBError(c, err)
+		return
+	}
+
+	var ids []int64
+	for _, ch := range changes {
+		if ch.Type == "task" {
+			ids = append(ids, ch.ID)
+		}
+	}
+	names, err := davNames(ctx, h.Store.Queries, userID, ids)
+	if err != nil {
+		writeDAVDBError(c, err)
+		return
+	}
+	for _, ch := range changes {
+		after = ch.Seq
+		if ch.Type != "task" {
+			continue
+		}
+		o := davObject{name: defaultObjectName(ch.ID), uid: taskUID(ch.ID)}
+		if row, ok := names[ch.ID]; ok {
+			o.name, o.uid = row.Name, row.Uid
+		}
+		href := objectHref(col.project, o.name)
+		if ch.Task == nil || ch.Task.ProjectID != col.project {
+			ms.Responses = append(ms.Responses, caldav.Response{Href: href, Status: http.StatusNotFound})
+			continue
+		}
+		o.task = *ch.Task
+		ms.Responses = append(ms.Responses, davResponse(href, objectProps(o, true), r.Props))
+	}
+	if more {
+		// RFC 6578 section 3.6: the rest comes with the next token.
+		ms.Responses = append(ms.Responses, caldav.Response{Href: collectionHref(col.project), Status: http.StatusInsufficientStorage})
+	}
+	ms.SyncToken = davSyncTokenPrefix + encodeSyncToken(after, issued)
+	writeMultistatus(c, ms)
+}
+
+// get serves a task as an iCalendar object.
+func (h *CalDAVHandler) get(c *gin.Context, path davPath) {
+	if path.kind != davObjectKind {
+		c.Header("Allow", "OPTIONS, PROPFIND, REPORT")
+		c.JSON(http.StatusMethodNotAllowed, gin.H{"error": "method_not_allowed"})
+		return
+	}
+	o, err := h.object(c.Request.Context(), c.GetInt64("userID"), path)
+	if err != nil {
+		writeDAVDBError(c, err)
+		return
+	}
+	if notModified(c, versionETag(o.task.Version)) {
+		return
+	}
+	c.Data(http.StatusOK, davContentType, []byte(encodeCalendar(davCalendar(o))))
+}
+
+// put creates or replaces a task from a VTODO. The stored task only keeps
+// what maps onto task fields, so, as RFC 4791 section 5.3.4 asks, no ETag
+// is returned and clients fetch the task again.
+func (h *CalDAVHandler) put(c *gin.Context, path davPath) {
+	if path.kind != davObjectKind {
+		c.Header("Allow", "OPTIONS, PROPFIND, REPORT")
+		c.JSON(http.StatusMethodNotAllowed, gin.H{"error": "method_not_allowed"})
+		return
+	}
+	if len(path.name) > 255 || !utf8.ValidString(path.name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "detail": "resource names must be valid UTF-8 and at most 255 bytes"})
+		return
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "invalid_request", "detail": err.Error()})
+		return
+	}
+	cal, err := ical.Decode(bytes.NewReader(body))
+	if err != nil {
+		writeDAVError(c, http.StatusForbidden, caldav.Cal("valid-calendar-data"))
+		return
+	}
+	fields, condition := parseDAVTodo(cal)
+	if condition != "" {
+		writeDAVError(c, http.StatusForbidden, caldav.Cal(condition))
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetInt64("userID")
+	if _, err := h.collection(ctx, userID, path.project); err != nil {
+		writeDAVDBError(c, err)
+		return
+	}
+
+	ifNoneMatch := c.GetHeader("If-None-Match")
+	match := requestIfMatch(c)
+	created := false
+	var task db.Task
+	err = h.Store.ExecTx(ctx, func(q *db.Queries) error {
+		o, err := findDAVObject(ctx, q, userID, path.name, true)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return err
+		}
+		exists := err == nil
+		if exists && ifNoneMatch != "" && etagMatches(ifNoneMatch, versionETag(o.task.Version), true) {
+			return errPreconditionFailed
+		}
+		if match != "" && (!exists || match.check(o.task.Version) != nil) {
+			return errPreconditionFailed
+		}
+
+		w, err := repository.LoadWorkflow(ctx, q, path.project)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			arg := fields.createParams(userID, path.project)
+			if s, ok := w.First(fields.category); ok {
+				arg.Status = s.Key
+			}
+			if task, err = repository.CreateTask(ctx, q, userID, arg); err != nil {
+				return err
+			}
+			// The name may still belong to a task in the trash.
+			if err := q.DeleteCaldavObjectByName(ctx, db.DeleteCaldavObjectByNameParams{UserID: userID, Name: path.name}); err != nil {
+				return err
+			}
+			created = true
+			return q.SetCaldavObject(ctx, db.SetCaldavObjectParams{TaskID: task.ID, UserID: userID, Name: path.name, Uid: fields.uid})
+		}
+
+		arg := fields.updateParams(o.task.ID, userID)
+		// A PUT into another collection moves the task there.
+		if o.task.ProjectID != path.project {
+			arg.ProjectID = path.project
+			arg.ClearProject = !path.project.Valid
+		}
+		if o.task.StatusCategory != fields.category {
+			if s, ok := w.First(fields.category); ok {
+				arg.Status = pgtype.Text{String: s.Key, Valid: true}
+			}
+		}
+		if task, err = repository.UpdateTask(ctx, q, userID, arg); err != nil {
+			return err
+		}
+		if fields.uid == o.uid {
+			return nil
+		}
+		return q.SetCaldavObject(ctx, db.SetCaldavObjectParams{TaskID: task.ID, UserID: userID, Name: path.name, Uid: fields.uid})
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, errPreconditionFailed):
+			writePreconditionFailed(c)
+		case taskFieldErrorCode(err) != "":
+			writeDAVError(c, http.StatusForbidden, caldav.Cal("valid-calendar-object-resource"))
+		default:
+			writeDAVDBError(c, err)
+		}
+		return
+	}
+
+	h.cache.Delete(fmt.Sprintf("task:%d", task.ID))
+	if created {
+		c.Header("Location", objectHref(path.project, path.name))
+		c.Status(http.StatusCreated)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// delete moves a task to the trash. Collections cannot be deleted over
+// CalDAV.
+func (h *CalDAVHandler) delete(c *gin.Context, path davPath) {
+	if path.kind != davObjectKind {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden", "detail": "only tasks can be deleted"})
+		return
+	}
+	ctx := c.Request.Context()
+	userID := c.GetInt64("userID")
+	match := requestIfMatch(c)
+	var taskID int64
+	err := h.Store.ExecTx(ctx, func(q *db.Queries) error {
+		o, err := findDAVObject(ctx, q, userID, path.name, true)
+		if err != nil {
+			return err
+		}
+		if o.task.ProjectID != path.project {
+			return pgx.ErrNoRows
+		}
+		if err := match.check(o.task.Version); err != nil {
+			return err
+		}
+		taskID = o.task.ID
+		_, err = repository.DeleteTask(ctx, q, userID, db.DeleteTaskParams{ID: o.task.ID, UserID: userID})
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, errPreconditionFailed) {
+			writePreconditionFailed(c)
+			return
+		}
+		writeDAVDBError(c, err)
+		return
+	}
+
+	h.cache.Delete(fmt.Sprintf("task:%d", taskID))
+	c.Status(http.StatusNoContent)
+}
+
+// davTodoFields are the task fields read from a VTODO.
+type davTodoFields struct {
+	uid         string
+	title       string
+	description *string
+	due         pgtype.Timestamptz
+	category    string
+	priority    int32
+	tags        []string
+	recurrence  string
+}
+
+// parseDAVTodo reads the to-do of a calendar object resource. On failure
+// it returns the CalDAV precondition the data violates. Of a repeating
+// to-do with exceptions only the master is read.
+func parseDAVTodo(cal *ical.Component) (davTodoFields, string) {
+	if cal.Name != "VCALENDAR" {
+		return davTodoFields{}, "valid-calendar-data"
+	}
+	var todo *ical.Component
+	for _, comp := range cal.Components {
+		switch comp.Name {
+		case "VTIMEZONE":
+			continue
+		case "VTODO":
+		default:
+			return davTodoFields{}, "supported-calendar-component"
+		}
+		if todo != nil && comp.Prop("UID") != nil && todo.Prop("UID") != nil && comp.Prop("UID").Value != todo.Prop("UID").Value {
+			return davTodoFields{}, "valid-calendar-object-resource"
+		}
+		if todo == nil || comp.Prop("RECURRENCE-ID") == nil {
+			todo = comp
+		}
+	}
+	if todo == nil {
+		return davTodoFields{}, "supported-calendar-component"
+	}
+	uid := todo.Prop("UID")
+	if uid == nil || uid.Value == "" {
+		return davTodoFields{}, "valid-calendar-object-resource"
+	}
+
+	f := davTodoFields{uid: uid.Value, category: repository.CategoryTodo, tags: []string{}}
+	if p := todo.Prop("SUMMARY"); p != nil {
+		f.title = strings.TrimSpace(ical.ParseText(p.Value))
+	}
+	if f.title == "" {
+		f.title = "Untitled"
+	}
+	if n := []rune(f.title); len(n) > 255 {
+		f.title = string(n[:255])
+	}
+	if p := todo.Prop("DESCRIPTION"); p != nil {
+		if d := ical.ParseText(p.Value); d != "" {
+			f.description = &d
+		}
+	}
+	if p := todo.Prop("DUE"); p != nil {
+		t, _, err := ical.ParseDateTime(*p)
+		if err != nil {
+			return davTodoFields{}, "valid-calendar-data"
+		}
+		f.due = pgtype.Timestamptz{Time: t, Valid: true}
+	}
+	status := ""
+	if p := todo.Prop("STATUS"); p != nil {
+		status = strings.ToUpper(p.Value)
+	}
+	switch {
+	case status == "COMPLETED" || status == "CANCELLED" || todo.Prop("COMPLETED") != nil:
+		f.category = repository.CategoryDone
+	case status == "IN-PROCESS":
+		f.category = repository.CategoryDoing
+	}
+	if p := todo.Prop("PRIORITY"); p != nil {
+		if v, err := strconv.Atoi(p.Value); err == nil && v >= 1 && v <= 9 {
+			f.priority = taskPriority(int32(v))
+		}
+	}
+	for _, p := range todo.PropsNamed("CATEGORIES") {
+		for _, tag := range ical.ParseTextList(p.Value) {
+			if tag = strings.TrimSpace(tag); tag != "" && len(f.tags) < 20 {
+				f.tags = append(f.tags, tag)
+			}
+		}
+	}
+	if p := todo.Prop("RRULE"); p != nil {
+		f.recurrence = p.Value
+	}
+	return f, ""
+}
+
+// taskPriority maps iCalendar's 1 (highest) to 9 (lowest) onto task
+// priorities; it undoes icalPriority.
+func taskPriority(p int32) int32 {
+	return (11 - p) / 2
+}
+
+func (f davTodoFields) createParams(userID int64, project pgtype.Int8) db.CreateTaskParams {
+	arg := db.CreateTaskParams{
+		Title:       f.title,
+		Description: f.description,
+		Priority:    f.priority,
+		DueDate:     f.due,
+		UserID:      userID,
+		ProjectID:   project,
+		Tags:        f.tags,
+	}
+	if f.recurrence != "" {
+		arg.Recurrence = &f.recurrence
+	}
+	return arg
+}
+
+// updateParams replaces the task's fields with the to-do's; what the to-do
+// leaves out is cleared, except the priority, which is kept. Tasks without
+// a due date are served without their repeat rule, so for those it is kept
+// too.
+func (f davTodoFields) updateParams(id, userID int64) db.UpdateTaskParams {
+	arg := db.UpdateTaskParams{
+		ID:               id,
+		UserID:           userID,
+		Title:            pgtype.Text{String: f.title, Valid: true},
+		ClearDescription: f.description == nil,
+		Description:      f.description,
+		ClearDueDate:     !f.due.Valid,
+		DueDate:          f.due,
+		Tags:             f.tags,
+	}
+	if f.recurrence != "" || f.due.Valid {
+		arg.Recurrence = &f.recurrence
+	}
+	if f.priority != 0 {
+		arg.Priority = pgtype.Int4{Int32: f.priority, Valid: true}
+	}
+	return arg
+}
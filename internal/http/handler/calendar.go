@@ -0,0 +1,326 @@
+package handler
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/pavelc4/auriya-todolist-go/internal/db/sqlc"
+	"github.com/pavelc4/auriya-todolist-go/internal/http/repository"
+	"github.com/pavelc4/auriya-todolist-go/internal/ical"
+)
+
+// maxFeedTasks caps the tasks in one calendar feed, soonest due first.
+const maxFeedTasks = 2000
+
+// defaultEventDuration is how long feed events last for tasks without an
+// estimate.
+const defaultEventDuration = 30 * time.Minute
+
+type CalendarHandler struct {
+	Store *repository.Store
+}
+
+func NewCalendarHandler(store *repository.Store) *CalendarHandler {
+	return &CalendarHandler{Store: store}
+}
+
+// hashFeedToken returns what is stored for a feed token.
+func hashFeedToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
+
+// feedURL returns the feed URL for a token on the host the request came to.
+func feedURL(c *gin.Context, token string) string {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host + "/ical/" + token + ".ics"
+}
+
+func newCalendarFeedResponse(f db.CalendarFeed) CalendarFeedResponse {
+	resp := CalendarFeedResponse{Enabled: true, CreatedAt: &f.CreatedAt.Time}
+	if f.LastUsedAt.Valid {
+		resp.LastUsedAt = &f.LastUsedAt.Time
+	}
+	return resp
+}
+
+// FeedInfo says whether the user has a calendar feed. The URL itself is
+// only shown when the token is made.
+func (h *CalendarHandler) FeedInfo(c *gin.Context) {
+	f, err := h.Store.Queries.GetCalendarFeed(c.Request.Context(), c.GetInt64("userID"))
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusOK, CalendarFeedResponse{Enabled: false})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, newCalendarFeedResponse(f))
+}
+
+// RegenerateFeed makes a new feed token and returns the feed URL. The
+// previous URL stops working.
+func (h *CalendarHandler) RegenerateFeed(c *gin.Context) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "detail": err.Error()})
+		return
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	f, err := h.Store.Queries.SetCalendarFeedToken(c.Request.Context(), db.SetCalendarFeedTokenParams{
+		UserID:    c.GetInt64("userID"),
+		TokenHash: hashFeedToken(token),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		return
+	}
+
+	resp := newCalendarFeedResponse(f)
+	resp.Token = token
+	resp.URL = feedURL(c, token)
+	c.JSON(http.StatusOK, resp)
+}
+
+// RevokeFeed turns the calendar feed off.
+func (h *CalendarHandler) RevokeFeed(c *gin.Context) {
+	n, err := h.Store.Queries.DeleteCalendarFeed(c.Request.Context(), c.GetInt64("userID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		return
+	}
+	if n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Feed serves the tasks with a due date as an iCalendar file. It is public:
+// the secret token in the path identifies the user.
+func (h *CalendarHandler) Feed(c *gin.Context) {
+	ctx := c.Request.Context()
+	token := strings.TrimSuffix(c.Param("file"), ".ics")
+	f, err := h.Store.Queries.UseCalendarFeedToken(ctx, hashFeedToken(token))
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		return
+	}
+	userID := f.UserID
+
+	var q CalendarFeedQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_query", "detail": err.Error()})
+		return
+	}
+	loc, err := time.LoadLocation(q.Tz)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_query", "detail": "tz: " + err.Error()})
+		return
+	}
+	alarm, err := parseFeedAlarm(q.Alarm)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_query", "detail": "alarm: " + err.Error()})
+		return
+	}
+
+	name := "Auriya tasks"
+	arg := db.FilterTasksParams{UserID: userID}
+	switch {
+	case q.Filter != "":
+		// Filters are looked up as the feed's user.
+		c.Set("userID", userID)
+		filter, err := (&FilterHandler{Store: h.Store}).lookupFilter(c, q.Filter)
+		if err != nil {
+			writeFilterError(c, err)
+			return
+		}
+		arg = filter.Definition.params(userID, time.Now().In(loc))
+		name += ": " + filter.Name
+	case q.ProjectID != 0:
+		p, err := h.Store.Queries.GetProject(ctx, db.GetProjectParams{ID: q.ProjectID, UserID: userID})
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+			return
+		}
+		arg.ProjectIds = []int64{p.ID}
+		name += ": " + p.Name
+	}
+	if len(arg.Categories) == 0 && !q.IncludeDone {
+		arg.Categories = openCategories
+	}
+	arg.HasDueDate = pgtype.Bool{Bool: true, Valid: true}
+	arg.Sort = "due_date"
+	arg.Limit = maxFeedTasks
+
+	tasks, err := h.Store.Queries.FilterTasks(ctx, arg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		return
+	}
+
+	cal := newCalendar(name)
+	for _, t := range tasks {
+		var entry *ical.Component
+		if q.Kind == "todo" {
+			entry = taskTodo(t)
+		} else {
+			entry = taskEvent(t)
+		}
+		if alarm != nil {
+			entry.Append(taskAlarm(t, *alarm, q.Kind == "todo"))
+		}
+		cal.Append(entry)
+	}
+
+	c.Header("Content-Type", "text/calendar; charset=utf-8")
+	c.Header("Content-Disposition", `inline; filename="tasks.ics"`)
+	c.Header("Cache-Control", "private, max-age=300")
+	c.Status(http.StatusOK)
+	ical.Encode(c.Writer, cal)
+}
+
+// parseFeedAlarm reads the alarm query parameter: nil for "off", otherwise
+// how long before the due date the alarm goes off.
+func parseFeedAlarm(s string) (*time.Duration, error) {
+	if s == "off" {
+		return nil, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return nil, err
+	}
+	if d < 0 || d > 7*24*time.Hour {
+		return nil, errors.New("must be between 0s and 168h")
+	}
+	return &d, nil
+}
+
+// newCalendar returns an empty VCALENDAR named for calendar apps.
+func newCalendar(name string) *ical.Component {
+	cal := ical.NewComponent("VCALENDAR")
+	cal.Add("VERSION", "2.0")
+	cal.Add("PRODID", "-//Auriya//Todolist//EN")
+	cal.Add("CALSCALE", "GREGORIAN")
+	cal.Add("METHOD", "PUBLISH")
+	cal.AddText("X-WR-CALNAME", name)
+	cal.Add("REFRESH-INTERVAL", "PT1H", ical.Param{Name: "VALUE", Value: "DURATION"})
+	cal.Add("X-PUBLISHED-TTL", "PT1H")
+	return cal
+}
+
+// taskUID is the iCalendar UID of a task, which stays the same across feeds
+// and edits.
+func taskUID(id int64) string {
+	return "task-" + strconv.FormatInt(id, 10) + "@auriya"
+}
+
+// icalPriority maps task priorities, 5 being the most urgent, onto
+// iCalendar's 1 (highest) to 9 (lowest).
+func icalPriority(p int32) int32 {
+	return min(max(11-2*p, 1), 9)
+}
+
+// addTaskProps adds what tasks look like in any calendar component.
+func addTaskProps(comp *ical.Component, t db.Task) {
+	comp.Add("UID", taskUID(t.ID))
+	comp.Add("DTSTAMP", ical.DateTime(t.UpdatedAt.Time))
+	comp.Add("CREATED", ical.DateTime(t.CreatedAt.Time))
+	comp.Add("LAST-MODIFIED", ical.DateTime(t.UpdatedAt.Time))
+	comp.Add("SEQUENCE", strconv.FormatInt(t.Version-1, 10))
+	comp.AddText("SUMMARY", t.Title)
+	if t.Description != nil && *t.Description != "" {
+		comp.AddText("DESCRIPTION", *t.Description)
+	}
+	comp.Add("PRIORITY", strconv.Itoa(int(icalPriority(t.Priority))))
+	if len(t.Tags) > 0 {
+		tags := make([]string, len(t.Tags))
+		for i, tag := range t.Tags {
+			tags[i] = ical.Text(tag)
+		}
+		comp.Add("CATEGORIES", strings.Join(tags, ","))
+	}
+}
+
+// taskEvent renders a task as a VEVENT that starts at its due date and
+// lasts its estimate.
+func taskEvent(t db.Task) *ical.Component {
+	ev := ical.NewComponent("VEVENT")
+	addTaskProps(ev, t)
+	length := defaultEventDuration
+	if t.EstimateSeconds.Valid && t.EstimateSeconds.Int32 > 0 {
+		length = time.Duration(t.EstimateSeconds.Int32) * time.Second
+	}
+	ev.Add("DTSTART", ical.DateTime(t.DueDate.Time))
+	ev.Add("DURATION", ical.Duration(length))
+	if t.Recurrence != nil {
+		ev.Add("RRULE", *t.Recurrence)
+	}
+	ev.Add("STATUS", "CONFIRMED")
+	// Tasks are not appointments; they should not show the time as busy.
+	ev.Add("TRANSP", "TRANSPARENT")
+	return ev
+}
+
+// taskTodo renders a task as a VTODO.
+func taskTodo(t db.Task) *ical.Component {
+	todo := ical.NewComponent("VTODO")
+	addTaskProps(todo, t)
+	if t.DueDate.Valid {
+		due := ical.DateTime(t.DueDate.Time)
+		if t.Recurrence != nil {
+			// A recurrence needs a start to count from.
+			todo.Add("DTSTART", due)
+			todo.Add("RRULE", *t.Recurrence)
+		}
+		todo.Add("DUE", due)
+	}
+	switch t.StatusCategory {
+	case repository.CategoryDone:
+		todo.Add("STATUS", "COMPLETED")
+		todo.Add("PERCENT-COMPLETE", "100")
+		todo.Add("COMPLETED", ical.DateTime(t.UpdatedAt.Time))
+	case repository.CategoryDoing:
+		todo.Add("STATUS", "IN-PROCESS")
+	default:
+		todo.Add("STATUS", "NEEDS-ACTION")
+	}
+	return todo
+}
+
+// taskAlarm returns a reminder before a task is due. Event alarms are
+// relative to the start, which is the due date; to-do alarms to the due
+// date itself.
+func taskAlarm(t db.Task, before time.Duration, todo bool) *ical.Component {
+	alarm := ical.NewComponent("VALARM")
+	alarm.Add("ACTION", "DISPLAY")
+	alarm.AddText("DESCRIPTION", t.Title)
+	var params []ical.Param
+	if todo {
+		params = append(params, ical.Param{Name: "RELATED", Value: "END"})
+	}
+	alarm.Add("TRIGGER", ical.Duration(-before), params...)
+	return alarm
+}
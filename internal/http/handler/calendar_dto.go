@@ -0,0 +1,31 @@
+package handler
+
+import "time"
+
+// CalendarFeedQuery defines the query parameters of a calendar feed URL.
+// Kind picks VEVENT entries, which every calendar app shows, or VTODO
+// entries for apps with task lists. ProjectID and Filter, a saved filter or
+// smart list key, narrow the tasks; done tasks are left out unless
+// IncludeDone is set or the filter picks status categories itself. Alarm
+// is how long before the due date reminders go off, such as "15m", or
+// "off"; it defaults to the due date itself. Tz is the time zone relative
+// filter windows are computed in.
+type CalendarFeedQuery struct {
+	Kind        string `form:"kind,default=event" binding:"oneof=event todo"`
+	ProjectID   int64  `form:"project_id" binding:"omitempty,min=1,excluded_with=Filter"`
+	Filter      string `form:"filter" binding:"omitempty,max=50"`
+	IncludeDone bool   `form:"include_done"`
+	Alarm       string `form:"alarm,default=0s" binding:"max=20"`
+	Tz          string `form:"tz,default=UTC" binding:"max=64"`
+}
+
+// CalendarFeedResponse describes the user's calendar feed. URL and Token
+// are only returned when a token is made; keep them secret, as anyone with
+// the URL can read the feed.
+type CalendarFeedResponse struct {
+	Enabled    bool       `json:"enabled"`
+	URL        string     `json:"url,omitempty"`
+	Token      string     `json:"token,omitempty"`
+	CreatedAt  *time.Time `json:"created_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
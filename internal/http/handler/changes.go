@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	db "github.com/pavelc4/auriya-todolist-go/internal/db/sqlc"
+	"github.com/pavelc4/auriya-todolist-go/internal/http/repository"
+	"github.com/pavelc4/auriya-todolist-go/internal/realtime"
+)
+
+// storedChange is the latest change of a task or project, or a tombstone,
+// as read back from the database for sync and for resuming event streams.
+// Task or Project is set unless the change is a delete.
+type storedChange struct {
+	realtime.Change
+	Task      *db.Task
+	Project   *db.Project
+	DeletedAt time.Time
+}
+
+// listChanges returns the user's changes after a change number, oldest
+// first, and whether there are more than limit. All lists are read from one
+// snapshot, so no change before the last one returned can be missed.
+func listChanges(ctx context.Context, store *repository.Store, userID, after int64, limit int32) ([]storedChange, bool, error) {
+	arg := db.ListTaskChangesParams{UserID: userID, After: after, Limit: limit + 1}
+
+	var changes []storedChange
+	err := store.ExecSnapshot(ctx, func(q *db.Queries) error {
+		tasks, err := q.ListTaskChanges(ctx, arg)
+		if err != nil {
+			return err
+		}
+		projects, err := q.ListProjectChanges(ctx, db.ListProjectChangesParams(arg))
+		if err != nil {
+			return err
+		}
+		tombstones, err := q.ListSyncTombstones(ctx, db.ListSyncTombstonesParams(arg))
+		if err != nil {
+			return err
+		}
+
+		for _, t := range tasks {
+			ch := storedChange{Change: realtime.Change{Seq: t.ChangeSeq, UserID: userID, Type: "task", ID: t.ID, Op: storedOp(t.DeletedAt.Valid, t.Version)}}
+			if t.DeletedAt.Valid {
+				ch.DeletedAt = t.DeletedAt.Time
+			} else {
+				ch.Task = &t
+			}
+			changes = append(changes, ch)
+		}
+		for _, p := range projects {
+			ch := storedChange{Change: realtime.Change{Seq: p.ChangeSeq, UserID: userID, Type: "project", ID: p.ID, Op: storedOp(p.DeletedAt.Valid, p.Version)}}
+			if p.DeletedAt.Valid {
+				ch.DeletedAt = p.DeletedAt.Time
+			} else {
+				ch.Project = &p
+			}
+			changes = append(changes, ch)
+		}
+		for _, t := range tombstones {
+			changes = append(changes, storedChange{
+				Change:    realtime.Change{Seq: t.ChangeSeq, UserID: userID, Type: t.EntityType, ID: t.EntityID, Op: "deleted"},
+				DeletedAt: t.DeletedAt.Time,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Each list holds up to limit+1 of its oldest changes, so the oldest
+	// limit changes overall are all among them.
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Seq < changes[j].Seq })
+	if len(changes) > int(limit) {
+		return changes[:limit], true, nil
+	}
+	return changes, false, nil
+}
+
+// storedOp names the change that left a row as it is. Only a row that was
+// never updated is known to be new.
+func storedOp(deleted bool, version int64) string {
+	switch {
+	case deleted:
+		return "deleted"
+	case version == 1:
+		return "created"
+	}
+	return "updated"
+}
+
+// data is the resource after the change as the API renders it, or nil.
+func (ch storedChange) data() (json.RawMessage, error) {
+	switch {
+	case ch.Task != nil:
+		return json.Marshal(newTaskResponse(*ch.Task))
+	case ch.Project != nil:
+		return json.Marshal(newProjectResponse(*ch.Project))
+	}
+	return nil, nil
+}
+
+// ChangeLoader reads the resource of a live change for realtime events.
+func ChangeLoader(store *repository.Store) realtime.LoadFunc {
+	return func(ctx context.Context, ch realtime.Change) (json.RawMessage, error) {
+		var v any
+		var err error
+		switch ch.Type {
+		case "task":
+			var t db.Task
+			t, err = store.Queries.GetTask(ctx, db.GetTaskParams{ID: ch.ID, UserID: ch.UserID})
+			v = newTaskResponse(t)
+		case "project":
+			var p db.Project
+			p, err = store.Queries.GetProject(ctx, db.GetProjectParams{ID: ch.ID, UserID: ch.UserID})
+			v = newProjectResponse(p)
+		default:
+			return nil, nil
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(v)
+	}
+}
@@ -0,0 +1,267 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/pavelc4/auriya-todolist-go/internal/cache"
+	db "github.com/pavelc4/auriya-todolist-go/internal/db/sqlc"
+	"github.com/pavelc4/auriya-todolist-go/internal/http/repository"
+)
+
+type ChecklistHandler struct {
+	Store *repository.Store
+	cache *cache.Service
+}
+
+func NewChecklistHandler(store *repository.Store, cache *cache.Service) *ChecklistHandler {
+	return &ChecklistHandler{Store: store, cache: cache}
+}
+
+// checklistItemURI identifies a checklist item of a task.
+type checklistItemURI struct {
+	ID     int64 `uri:"id" binding:"required,min=1"`
+	ItemID int64 `uri:"item_id" binding:"required,min=1"`
+}
+
+// newChecklistItemResponse converts a database checklist item to a JSON response model.
+func newChecklistItemResponse(item db.ChecklistItem) ChecklistItemResponse {
+	return ChecklistItemResponse{
+		ID:        item.ID,
+		TaskID:    item.TaskID,
+		Text:      item.Text,
+		Checked:   item.Checked,
+		Position:  item.Position,
+		CreatedAt: item.CreatedAt.Time,
+		UpdatedAt: item.UpdatedAt.Time,
+	}
+}
+
+func newChecklistItemResult(item db.ChecklistItem, task db.Task) ChecklistItemResult {
+	return ChecklistItemResult{
+		Item:             newChecklistItemResponse(item),
+		ChecklistChecked: task.ChecklistChecked,
+		ChecklistTotal:   task.ChecklistTotal,
+	}
+}
+
+// writeChecklistError maps errors from the checklist write path to responses.
+func writeChecklistError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+	case errors.Is(err, repository.ErrInvalidChecklistMove):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "invalid_move", "detail": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+	}
+}
+
+// List returns a task's checklist in order.
+func (h *ChecklistHandler) List(c *gin.Context) {
+	var uri struct {
+		ID int64 `uri:"id" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "detail": err.Error()})
+		return
+	}
+
+	userID := c.GetInt64("userID")
+	ctx := c.Request.Context()
+
+	if _, err := h.Store.Queries.GetTask(ctx, db.GetTaskParams{ID: uri.ID, UserID: userID}); err != nil {
+		writeChecklistError(c, err)
+		return
+	}
+
+	items, err := h.Store.Queries.ListChecklistItems(ctx, db.ListChecklistItemsParams{TaskID: uri.ID, UserID: userID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		return
+	}
+
+	resp := make([]ChecklistItemResponse, 0, len(items))
+	for _, item := range items {
+		resp = append(resp, newChecklistItemResponse(item))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// Add adds an item to a task's checklist.
+func (h *ChecklistHandler) Add(c *gin.Context) {
+	var uri struct {
+		ID int64 `uri:"id" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "detail": err.Error()})
+		return
+	}
+
+	var req AddChecklistItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "detail": err.Error()})
+		return
+	}
+
+	userID := c.GetInt64("userID")
+	ctx := c.Request.Context()
+
+	var (
+		item db.ChecklistItem
+		task db.Task
+	)
+	err := h.Store.ExecTx(ctx, func(q *db.Queries) error {
+		var err error
+		item, task, err = repository.AddChecklistItem(ctx, q, userID, uri.ID, req.Text, req.AfterID, req.BeforeID)
+		return err
+	})
+	if err != nil {
+		writeChecklistError(c, err)
+		return
+	}
+
+	h.cache.Delete(fmt.Sprintf("task:%d", uri.ID))
+
+	c.JSON(http.StatusCreated, newChecklistItemResult(item, task))
+}
+
+// Update edits the text of an item or sets whether it is checked.
+func (h *ChecklistHandler) Update(c *gin.Context) {
+	var uri checklistItemURI
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "detail": err.Error()})
+		return
+	}
+
+	var req UpdateChecklistItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "detail": err.Error()})
+		return
+	}
+
+	arg := db.UpdateChecklistItemParams{
+		Text:   toPgText(req.Text),
+		ID:     uri.ItemID,
+		TaskID: uri.ID,
+		UserID: c.GetInt64("userID"),
+	}
+	if req.Checked != nil {
+		arg.Checked = pgtype.Bool{Bool: *req.Checked, Valid: true}
+	}
+
+	ctx := c.Request.Context()
+
+	var (
+		item db.ChecklistItem
+		task db.Task
+	)
+	err := h.Store.ExecTx(ctx, func(q *db.Queries) error {
+		var err error
+		item, task, err = repository.UpdateChecklistItem(ctx, q, arg)
+		return err
+	})
+	if err != nil {
+		writeChecklistError(c, err)
+		return
+	}
+
+	h.cache.Delete(fmt.Sprintf("task:%d", uri.ID))
+
+	c.JSON(http.StatusOK, newChecklistItemResult(item, task))
+}
+
+// Toggle flips whether an item is checked.
+func (h *ChecklistHandler) Toggle(c *gin.Context) {
+	var uri checklistItemURI
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "detail": err.Error()})
+		return
+	}
+
+	userID := c.GetInt64("userID")
+	ctx := c.Request.Context()
+
+	var (
+		item db.ChecklistItem
+		task db.Task
+	)
+	err := h.Store.ExecTx(ctx, func(q *db.Queries) error {
+		var err error
+		item, task, err = repository.ToggleChecklistItem(ctx, q, userID, uri.ID, uri.ItemID)
+		return err
+	})
+	if err != nil {
+		writeChecklistError(c, err)
+		return
+	}
+
+	h.cache.Delete(fmt.Sprintf("task:%d", uri.ID))
+
+	c.JSON(http.StatusOK, newChecklistItemResult(item, task))
+}
+
+// Move reorders an item within its checklist.
+func (h *ChecklistHandler) Move(c *gin.Context) {
+	var uri checklistItemURI
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "detail": err.Error()})
+		return
+	}
+
+	var req MoveChecklistItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "detail": err.Error()})
+		return
+	}
+
+	userID := c.GetInt64("userID")
+	ctx := c.Request.Context()
+
+	var (
+		item db.ChecklistItem
+		task db.Task
+	)
+	err := h.Store.ExecTx(ctx, func(q *db.Queries) error {
+		var err error
+		item, task, err = repository.MoveChecklistItem(ctx, q, userID, uri.ID, uri.ItemID, req.AfterID, req.BeforeID)
+		return err
+	})
+	if err != nil {
+		writeChecklistError(c, err)
+		return
+	}
+
+	h.cache.Delete(fmt.Sprintf("task:%d", uri.ID))
+
+	c.JSON(http.StatusOK, newChecklistItemResult(item, task))
+}
+
+// Delete removes an item from a task's checklist.
+func (h *ChecklistHandler) Delete(c *gin.Context) {
+	var uri checklistItemURI
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "detail": err.Error()})
+		return
+	}
+
+	userID := c.GetInt64("userID")
+	ctx := c.Request.Context()
+
+	err := h.Store.ExecTx(ctx, func(q *db.Queries) error {
+		_, err := repository.DeleteChecklistItem(ctx, q, userID, uri.ID, uri.ItemID)
+		return err
+	})
+	if err != nil {
+		writeChecklistError(c, err)
+		return
+	}
+
+	h.cache.Delete(fmt.Sprintf("task:%d", uri.ID))
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,45 @@
+package handler
+
+import "time"
+
+// AddChecklistItemRequest defines the request body for adding a checklist
+// item. The item is placed directly after AfterID and/or before BeforeID;
+// with neither it goes to the end of the checklist.
+type AddChecklistItemRequest struct {
+	Text     string `json:"text" binding:"required,max=500"`
+	AfterID  *int64 `json:"after_id" binding:"omitempty,min=1"`
+	BeforeID *int64 `json:"before_id" binding:"omitempty,min=1"`
+}
+
+// UpdateChecklistItemRequest defines the request body for editing a
+// checklist item.
+type UpdateChecklistItemRequest struct {
+	Text    *string `json:"text" binding:"omitempty,min=1,max=500"`
+	Checked *bool   `json:"checked"`
+}
+
+// MoveChecklistItemRequest defines the request body for reordering a
+// checklist item, with the same placement rules as AddChecklistItemRequest.
+type MoveChecklistItemRequest struct {
+	AfterID  *int64 `json:"after_id" binding:"omitempty,min=1"`
+	BeforeID *int64 `json:"before_id" binding:"omitempty,min=1"`
+}
+
+// ChecklistItemResponse defines the standard response for a checklist item.
+type ChecklistItemResponse struct {
+	ID        int64     `json:"id"`
+	TaskID    int64     `json:"task_id"`
+	Text      string    `json:"text"`
+	Checked   bool      `json:"checked"`
+	Position  string    `json:"position"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ChecklistItemResult is returned after a checklist item changes, together
+// with the task's updated checklist counts.
+type ChecklistItemResult struct {
+	Item             ChecklistItemResponse `json:"item"`
+	ChecklistChecked int32                 `json:"checklist_checked"`
+	ChecklistTotal   int32                 `json:"checklist_total"`
+}
@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pavelc4/auriya-todolist-go/internal/http/repository"
+	"github.com/pavelc4/auriya-todolist-go/internal/realtime"
+)
+
+const (
+	// sseHeartbeat is how often an idle stream gets a comment line, so that
+	// proxies keep it open and dead clients are noticed.
+	sseHeartbeat = 15 * time.Second
+	// sseWriteTimeout replaces the server's write timeout for each write to
+	// a stream, which would otherwise end it.
+	sseWriteTimeout = 10 * time.Second
+	// maxEventReplay is how many missed changes a resumed stream replays
+	// before asking the client to resync instead.
+	maxEventReplay = 1000
+)
+
+type EventsHandler struct {
+	Store *repository.Store
+	hub   *realtime.Hub
+}
+
+func NewEventsHandler(store *repository.Store, hub *realtime.Hub) *EventsHandler {
+	return &EventsHandler{Store: store, hub: hub}
+}
+
+// Stream sends the user's task and project changes as Server-Sent Events
+// named like "task.updated", with the change number as event ID. A client
+// that reconnects with Last-Event-ID first gets the changes it missed, read
+// back from the database; when there are too many it gets a "resync" event
+// and should reload everything. A client that reads too slowly gets such
+// a "resync" event in place of the changes it fell behind on. The stream
+// ends when the server shuts down, and the client is expected to
+// reconnect.
+func (h *EventsHandler) Stream(c *gin.Context) {
+	var lastID int64
+	if v := c.GetHeader("Last-Event-ID"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_last_event_id", "detail": "Last-Event-ID must be an event ID from this stream"})
+			return
+		}
+		lastID = id
+	}
+
+	userID := c.GetInt64("userID")
+	sub, err := h.hub.Subscribe(userID)
+	if err != nil {
+		status, code := subscribeErrorStatus(err)
+		c.JSON(status, gin.H{"error": code, "detail": err.Error()})
+		return
+	}
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	rc := http.NewResponseController(c.Writer)
+	send := func(b []byte) error {
+		if err := rc.SetWriteDeadline(time.Now().Add(sseWriteTimeout)); err != nil {
+			return err
+		}
+		if _, err := c.Writer.Write(b); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	ctx := c.Request.Context()
+	if err := send([]byte("retry: 3000\n\n")); err != nil {
+		return
+	}
+	if lastID > 0 {
+		if lastID, err = h.replay(ctx, userID, lastID, send); err != nil {
+			log.Printf("events: replay for user %d: %v", userID, err)
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sub.Done():
+			return
+		case ev := <-sub.Events():
+			if ev.Kind == realtime.KindResync {
+				if err := send([]byte("event: resync\ndata: {}\n\n")); err != nil {
+					return
+				}
+				continue
+			}
+			// Changes already replayed may also be waiting here.
+			if ev.Change.Seq <= lastID {
+				continue
+			}
+			if err := send(formatChangeEvent(ev.Change, ev.Data)); err != nil {
+				return
+			}
+			lastID = ev.Change.Seq
+		case <-heartbeat.C:
+			if err := send([]byte(": ping\n\n")); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// replay sends the changes after lastID and returns the ID of the last one
+// sent.
+func (h *EventsHandler) replay(ctx context.Context, userID, lastID int64, send func([]byte) error) (int64, error) {
+	changes, more, err := listChanges(ctx, h.Store, userID, lastID, maxEventReplay)
+	if err != nil {
+		return lastID, err
+	}
+	if more {
+		return lastID, send([]byte("event: resync\ndata: {}\n\n"))
+	}
+	for _, ch := range changes {
+		data, err := ch.data()
+		if err != nil {
+			return lastID, err
+		}
+		if err := send(formatChangeEvent(ch.Change, data)); err != nil {
+			return lastID, err
+		}
+		lastID = ch.Seq
+	}
+	return lastID, nil
+}
+
+func newChangeEvent(ch realtime.Change, data json.RawMessage) ChangeEvent {
+	return ChangeEvent{Seq: ch.Seq, Type: ch.Type, ID: ch.ID, Op: ch.Op, Data: data}
+}
+
+func formatChangeEvent(ch realtime.Change, data json.RawMessage) []byte {
+	payload, _ := json.Marshal(newChangeEvent(ch, data))
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "id: %d\nevent: %s.%s\ndata: %s\n\n", ch.Seq, ch.Type, ch.Op, payload)
+	return b.Bytes()
+}
+
+// subscribeErrorStatus maps why a live subscription could not start or
+// ended to a status and error code.
+func subscribeErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, realtime.ErrTooManySubscriptions):
+		return http.StatusTooManyRequests, "too_many_connections"
+	case errors.Is(err, realtime.ErrClosed), errors.Is(err, realtime.ErrListenerReset):
+		return http.StatusServiceUnavailable, "unavailable"
+	}
+	return http.StatusInternalServerError, "internal_error"
+}
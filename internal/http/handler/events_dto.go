@@ -0,0 +1,14 @@
+package handler
+
+import "encoding/json"
+
+// ChangeEvent is the data of a task or project event. Data holds the
+// TaskResponse or ProjectResponse after the change and is left out for
+// deletes.
+type ChangeEvent struct {
+	Seq  int64           `json:"seq"`
+	Type string          `json:"type"`
+	ID   int64           `json:"id"`
+	Op   string          `json:"op"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
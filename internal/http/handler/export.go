@@ -0,0 +1,584 @@
+package handler
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	db "github.com/pavelc4/auriya-todolist-go/internal/db/sqlc"
+	"github.com/pavelc4/auriya-todolist-go/internal/http/repository"
+)
+
+// exportBatch is how many tasks an export reads at a time.
+const exportBatch = 500
+
+// exportWriteTimeout is how long the client has to take each batch of a
+// streamed export. It replaces the server's write timeout, which the whole
+// response would otherwise have to fit in.
+const exportWriteTimeout = 30 * time.Second
+
+// exportDownloadTimeout is how long the download of a background export
+// may take.
+const exportDownloadTimeout = 30 * time.Minute
+
+// exportVersion is the version of the JSON export layout. It changes only
+// when fields are removed or change meaning; new fields may appear at any
+// time.
+const exportVersion = 1
+
+// exportExtensions are the file name extensions of the export formats.
+var exportExtensions = map[string]string{"json": "json", "csv": "csv", "markdown": "md"}
+
+// exportContentTypes are the content types of the export formats.
+var exportContentTypes = map[string]string{
+	"json":     "application/json; charset=utf-8",
+	"csv":      "text/csv; charset=utf-8",
+	"markdown": "text/markdown; charset=utf-8",
+}
+
+type ExportHandler struct {
+	Store *repository.Store
+	// Threshold is the number of tasks above which exports run in the
+	// background.
+	Threshold int64
+}
+
+func NewExportHandler(store *repository.Store, threshold int64) *ExportHandler {
+	return &ExportHandler{Store: store, Threshold: threshold}
+}
+
+// exportSpec says what goes into an export.
+type exportSpec struct {
+	UserID     int64
+	Format     string
+	ProjectIDs []int64
+	Inbox      bool
+}
+
+// includes reports whether the export holds the tasks of a project.
+func (s exportSpec) includes(projectID int64) bool {
+	return (len(s.ProjectIDs) == 0 && !s.Inbox) || slices.Contains(s.ProjectIDs, projectID)
+}
+
+// exportFilename returns the name an export is downloaded as.
+func exportFilename(format string, at time.Time) string {
+	return "auriya-export-" + at.UTC().Format("20060102") + "." + exportExtensions[format]
+}
+
+func newExportResponse(e db.Export) ExportResponse {
+	resp := ExportResponse{
+		ID:         e.ID,
+		Format:     e.Format,
+		ProjectIDs: e.ProjectIds,
+		Inbox:      e.Inbox,
+		Status:     e.Status,
+		CreatedAt:  e.CreatedAt.Time,
+	}
+	if e.SizeBytes.Valid {
+		resp.SizeBytes = &e.SizeBytes.Int64
+	}
+	if e.Error.Valid {
+		resp.Error = &e.Error.String
+	}
+	if e.Status == "succeeded" {
+		resp.DownloadURL = fmt.Sprintf("/api/exports/%d/download", e.ID)
+	}
+	if e.CompletedAt.Valid {
+		resp.CompletedAt = &e.CompletedAt.Time
+	}
+	if e.ExpiresAt.Valid {
+		resp.ExpiresAt = &e.ExpiresAt.Time
+	}
+	return resp
+}
+
+// Export writes the user's projects and tasks as JSON, CSV or Markdown.
+// Small exports are streamed in the response; larger ones, or any with
+// async=true, are queued for the export worker and answered with 202 and
+// the export to poll.
+func (h *ExportHandler) Export(c *gin.Context) {
+	var q ExportQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_query", "detail": err.Error()})
+		return
+	}
+	ctx := c.Request.Context()
+	userID := c.GetInt64("userID")
+
+	spec := exportSpec{UserID: userID, Format: q.Format, Inbox: q.Inbox}
+	for _, id := range q.ProjectID {
+		if !slices.Contains(spec.ProjectIDs, id) {
+			spec.ProjectIDs = append(spec.ProjectIDs, id)
+		}
+	}
+	for _, id := range spec.ProjectIDs {
+		_, err := h.Store.Queries.GetProject(ctx, db.GetProjectParams{ID: id, UserID: userID})
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "unknown_project", "detail": fmt.Sprintf("project %d not found", id)})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+			return
+		}
+	}
+
+	async := q.Async
+	if !async {
+		n, err := h.Store.Queries.CountExportTasks(ctx, db.CountExportTasksParams{UserID: userID, ProjectIds: spec.ProjectIDs, Inbox: spec.Inbox})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+			return
+		}
+		async = n > h.Threshold
+	}
+	if async {
+		e, err := h.Store.Queries.CreateExport(ctx, db.CreateExportParams{
+			UserID:     userID,
+			Format:     spec.Format,
+			ProjectIds: spec.ProjectIDs,
+			Inbox:      spec.Inbox,
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+			return
+		}
+		c.Header("Location", fmt.Sprintf("/api/exports/%d", e.ID))
+		c.JSON(http.StatusAccepted, newExportResponse(e))
+		return
+	}
+
+	now := time.Now()
+	c.Header("Content-Type", exportContentTypes[spec.Format])
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, exportFilename(spec.Format, now)))
+	rc := http.NewResponseController(c.Writer)
+	flush := func() {
+		_ = rc.Flush()
+		_ = rc.SetWriteDeadline(time.Now().Add(exportWriteTimeout))
+	}
+	_ = rc.SetWriteDeadline(time.Now().Add(exportWriteTimeout))
+	err := writeExport(ctx, h.Store, c.Writer, spec, now, flush)
+	if err != nil {
+		// Nothing is sent before the first batch has been read, so an early
+		// failure can still be reported properly. Later ones cut the file
+		// short.
+		if !c.Writer.Written() {
+			c.Header("Content-Disposition", "")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+			return
+		}
+		log.Printf("export for user %d: %v", userID, err)
+	}
+}
+
+// GetExport returns a background export, with its download link once it
+// is ready.
+func (h *ExportHandler) GetExport(c *gin.Context) {
+	e, ok := h.loadExport(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, newExportResponse(e))
+}
+
+// Download sends the file of a finished background export.
+func (h *ExportHandler) Download(c *gin.Context) {
+	e, ok := h.loadExport(c)
+	if !ok {
+		return
+	}
+	switch {
+	case e.Status == "failed":
+		c.JSON(http.StatusConflict, gin.H{"error": "export_failed", "detail": e.Error.String})
+		return
+	case e.Status != "succeeded":
+		c.JSON(http.StatusConflict, gin.H{"error": "export_not_ready", "detail": "the export is " + e.Status})
+		return
+	case e.ExpiresAt.Valid && e.ExpiresAt.Time.Before(time.Now()):
+		c.JSON(http.StatusGone, gin.H{"error": "export_expired"})
+		return
+	}
+	if _, err := os.Stat(e.FilePath.String); err != nil {
+		c.JSON(http.StatusGone, gin.H{"error": "export_expired", "detail": "the export file is gone"})
+		return
+	}
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Now().Add(exportDownloadTimeout))
+	c.Header("Content-Type", exportContentTypes[e.Format])
+	c.FileAttachment(e.FilePath.String, exportFilename(e.Format, e.CreatedAt.Time))
+}
+
+// loadExport reads the export named in the URL. It writes the error
+// response when that fails.
+func (h *ExportHandler) loadExport(c *gin.Context) (db.Export, bool) {
+	var uri struct {
+		ID int64 `uri:"id" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "detail": err.Error()})
+		return db.Export{}, false
+	}
+	e, err := h.Store.Queries.GetExport(c.Request.Context(), db.GetExportParams{ID: uri.ID, UserID: c.GetInt64("userID")})
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+		return db.Export{}, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		return db.Export{}, false
+	}
+	return e, true
+}
+
+// ExportWriter writes background exports for the export worker.
+func ExportWriter(store *repository.Store) func(ctx context.Context, w io.Writer, e db.Export) error {
+	return func(ctx context.Context, w io.Writer, e db.Export) error {
+		spec := exportSpec{UserID: e.UserID, Format: e.Format, ProjectIDs: e.ProjectIds, Inbox: e.Inbox}
+		return writeExport(ctx, store, w, spec, e.CreatedAt.Time, nil)
+	}
+}
+
+// exportFormat writes one export format. Projects are passed first, sorted
+// by id, and then the tasks in ListExportTasks order: the inbox first, then
+// each project's tasks together.
+type exportFormat interface {
+	begin(projects []ExportProject) error
+	task(t TaskResponse) error
+	end() error
+}
+
+// writeExport writes an export to w, reading everything from one snapshot
+// a batch of tasks at a time. flush, when set, is called after each batch
+// has been written.
+func writeExport(ctx context.Context, store *repository.Store, w io.Writer, spec exportSpec, exportedAt time.Time, flush func()) error {
+	return store.ExecSnapshot(ctx, func(q *db.Queries) error {
+		projects, err := q.ListProjects(ctx, spec.UserID)
+		if err != nil {
+			return err
+		}
+		slices.SortFunc(projects, func(a, b db.Project) int { return cmp.Compare(a.ID, b.ID) })
+
+		var exported []ExportProject
+		sectionNames := make(map[int64]string)
+		for _, p := range projects {
+			if !spec.includes(p.ID) {
+				continue
+			}
+			sections, err := q.ListSections(ctx, db.ListSectionsParams{ProjectID: p.ID, UserID: spec.UserID})
+			if err != nil {
+				return err
+			}
+			ep := ExportProject{ProjectResponse: newProjectResponse(p), Sections: make([]SectionResponse, 0, len(sections))}
+			for _, s := range sections {
+				ep.Sections = append(ep.Sections, newSectionResponse(s))
+				sectionNames[s.ID] = s.Name
+			}
+			exported = append(exported, ep)
+		}
+
+		bw := bufio.NewWriter(w)
+		var f exportFormat
+		switch spec.Format {
+		case "csv":
+			f = newCSVExport(bw, exported, sectionNames)
+		case "markdown":
+			f = &markdownExport{w: bw, exportedAt: exportedAt, sectionNames: sectionNames}
+		default:
+			f = &jsonExport{w: bw, exportedAt: exportedAt}
+		}
+
+		arg := db.ListExportTasksParams{UserID: spec.UserID, ProjectIds: spec.ProjectIDs, Inbox: spec.Inbox, Limit: exportBatch}
+		started := false
+		for {
+			tasks, err := q.ListExportTasks(ctx, arg)
+			if err != nil {
+				return err
+			}
+			if !started {
+				if err := f.begin(exported); err != nil {
+					return err
+				}
+				started = true
+			}
+			if len(tasks) == 0 {
+				break
+			}
+
+			ids := make([]int64, len(tasks))
+			for i, t := range tasks {
+				ids[i] = t.ID
+			}
+			items, err := q.ListChecklistItemsByTasks(ctx, db.ListChecklistItemsByTasksParams{UserID: spec.UserID, TaskIds: ids})
+			if err != nil {
+				return err
+			}
+			checklists := make(map[int64][]ChecklistItemResponse)
+			for _, item := range items {
+				checklists[item.TaskID] = append(checklists[item.TaskID], newChecklistItemResponse(item))
+			}
+
+			for _, t := range tasks {
+				resp := newTaskResponse(t)
+				resp.Checklist = checklists[t.ID]
+				if err := f.task(resp); err != nil {
+					return err
+				}
+			}
+			if err := bw.Flush(); err != nil {
+				return err
+			}
+			if flush != nil {
+				flush()
+			}
+
+			last := tasks[len(tasks)-1]
+			arg.AfterProject, arg.AfterID = last.ProjectID.Int64, last.ID
+			if len(tasks) < exportBatch {
+				break
+			}
+		}
+		if err := f.end(); err != nil {
+			return err
+		}
+		return bw.Flush()
+	})
+}
+
+// jsonExport writes an ExportDocument without holding its tasks in memory.
+type jsonExport struct {
+	w          *bufio.Writer
+	exportedAt time.Time
+	tasks      int
+}
+
+func (e *jsonExport) begin(projects []ExportProject) error {
+	if projects == nil {
+		projects = []ExportProject{}
+	}
+	head, err := json.Marshal(ExportDocument{Version: exportVersion, ExportedAt: e.exportedAt, Projects: projects})
+	if err != nil {
+		return err
+	}
+	// Reopen the document where its empty task list ends.
+	head = head[:len(head)-len(`null}`)]
+	_, err = e.w.Write(append(head, '['))
+	return err
+}
+
+func (e *jsonExport) task(t TaskResponse) error {
+	b, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	if e.tasks > 0 {
+		e.w.WriteByte(',')
+	}
+	e.tasks++
+	_, err = e.w.Write(b)
+	return err
+}
+
+func (e *jsonExport) end() error {
+	_, err := e.w.WriteString("]}\n")
+	return err
+}
+
+// csvHeader lists the columns of a CSV export, one line per task. Tags are
+// separated by commas and checklist items by line breaks, each starting
+// with [ ] or [x].
+var csvHeader = []string{
+	"id", "project_id", "project", "section", "title", "description",
+	"status", "status_category", "priority", "due_date", "estimate",
+	"time_spent", "tags", "recurrence", "checklist", "created_at", "updated_at",
+}
+
+// csvExport writes the tasks of an export as CSV. Projects without tasks
+// do not appear.
+type csvExport struct {
+	w            *csv.Writer
+	projectNames map[int64]string
+	sectionNames map[int64]string
+}
+
+func newCSVExport(w io.Writer, projects []ExportProject, sectionNames map[int64]string) *csvExport {
+	names := make(map[int64]string, len(projects))
+	for _, p := range projects {
+		names[p.ID] = p.Name
+	}
+	return &csvExport{w: csv.NewWriter(w), projectNames: names, sectionNames: sectionNames}
+}
+
+func (e *csvExport) begin([]ExportProject) error {
+	return e.w.Write(csvHeader)
+}
+
+func (e *csvExport) task(t TaskResponse) error {
+	var projectID, project, section, due, estimate, recurrence string
+	if t.ProjectID != nil {
+		projectID = strconv.FormatInt(*t.ProjectID, 10)
+		project = e.projectNames[*t.ProjectID]
+	}
+	if t.SectionID != nil {
+		section = e.sectionNames[*t.SectionID]
+	}
+	if t.DueDate != nil {
+		due = t.DueDate.UTC().Format(time.RFC3339)
+	}
+	if t.Estimate != nil {
+		estimate = strconv.Itoa(int(*t.Estimate))
+	}
+	if t.Recurrence != nil {
+		recurrence = *t.Recurrence
+	}
+	checklist := make([]string, len(t.Checklist))
+	for i, item := range t.Checklist {
+		checklist[i] = checkbox(item.Checked) + " " + item.Text
+	}
+	err := e.w.Write([]string{
+		strconv.FormatInt(t.ID, 10),
+		projectID,
+		csvSafe(project),
+		csvSafe(section),
+		csvSafe(t.Title),
+		csvSafe(t.Description),
+		t.Status,
+		t.StatusCategory,
+		strconv.Itoa(int(t.Priority)),
+		due,
+		estimate,
+		strconv.FormatInt(t.TimeSpent, 10),
+		csvSafe(strings.Join(t.Tags, ",")),
+		recurrence,
+		csvSafe(strings.Join(checklist, "\n")),
+		t.CreatedAt.UTC().Format(time.RFC3339),
+		t.UpdatedAt.UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return err
+	}
+	return e.w.Error()
+}
+
+func (e *csvExport) end() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// markdownExport writes an export as a Markdown checklist with a heading
+// for the inbox and each project, including projects without tasks.
+type markdownExport struct {
+	w            *bufio.Writer
+	exportedAt   time.Time
+	sectionNames map[int64]string
+	// projects are the ones that still need their heading.
+	projects []ExportProject
+	inbox    bool
+}
+
+func (e *markdownExport) begin(projects []ExportProject) error {
+	e.projects = projects
+	fmt.Fprintf(e.w, "# Auriya export\n\nExported %s.\n", e.exportedAt.UTC().Format(time.RFC1123))
+	return nil
+}
+
+// heading writes the headings up to the one of the project a task is in.
+func (e *markdownExport) heading(projectID *int64) {
+	if projectID == nil {
+		if !e.inbox {
+			e.w.WriteString("\n## Inbox\n\n")
+			e.inbox = true
+		}
+		return
+	}
+	for len(e.projects) > 0 && e.projects[0].ID <= *projectID {
+		e.writeProject(e.projects[0])
+		e.projects = e.projects[1:]
+	}
+}
+
+func (e *markdownExport) writeProject(p ExportProject) {
+	fmt.Fprintf(e.w, "\n## %s\n\n", markdownEscape(p.Name))
+	if len(p.Sections) > 0 {
+		names := make([]string, len(p.Sections))
+		for i, s := range p.Sections {
+			names[i] = markdownEscape(s.Name)
+		}
+		fmt.Fprintf(e.w, "Sections: %s\n\n", strings.Join(names, ", "))
+	}
+}
+
+func (e *markdownExport) task(t TaskResponse) error {
+	e.heading(t.ProjectID)
+	fmt.Fprintf(e.w, "- %s %s\n", checkbox(t.StatusCategory == "done"), markdownEscape(t.Title))
+
+	details := []string{"Status: " + markdownEscape(t.Status), "Priority: " + strconv.Itoa(int(t.Priority))}
+	if t.DueDate != nil {
+		details = append(details, "Due: "+t.DueDate.UTC().Format("2006-01-02 15:04")+" UTC")
+	}
+	if t.SectionID != nil {
+		details = append(details, "Section: "+markdownEscape(e.sectionNames[*t.SectionID]))
+	}
+	if len(t.Tags) > 0 {
+		tags := make([]string, len(t.Tags))
+		for i, tag := range t.Tags {
+			tags[i] = markdownEscape(tag)
+		}
+		details = append(details, "Tags: "+strings.Join(tags, ", "))
+	}
+	if t.Recurrence != nil {
+		details = append(details, "Repeats: "+markdownEscape(*t.Recurrence))
+	}
+	fmt.Fprintf(e.w, "  %s\n", strings.Join(details, " · "))
+
+	if desc := strings.TrimSpace(t.Description); desc != "" {
+		e.w.WriteString("\n")
+		for _, line := range strings.Split(desc, "\n") {
+			fmt.Fprintf(e.w, "  > %s\n", strings.TrimRight(line, "\r"))
+		}
+		e.w.WriteString("\n")
+	}
+	for _, item := range t.Checklist {
+		fmt.Fprintf(e.w, "  - %s %s\n", checkbox(item.Checked), markdownEscape(item.Text))
+	}
+	return nil
+}
+
+func (e *markdownExport) end() error {
+	for _, p := range e.projects {
+		e.writeProject(p)
+	}
+	e.projects = nil
+	return nil
+}
+
+// checkbox returns a Markdown task list box.
+func checkbox(checked bool) string {
+	if checked {
+		return "[x]"
+	}
+	return "[ ]"
+}
+
+// markdownEscaper escapes the characters that would otherwise be read as
+// Markdown formatting in titles and names.
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`,
+	"<", `\<`, ">", `\>`, "#", `\#`, "|", `\|`, "\n", " ", "\r", "",
+)
+
+func markdownEscape(s string) string {
+	return markdownEscaper.Replace(s)
+}
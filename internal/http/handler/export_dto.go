@@ -0,0 +1,45 @@
+package handler
+
+import "time"
+
+// ExportQuery defines the query parameters for a data export. ProjectID may
+// be repeated; together with Inbox it narrows the export to those projects
+// and the tasks without one. Without either, everything is exported. Async
+// asks for a background export even when the account is small.
+type ExportQuery struct {
+	Format    string  `form:"format,default=json" binding:"oneof=json csv markdown"`
+	ProjectID []int64 `form:"project_id" binding:"omitempty,max=100,dive,min=1"`
+	Inbox     bool    `form:"inbox"`
+	Async     bool    `form:"async"`
+}
+
+// ExportResponse describes a background export. DownloadURL is set once the
+// file is ready and works until ExpiresAt.
+type ExportResponse struct {
+	ID          int64      `json:"id"`
+	Format      string     `json:"format"`
+	ProjectIDs  []int64    `json:"project_ids,omitempty"`
+	Inbox       bool       `json:"inbox"`
+	Status      string     `json:"status"`
+	SizeBytes   *int64     `json:"size_bytes,omitempty"`
+	Error       *string    `json:"error,omitempty"`
+	DownloadURL string     `json:"download_url,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+// ExportDocument is the top level of a JSON export. Tasks carry every field
+// of the task API, with their checklists filled in.
+type ExportDocument struct {
+	Version    int             `json:"version"`
+	ExportedAt time.Time       `json:"exported_at"`
+	Projects   []ExportProject `json:"projects"`
+	Tasks      []TaskResponse  `json:"tasks"`
+}
+
+// ExportProject is a project in an export, with its sections.
+type ExportProject struct {
+	ProjectResponse
+	Sections []SectionResponse `json:"sections"`
+}
@@ -0,0 +1,313 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/pavelc4/auriya-todolist-go/internal/db/sqlc"
+	"github.com/pavelc4/auriya-todolist-go/internal/http/repository"
+)
+
+type FilterHandler struct {
+	Store *repository.Store
+}
+
+func NewFilterHandler(store *repository.Store) *FilterHandler {
+	return &FilterHandler{Store: store}
+}
+
+// openCategories are the status categories of tasks that are not done.
+var openCategories = []string{repository.CategoryTodo, repository.CategoryDoing}
+
+// builtinFilters are the smart lists every user has, in display order.
+var builtinFilters = []FilterResponse{
+	{Key: "today", Name: "Today", Builtin: true, Definition: FilterDefinition{Category: openCategories, Due: "today", Sort: "due_date"}},
+	{Key: "upcoming", Name: "Upcoming", Builtin: true, Definition: FilterDefinition{Category: openCategories, Due: "upcoming", Sort: "due_date"}},
+	{Key: "overdue", Name: "Overdue", Builtin: true, Definition: FilterDefinition{Category: openCategories, Due: "overdue", Sort: "due_date"}},
+	{Key: "no_due_date", Name: "No due date", Builtin: true, Definition: FilterDefinition{Category: openCategories, Due: "none", Sort: "priority"}},
+}
+
+// newFilterResponse converts a saved filter to a JSON response model.
+func newFilterResponse(f db.SavedFilter) (FilterResponse, error) {
+	var def FilterDefinition
+	if err := json.Unmarshal(f.Definition, &def); err != nil {
+		return FilterResponse{}, err
+	}
+	return FilterResponse{
+		ID:         &f.ID,
+		Key:        strconv.FormatInt(f.ID, 10),
+		Name:       f.Name,
+		Definition: def,
+		CreatedAt:  &f.CreatedAt.Time,
+		UpdatedAt:  &f.UpdatedAt.Time,
+	}, nil
+}
+
+// lookupFilter resolves a filter key from the URL to a built-in smart list or
+// one of the user's saved filters.
+func (h *FilterHandler) lookupFilter(c *gin.Context, key string) (FilterResponse, error) {
+	for _, f := range builtinFilters {
+		if f.Key == key {
+			return f, nil
+		}
+	}
+	id, err := strconv.ParseInt(key, 10, 64)
+	if err != nil || id < 1 {
+		return FilterResponse{}, pgx.ErrNoRows
+	}
+	f, err := h.Store.Queries.GetSavedFilter(c.Request.Context(), db.GetSavedFilterParams{ID: id, UserID: c.GetInt64("userID")})
+	if err != nil {
+		return FilterResponse{}, err
+	}
+	return newFilterResponse(f)
+}
+
+// writeFilterError maps errors from the saved filter queries to responses.
+func writeFilterError(c *gin.Context, err error) {
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+	case errors.As(err, &pgErr) && pgErr.Code == "23505":
+		c.JSON(http.StatusConflict, gin.H{"error": "filter_exists", "detail": "a filter with this name already exists"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+	}
+}
+
+// List returns the built-in smart lists followed by the user's saved filters.
+func (h *FilterHandler) List(c *gin.Context) {
+	saved, err := h.Store.Queries.ListSavedFilters(c.Request.Context(), c.GetInt64("userID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		return
+	}
+
+	resp := make([]FilterResponse, 0, len(builtinFilters)+len(saved))
+	resp = append(resp, builtinFilters...)
+	for _, f := range saved {
+		r, err := newFilterResponse(f)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+			return
+		}
+		resp = append(resp, r)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// Get returns a built-in smart list or saved filter by key.
+func (h *FilterHandler) Get(c *gin.Context) {
+	f, err := h.lookupFilter(c, c.Param("id"))
+	if err != nil {
+		writeFilterError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, f)
+}
+
+// Create saves a named filter.
+func (h *FilterHandler) Create(c *gin.Context) {
+	var req CreateFilterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "detail": err.Error()})
+		return
+	}
+
+	def, err := json.Marshal(req.Definition)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "detail": err.Error()})
+		return
+	}
+
+	f, err := h.Store.Queries.CreateSavedFilter(c.Request.Context(), db.CreateSavedFilterParams{
+		UserID:     c.GetInt64("userID"),
+		Name:       req.Name,
+		Definition: def,
+	})
+	if err != nil {
+		writeFilterError(c, err)
+		return
+	}
+
+	resp, err := newFilterResponse(f)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, resp)
+}
+
+// Update renames a saved filter or replaces its definition.
+func (h *FilterHandler) Update(c *gin.Context) {
+	var uri struct {
+		ID int64 `uri:"id" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "detail": err.Error()})
+		return
+	}
+
+	var req UpdateFilterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "detail": err.Error()})
+		return
+	}
+
+	arg := db.UpdateSavedFilterParams{
+		Name:   toPgText(req.Name),
+		ID:     uri.ID,
+		UserID: c.GetInt64("userID"),
+	}
+	if req.Definition != nil {
+		def, err := json.Marshal(req.Definition)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "detail": err.Error()})
+			return
+		}
+		arg.Definition = def
+	}
+
+	f, err := h.Store.Queries.UpdateSavedFilter(c.Request.Context(), arg)
+	if err != nil {
+		writeFilterError(c, err)
+		return
+	}
+
+	resp, err := newFilterResponse(f)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// Delete removes a saved filter.
+func (h *FilterHandler) Delete(c *gin.Context) {
+	var uri struct {
+		ID int64 `uri:"id" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "detail": err.Error()})
+		return
+	}
+
+	rows, err := h.Store.Queries.DeleteSavedFilter(c.Request.Context(), db.DeleteSavedFilterParams{ID: uri.ID, UserID: c.GetInt64("userID")})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		return
+	}
+	if rows == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Tasks runs a built-in smart list or saved filter, one page at a time.
+func (h *FilterHandler) Tasks(c *gin.Context) {
+	var q FilterTasksQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_query", "detail": err.Error()})
+		return
+	}
+	if q.Tz == "" {
+		q.Tz = "UTC"
+	}
+	loc, err := time.LoadLocation(q.Tz)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_query", "detail": "tz: " + err.Error()})
+		return
+	}
+
+	f, err := h.lookupFilter(c, c.Param("id"))
+	if err != nil {
+		writeFilterError(c, err)
+		return
+	}
+
+	arg := f.Definition.params(c.GetInt64("userID"), time.Now().In(loc))
+	arg.Limit = q.Limit
+	arg.Offset = (q.Page - 1) * q.Limit
+
+	tasks, err := h.Store.Queries.FilterTasks(c.Request.Context(), arg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		return
+	}
+
+	items := make([]TaskResponse, 0, len(tasks))
+	for _, t := range tasks {
+		items = append(items, newTaskResponse(t))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"filter": f,
+		"items":  items,
+		"page":   q.Page,
+		"limit":  q.Limit,
+	})
+}
+
+// params turns the definition into FilterTasks arguments, resolving relative
+// due windows against now in now's location.
+func (d FilterDefinition) params(userID int64, now time.Time) db.FilterTasksParams {
+	arg := db.FilterTasksParams{
+		UserID:      userID,
+		Statuses:    d.Status,
+		Categories:  d.Category,
+		PriorityMin: toPgInt4(d.PriorityMin),
+		PriorityMax: toPgInt4(d.PriorityMax),
+		ProjectIds:  d.ProjectIDs,
+		Inbox:       d.Inbox,
+		Tags:        d.Tags,
+		Sort:        d.Sort,
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	var after, before *time.Time
+	window := func(from, to time.Time) { after, before = &from, &to }
+	switch d.Due {
+	case "any":
+		arg.HasDueDate = pgtype.Bool{Bool: true, Valid: true}
+	case "none":
+		arg.HasDueDate = pgtype.Bool{Bool: false, Valid: true}
+	case "overdue":
+		before = &now
+	case "today":
+		window(today, today.AddDate(0, 0, 1))
+	case "tomorrow":
+		window(today.AddDate(0, 0, 1), today.AddDate(0, 0, 2))
+	case "this_week":
+		monday := today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+		window(monday, monday.AddDate(0, 0, 7))
+	case "next_7_days":
+		window(today, today.AddDate(0, 0, 7))
+	case "upcoming":
+		tomorrow := today.AddDate(0, 0, 1)
+		after = &tomorrow
+	}
+
+	// Absolute bounds narrow the relative window.
+	if d.DueAfter != nil && (after == nil || d.DueAfter.After(*after)) {
+		after = d.DueAfter
+	}
+	if d.DueBefore != nil && (before == nil || d.DueBefore.Before(*before)) {
+		before = d.DueBefore
+	}
+	if after != nil {
+		arg.DueAfter = pgtype.Timestamptz{Time: *after, Valid: true}
+	}
+	if before != nil {
+		arg.DueBefore = pgtype.Timestamptz{Time: *before, Valid: true}
+	}
+	return arg
+}
@@ -0,0 +1,65 @@
+package handler
+
+import "time"
+
+// FilterDefinition describes which tasks a filter returns. Every field is
+// optional and set fields must all match. Status matches any of the given
+// workflow statuses and Category any of todo, doing and done. Tags must all
+// be present. Due is a window relative to the day the filter runs:
+//
+//	any, none        has / has no due date
+//	overdue          due before now
+//	today, tomorrow  due that day
+//	this_week        due this week, Monday to Sunday
+//	next_7_days      due today or in the 6 days after
+//	upcoming         due after today
+//
+// DueAfter (inclusive) and DueBefore (exclusive) narrow the window to
+// absolute bounds.
+type FilterDefinition struct {
+	Status      []string   `json:"status,omitempty" binding:"omitempty,max=20,dive,min=1,max=50"`
+	Category    []string   `json:"category,omitempty" binding:"omitempty,dive,oneof=todo doing done"`
+	PriorityMin *int32     `json:"priority_min,omitempty" binding:"omitempty,min=1,max=5"`
+	PriorityMax *int32     `json:"priority_max,omitempty" binding:"omitempty,min=1,max=5"`
+	ProjectIDs  []int64    `json:"project_ids,omitempty" binding:"omitempty,max=50,dive,min=1"`
+	Inbox       bool       `json:"inbox,omitempty"`
+	Tags        []string   `json:"tags,omitempty" binding:"omitempty,max=20,dive,min=1,max=50"`
+	Due         string     `json:"due,omitempty" binding:"omitempty,oneof=any none overdue today tomorrow this_week next_7_days upcoming"`
+	DueAfter    *time.Time `json:"due_after,omitempty"`
+	DueBefore   *time.Time `json:"due_before,omitempty"`
+	Sort        string     `json:"sort,omitempty" binding:"omitempty,oneof=created_at position due_date priority"`
+}
+
+// CreateFilterRequest defines the request body for saving a filter.
+type CreateFilterRequest struct {
+	Name       string           `json:"name" binding:"required,max=100"`
+	Definition FilterDefinition `json:"definition"`
+}
+
+// UpdateFilterRequest defines the request body for changing a saved filter.
+// A Definition replaces the stored one as a whole.
+type UpdateFilterRequest struct {
+	Name       *string           `json:"name" binding:"omitempty,min=1,max=100"`
+	Definition *FilterDefinition `json:"definition"`
+}
+
+// FilterResponse defines the standard response for a filter. Key identifies
+// the filter in URLs: the ID of a saved filter, or the name of a built-in
+// smart list such as "today".
+type FilterResponse struct {
+	ID         *int64           `json:"id,omitempty"`
+	Key        string           `json:"key"`
+	Name       string           `json:"name"`
+	Builtin    bool             `json:"builtin"`
+	Definition FilterDefinition `json:"definition"`
+	CreatedAt  *time.Time       `json:"created_at,omitempty"`
+	UpdatedAt  *time.Time       `json:"updated_at,omitempty"`
+}
+
+// FilterTasksQuery defines the query parameters for running a filter. Tz is
+// the IANA time zone relative due windows are computed in and defaults to UTC.
+type FilterTasksQuery struct {
+	Page  int32  `form:"page,default=1" binding:"min=1"`
+	Limit int32  `form:"limit,default=10" binding:"min=1,max=100"`
+	Tz    string `form:"tz"`
+}
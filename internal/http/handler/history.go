@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	db "github.com/pavelc4/auriya-todolist-go/internal/db/sqlc"
+)
+
+// newTaskEventResponse converts a database task event to a JSON response model.
+func newTaskEventResponse(event db.TaskEvent) TaskEventResponse {
+	var actorID *int64
+	if event.ActorID.Valid {
+		actorID = &event.ActorID.Int64
+	}
+
+	var revertsEventID *int64
+	if event.RevertsEventID.Valid {
+		revertsEventID = &event.RevertsEventID.Int64
+	}
+
+	var undoneAt *time.Time
+	if event.UndoneAt.Valid {
+		undoneAt = &event.UndoneAt.Time
+	}
+
+	return TaskEventResponse{
+		ID:             event.ID,
+		TaskID:         event.TaskID,
+		ActorID:        actorID,
+		Action:         event.Action,
+		Changes:        event.Changes,
+		RevertsEventID: revertsEventID,
+		UndoneAt:       undoneAt,
+		CreatedAt:      event.CreatedAt.Time,
+	}
+}
+
+// History lists the recorded changes of a task, newest first. It also works
+// for tasks that are in the trash.
+func (h *TaskHandler) History(c *gin.Context) {
+	var uri struct {
+		ID int64 `uri:"id" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "detail": err.Error()})
+		return
+	}
+
+	var q TaskHistoryQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_query", "detail": err.Error()})
+		return
+	}
+
+	userID := c.GetInt64("userID")
+	ctx := c.Request.Context()
+
+	events, err := h.Store.Queries.ListTaskEvents(ctx, db.ListTaskEventsParams{
+		TaskID: uri.ID,
+		UserID: userID,
+		Limit:  q.Limit,
+		Offset: (q.Page - 1) * q.Limit,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		return
+	}
+
+	// Tasks created before history existed have no events; tell them apart
+	// from tasks that do not exist at all.
+	if len(events) == 0 {
+		_, err := h.Store.Queries.GetTask(ctx, db.GetTaskParams{ID: uri.ID, UserID: userID})
+		if errors.Is(err, pgx.ErrNoRows) {
+			_, err = h.Store.Queries.GetTrashedTask(ctx, db.GetTrashedTaskParams{ID: uri.ID, UserID: userID})
+		}
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+			return
+		}
+	}
+
+	items := make([]TaskEventResponse, 0, len(events))
+	for _, e := range events {
+		items = append(items, newTaskEventResponse(e))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items": items,
+		"page":  q.Page,
+		"limit": q.Limit,
+	})
+}
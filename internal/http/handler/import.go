@@ -0,0 +1,479 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/pavelc4/auriya-todolist-go/internal/db/sqlc"
+	"github.com/pavelc4/auriya-todolist-go/internal/http/repository"
+	"github.com/pavelc4/auriya-todolist-go/internal/importer"
+)
+
+// maxImportSize is the largest file an import accepts.
+const maxImportSize = 20 << 20
+
+// importTimeout is how long reading the file and applying it may take. It
+// replaces the server's read and write timeouts, which a large file would
+// not fit in, and bounds how long the import holds the user's sync lock.
+const importTimeout = 2 * time.Minute
+
+var (
+	// errImportRolledBack ends an import that must not be committed, a dry
+	// run or an atomic import with failed rows.
+	errImportRolledBack = errors.New("import rolled back")
+	// errProjectNotImported is returned for tasks whose project failed.
+	errProjectNotImported = errors.New("project not imported")
+	// errImportedElsewhere is returned when another import recorded the same
+	// item first. The row is then run again and finds it.
+	errImportedElsewhere = errors.New("imported by another request")
+)
+
+type ImportHandler struct {
+	Store *repository.Store
+}
+
+func NewImportHandler(store *repository.Store) *ImportHandler {
+	return &ImportHandler{Store: store}
+}
+
+// Import reads a file exported from another task manager and creates its
+// projects, sections and tasks. Every project and task runs in its own
+// savepoint, so all rows are checked and reported even when some fail; an
+// atomic import is then rolled back as a whole and answered with 422, while
+// a best-effort one keeps the rows that succeeded. A dry run is always
+// rolled back. Projects and tasks are remembered by their ID in the source,
+// so importing the same file again only adds what is new.
+func (h *ImportHandler) Import(c *gin.Context) {
+	var q ImportQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_query", "detail": err.Error()})
+		return
+	}
+	if q.Mode == "" {
+		q.Mode = BatchAtomic
+	}
+	loc, err := time.LoadLocation(q.Tz)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_query", "detail": "tz: " + err.Error()})
+		return
+	}
+
+	rc := http.NewResponseController(c.Writer)
+	_ = rc.SetReadDeadline(time.Now().Add(importTimeout))
+	_ = rc.SetWriteDeadline(time.Now().Add(importTimeout))
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+	file, name, err := readImportFile(c)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "invalid_request", "detail": fmt.Sprintf("the file is larger than %d bytes", maxImportSize)})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "detail": err.Error()})
+		return
+	}
+	if q.Source == importer.TodoistCSV && q.Project == "" {
+		q.Project = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+
+	plan, err := importer.Parse(q.Source, bytes.NewReader(file), importer.Options{Project: q.Project, Location: loc})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_file", "detail": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), importTimeout)
+	defer cancel()
+	run := &importRun{
+		userID:    c.GetInt64("userID"),
+		source:    q.Source,
+		projects:  make(map[string]int64),
+		sections:  make(map[int64]map[string]int64),
+		workflows: make(map[int64]repository.Workflow),
+	}
+	resp := ImportResponse{Source: q.Source, Mode: q.Mode, DryRun: q.DryRun}
+	err = h.Store.ExecTxSteps(ctx, func(tx *repository.Tx) error {
+		var err error
+		if resp.Projects, resp.Tasks, err = run.apply(ctx, tx, plan); err != nil {
+			return err
+		}
+		failed := false
+		for _, r := range append(resp.Projects, resp.Tasks...) {
+			failed = failed || r.Action == "error"
+		}
+		if q.DryRun || (failed && q.Mode == BatchAtomic) {
+			return errImportRolledBack
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errImportRolledBack) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		return
+	}
+	resp.Committed = err == nil
+
+	for _, list := range [][]ImportResult{resp.Projects, resp.Tasks} {
+		for i := range list {
+			r := &list[i]
+			switch r.Action {
+			case "create":
+				resp.Created++
+				if !resp.Committed {
+					r.ID = nil
+				}
+			case "existing":
+				resp.Existing++
+			case "skip":
+				resp.Skipped++
+			case "error":
+				resp.Failed++
+			}
+		}
+	}
+
+	if !resp.Committed && !q.DryRun {
+		c.JSON(http.StatusUnprocessableEntity, resp)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// readImportFile returns the uploaded file and its name, from the "file"
+// field of a multipart form or else the whole request body.
+func readImportFile(c *gin.Context) ([]byte, string, error) {
+	if c.ContentType() != "multipart/form-data" {
+		body, err := io.ReadAll(c.Request.Body)
+		if err == nil && len(body) == 0 {
+			err = errors.New("the request body is empty")
+		}
+		return body, "", err
+	}
+	header, err := c.FormFile("file")
+	if err != nil {
+		return nil, "", fmt.Errorf("file: %w", err)
+	}
+	f, err := header.Open()
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+	body, err := io.ReadAll(f)
+	return body, header.Filename, err
+}
+
+// importRun applies a plan for one user. It maps the plan's external IDs to
+// the projects they became and caches sections and workflows by project,
+// with 0 for the inbox.
+type importRun struct {
+	userID    int64
+	source    string
+	projects  map[string]int64
+	sections  map[int64]map[string]int64
+	workflows map[int64]repository.Workflow
+}
+
+// apply imports every project and task of plan, each in its own savepoint.
+// Rows that fail are reported; only unexpected errors are returned.
+func (r *importRun) apply(ctx context.Context, tx *repository.Tx, plan *importer.Plan) ([]ImportResult, []ImportResult, error) {
+	projects := make([]ImportResult, len(plan.Projects))
+	for i, p := range plan.Projects {
+		res := ImportResult{Row: p.Row, ExternalID: p.ExternalID, Title: p.Name, Warnings: p.Warnings}
+		res, err := importStep(ctx, tx, res, func(q *db.Queries, res ImportResult) (ImportResult, error) {
+			return r.project(ctx, q, p, res)
+		})
+		if err != nil {
+			if res, err = importFailure(res, err); err != nil {
+				return nil, nil, err
+			}
+		}
+		projects[i] = res
+	}
+
+	tasks := make([]ImportResult, len(plan.Tasks))
+	for i, t := range plan.Tasks {
+		res := ImportResult{Row: t.Row, ExternalID: t.ExternalID, Title: t.Title, Warnings: t.Warnings}
+		switch {
+		case t.Skip != "":
+			res.Action = "skip"
+			res.Detail = t.Skip
+		case t.Err != nil:
+			res.Action = "error"
+			res.Error = "invalid_row"
+			res.Detail = t.Err.Error()
+		default:
+			var err error
+			res, err = importStep(ctx, tx, res, func(q *db.Queries, res ImportResult) (ImportResult, error) {
+				return r.task(ctx, q, t, res)
+			})
+			if err != nil {
+				if res, err = importFailure(res, err); err != nil {
+					return nil, nil, err
+				}
+			}
+		}
+		tasks[i] = res
+	}
+	return projects, tasks, nil
+}
+
+// importStep runs one row in a savepoint. When another import recorded the
+// same item in the meantime, the savepoint is rolled back and the row runs
+// once more, now finding the item as existing.
+func importStep(ctx context.Context, tx *repository.Tx, res ImportResult, fn func(q *db.Queries, res ImportResult) (ImportResult, error)) (ImportResult, error) {
+	out := res
+	for attempt := 0; ; attempt++ {
+		err := tx.Savepoint(ctx, func(q *db.Queries) error {
+			var err error
+			out, err = fn(q, res)
+			return err
+		})
+		if !errors.Is(err, errImportedElsewhere) || attempt > 0 {
+			return out, err
+		}
+	}
+}
+
+// importFailure reports a row that failed, or returns err when it should
+// fail the whole import.
+func importFailure(res ImportResult, err error) (ImportResult, error) {
+	code := taskFieldErrorCode(err)
+	switch {
+	case errors.Is(err, repository.ErrProjectInTrash):
+		code = "project_in_trash"
+	case errors.Is(err, errProjectNotImported):
+		code = "project_not_imported"
+	}
+	if code == "" {
+		return res, err
+	}
+	res.Action = "error"
+	res.ID = nil
+	res.Error = code
+	res.Detail = err.Error()
+	return res, nil
+}
+
+// project finds or creates the project of p and its sections. A project an
+// earlier import created is used again, unless it has since been trashed.
+func (r *importRun) project(ctx context.Context, q *db.Queries, p importer.Project, res ImportResult) (ImportResult, error) {
+	var project db.Project
+	rec, err := q.GetImportRecord(ctx, db.GetImportRecordParams{UserID: r.userID, Source: r.source, ExternalID: p.ExternalID})
+	switch {
+	case err == nil:
+		// Records go when their project is purged, so a project that is
+		// not live is in the trash.
+		project, err = q.GetProject(ctx, db.GetProjectParams{ID: rec.ProjectID.Int64, UserID: r.userID})
+		if errors.Is(err, pgx.ErrNoRows) {
+			return res, fmt.Errorf("%w: restore project %d or delete it for good to import it again", repository.ErrProjectInTrash, rec.ProjectID.Int64)
+		}
+		if err != nil {
+			return res, err
+		}
+		res.Action = "existing"
+	case errors.Is(err, pgx.ErrNoRows):
+		if p.MatchByName {
+			live, err := q.ListProjects(ctx, r.userID)
+			if err != nil {
+				return res, err
+			}
+			for _, l := range live {
+				if strings.EqualFold(l.Name, p.Name) {
+					project, res.Action = l, "existing"
+					break
+				}
+			}
+		}
+		if res.Action == "" {
+			if project, err = q.CreateProject(ctx, db.CreateProjectParams{UserID: r.userID, Name: p.Name}); err != nil {
+				return res, err
+			}
+			res.Action = "create"
+		}
+		n, err := q.CreateImportRecord(ctx, db.CreateImportRecordParams{
+			UserID:     r.userID,
+			Source:     r.source,
+			ExternalID: p.ExternalID,
+			ProjectID:  pgtype.Int8{Int64: project.ID, Valid: true},
+		})
+		if err != nil {
+			return res, err
+		}
+		if n == 0 {
+			return res, errImportedElsewhere
+		}
+	default:
+		return res, err
+	}
+	res.ID = &project.ID
+
+	existing, err := q.ListSections(ctx, db.ListSectionsParams{ProjectID: project.ID, UserID: r.userID})
+	if err != nil {
+		return res, err
+	}
+	sections := make(map[string]int64, len(existing))
+	for _, s := range existing {
+		sections[strings.ToLower(s.Name)] = s.ID
+	}
+	for _, name := range p.Sections {
+		key := strings.ToLower(name)
+		if _, ok := sections[key]; ok || name == "" {
+			continue
+		}
+		s, err := repository.CreateSection(ctx, q, db.CreateSectionParams{ProjectID: project.ID, UserID: r.userID, Name: name})
+		if err != nil {
+			return res, err
+		}
+		sections[key] = s.ID
+	}
+
+	r.projects[p.ExternalID] = project.ID
+	r.sections[project.ID] = sections
+	return res, nil
+}
+
+// task creates t with its checklist, unless an earlier import already did.
+func (r *importRun) task(ctx context.Context, q *db.Queries, t importer.Task, res ImportResult) (ImportResult, error) {
+	rec, err := q.GetImportRecord(ctx, db.GetImportRecordParams{UserID: r.userID, Source: r.source, ExternalID: t.ExternalID})
+	if err == nil {
+		res.Action = "existing"
+		res.ID = &rec.TaskID.Int64
+		return res, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return res, err
+	}
+
+	var projectID pgtype.Int8
+	var sectionID int64
+	if t.Project != "" {
+		id, ok := r.projects[t.Project]
+		if !ok {
+			return res, fmt.Errorf("%w: the project of this task failed", errProjectNotImported)
+		}
+		projectID = pgtype.Int8{Int64: id, Valid: true}
+		if t.Section != "" {
+			sectionID = r.sections[id][strings.ToLower(t.Section)]
+		}
+	} else if t.Section != "" {
+		res.Warnings = append(res.Warnings, fmt.Sprintf("section %q left out, since inbox tasks have no sections", t.Section))
+	}
+
+	workflow, err := r.workflow(ctx, q, projectID)
+	if err != nil {
+		return res, err
+	}
+	status, err := importStatus(workflow, t.Status, t.Category)
+	if err != nil {
+		return res, err
+	}
+
+	arg := db.CreateTaskParams{
+		Title:           t.Title,
+		Status:          status,
+		UserID:          r.userID,
+		ProjectID:       projectID,
+		EstimateSeconds: toPgInt4(t.Estimate),
+		Tags:            t.Tags,
+	}
+	if t.Description != "" {
+		arg.Description = &t.Description
+	}
+	if t.Priority != 0 {
+		arg.Priority = t.Priority
+	}
+	if t.Due != nil {
+		arg.DueDate = pgtype.Timestamptz{Time: *t.Due, Valid: true}
+	}
+	if t.Recurrence != "" {
+		arg.Recurrence = &t.Recurrence
+	}
+	task, err := repository.CreateTask(ctx, q, r.userID, arg)
+	if err != nil {
+		return res, err
+	}
+	if sectionID != 0 {
+		if _, err := repository.MoveTask(ctx, q, r.userID, r.userID, task.ID, repository.TaskMove{SectionID: &sectionID}); err != nil {
+			return res, err
+		}
+	}
+	for _, item := range t.Checklist {
+		created, _, err := repository.AddChecklistItem(ctx, q, r.userID, task.ID, item.Text, nil, nil)
+		if err != nil {
+			return res, err
+		}
+		if item.Checked {
+			_, _, err := repository.UpdateChecklistItem(ctx, q, db.UpdateChecklistItemParams{
+				Checked: pgtype.Bool{Bool: true, Valid: true},
+				ID:      created.ID,
+				TaskID:  task.ID,
+				UserID:  r.userID,
+			})
+			if err != nil {
+				return res, err
+			}
+		}
+	}
+
+	n, err := q.CreateImportRecord(ctx, db.CreateImportRecordParams{
+		UserID:     r.userID,
+		Source:     r.source,
+		ExternalID: t.ExternalID,
+		TaskID:     pgtype.Int8{Int64: task.ID, Valid: true},
+	})
+	if err != nil {
+		return res, err
+	}
+	if n == 0 {
+		return res, errImportedElsewhere
+	}
+	res.Action = "create"
+	res.ID = &task.ID
+	return res, nil
+}
+
+// workflow returns the workflow of a project, loading it once per import.
+func (r *importRun) workflow(ctx context.Context, q *db.Queries, projectID pgtype.Int8) (repository.Workflow, error) {
+	if w, ok := r.workflows[projectID.Int64]; ok {
+		return w, nil
+	}
+	w, err := repository.LoadWorkflow(ctx, q, projectID)
+	if err != nil {
+		return repository.Workflow{}, err
+	}
+	r.workflows[projectID.Int64] = w
+	return w, nil
+}
+
+// importStatus picks the status of an imported task: its status when the
+// workflow has it, or else the first status of the category it names or
+// falls back to. nil leaves the workflow's default.
+func importStatus(w repository.Workflow, status, category string) (any, error) {
+	if status != "" {
+		if s, ok := w.Status(status); ok {
+			return s.Key, nil
+		}
+		if s, ok := w.First(status); ok {
+			return s.Key, nil
+		}
+	}
+	if category != "" {
+		if s, ok := w.First(category); ok {
+			return s.Key, nil
+		}
+	}
+	if status != "" {
+		_, err := w.Lookup(status)
+		return nil, err
+	}
+	return nil, nil
+}
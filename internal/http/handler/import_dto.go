@@ -0,0 +1,47 @@
+package handler
+
+// ImportQuery defines the query parameters for an import. The file is the
+// request body, or the "file" field of a multipart form. Project names the
+// project of a Todoist CSV file, defaulting to the uploaded file's name, and
+// renames a Trello board. Tz is the IANA time zone dates without one are read
+// in. Mode defaults to atomic; DryRun reports what would happen without
+// saving anything.
+type ImportQuery struct {
+	Source  string `form:"source" binding:"required,oneof=todoist_json todoist_csv trello csv"`
+	Mode    string `form:"mode" binding:"omitempty,oneof=atomic best_effort"`
+	DryRun  bool   `form:"dry_run"`
+	Project string `form:"project" binding:"max=100"`
+	Tz      string `form:"tz,default=UTC" binding:"max=64"`
+}
+
+// ImportResult is the outcome for one project or task of the file. Row is
+// its line in a CSV file or its position in a JSON list, from 1. Action is
+// "create", "existing" when an earlier import or a project of the same name
+// already has it, "skip" when it is left out on purpose, such as an
+// archived card, or "error". ID is the project or task the row maps to; it
+// is left out for creates that were not committed.
+type ImportResult struct {
+	Row        int      `json:"row"`
+	ExternalID string   `json:"external_id"`
+	Title      string   `json:"title"`
+	Action     string   `json:"action"`
+	ID         *int64   `json:"id,omitempty"`
+	Error      string   `json:"error,omitempty"`
+	Detail     string   `json:"detail,omitempty"`
+	Warnings   []string `json:"warnings,omitempty"`
+}
+
+// ImportResponse is the outcome of an import. Committed is false for dry runs
+// and for atomic imports rolled back because a row failed.
+type ImportResponse struct {
+	Source    string         `json:"source"`
+	Mode      string         `json:"mode"`
+	DryRun    bool           `json:"dry_run"`
+	Committed bool           `json:"committed"`
+	Created   int            `json:"created"`
+	Existing  int            `json:"existing"`
+	Skipped   int            `json:"skipped"`
+	Failed    int            `json:"failed"`
+	Projects  []ImportResult `json:"projects"`
+	Tasks     []ImportResult `json:"tasks"`
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/pavelc4/auriya-todolist-go/internal/jsonpatch"
+)
+
+// PATCH routes accept three body types. Plain JSON keeps its original
+// meaning, where null and absent members both leave a field alone. An RFC
+// 7396 merge patch clears nullable fields set to null. An RFC 6902 JSON
+// Patch is applied to the editable fields of the current resource and then
+// handled as the merge patch that has the same effect.
+const (
+	contentTypeMergePatch = "application/merge-patch+json"
+	contentTypeJSONPatch  = "application/json-patch+json"
+)
+
+// errInvalidPatch is returned for merge patches that are not JSON objects
+// or give a field a value it cannot have. The wrapped message says why.
+var errInvalidPatch = errors.New("invalid patch")
+
+// patchFailure wraps the errors of a well-formed JSON Patch that cannot be
+// applied to the current resource, including one whose result is not a
+// valid resource.
+type patchFailure struct{ err error }
+
+func (e *patchFailure) Error() string { return e.err.Error() }
+func (e *patchFailure) Unwrap() error { return e.err }
+
+// readMergePatch decodes a merge patch into req, which is validated like a
+// JSON request body, and returns the members the patch sets to null.
+func readMergePatch(body []byte, req any) (map[string]bool, error) {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(body, &members); err != nil || members == nil {
+		return nil, fmt.Errorf("%w: a merge patch must be a JSON object", errInvalidPatch)
+	}
+	if err := json.Unmarshal(body, req); err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidPatch, err)
+	}
+	if err := binding.Validator.ValidateStruct(req); err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidPatch, err)
+	}
+
+	nulls := make(map[string]bool)
+	for name, value := range members {
+		if string(value) == "null" {
+			nulls[name] = true
+		}
+	}
+	return nulls, nil
+}
+
+// mergePatchFromJSONPatch applies a JSON Patch to doc, a JSON object, and
+// returns the merge patch with the same effect: changed members with their
+// new value and removed members as null.
+func mergePatchFromJSONPatch(patch jsonpatch.Patch, doc []byte) ([]byte, error) {
+	patched, err := patch.Apply(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	var before, after map[string]json.RawMessage
+	if err := json.Unmarshal(doc, &before); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(patched, &after); err != nil || after == nil {
+		return nil, &patchFailure{errors.New("the patched document must be a JSON object")}
+	}
+
+	merge := make(map[string]json.RawMessage)
+	for name, value := range after {
+		if !bytes.Equal(before[name], value) {
+			merge[name] = value
+		}
+	}
+	for name := range before {
+		if _, ok := after[name]; !ok {
+			merge[name] = json.RawMessage("null")
+		}
+	}
+	return json.Marshal(merge)
+}
+
+// patchError writes the response for errors from reading or applying a
+// patch and reports whether err was one.
+func patchError(c *gin.Context, err error) bool {
+	var failure *patchFailure
+	switch {
+	case errors.Is(err, jsonpatch.ErrTestFailed):
+		c.JSON(http.StatusConflict, gin.H{"error": "patch_test_failed", "detail": err.Error()})
+	case errors.As(err, &failure) || errors.Is(err, jsonpatch.ErrFailed):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "patch_failed", "detail": err.Error()})
+	case errors.Is(err, errInvalidPatch) || errors.Is(err, jsonpatch.ErrInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_patch", "detail": err.Error()})
+	default:
+		return false
+	}
+	return true
+}
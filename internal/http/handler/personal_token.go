@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/pavelc4/auriya-todolist-go/internal/db/sqlc"
+	"github.com/pavelc4/auriya-todolist-go/internal/http/repository"
+	"github.com/pavelc4/auriya-todolist-go/internal/http/service"
+)
+
+// maxPersonalTokens caps how many personal access tokens a user can hold.
+const maxPersonalTokens = 50
+
+// PersonalTokenHandler manages personal access tokens, which sign in
+// clients such as CalDAV apps that cannot use the regular login.
+type PersonalTokenHandler struct {
+	Store *repository.Store
+}
+
+func NewPersonalTokenHandler(store *repository.Store) *PersonalTokenHandler {
+	return &PersonalTokenHandler{Store: store}
+}
+
+func newPersonalTokenResponse(t db.PersonalToken) PersonalTokenResponse {
+	resp := PersonalTokenResponse{ID: t.ID, Name: t.Name, CreatedAt: t.CreatedAt.Time}
+	if t.LastUsedAt.Valid {
+		resp.LastUsedAt = &t.LastUsedAt.Time
+	}
+	return resp
+}
+
+// List returns the user's personal access tokens, without the tokens
+// themselves.
+func (h *PersonalTokenHandler) List(c *gin.Context) {
+	tokens, err := h.Store.Queries.ListPersonalTokens(c.Request.Context(), c.GetInt64("userID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		return
+	}
+	resp := make([]PersonalTokenResponse, 0, len(tokens))
+	for _, t := range tokens {
+		resp = append(resp, newPersonalTokenResponse(t))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// Create makes a personal access token and returns it; it cannot be shown
+// again.
+func (h *PersonalTokenHandler) Create(c *gin.Context) {
+	var req CreatePersonalTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "detail": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetInt64("userID")
+	existing, err := h.Store.Queries.ListPersonalTokens(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		return
+	}
+	if len(existing) >= maxPersonalTokens {
+		c.JSON(http.StatusConflict, gin.H{"error": "too_many_tokens", "detail": "delete a token before making another"})
+		return
+	}
+
+	token, err := service.NewPersonalToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "detail": err.Error()})
+		return
+	}
+	t, err := h.Store.Queries.CreatePersonalToken(ctx, db.CreatePersonalTokenParams{
+		UserID:    userID,
+		Name:      req.Name,
+		TokenHash: service.HashPersonalToken(token),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		return
+	}
+
+	resp := newPersonalTokenResponse(t)
+	resp.Token = token
+	c.JSON(http.StatusCreated, resp)
+}
+
+// Delete revokes a personal access token.
+func (h *PersonalTokenHandler) Delete(c *gin.Context) {
+	var uri struct {
+		ID int64 `uri:"id" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "detail": err.Error()})
+		return
+	}
+
+	n, err := h.Store.Queries.DeletePersonalToken(c.Request.Context(), db.DeletePersonalTokenParams{ID: uri.ID, UserID: c.GetInt64("userID")})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		return
+	}
+	if n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
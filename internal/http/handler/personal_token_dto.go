@@ -0,0 +1,19 @@
+package handler
+
+import "time"
+
+// CreatePersonalTokenRequest defines the request body for making a personal
+// access token. Name says where it is used, such as "Thunderbird".
+type CreatePersonalTokenRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+// PersonalTokenResponse defines the response for a personal access token.
+// The token itself is only returned when it is made.
+type PersonalTokenResponse struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Token      string     `json:"token,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
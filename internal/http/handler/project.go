@@ -2,29 +2,44 @@ package handler
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/pavelc4/auriya-todolist-go/internal/cache"
 	db "github.com/pavelc4/auriya-todolist-go/internal/db/sqlc"
 	"github.com/pavelc4/auriya-todolist-go/internal/http/repository"
+	"github.com/pavelc4/auriya-todolist-go/internal/jsonpatch"
 )
 
 type ProjectHandler struct {
 	Store *repository.Store
+	cache *cache.Service
 }
 
-func NewProjectHandler(store *repository.Store) *ProjectHandler {
-	return &ProjectHandler{Store: store}
+func NewProjectHandler(store *repository.Store, cache *cache.Service) *ProjectHandler {
+	return &ProjectHandler{Store: store, cache: cache}
 }
 
 // newProjectResponse converts a database project model to a JSON response model.
 func newProjectResponse(project db.Project) ProjectResponse {
+	var deletedAt *time.Time
+	if project.DeletedAt.Valid {
+		deletedAt = &project.DeletedAt.Time
+	}
+
 	return ProjectResponse{
 		ID:        project.ID,
 		UserID:    project.UserID,
 		Name:      project.Name,
+		Version:   project.Version,
 		CreatedAt: project.CreatedAt.Time,
 		UpdatedAt: project.UpdatedAt.Time,
+		DeletedAt: deletedAt,
 	}
 }
 
@@ -42,12 +57,20 @@ func (h *ProjectHandler) Create(c *gin.Context) {
 		Name:   req.Name,
 	}
 
-	project, err := h.Store.Queries.CreateProject(c.Request.Context(), arg)
+	// Even a single insert runs in a transaction, which takes the user's
+	// sync lock first; see Store.ExecTx.
+	var project db.Project
+	err := h.Store.ExecTx(c.Request.Context(), func(q *db.Queries) error {
+		var err error
+		project, err = q.CreateProject(c.Request.Context(), arg)
+		return err
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
 		return
 	}
 
+	c.Header("ETag", versionETag(project.Version))
 	c.JSON(http.StatusCreated, newProjectResponse(project))
 }
 
@@ -76,6 +99,9 @@ func (h *ProjectHandler) Get(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
 		return
 	}
+	if notModified(c, versionETag(project.Version)) {
+		return
+	}
 	c.JSON(http.StatusOK, newProjectResponse(project))
 }
 
@@ -93,7 +119,7 @@ func (h *ProjectHandler) List(c *gin.Context) {
 		projectResponses = append(projectResponses, newProjectResponse(p))
 	}
 
-	c.JSON(http.StatusOK, projectResponses)
+	writeListJSON(c, projectResponses)
 }
 
 func (h *ProjectHandler) Update(c *gin.Context) {
@@ -105,32 +131,116 @@ func (h *ProjectHandler) Update(c *gin.Context) {
 		return
 	}
 
-	var req UpdateProjectRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "detail": err.Error()})
-		return
-	}
-
 	userID, _ := c.Get("userID")
 
-	arg := db.UpdateProjectParams{
-		ID:     uri.ID,
-		UserID: userID.(int64),
-		Name:   req.Name,
+	// build returns the new name for the current project, or nil when the
+	// request leaves it alone.
+	var build func(current db.Project) (*string, error)
+	switch c.ContentType() {
+	case contentTypeJSONPatch:
+		body, err := c.GetRawData()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "detail": err.Error()})
+			return
+		}
+		patch, err := jsonpatch.Decode(body)
+		if err != nil {
+			patchError(c, err)
+			return
+		}
+		build = func(current db.Project) (*string, error) {
+			doc, err := json.Marshal(UpdateProjectRequest{Name: current.Name})
+			if err != nil {
+				return nil, err
+			}
+			merge, err := mergePatchFromJSONPatch(patch, doc)
+			if err != nil {
+				return nil, err
+			}
+			name, err := projectMergePatchName(merge)
+			if err != nil {
+				return nil, &patchFailure{err}
+			}
+			return name, nil
+		}
+	case contentTypeMergePatch:
+		body, err := c.GetRawData()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "detail": err.Error()})
+			return
+		}
+		name, err := projectMergePatchName(body)
+		if err != nil {
+			patchError(c, err)
+			return
+		}
+		build = func(db.Project) (*string, error) { return name, nil }
+	default:
+		var req UpdateProjectRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "detail": err.Error()})
+			return
+		}
+		build = func(db.Project) (*string, error) { return &req.Name, nil }
 	}
 
-	project, err := h.Store.Queries.UpdateProject(c.Request.Context(), arg)
+	match := requestIfMatch(c)
+	ctx := c.Request.Context()
+	var project db.Project
+	err := h.Store.ExecTx(ctx, func(q *db.Queries) error {
+		current, err := q.GetProjectForUpdate(ctx, db.GetProjectForUpdateParams{ID: uri.ID, UserID: userID.(int64)})
+		if err != nil {
+			return err
+		}
+		if err := match.check(current.Version); err != nil {
+			return err
+		}
+		name, err := build(current)
+		if err != nil {
+			return err
+		}
+		if name == nil {
+			project = current
+			return nil
+		}
+		project, err = q.UpdateProject(ctx, db.UpdateProjectParams{ID: uri.ID, UserID: userID.(int64), Name: *name})
+		return err
+	})
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, pgx.ErrNoRows) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
 			return
 		}
+		if errors.Is(err, errPreconditionFailed) {
+			writePreconditionFailed(c)
+			return
+		}
+		if patchError(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
 		return
 	}
+	c.Header("ETag", versionETag(project.Version))
 	c.JSON(http.StatusOK, newProjectResponse(project))
 }
 
+// projectMergePatchName reads a merge patch for a project and returns the
+// new name, or nil when the patch does not set one. The name cannot be null.
+func projectMergePatchName(body []byte) (*string, error) {
+	var req struct {
+		Name *string `json:"name" binding:"omitempty,min=1,max=100"`
+	}
+	nulls, err := readMergePatch(body, &req)
+	if err != nil {
+		return nil, err
+	}
+	if nulls["name"] {
+		return nil, fmt.Errorf("%w: name cannot be null", errInvalidPatch)
+	}
+	return req.Name, nil
+}
+
 func (h *ProjectHandler) Delete(c *gin.Context) {
 	var uri struct {
 		ID int64 `uri:"id" binding:"required,min=1"`
@@ -140,16 +250,67 @@ func (h *ProjectHandler) Delete(c *gin.Context) {
 		return
 	}
 
-	userID, _ := c.Get("userID")
+	userID := c.GetInt64("userID")
+	ctx := c.Request.Context()
 
-	arg := db.DeleteProjectParams{
-		ID:     uri.ID,
-		UserID: userID.(int64),
+	match := requestIfMatch(c)
+	var trashed []db.Task
+	err := h.Store.ExecTx(ctx, func(q *db.Queries) error {
+		if err := match.lockProject(ctx, q, uri.ID, userID); err != nil {
+			return err
+		}
+		var err error
+		trashed, err = repository.TrashProject(ctx, q, userID, db.DeleteProjectParams{ID: uri.ID, UserID: userID})
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+			return
+		}
+		if errors.Is(err, errPreconditionFailed) {
+			writePreconditionFailed(c)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		return
 	}
 
-	if err := h.Store.Queries.DeleteProject(c.Request.Context(), arg); err != nil {
+	for _, task := range trashed {
+		h.cache.Delete(fmt.Sprintf("task:%d", task.ID))
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Restore brings a project back from the trash together with the tasks that
+// were trashed along with it.
+func (h *ProjectHandler) Restore(c *gin.Context) {
+	var uri struct {
+		ID int64 `uri:"id" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "detail": err.Error()})
+		return
+	}
+
+	userID := c.GetInt64("userID")
+	ctx := c.Request.Context()
+
+	var project db.Project
+	err := h.Store.ExecTx(ctx, func(q *db.Queries) error {
+		var err error
+		project, err = repository.RestoreProject(ctx, q, userID, db.RestoreProjectParams{ID: uri.ID, UserID: userID})
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
 		return
 	}
-	c.Status(http.StatusNoContent)
+
+	c.JSON(http.StatusOK, newProjectResponse(project))
 }
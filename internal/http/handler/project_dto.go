@@ -11,9 +11,42 @@ type UpdateProjectRequest struct {
 }
 
 type ProjectResponse struct {
-	ID        int64     `json:"id"`
-	UserID    int64     `json:"user_id"`
-	Name      string    `json:"name"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        int64      `json:"id"`
+	UserID    int64      `json:"user_id"`
+	Name      string     `json:"name"`
+	Version   int64      `json:"version"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// WorkflowStatusRequest is one status of a project workflow. Key is what
+// tasks store in their status field; Name is for display.
+type WorkflowStatusRequest struct {
+	Key      string `json:"key" binding:"required,max=50"`
+	Name     string `json:"name" binding:"required,max=100"`
+	Category string `json:"category" binding:"required,oneof=todo doing done"`
+}
+
+// UpdateWorkflowRequest replaces a project's workflow. Transitions maps a
+// status to the statuses tasks may move to from it; leave it empty to allow
+// every change. Remap gives a replacement for each removed status that tasks
+// still have.
+type UpdateWorkflowRequest struct {
+	Statuses    []WorkflowStatusRequest `json:"statuses" binding:"required,min=1,dive"`
+	Default     string                  `json:"default" binding:"required"`
+	Transitions map[string][]string     `json:"transitions"`
+	Remap       map[string]string       `json:"remap"`
+}
+
+type WorkflowStatusResponse struct {
+	Key      string `json:"key"`
+	Name     string `json:"name"`
+	Category string `json:"category"`
+}
+
+type WorkflowResponse struct {
+	Statuses    []WorkflowStatusResponse `json:"statuses"`
+	Default     string                   `json:"default"`
+	Transitions map[string][]string      `json:"transitions"`
 }
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/pavelc4/auriya-todolist-go/internal/db/sqlc"
+	"github.com/pavelc4/auriya-todolist-go/internal/quickadd"
+)
+
+// QuickAdd creates a task from a line such as
+// "Pay rent every month on the 1st #home !3 tomorrow 9am". See package
+// quickadd for the syntax. With dry_run the parse result is returned and
+// nothing is saved.
+func (h *TaskHandler) QuickAdd(c *gin.Context) {
+	var req QuickAddRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "detail": err.Error()})
+		return
+	}
+
+	if req.Tz == "" {
+		req.Tz = "UTC"
+	}
+	loc, err := time.LoadLocation(req.Tz)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "detail": "tz: " + err.Error()})
+		return
+	}
+
+	userID := c.GetInt64("userID")
+	ctx := c.Request.Context()
+
+	res := quickadd.Parse(req.Text, time.Now().In(loc))
+	parsed := QuickAddParse{
+		Title:   res.Title,
+		DueDate: res.Due,
+		AllDay:  res.AllDay,
+		Tags:    res.Tags,
+	}
+	if parsed.Tags == nil {
+		parsed.Tags = []string{}
+	}
+	if res.Priority != 0 {
+		parsed.Priority = &res.Priority
+	}
+	if res.Recurrence != "" {
+		parsed.Recurrence = &res.Recurrence
+	}
+	if res.Project != "" {
+		parsed.Project = &res.Project
+		projects, err := h.Store.Queries.ListProjects(ctx, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+			return
+		}
+		parsed.ProjectID = matchProject(projects, res.Project)
+	}
+
+	if req.DryRun {
+		c.JSON(http.StatusOK, parsed)
+		return
+	}
+
+	switch {
+	case parsed.Title == "":
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "invalid_quick_add", "detail": "nothing is left for the title"})
+		return
+	case utf8.RuneCountInString(parsed.Title) > 255:
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "invalid_quick_add", "detail": "title is longer than 255 characters"})
+		return
+	case parsed.Project != nil && parsed.ProjectID == nil:
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "unknown_project", "detail": "no project named " + *parsed.Project})
+		return
+	}
+
+	arg := db.CreateTaskParams{
+		Title:      parsed.Title,
+		UserID:     userID,
+		Tags:       res.Tags,
+		Recurrence: parsed.Recurrence,
+	}
+	if parsed.Priority != nil {
+		arg.Priority = *parsed.Priority
+	}
+	if parsed.DueDate != nil {
+		arg.DueDate = pgtype.Timestamptz{Time: *parsed.DueDate, Valid: true}
+	}
+	if parsed.ProjectID != nil {
+		arg.ProjectID = pgtype.Int8{Int64: *parsed.ProjectID, Valid: true}
+	}
+
+	task, ok := h.createTask(c, arg)
+	if !ok {
+		return
+	}
+
+	c.Header("ETag", versionETag(task.Version))
+	c.JSON(http.StatusCreated, QuickAddResponse{Task: newTaskResponse(task), Parsed: parsed})
+}
+
+// matchProject finds the project a "#name" refers to, ignoring case. Since
+// the name cannot contain spaces, "_" and "-" in it also match spaces.
+func matchProject(projects []db.Project, name string) *int64 {
+	spaced := strings.NewReplacer("_", " ", "-", " ").Replace(name)
+	for _, p := range projects {
+		if strings.EqualFold(p.Name, name) {
+			return &p.ID
+		}
+	}
+	for _, p := range projects {
+		if strings.EqualFold(p.Name, spaced) {
+			return &p.ID
+		}
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package handler
+
+import "time"
+
+// QuickAddRequest defines the request body for creating a task from a single
+// line of text. Tz is the IANA time zone relative dates are resolved in and
+// defaults to UTC. With DryRun set the line is only parsed.
+type QuickAddRequest struct {
+	Text   string `json:"text" binding:"required,max=1000"`
+	Tz     string `json:"tz" binding:"omitempty,max=64"`
+	DryRun bool   `json:"dry_run"`
+}
+
+// QuickAddParse is what was read from a quick-add line. Project is the name
+// as typed and ProjectID the project it matched, if any. A DueDate without a
+// time of day is at the end of that day and has AllDay set.
+type QuickAddParse struct {
+	Title      string     `json:"title"`
+	DueDate    *time.Time `json:"due_date"`
+	AllDay     bool       `json:"all_day"`
+	Priority   *int32     `json:"priority"`
+	Project    *string    `json:"project"`
+	ProjectID  *int64     `json:"project_id"`
+	Tags       []string   `json:"tags"`
+	Recurrence *string    `json:"recurrence"`
+}
+
+// QuickAddResponse is returned after a quick-add line created a task.
+type QuickAddResponse struct {
+	Task   TaskResponse  `json:"task"`
+	Parsed QuickAddParse `json:"parsed"`
+}
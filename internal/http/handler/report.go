@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/pavelc4/auriya-todolist-go/internal/db/sqlc"
+)
+
+// reportDimensions are the values group_by accepts, in output column order.
+var reportDimensions = []string{"project", "day", "user"}
+
+// Report aggregates finished time entries by project, day and/or user, as
+// JSON or CSV. Entries count towards the day they started on.
+func (h *TimeEntryHandler) Report(c *gin.Context) {
+	var q TimeReportQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_query", "detail": err.Error()})
+		return
+	}
+
+	var groupBy []string
+	for _, dim := range strings.Split(q.GroupBy, ",") {
+		dim = strings.TrimSpace(dim)
+		if !slices.Contains(reportDimensions, dim) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_query", "detail": fmt.Sprintf("group_by: unknown dimension %q", dim)})
+			return
+		}
+		if !slices.Contains(groupBy, dim) {
+			groupBy = append(groupBy, dim)
+		}
+	}
+	slices.SortFunc(groupBy, func(a, b string) int {
+		return slices.Index(reportDimensions, a) - slices.Index(reportDimensions, b)
+	})
+
+	if q.Tz == "" {
+		q.Tz = "UTC"
+	}
+	loc, err := time.LoadLocation(q.Tz)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_query", "detail": fmt.Sprintf("tz: %v", err)})
+		return
+	}
+
+	// Default to the last 30 days, today included.
+	today := time.Now().In(loc)
+	to := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, loc)
+	if q.To != "" {
+		to, _ = time.ParseInLocation(time.DateOnly, q.To, loc)
+	}
+	from := to.AddDate(0, 0, -29)
+	if q.From != "" {
+		from, _ = time.ParseInLocation(time.DateOnly, q.From, loc)
+	}
+	if to.Before(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_query", "detail": "to is before from"})
+		return
+	}
+
+	arg := db.TimeReportParams{
+		Tz:     q.Tz,
+		UserID: c.GetInt64("userID"),
+		From:   pgtype.Timestamptz{Time: from, Valid: true},
+		To:     pgtype.Timestamptz{Time: to.AddDate(0, 0, 1), Valid: true},
+	}
+	if q.ProjectID != nil {
+		arg.ProjectID = pgtype.Int8{Int64: *q.ProjectID, Valid: true}
+	}
+
+	rows, err := h.Store.Queries.TimeReport(c.Request.Context(), arg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		return
+	}
+
+	// Rows come ordered by day, project and user; grouping keeps the first
+	// occurrence of each key, which preserves that order.
+	report := TimeReportResponse{
+		From:    from.Format(time.DateOnly),
+		To:      to.Format(time.DateOnly),
+		Tz:      q.Tz,
+		GroupBy: groupBy,
+		Rows:    []TimeReportRow{},
+	}
+	index := make(map[string]int)
+	for _, r := range rows {
+		var row TimeReportRow
+		var key []string
+		for _, dim := range groupBy {
+			switch dim {
+			case "project":
+				if r.ProjectID.Valid {
+					row.ProjectID = &r.ProjectID.Int64
+				}
+				if r.ProjectName.Valid {
+					row.ProjectName = &r.ProjectName.String
+				}
+				key = append(key, strconv.FormatInt(r.ProjectID.Int64, 10))
+			case "day":
+				row.Day = r.Day.Time.Format(time.DateOnly)
+				key = append(key, row.Day)
+			case "user":
+				row.UserID = &r.UserID
+				key = append(key, strconv.FormatInt(r.UserID, 10))
+			}
+		}
+
+		k := strings.Join(key, "|")
+		i, ok := index[k]
+		if !ok {
+			i = len(report.Rows)
+			index[k] = i
+			report.Rows = append(report.Rows, row)
+		}
+		report.Rows[i].Seconds += r.Seconds
+		report.Rows[i].Entries += r.Entries
+		report.TotalSeconds += r.Seconds
+	}
+
+	if q.Format == "csv" {
+		writeTimeReportCSV(c, report)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// writeTimeReportCSV writes one line per report row with a header naming the
+// grouped columns followed by seconds, hours and entries.
+func writeTimeReportCSV(c *gin.Context, report TimeReportResponse) {
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="time-report-%s-%s.csv"`, report.From, report.To))
+	c.Status(http.StatusOK)
+
+	var header []string
+	for _, dim := range report.GroupBy {
+		switch dim {
+		case "project":
+			header = append(header, "project_id", "project_name")
+		case "day":
+			header = append(header, "day")
+		case "user":
+			header = append(header, "user_id")
+		}
+	}
+	header = append(header, "seconds", "hours", "entries")
+
+	w := csv.NewWriter(c.Writer)
+	_ = w.Write(header)
+	for _, row := range report.Rows {
+		var record []string
+		for _, dim := range report.GroupBy {
+			switch dim {
+			case "project":
+				id, name := "", ""
+				if row.ProjectID != nil {
+					id = strconv.FormatInt(*row.ProjectID, 10)
+				}
+				if row.ProjectName != nil {
+					name = csvSafe(*row.ProjectName)
+				}
+				record = append(record, id, name)
+			case "day":
+				record = append(record, row.Day)
+			case "user":
+				record = append(record, strconv.FormatInt(*row.UserID, 10))
+			}
+		}
+		record = append(record,
+			strconv.FormatInt(row.Seconds, 10),
+			strconv.FormatFloat(float64(row.Seconds)/3600, 'f', 2, 64),
+			strconv.FormatInt(row.Entries, 10),
+		)
+		_ = w.Write(record)
+	}
+	w.Flush()
+}
+
+// csvSafe keeps user-entered text from being read as a formula by
+// spreadsheet applications.
+func csvSafe(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
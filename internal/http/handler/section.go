@@ -0,0 +1,303 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/pavelc4/auriya-todolist-go/internal/db/sqlc"
+	"github.com/pavelc4/auriya-todolist-go/internal/http/repository"
+)
+
+type SectionHandler struct {
+	Store *repository.Store
+}
+
+func NewSectionHandler(store *repository.Store) *SectionHandler {
+	return &SectionHandler{Store: store}
+}
+
+// sectionURI identifies a section within a project.
+type sectionURI struct {
+	ID        int64 `uri:"id" binding:"required,min=1"`
+	SectionID int64 `uri:"section_id" binding:"required,min=1"`
+}
+
+// newSectionResponse converts a database section model to a JSON response model.
+func newSectionResponse(section db.ProjectSection) SectionResponse {
+	var status *string
+	if section.Status.Valid {
+		status = &section.Status.String
+	}
+
+	return SectionResponse{
+		ID:        section.ID,
+		ProjectID: section.ProjectID,
+		Name:      section.Name,
+		Position:  section.Position,
+		Status:    status,
+		CreatedAt: section.CreatedAt.Time,
+		UpdatedAt: section.UpdatedAt.Time,
+	}
+}
+
+// Create adds a section at the end of a project's sections.
+func (h *SectionHandler) Create(c *gin.Context) {
+	var uri struct {
+		ID int64 `uri:"id" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "detail": err.Error()})
+		return
+	}
+
+	var req CreateSectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "detail": err.Error()})
+		return
+	}
+
+	userID := c.GetInt64("userID")
+	ctx := c.Request.Context()
+
+	var section db.ProjectSection
+	err := h.Store.ExecTx(ctx, func(q *db.Queries) error {
+		var err error
+		section, err = repository.CreateSection(ctx, q, db.CreateSectionParams{
+			ProjectID: uri.ID,
+			UserID:    userID,
+			Name:      req.Name,
+			Status:    toPgText(req.Status),
+		})
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+			return
+		}
+		if taskFieldError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, newSectionResponse(section))
+}
+
+// List returns a project's sections in order.
+func (h *SectionHandler) List(c *gin.Context) {
+	var uri struct {
+		ID int64 `uri:"id" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "detail": err.Error()})
+		return
+	}
+
+	userID := c.GetInt64("userID")
+	ctx := c.Request.Context()
+
+	if _, err := h.Store.Queries.GetProject(ctx, db.GetProjectParams{ID: uri.ID, UserID: userID}); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		return
+	}
+
+	sections, err := h.Store.Queries.ListSections(ctx, db.ListSectionsParams{ProjectID: uri.ID, UserID: userID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		return
+	}
+
+	resp := make([]SectionResponse, 0, len(sections))
+	for _, s := range sections {
+		resp = append(resp, newSectionResponse(s))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// Update renames a section or changes the status it maps to.
+func (h *SectionHandler) Update(c *gin.Context) {
+	var uri sectionURI
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "detail": err.Error()})
+		return
+	}
+
+	var req UpdateSectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "detail": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	var section db.ProjectSection
+	err := h.Store.ExecTx(ctx, func(q *db.Queries) error {
+		var err error
+		section, err = repository.UpdateSection(ctx, q, db.UpdateSectionParams{
+			Name:      toPgText(req.Name),
+			Status:    toPgText(req.Status),
+			ID:        uri.SectionID,
+			ProjectID: uri.ID,
+			UserID:    c.GetInt64("userID"),
+		})
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+			return
+		}
+		if taskFieldError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, newSectionResponse(section))
+}
+
+// Delete removes a section. Its tasks stay in the project without a section.
+func (h *SectionHandler) Delete(c *gin.Context) {
+	var uri sectionURI
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "detail": err.Error()})
+		return
+	}
+
+	// A transaction of its own, so the user's sync lock is taken before the
+	// section's tasks are locked to clear their section.
+	ctx := c.Request.Context()
+	var rows int64
+	err := h.Store.ExecTx(ctx, func(q *db.Queries) error {
+		var err error
+		rows, err = q.DeleteSection(ctx, db.DeleteSectionParams{
+			ID:        uri.SectionID,
+			ProjectID: uri.ID,
+			UserID:    c.GetInt64("userID"),
+		})
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		return
+	}
+	if rows == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Move reorders a section within its project.
+func (h *SectionHandler) Move(c *gin.Context) {
+	var uri sectionURI
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "detail": err.Error()})
+		return
+	}
+
+	var req MoveSectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "detail": err.Error()})
+		return
+	}
+
+	userID := c.GetInt64("userID")
+	ctx := c.Request.Context()
+
+	var section db.ProjectSection
+	err := h.Store.ExecTx(ctx, func(q *db.Queries) error {
+		var err error
+		section, err = repository.MoveSection(ctx, q, userID, uri.ID, uri.SectionID, req.AfterID, req.BeforeID)
+		return err
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+		case errors.Is(err, repository.ErrInvalidSectionMove):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "invalid_move", "detail": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, newSectionResponse(section))
+}
+
+// Board returns a project's sections with their tasks in manual order, plus
+// the tasks that are not in any section.
+func (h *SectionHandler) Board(c *gin.Context) {
+	var uri struct {
+		ID int64 `uri:"id" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "detail": err.Error()})
+		return
+	}
+
+	userID := c.GetInt64("userID")
+	ctx := c.Request.Context()
+
+	// Read everything in one transaction so the sections and tasks agree.
+	var (
+		project  db.Project
+		sections []db.ProjectSection
+		tasks    []db.Task
+	)
+	err := h.Store.ExecTx(ctx, func(q *db.Queries) error {
+		var err error
+		if project, err = q.GetProject(ctx, db.GetProjectParams{ID: uri.ID, UserID: userID}); err != nil {
+			return err
+		}
+		if sections, err = q.ListSections(ctx, db.ListSectionsParams{ProjectID: uri.ID, UserID: userID}); err != nil {
+			return err
+		}
+		tasks, err = q.ListTasksByProject(ctx, db.ListTasksByProjectParams{
+			UserID:    userID,
+			ProjectID: pgtype.Int8{Int64: uri.ID, Valid: true},
+			Sort:      "position",
+		})
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		return
+	}
+
+	resp := BoardResponse{
+		Project:     newProjectResponse(project),
+		Sections:    make([]BoardColumn, len(sections)),
+		Unsectioned: []TaskResponse{},
+	}
+	columns := make(map[int64]*BoardColumn, len(sections))
+	for i, s := range sections {
+		resp.Sections[i] = BoardColumn{SectionResponse: newSectionResponse(s), Tasks: []TaskResponse{}}
+		columns[s.ID] = &resp.Sections[i]
+	}
+	for _, t := range tasks {
+		if col, ok := columns[t.SectionID.Int64]; ok && t.SectionID.Valid {
+			col.Tasks = append(col.Tasks, newTaskResponse(t))
+			continue
+		}
+		resp.Unsectioned = append(resp.Unsectioned, newTaskResponse(t))
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,49 @@
+package handler
+
+import "time"
+
+// CreateSectionRequest defines the request body for creating a section.
+// Status is the task status, from the project's workflow, applied to tasks
+// moved into the section.
+type CreateSectionRequest struct {
+	Name   string  `json:"name" binding:"required,max=100"`
+	Status *string `json:"status" binding:"omitempty,max=50"`
+}
+
+// UpdateSectionRequest defines the request body for updating a section. An
+// empty status removes the section's status mapping.
+type UpdateSectionRequest struct {
+	Name   *string `json:"name" binding:"omitempty,min=1,max=100"`
+	Status *string `json:"status" binding:"omitempty,max=50"`
+}
+
+// MoveSectionRequest defines the request body for reordering a section.
+type MoveSectionRequest struct {
+	AfterID  *int64 `json:"after_id" binding:"omitempty,min=1"`
+	BeforeID *int64 `json:"before_id" binding:"omitempty,min=1"`
+}
+
+// SectionResponse defines the standard response for a section.
+type SectionResponse struct {
+	ID        int64     `json:"id"`
+	ProjectID int64     `json:"project_id"`
+	Name      string    `json:"name"`
+	Position  string    `json:"position"`
+	Status    *string   `json:"status,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// BoardColumn is a section together with its tasks in manual order.
+type BoardColumn struct {
+	SectionResponse
+	Tasks []TaskResponse `json:"tasks"`
+}
+
+// BoardResponse is a project laid out as a board. Unsectioned holds the
+// project's tasks that are not in any section.
+type BoardResponse struct {
+	Project     ProjectResponse `json:"project"`
+	Sections    []BoardColumn   `json:"sections"`
+	Unsectioned []TaskResponse  `json:"unsectioned"`
+}
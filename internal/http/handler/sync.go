@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pavelc4/auriya-todolist-go/internal/http/repository"
+)
+
+// syncTokenSlack covers writes that commit after a token is issued but
+// stamped their tombstones before it. It is well above the server's write
+// timeout.
+const syncTokenSlack = time.Minute
+
+var errInvalidSyncToken = errors.New("malformed sync token")
+
+type SyncHandler struct {
+	Store     *repository.Store
+	retention time.Duration
+}
+
+func NewSyncHandler(store *repository.Store, retention time.Duration) *SyncHandler {
+	return &SyncHandler{Store: store, retention: retention}
+}
+
+// Sync returns the tasks, projects and deletes that changed after the
+// since token, oldest change first, with a token to continue from. Without
+// a token, or with one older than the tombstone retention, it starts over
+// and sets full_resync.
+func (h *SyncHandler) Sync(c *gin.Context) {
+	var query SyncQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_query", "detail": err.Error()})
+		return
+	}
+
+	// Taken before the snapshot, so the next token is never newer than
+	// what it covers.
+	issued := time.Now()
+
+	var after int64
+	full := true
+	if query.Since != "" {
+		seq, since, err := decodeSyncToken(query.Since)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_sync_token", "detail": err.Error()})
+			return
+		}
+		if !since.Before(issued.Add(-h.retention + syncTokenSlack)) {
+			after, full = seq, false
+		}
+	}
+
+	changes, more, err := listChanges(c.Request.Context(), h.Store, c.GetInt64("userID"), after, query.Limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		return
+	}
+
+	resp := SyncResponse{
+		FullResync: full,
+		Tasks:      []TaskResponse{},
+		Projects:   []ProjectResponse{},
+		Deleted:    []SyncDeleted{},
+		HasMore:    more,
+	}
+	for _, ch := range changes {
+		switch {
+		case ch.Task != nil:
+			resp.Tasks = append(resp.Tasks, newTaskResponse(*ch.Task))
+		case ch.Project != nil:
+			resp.Projects = append(resp.Projects, newProjectResponse(*ch.Project))
+		default:
+			resp.Deleted = append(resp.Deleted, SyncDeleted{Type: ch.Type, ID: ch.ID, DeletedAt: ch.DeletedAt})
+		}
+		after = ch.Seq
+	}
+	resp.SyncToken = encodeSyncToken(after, issued)
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// Sync tokens hold the last change number they cover and when they were
+// issued. Clients treat them as opaque.
+func encodeSyncToken(seq int64, issued time.Time) string {
+	return base64.RawURLEncoding.EncodeToString(fmt.Appendf(nil, "1.%d.%d", seq, issued.Unix()))
+}
+
+func decodeSyncToken(token string) (int64, time.Time, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, time.Time{}, errInvalidSyncToken
+	}
+	parts := strings.Split(string(b), ".")
+	if len(parts) != 3 || parts[0] != "1" {
+		return 0, time.Time{}, errInvalidSyncToken
+	}
+	seq, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || seq < 0 {
+		return 0, time.Time{}, errInvalidSyncToken
+	}
+	unix, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return 0, time.Time{}, errInvalidSyncToken
+	}
+	return seq, time.Unix(unix, 0), nil
+}
@@ -0,0 +1,30 @@
+package handler
+
+import "time"
+
+// SyncQuery defines the query parameters for GET /api/sync. Since is the
+// sync_token of the previous response; leave it out for the first sync.
+type SyncQuery struct {
+	Since string `form:"since"`
+	Limit int32  `form:"limit,default=500" binding:"min=1,max=1000"`
+}
+
+// SyncDeleted reports a task or project that was deleted or moved to the
+// trash. A trashed one comes back as a change if it is restored.
+type SyncDeleted struct {
+	Type      string    `json:"type"`
+	ID        int64     `json:"id"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// SyncResponse is one page of changes. With FullResync set the client must
+// drop what it has stored and rebuild from the pages that follow. While
+// HasMore is set the client should sync again with SyncToken straight away.
+type SyncResponse struct {
+	FullResync bool              `json:"full_resync"`
+	Tasks      []TaskResponse    `json:"tasks"`
+	Projects   []ProjectResponse `json:"projects"`
+	Deleted    []SyncDeleted     `json:"deleted"`
+	SyncToken  string            `json:"sync_token"`
+	HasMore    bool              `json:"has_more"`
+}
@@ -2,15 +2,20 @@ package handler
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/pavelc4/auriya-todolist-go/internal/cache"
 	db "github.com/pavelc4/auriya-todolist-go/internal/db/sqlc"
 	"github.com/pavelc4/auriya-todolist-go/internal/http/repository"
+	"github.com/pavelc4/auriya-todolist-go/internal/jsonpatch"
+	"github.com/pavelc4/auriya-todolist-go/internal/taskquery"
 )
 
 type TaskHandler struct {
@@ -39,16 +44,53 @@ func newTaskResponse(task db.Task) TaskResponse {
 		status = task.Status
 	}
 
+	var projectID *int64
+	if task.ProjectID.Valid {
+		projectID = &task.ProjectID.Int64
+	}
+
+	var estimate *int32
+	if task.EstimateSeconds.Valid {
+		estimate = &task.EstimateSeconds.Int32
+	}
+
+	tags := task.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	var sectionID *int64
+	if task.SectionID.Valid {
+		sectionID = &task.SectionID.Int64
+	}
+
+	var deletedAt *time.Time
+	if task.DeletedAt.Valid {
+		deletedAt = &task.DeletedAt.Time
+	}
+
 	return TaskResponse{
-		ID:          task.ID,
-		UserID:      task.UserID,
-		Title:       task.Title,
-		Description: desc,
-		Status:      status,
-		Priority:    task.Priority,
-		DueDate:     dueDatePtr,
-		CreatedAt:   task.CreatedAt.Time,
-		UpdatedAt:   task.UpdatedAt.Time,
+		ID:               task.ID,
+		UserID:           task.UserID,
+		Title:            task.Title,
+		Description:      desc,
+		Status:           status,
+		StatusCategory:   task.StatusCategory,
+		Priority:         task.Priority,
+		DueDate:          dueDatePtr,
+		Estimate:         estimate,
+		TimeSpent:        task.TimeSpentSeconds,
+		ChecklistChecked: task.ChecklistChecked,
+		ChecklistTotal:   task.ChecklistTotal,
+		Tags:             tags,
+		Recurrence:       task.Recurrence,
+		ProjectID:        projectID,
+		SectionID:        sectionID,
+		Position:         task.Position,
+		Version:          task.Version,
+		CreatedAt:        task.CreatedAt.Time,
+		UpdatedAt:        task.UpdatedAt.Time,
+		DeletedAt:        deletedAt,
 	}
 }
 
@@ -72,49 +114,42 @@ func (h *TaskHandler) Create(c *gin.Context) {
 
 	userID, _ := c.Get("userID")
 
-	var dueDate pgtype.Timestamptz
-	if req.DueDate != nil {
-		dueDate = pgtype.Timestamptz{Time: *req.DueDate, Valid: true}
-	}
-
-	var projectID pgtype.Int8
-	if req.ProjectID != nil {
-		projectID = pgtype.Int8{Int64: *req.ProjectID, Valid: true}
-	}
-
-	var description *string
-	if req.Description != "" {
-		description = &req.Description
-	}
-
-	var status *string
-	if req.Status != "" {
-		status = &req.Status
+	task, ok := h.createTask(c, req.params(userID.(int64)))
+	if !ok {
+		return
 	}
 
-	arg := db.CreateTaskParams{
-		Title:       req.Title,
-		Description: description,
-		Status:      status,
-		Priority:    req.Priority,
-		DueDate:     dueDate,
-		UserID:      userID.(int64),
-		ProjectID:   projectID,
-	}
+	c.Header("ETag", versionETag(task.Version))
+	c.JSON(http.StatusCreated, newTaskResponse(task))
+}
 
-	task, err := h.Store.Queries.CreateTask(c.Request.Context(), arg)
+// createTask is the part of task creation shared by Create and QuickAdd. It
+// writes the error response itself and reports whether the task was created.
+func (h *TaskHandler) createTask(c *gin.Context, arg db.CreateTaskParams) (db.Task, bool) {
+	ctx := c.Request.Context()
+	var task db.Task
+	err := h.Store.ExecTx(ctx, func(q *db.Queries) error {
+		var err error
+		task, err = repository.CreateTask(ctx, q, arg.UserID, arg)
+		return err
+	})
 	if err != nil {
+		if errors.Is(err, repository.ErrProjectNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not_found", "detail": err.Error()})
+			return db.Task{}, false
+		}
+		if taskFieldError(c, err) {
+			return db.Task{}, false
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
-		return
+		return db.Task{}, false
 	}
 
-	resp := newTaskResponse(task)
-
 	// Set cache
 	cacheKey := fmt.Sprintf("task:%d", task.ID)
 	h.cache.Set(cacheKey, task, 5*time.Minute)
 
-	c.JSON(http.StatusCreated, resp)
+	return task, true
 }
 
 func (h *TaskHandler) Get(c *gin.Context) {
@@ -133,7 +168,7 @@ func (h *TaskHandler) Get(c *gin.Context) {
 			// Verify user ID just in case
 			userID, _ := c.Get("userID")
 			if task.UserID == userID.(int64) {
-				c.JSON(http.StatusOK, newTaskResponse(task))
+				h.writeTask(c, task)
 				return
 			}
 		}
@@ -159,7 +194,28 @@ func (h *TaskHandler) Get(c *gin.Context) {
 	// Set cache
 	h.cache.Set(cacheKey, task, 5*time.Minute)
 
-	c.JSON(http.StatusOK, newTaskResponse(task))
+	h.writeTask(c, task)
+}
+
+// writeTask responds with a single task and its checklist, or with 304 when
+// the client already has this version.
+func (h *TaskHandler) writeTask(c *gin.Context, task db.Task) {
+	if notModified(c, versionETag(task.Version)) {
+		return
+	}
+	resp := newTaskResponse(task)
+	if task.ChecklistTotal > 0 {
+		items, err := h.Store.Queries.ListChecklistItems(c.Request.Context(), db.ListChecklistItemsParams{TaskID: task.ID, UserID: task.UserID})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+			return
+		}
+		resp.Checklist = make([]ChecklistItemResponse, 0, len(items))
+		for _, item := range items {
+			resp.Checklist = append(resp.Checklist, newChecklistItemResponse(item))
+		}
+	}
+	c.JSON(http.StatusOK, resp)
 }
 
 func (h *TaskHandler) List(c *gin.Context) {
@@ -181,14 +237,27 @@ func (h *TaskHandler) List(c *gin.Context) {
 		status = &q.Status
 	}
 
-	// Caching for list endpoints is more complex, skipping for now.
-	items, err := h.Store.Queries.ListTasks(c.Request.Context(), db.ListTasksParams{
+	arg := db.ListTasksParams{
 		UserID:    userID.(int64),
 		Status:    status,
 		DueBefore: dueBefore,
+		Sort:      q.Sort,
 		Limit:     q.Limit,
 		Offset:    offset,
-	})
+	}
+
+	// Caching for list endpoints is more complex, skipping for now.
+	var items []db.Task
+	var err error
+	if q.Filter != "" {
+		cond, ok := compileFilter(c, q.Filter, q.Tz)
+		if !ok {
+			return
+		}
+		items, err = repository.ListTasksMatching(c.Request.Context(), h.Store.DB, cond, arg)
+	} else {
+		items, err = h.Store.Queries.ListTasks(c.Request.Context(), arg)
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
 		return
@@ -199,7 +268,7 @@ func (h *TaskHandler) List(c *gin.Context) {
 		taskResponses = append(taskResponses, newTaskResponse(item))
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	writeListJSON(c, gin.H{
 		"items": taskResponses,
 		"page":  q.Page,
 		"limit": q.Limit,
@@ -215,11 +284,18 @@ func (h *TaskHandler) ListByProject(c *gin.Context) {
 		return
 	}
 
+	var q ListProjectTasksQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_query", "detail": err.Error()})
+		return
+	}
+
 	userID, _ := c.Get("userID")
 
 	arg := db.ListTasksByProjectParams{
 		UserID:    userID.(int64),
 		ProjectID: pgtype.Int8{Int64: uri.ID, Valid: true},
+		Sort:      q.Sort,
 	}
 
 	tasks, err := h.Store.Queries.ListTasksByProject(c.Request.Context(), arg)
@@ -233,7 +309,7 @@ func (h *TaskHandler) ListByProject(c *gin.Context) {
 		taskResponses = append(taskResponses, newTaskResponse(task))
 	}
 
-	c.JSON(http.StatusOK, taskResponses)
+	writeListJSON(c, taskResponses)
 }
 
 func (h *TaskHandler) Update(c *gin.Context) {
@@ -245,43 +321,103 @@ func (h *TaskHandler) Update(c *gin.Context) {
 		return
 	}
 
-	var req UpdateTaskRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "detail": err.Error()})
-		return
-	}
-
 	userID, _ := c.Get("userID")
 
-	var priority pgtype.Int4
-	if req.Priority != nil {
-		priority = pgtype.Int4{Int32: *req.Priority, Valid: true}
-	}
-	var dueDate pgtype.Timestamptz
-	if req.DueDate != nil {
-		dueDate = pgtype.Timestamptz{Time: *req.DueDate, Valid: true}
-	}
-	var projectID pgtype.Int8
-	if req.ProjectID != nil {
-		projectID = pgtype.Int8{Int64: *req.ProjectID, Valid: true}
-	}
-
-	arg := db.UpdateTaskParams{
-		ID:          uri.ID,
-		UserID:      userID.(int64),
-		Title:       toPgText(req.Title),
-		Description: req.Description,
-		Status:      toPgText(req.Status),
-		Priority:    priority,
-		DueDate:     dueDate,
-		ProjectID:   projectID,
-	}
-	task, err := h.Store.Queries.UpdateTask(c.Request.Context(), arg)
+	// build turns the request into update arguments. A JSON Patch is applied
+	// to the current task, so that is read and locked first.
+	var build func(current db.Task) (db.UpdateTaskParams, error)
+	needCurrent := false
+	switch c.ContentType() {
+	case contentTypeJSONPatch:
+		body, err := c.GetRawData()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "detail": err.Error()})
+			return
+		}
+		patch, err := jsonpatch.Decode(body)
+		if err != nil {
+			patchError(c, err)
+			return
+		}
+		needCurrent = true
+		build = func(current db.Task) (db.UpdateTaskParams, error) {
+			doc, err := taskPatchDocument(current)
+			if err != nil {
+				return db.UpdateTaskParams{}, err
+			}
+			merge, err := mergePatchFromJSONPatch(patch, doc)
+			if err != nil {
+				return db.UpdateTaskParams{}, err
+			}
+			arg, err := taskMergePatchParams(merge, uri.ID, userID.(int64))
+			if err != nil {
+				return db.UpdateTaskParams{}, &patchFailure{err}
+			}
+			return arg, nil
+		}
+	case contentTypeMergePatch:
+		body, err := c.GetRawData()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "detail": err.Error()})
+			return
+		}
+		arg, err := taskMergePatchParams(body, uri.ID, userID.(int64))
+		if err != nil {
+			patchError(c, err)
+			return
+		}
+		build = func(db.Task) (db.UpdateTaskParams, error) { return arg, nil }
+	default:
+		var req UpdateTaskRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "detail": err.Error()})
+			return
+		}
+		arg := req.params(uri.ID, userID.(int64))
+		build = func(db.Task) (db.UpdateTaskParams, error) { return arg, nil }
+	}
+
+	match := requestIfMatch(c)
+	ctx := c.Request.Context()
+	var task db.Task
+	err := h.Store.ExecTx(ctx, func(q *db.Queries) error {
+		var current db.Task
+		if match != "" || needCurrent {
+			var err error
+			current, err = q.GetTaskForUpdate(ctx, db.GetTaskForUpdateParams{ID: uri.ID, UserID: userID.(int64)})
+			if err != nil {
+				return err
+			}
+			if err := match.check(current.Version); err != nil {
+				return err
+			}
+		}
+		arg, err := build(current)
+		if err != nil {
+			return err
+		}
+		task, err = repository.UpdateTask(ctx, q, userID.(int64), arg)
+		return err
+	})
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, repository.ErrProjectNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not_found", "detail": err.Error()})
+			return
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
 			return
 		}
+		if errors.Is(err, errPreconditionFailed) {
+			writePreconditionFailed(c)
+			return
+		}
+		if patchError(c, err) {
+			return
+		}
+		if taskFieldError(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
 		return
 	}
@@ -290,6 +426,7 @@ func (h *TaskHandler) Update(c *gin.Context) {
 	cacheKey := fmt.Sprintf("task:%d", uri.ID)
 	h.cache.Delete(cacheKey)
 
+	c.Header("ETag", versionETag(task.Version))
 	c.JSON(http.StatusOK, newTaskResponse(task))
 }
 
@@ -309,7 +446,25 @@ func (h *TaskHandler) Delete(c *gin.Context) {
 		UserID: userID.(int64),
 	}
 
-	if err := h.Store.Queries.DeleteTask(c.Request.Context(), arg); err != nil {
+	// Tasks are only moved to the trash here; see TrashHandler for purging.
+	match := requestIfMatch(c)
+	ctx := c.Request.Context()
+	err := h.Store.ExecTx(ctx, func(q *db.Queries) error {
+		if err := match.lockTask(ctx, q, arg.ID, arg.UserID); err != nil {
+			return err
+		}
+		_, err := repository.DeleteTask(ctx, q, userID.(int64), arg)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+			return
+		}
+		if errors.Is(err, errPreconditionFailed) {
+			writePreconditionFailed(c)
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
 		return
 	}
@@ -321,6 +476,267 @@ func (h *TaskHandler) Delete(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+// Restore brings a task back from the trash. A task that was trashed together
+// with its project can only come back by restoring the project.
+func (h *TaskHandler) Restore(c *gin.Context) {
+	var uri struct {
+		ID int64 `uri:"id" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "detail": err.Error()})
+		return
+	}
+
+	userID := c.GetInt64("userID")
+	ctx := c.Request.Context()
+
+	var task db.Task
+	err := h.Store.ExecTx(ctx, func(q *db.Queries) error {
+		var err error
+		task, err = repository.RestoreTask(ctx, q, userID, db.RestoreTaskParams{ID: uri.ID, UserID: userID})
+		return err
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+		case errors.Is(err, repository.ErrProjectInTrash):
+			c.JSON(http.StatusConflict, gin.H{"error": "project_in_trash", "detail": "restore the task's project first"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		}
+		return
+	}
+
+	h.cache.Delete(fmt.Sprintf("task:%d", task.ID))
+
+	c.JSON(http.StatusOK, newTaskResponse(task))
+}
+
+// Move places a task at a new spot in the manual order, optionally in
+// another project.
+func (h *TaskHandler) Move(c *gin.Context) {
+	var uri struct {
+		ID int64 `uri:"id" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "detail": err.Error()})
+		return
+	}
+
+	var req MoveTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "detail": err.Error()})
+		return
+	}
+
+	userID := c.GetInt64("userID")
+	ctx := c.Request.Context()
+
+	var task db.Task
+	err := h.Store.ExecTx(ctx, func(q *db.Queries) error {
+		var err error
+		task, err = repository.MoveTask(ctx, q, userID, userID, uri.ID, repository.TaskMove{
+			AfterID:   req.AfterID,
+			BeforeID:  req.BeforeID,
+			ProjectID: req.ProjectID,
+			SectionID: req.SectionID,
+		})
+		return err
+	})
+	if err != nil {
+		if taskFieldError(c, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+		case errors.Is(err, repository.ErrInvalidMove):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "invalid_move", "detail": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		}
+		return
+	}
+
+	h.cache.Delete(fmt.Sprintf("task:%d", task.ID))
+
+	c.JSON(http.StatusOK, newTaskResponse(task))
+}
+
+// compileFilter compiles a ?filter= expression, reading its dates in the
+// IANA time zone tz (UTC when empty). It writes the error response itself,
+// pointing at the offending token, and reports whether it succeeded.
+func compileFilter(c *gin.Context, filter, tz string) (taskquery.Condition, bool) {
+	if tz == "" {
+		tz = "UTC"
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_query", "detail": "tz: " + err.Error()})
+		return taskquery.Condition{}, false
+	}
+
+	cond, err := taskquery.Compile(filter, time.Now().In(loc))
+	if err != nil {
+		var qerr *taskquery.Error
+		if errors.As(err, &qerr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_filter", "detail": qerr.Error(), "column": qerr.Column, "token": qerr.Token})
+			return taskquery.Condition{}, false
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_filter", "detail": err.Error()})
+		return taskquery.Condition{}, false
+	}
+	return cond, true
+}
+
+// taskFieldError writes the response for a status the project's workflow
+// rejects or an invalid repeat rule, and reports whether err was such an
+// error.
+func taskFieldError(c *gin.Context, err error) bool {
+	code := taskFieldErrorCode(err)
+	if code == "" {
+		return false
+	}
+	c.JSON(http.StatusUnprocessableEntity, gin.H{"error": code, "detail": err.Error()})
+	return true
+}
+
+// taskFieldErrorCode returns the error code taskFieldError responds with, or
+// "" when err is not a task field error.
+func taskFieldErrorCode(err error) string {
+	switch {
+	case errors.Is(err, repository.ErrInvalidRecurrence):
+		return "invalid_recurrence"
+	case errors.Is(err, repository.ErrUnknownStatus):
+		return "invalid_status"
+	case errors.Is(err, repository.ErrTransitionNotAllowed):
+		return "invalid_transition"
+	}
+	return ""
+}
+
+// params converts the request to the arguments for creating a task.
+func (r CreateTaskRequest) params(userID int64) db.CreateTaskParams {
+	var dueDate pgtype.Timestamptz
+	if r.DueDate != nil {
+		dueDate = pgtype.Timestamptz{Time: *r.DueDate, Valid: true}
+	}
+
+	var projectID pgtype.Int8
+	if r.ProjectID != nil {
+		projectID = pgtype.Int8{Int64: *r.ProjectID, Valid: true}
+	}
+
+	var description *string
+	if r.Description != "" {
+		description = &r.Description
+	}
+
+	return db.CreateTaskParams{
+		Title:           r.Title,
+		Description:     description,
+		Status:          r.Status,
+		Priority:        r.Priority,
+		DueDate:         dueDate,
+		UserID:          userID,
+		ProjectID:       projectID,
+		EstimateSeconds: toPgInt4(r.Estimate),
+		Tags:            r.Tags,
+		Recurrence:      r.Recurrence,
+	}
+}
+
+// params converts the request to the arguments for updating task id.
+func (r UpdateTaskRequest) params(id, userID int64) db.UpdateTaskParams {
+	var priority pgtype.Int4
+	if r.Priority != nil {
+		priority = pgtype.Int4{Int32: *r.Priority, Valid: true}
+	}
+	var dueDate pgtype.Timestamptz
+	if r.DueDate != nil {
+		dueDate = pgtype.Timestamptz{Time: *r.DueDate, Valid: true}
+	}
+	var projectID pgtype.Int8
+	if r.ProjectID != nil {
+		projectID = pgtype.Int8{Int64: *r.ProjectID, Valid: true}
+	}
+
+	return db.UpdateTaskParams{
+		ID:              id,
+		UserID:          userID,
+		Title:           toPgText(r.Title),
+		Description:     r.Description,
+		Status:          toPgText(r.Status),
+		Priority:        priority,
+		DueDate:         dueDate,
+		EstimateSeconds: toPgInt4(r.Estimate),
+		Tags:            r.Tags,
+		Recurrence:      r.Recurrence,
+		ProjectID:       projectID,
+	}
+}
+
+// taskMergePatchParams reads a merge patch for task id. Members set to null
+// clear the field; tags become empty and the repeat rule is removed.
+func taskMergePatchParams(body []byte, id, userID int64) (db.UpdateTaskParams, error) {
+	var req UpdateTaskRequest
+	nulls, err := readMergePatch(body, &req)
+	if err != nil {
+		return db.UpdateTaskParams{}, err
+	}
+	for _, name := range []string{"title", "status", "priority"} {
+		if nulls[name] {
+			return db.UpdateTaskParams{}, fmt.Errorf("%w: %s cannot be null", errInvalidPatch, name)
+		}
+	}
+
+	arg := req.params(id, userID)
+	arg.ClearDescription = nulls["description"]
+	arg.ClearDueDate = nulls["due_date"]
+	arg.ClearEstimate = nulls["estimate"]
+	arg.ClearProject = nulls["project_id"]
+	if nulls["tags"] {
+		arg.Tags = []string{}
+	}
+	if nulls["recurrence"] {
+		none := ""
+		arg.Recurrence = &none
+	}
+	return arg, nil
+}
+
+// taskPatchDocument is the document a JSON Patch for the task is applied
+// to: its editable fields, named as in UpdateTaskRequest.
+func taskPatchDocument(task db.Task) ([]byte, error) {
+	resp := newTaskResponse(task)
+	return json.Marshal(UpdateTaskRequest{
+		Title:       &resp.Title,
+		Description: task.Description,
+		Status:      &resp.Status,
+		Priority:    &resp.Priority,
+		DueDate:     resp.DueDate,
+		ProjectID:   resp.ProjectID,
+		Estimate:    resp.Estimate,
+		Tags:        resp.Tags,
+		Recurrence:  resp.Recurrence,
+	})
+}
+
+func toPgInt4(v *int32) pgtype.Int4 {
+	if v != nil {
+		return pgtype.Int4{Int32: *v, Valid: true}
+	}
+	return pgtype.Int4{Valid: false}
+}
+
+func toPgInt8(v *int64) pgtype.Int8 {
+	if v != nil {
+		return pgtype.Int8{Int64: *v, Valid: true}
+	}
+	return pgtype.Int8{Valid: false}
+}
+
 func toPgText(s *string) pgtype.Text {
 	if s != nil {
 		return pgtype.Text{String: *s, Valid: true}
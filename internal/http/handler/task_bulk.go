@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	db "github.com/pavelc4/auriya-todolist-go/internal/db/sqlc"
+	"github.com/pavelc4/auriya-todolist-go/internal/http/repository"
+)
+
+// Bulk applies one action to many tasks in a single transaction. Each task
+// runs in its own savepoint, so a task that is missing, belongs to someone
+// else or is rejected by its workflow is reported in the results while the
+// others still go through. Only unexpected database errors fail the request
+// as a whole.
+func (h *TaskHandler) Bulk(c *gin.Context) {
+	var req BulkTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "detail": err.Error()})
+		return
+	}
+	switch {
+	case (len(req.IDs) == 0) == (req.Filter == ""):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "detail": "exactly one of ids and filter is required"})
+		return
+	case req.Action == "update" && req.Fields == nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "detail": "fields is required for update"})
+		return
+	case req.Action == "move" && req.ProjectID == nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "detail": "project_id is required for move"})
+		return
+	}
+
+	userID := c.GetInt64("userID")
+	ctx := c.Request.Context()
+
+	ids, ok := h.bulkTaskIDs(c, req, userID)
+	if !ok {
+		return
+	}
+
+	// Both "move" and an "update" of project_id put every task into the same
+	// project, so it is checked once for the whole request.
+	project := req.ProjectID
+	if req.Action == "update" {
+		project = req.Fields.ProjectID
+	}
+	if req.Action == "move" || (req.Action == "update" && project != nil) {
+		_, err := h.Store.Queries.GetProject(ctx, db.GetProjectParams{ID: *project, UserID: userID})
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "unknown_project", "detail": fmt.Sprintf("project %d not found", *project)})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+			return
+		}
+	}
+
+	apply := func(q *db.Queries, id int64) (db.Task, error) {
+		switch req.Action {
+		case "update":
+			return repository.UpdateTask(ctx, q, userID, req.Fields.params(id, userID))
+		case "move":
+			return repository.MoveTask(ctx, q, userID, userID, id, repository.TaskMove{
+				ProjectID: req.ProjectID,
+				SectionID: req.SectionID,
+			})
+		case "complete":
+			return repository.CompleteTask(ctx, q, userID, userID, id)
+		default:
+			return repository.DeleteTask(ctx, q, userID, db.DeleteTaskParams{ID: id, UserID: userID})
+		}
+	}
+
+	var results []BulkTaskResult
+	err := h.Store.ExecTxSteps(ctx, func(tx *repository.Tx) error {
+		results = make([]BulkTaskResult, 0, len(ids))
+		for _, id := range ids {
+			var task db.Task
+			err := tx.Savepoint(ctx, func(q *db.Queries) error {
+				var err error
+				task, err = apply(q, id)
+				return err
+			})
+			if err != nil {
+				code := bulkTaskErrorCode(err)
+				if code == "" {
+					return err
+				}
+				results = append(results, BulkTaskResult{ID: id, Error: code, Detail: err.Error()})
+				continue
+			}
+
+			result := BulkTaskResult{ID: id, OK: true}
+			if req.Action != "delete" {
+				resp := newTaskResponse(task)
+				result.Task = &resp
+			}
+			results = append(results, result)
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		return
+	}
+
+	resp := BulkTaskResponse{Action: req.Action, Results: results}
+	for _, r := range results {
+		if !r.OK {
+			resp.Failed++
+			continue
+		}
+		resp.Succeeded++
+		h.cache.Delete(fmt.Sprintf("task:%d", r.ID))
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// bulkTaskIDs returns the tasks a bulk request applies to, without
+// duplicates. Listed IDs are taken as they are, since ownership is checked
+// per task; a filter is resolved against the user's live tasks and may not
+// match more than maxBulkTasks of them. It writes the error response itself
+// and reports whether it succeeded.
+func (h *TaskHandler) bulkTaskIDs(c *gin.Context, req BulkTaskRequest, userID int64) ([]int64, bool) {
+	if req.Filter == "" {
+		ids := make([]int64, 0, len(req.IDs))
+		for _, id := range req.IDs {
+			if !slices.Contains(ids, id) {
+				ids = append(ids, id)
+			}
+		}
+		return ids, true
+	}
+
+	cond, ok := compileFilter(c, req.Filter, req.Tz)
+	if !ok {
+		return nil, false
+	}
+	tasks, err := repository.ListTasksMatching(c.Request.Context(), h.Store.DB, cond, db.ListTasksParams{
+		UserID: userID,
+		Limit:  maxBulkTasks + 1,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		return nil, false
+	}
+	if len(tasks) > maxBulkTasks {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "too_many_tasks", "detail": fmt.Sprintf("the filter matches more than %d tasks", maxBulkTasks)})
+		return nil, false
+	}
+
+	ids := make([]int64, len(tasks))
+	for i, t := range tasks {
+		ids[i] = t.ID
+	}
+	return ids, true
+}
+
+// bulkTaskErrorCode returns the error code reported for a single task of a
+// bulk request, or "" for errors that should fail the whole request.
+func bulkTaskErrorCode(err error) string {
+	switch {
+	case errors.Is(err, repository.ErrProjectNotFound):
+		return "unknown_project"
+	case errors.Is(err, pgx.ErrNoRows):
+		return "not_found"
+	case errors.Is(err, repository.ErrInvalidMove):
+		return "invalid_move"
+	}
+	return taskFieldErrorCode(err)
+}
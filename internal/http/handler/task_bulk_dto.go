@@ -0,0 +1,38 @@
+package handler
+
+// maxBulkTasks caps the number of tasks one bulk request may touch, whether
+// they are listed or matched by a filter.
+const maxBulkTasks = 500
+
+// BulkTaskRequest defines the request body for acting on many tasks at once.
+// The tasks are either listed in IDs or matched by Filter, an expression in
+// the package taskquery language read in time zone Tz. Fields is required for
+// "update" and ProjectID for "move"; SectionID optionally puts moved tasks
+// into a section of that project.
+type BulkTaskRequest struct {
+	Action    string             `json:"action" binding:"required,oneof=update move delete complete"`
+	IDs       []int64            `json:"ids" binding:"omitempty,max=500,dive,min=1"`
+	Filter    string             `json:"filter" binding:"omitempty,max=1000"`
+	Tz        string             `json:"tz"`
+	Fields    *UpdateTaskRequest `json:"fields"`
+	ProjectID *int64             `json:"project_id" binding:"omitempty,min=1"`
+	SectionID *int64             `json:"section_id" binding:"omitempty,min=0"`
+}
+
+// BulkTaskResult is the outcome for one task of a bulk request. Task is the
+// task after the change, except for deletes.
+type BulkTaskResult struct {
+	ID     int64         `json:"id"`
+	OK     bool          `json:"ok"`
+	Error  string        `json:"error,omitempty"`
+	Detail string        `json:"detail,omitempty"`
+	Task   *TaskResponse `json:"task,omitempty"`
+}
+
+// BulkTaskResponse lists the outcome for every task in request order.
+type BulkTaskResponse struct {
+	Action    string           `json:"action"`
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"`
+	Results   []BulkTaskResult `json:"results"`
+}
@@ -1,44 +1,121 @@
 package handler
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // CreateTaskRequest defines the request body for creating a new task.
+// Estimate is in seconds. Recurrence is an RRULE value such as
+// "FREQ=WEEKLY;BYDAY=MO".
 type CreateTaskRequest struct {
 	Title       string     `json:"title" binding:"required,max=255"`
 	Description string     `json:"description"`
-	Status      string     `json:"status" binding:"omitempty,oneof=pending in-progress completed"`
+	Status      string     `json:"status" binding:"omitempty,max=50"`
 	Priority    int32      `json:"priority" binding:"omitempty,min=1,max=5"`
 	DueDate     *time.Time `json:"due_date"`
 	ProjectID   *int64     `json:"project_id" binding:"omitempty,min=1"`
+	Estimate    *int32     `json:"estimate" binding:"omitempty,min=0"`
+	Tags        []string   `json:"tags" binding:"omitempty,max=20,dive,max=50"`
+	Recurrence  *string    `json:"recurrence" binding:"omitempty,max=200"`
 }
 
-// UpdateTaskRequest defines the request body for updating a task.
+// UpdateTaskRequest defines the request body for updating a task. Tags
+// replaces all tags when present, so [] removes them; an empty Recurrence
+// stops the task repeating. Sent as a merge patch, null also clears
+// description, due_date, estimate and project_id.
 type UpdateTaskRequest struct {
 	Title       *string    `json:"title" binding:"omitempty,max=255"`
 	Description *string    `json:"description"`
-	Status      *string    `json:"status" binding:"omitempty,oneof=pending in-progress completed"`
+	Status      *string    `json:"status" binding:"omitempty,min=1,max=50"`
 	Priority    *int32     `json:"priority" binding:"omitempty,min=1,max=5"`
 	DueDate     *time.Time `json:"due_date"`
 	ProjectID   *int64     `json:"project_id" binding:"omitempty,min=1"`
+	Estimate    *int32     `json:"estimate" binding:"omitempty,min=0"`
+	Tags        []string   `json:"tags" binding:"omitempty,max=20,dive,max=50"`
+	Recurrence  *string    `json:"recurrence" binding:"omitempty,max=200"`
 }
 
-// TaskResponse defines the standard response for a task.
+// TaskResponse defines the standard response for a task. Estimate and
+// TimeSpent are in seconds; TimeSpent covers finished time entries.
+// Checklist is only filled in when a single task is fetched; the counts are
+// always present. Version is also sent as the task's ETag.
 type TaskResponse struct {
-	ID          int64      `json:"id"`
-	UserID      int64      `json:"user_id"`
-	Title       string     `json:"title"`
-	Description string     `json:"description"`
-	Status      string     `json:"status"`
-	Priority    int32      `json:"priority"`
-	DueDate     *time.Time `json:"due_date,omitempty"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
+	ID               int64                   `json:"id"`
+	UserID           int64                   `json:"user_id"`
+	Title            string                  `json:"title"`
+	Description      string                  `json:"description"`
+	Status           string                  `json:"status"`
+	StatusCategory   string                  `json:"status_category"`
+	Priority         int32                   `json:"priority"`
+	DueDate          *time.Time              `json:"due_date,omitempty"`
+	Estimate         *int32                  `json:"estimate"`
+	TimeSpent        int64                   `json:"time_spent"`
+	ChecklistChecked int32                   `json:"checklist_checked"`
+	ChecklistTotal   int32                   `json:"checklist_total"`
+	Tags             []string                `json:"tags"`
+	Recurrence       *string                 `json:"recurrence,omitempty"`
+	Checklist        []ChecklistItemResponse `json:"checklist,omitempty"`
+	ProjectID        *int64                  `json:"project_id,omitempty"`
+	SectionID        *int64                  `json:"section_id,omitempty"`
+	Position         string                  `json:"position"`
+	Version          int64                   `json:"version"`
+	CreatedAt        time.Time               `json:"created_at"`
+	UpdatedAt        time.Time               `json:"updated_at"`
+	DeletedAt        *time.Time              `json:"deleted_at,omitempty"`
 }
 
-// ListTasksQuery defines the query parameters for listing tasks.
+// ListTasksQuery defines the query parameters for listing tasks. Filter is
+// an expression in the package taskquery language, applied on top of the
+// other parameters; Tz is the IANA time zone its dates are read in and
+// defaults to UTC.
 type ListTasksQuery struct {
 	Page      int32      `form:"page,default=1"`
 	Limit     int32      `form:"limit,default=10"`
 	Status    string     `form:"status"`
 	DueBefore *time.Time `form:"due_before"`
+	Sort      string     `form:"sort" binding:"omitempty,oneof=created_at position"`
+	Filter    string     `form:"filter" binding:"omitempty,max=1000"`
+	Tz        string     `form:"tz"`
+}
+
+// ListProjectTasksQuery defines the query parameters for listing a project's tasks.
+type ListProjectTasksQuery struct {
+	Sort string `form:"sort" binding:"omitempty,oneof=created_at position"`
+}
+
+// MoveTaskRequest defines the request body for manually reordering a task.
+// The task is placed directly after AfterID and/or before BeforeID; with
+// neither it goes to the end of the list. SectionID moves the task into a
+// section of its project, with 0 meaning no section.
+type MoveTaskRequest struct {
+	AfterID   *int64 `json:"after_id" binding:"omitempty,min=1"`
+	BeforeID  *int64 `json:"before_id" binding:"omitempty,min=1"`
+	ProjectID *int64 `json:"project_id" binding:"omitempty,min=1"`
+	SectionID *int64 `json:"section_id" binding:"omitempty,min=0"`
+}
+
+// TaskHistoryQuery defines the query parameters for a task's history.
+type TaskHistoryQuery struct {
+	Page  int32 `form:"page,default=1" binding:"min=1"`
+	Limit int32 `form:"limit,default=20" binding:"min=1,max=100"`
+}
+
+// TaskEventResponse is a single entry in a task's history. Changes maps each
+// changed field to its {"old", "new"} values.
+type TaskEventResponse struct {
+	ID             int64           `json:"id"`
+	TaskID         int64           `json:"task_id"`
+	ActorID        *int64          `json:"actor_id"`
+	Action         string          `json:"action"`
+	Changes        json.RawMessage `json:"changes"`
+	RevertsEventID *int64          `json:"reverts_event_id,omitempty"`
+	UndoneAt       *time.Time      `json:"undone_at,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+// UndoResponse is returned after a task event has been undone.
+type UndoResponse struct {
+	Task        TaskResponse      `json:"task"`
+	UndoneEvent TaskEventResponse `json:"undone_event"`
 }
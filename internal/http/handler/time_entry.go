@@ -0,0 +1,307 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/pavelc4/auriya-todolist-go/internal/cache"
+	db "github.com/pavelc4/auriya-todolist-go/internal/db/sqlc"
+	"github.com/pavelc4/auriya-todolist-go/internal/http/repository"
+)
+
+type TimeEntryHandler struct {
+	Store *repository.Store
+	cache *cache.Service
+}
+
+func NewTimeEntryHandler(store *repository.Store, cache *cache.Service) *TimeEntryHandler {
+	return &TimeEntryHandler{Store: store, cache: cache}
+}
+
+// timeEntryURI identifies a time entry of a task.
+type timeEntryURI struct {
+	ID      int64 `uri:"id" binding:"required,min=1"`
+	EntryID int64 `uri:"entry_id" binding:"required,min=1"`
+}
+
+// newTimeEntryResponse converts a database time entry to a JSON response model.
+func newTimeEntryResponse(entry db.TimeEntry) TimeEntryResponse {
+	resp := TimeEntryResponse{
+		ID:          entry.ID,
+		TaskID:      entry.TaskID,
+		UserID:      entry.UserID,
+		StartedAt:   entry.StartedAt.Time,
+		Running:     !entry.EndedAt.Valid,
+		Note:        entry.Note,
+		AutoStopped: entry.AutoStopped,
+		CreatedAt:   entry.CreatedAt.Time,
+		UpdatedAt:   entry.UpdatedAt.Time,
+	}
+	end := time.Now()
+	if entry.EndedAt.Valid {
+		resp.EndedAt = &entry.EndedAt.Time
+		end = entry.EndedAt.Time
+	}
+	if d := end.Sub(entry.StartedAt.Time); d > 0 {
+		resp.Duration = int64(d / time.Second)
+	}
+	return resp
+}
+
+// writeTimeEntryError maps errors from the time entry write path to responses.
+func writeTimeEntryError(c *gin.Context, err error) {
+	var conflict *repository.TimerConflictError
+	switch {
+	case errors.As(err, &conflict):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "timer_running",
+			"detail":  err.Error(),
+			"running": newTimeEntryResponse(conflict.Running),
+		})
+	case errors.Is(err, repository.ErrTimerRunning):
+		c.JSON(http.StatusConflict, gin.H{"error": "timer_running", "detail": err.Error()})
+	case errors.Is(err, repository.ErrNoRunningTimer):
+		c.JSON(http.StatusConflict, gin.H{"error": "no_running_timer", "detail": err.Error()})
+	case errors.Is(err, repository.ErrInvalidTimeEntry):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "invalid_time_entry", "detail": err.Error()})
+	case errors.Is(err, pgx.ErrNoRows):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+	}
+}
+
+// List returns a task's time entries, newest first.
+func (h *TimeEntryHandler) List(c *gin.Context) {
+	var uri struct {
+		ID int64 `uri:"id" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "detail": err.Error()})
+		return
+	}
+
+	userID := c.GetInt64("userID")
+	ctx := c.Request.Context()
+
+	if _, err := h.Store.Queries.GetTask(ctx, db.GetTaskParams{ID: uri.ID, UserID: userID}); err != nil {
+		writeTimeEntryError(c, err)
+		return
+	}
+
+	entries, err := h.Store.Queries.ListTimeEntries(ctx, db.ListTimeEntriesParams{TaskID: uri.ID, UserID: userID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		return
+	}
+
+	resp := make([]TimeEntryResponse, 0, len(entries))
+	for _, e := range entries {
+		resp = append(resp, newTimeEntryResponse(e))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// Create logs time spent on a task after the fact.
+func (h *TimeEntryHandler) Create(c *gin.Context) {
+	var uri struct {
+		ID int64 `uri:"id" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "detail": err.Error()})
+		return
+	}
+
+	var req CreateTimeEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "detail": err.Error()})
+		return
+	}
+
+	in := repository.TimeEntryInput{StartedAt: req.StartedAt, EndedAt: req.EndedAt, Note: req.Note}
+	if in.EndedAt == nil {
+		end := req.StartedAt.Add(time.Duration(*req.Duration) * time.Second)
+		in.EndedAt = &end
+	}
+
+	userID := c.GetInt64("userID")
+	ctx := c.Request.Context()
+
+	var entry db.TimeEntry
+	err := h.Store.ExecTx(ctx, func(q *db.Queries) error {
+		var err error
+		entry, err = repository.CreateTimeEntry(ctx, q, userID, uri.ID, in)
+		return err
+	})
+	if err != nil {
+		writeTimeEntryError(c, err)
+		return
+	}
+
+	h.cache.Delete(fmt.Sprintf("task:%d", uri.ID))
+
+	c.JSON(http.StatusCreated, newTimeEntryResponse(entry))
+}
+
+// Start starts a timer on a task.
+func (h *TimeEntryHandler) Start(c *gin.Context) {
+	var uri struct {
+		ID int64 `uri:"id" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "detail": err.Error()})
+		return
+	}
+
+	// The body is optional.
+	var req StartTimerRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "detail": err.Error()})
+			return
+		}
+	}
+
+	userID := c.GetInt64("userID")
+	ctx := c.Request.Context()
+
+	var (
+		entry   db.TimeEntry
+		stopped *db.TimeEntry
+	)
+	err := h.Store.ExecTx(ctx, func(q *db.Queries) error {
+		var err error
+		entry, stopped, err = repository.StartTimer(ctx, q, userID, uri.ID, req.Note, req.StopRunning)
+		return err
+	})
+	if err != nil {
+		writeTimeEntryError(c, err)
+		return
+	}
+
+	resp := StartTimerResponse{Entry: newTimeEntryResponse(entry)}
+	if stopped != nil {
+		h.cache.Delete(fmt.Sprintf("task:%d", stopped.TaskID))
+		s := newTimeEntryResponse(*stopped)
+		resp.Stopped = &s
+	}
+
+	c.JSON(http.StatusCreated, resp)
+}
+
+// Stop stops the running timer on a task.
+func (h *TimeEntryHandler) Stop(c *gin.Context) {
+	var uri struct {
+		ID int64 `uri:"id" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "detail": err.Error()})
+		return
+	}
+
+	userID := c.GetInt64("userID")
+	ctx := c.Request.Context()
+
+	var entry db.TimeEntry
+	err := h.Store.ExecTx(ctx, func(q *db.Queries) error {
+		var err error
+		entry, err = repository.StopTimer(ctx, q, userID, uri.ID)
+		return err
+	})
+	if err != nil {
+		writeTimeEntryError(c, err)
+		return
+	}
+
+	h.cache.Delete(fmt.Sprintf("task:%d", uri.ID))
+
+	c.JSON(http.StatusOK, newTimeEntryResponse(entry))
+}
+
+// Update corrects the times or note of an entry.
+func (h *TimeEntryHandler) Update(c *gin.Context) {
+	var uri timeEntryURI
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "detail": err.Error()})
+		return
+	}
+
+	var req UpdateTimeEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "detail": err.Error()})
+		return
+	}
+
+	userID := c.GetInt64("userID")
+	ctx := c.Request.Context()
+
+	var entry db.TimeEntry
+	err := h.Store.ExecTx(ctx, func(q *db.Queries) error {
+		var err error
+		entry, err = repository.UpdateTimeEntry(ctx, q, userID, uri.ID, uri.EntryID, func(current db.TimeEntry) repository.TimeEntryInput {
+			in := repository.TimeEntryInput{StartedAt: current.StartedAt.Time, EndedAt: req.EndedAt, Note: current.Note}
+			if req.StartedAt != nil {
+				in.StartedAt = *req.StartedAt
+			}
+			if in.EndedAt == nil && current.EndedAt.Valid {
+				in.EndedAt = &current.EndedAt.Time
+			}
+			if req.Note != nil {
+				in.Note = req.Note
+			}
+			return in
+		})
+		return err
+	})
+	if err != nil {
+		writeTimeEntryError(c, err)
+		return
+	}
+
+	h.cache.Delete(fmt.Sprintf("task:%d", uri.ID))
+
+	c.JSON(http.StatusOK, newTimeEntryResponse(entry))
+}
+
+// Delete removes a time entry.
+func (h *TimeEntryHandler) Delete(c *gin.Context) {
+	var uri timeEntryURI
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "detail": err.Error()})
+		return
+	}
+
+	userID := c.GetInt64("userID")
+	ctx := c.Request.Context()
+
+	err := h.Store.ExecTx(ctx, func(q *db.Queries) error {
+		return repository.DeleteTimeEntry(ctx, q, userID, uri.ID, uri.EntryID)
+	})
+	if err != nil {
+		writeTimeEntryError(c, err)
+		return
+	}
+
+	h.cache.Delete(fmt.Sprintf("task:%d", uri.ID))
+
+	c.Status(http.StatusNoContent)
+}
+
+// Running returns the user's running timer, or 204 when none is running.
+func (h *TimeEntryHandler) Running(c *gin.Context) {
+	entry, err := h.Store.Queries.GetRunningTimeEntry(c.Request.Context(), c.GetInt64("userID"))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.Status(http.StatusNoContent)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, newTimeEntryResponse(entry))
+}
@@ -0,0 +1,83 @@
+package handler
+
+import "time"
+
+// StartTimerRequest defines the optional request body for starting a timer.
+// StopRunning stops the user's running timer, if any, instead of failing.
+type StartTimerRequest struct {
+	Note        *string `json:"note" binding:"omitempty,max=1000"`
+	StopRunning bool    `json:"stop_running"`
+}
+
+// CreateTimeEntryRequest defines the request body for logging time manually.
+// Either EndedAt or Duration (in seconds) is required.
+type CreateTimeEntryRequest struct {
+	StartedAt time.Time  `json:"started_at" binding:"required"`
+	EndedAt   *time.Time `json:"ended_at" binding:"required_without=Duration"`
+	Duration  *int64     `json:"duration" binding:"omitempty,min=1"`
+	Note      *string    `json:"note" binding:"omitempty,max=1000"`
+}
+
+// UpdateTimeEntryRequest defines the request body for correcting an entry.
+// Setting EndedAt on a running entry stops it.
+type UpdateTimeEntryRequest struct {
+	StartedAt *time.Time `json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at"`
+	Note      *string    `json:"note" binding:"omitempty,max=1000"`
+}
+
+// TimeEntryResponse defines the standard response for a time entry. Duration
+// is in seconds; for a running timer it is the time elapsed so far.
+type TimeEntryResponse struct {
+	ID          int64      `json:"id"`
+	TaskID      int64      `json:"task_id"`
+	UserID      int64      `json:"user_id"`
+	StartedAt   time.Time  `json:"started_at"`
+	EndedAt     *time.Time `json:"ended_at,omitempty"`
+	Duration    int64      `json:"duration"`
+	Running     bool       `json:"running"`
+	Note        *string    `json:"note,omitempty"`
+	AutoStopped bool       `json:"auto_stopped"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// StartTimerResponse is returned when a timer starts. Stopped is the timer
+// that was stopped to make room for it, if any.
+type StartTimerResponse struct {
+	Entry   TimeEntryResponse  `json:"entry"`
+	Stopped *TimeEntryResponse `json:"stopped,omitempty"`
+}
+
+// TimeReportQuery defines the query parameters for the time report. From and
+// To are inclusive dates (YYYY-MM-DD) in Tz, which defaults to UTC. GroupBy
+// is a comma separated list of project, day and user.
+type TimeReportQuery struct {
+	From      string `form:"from" binding:"omitempty,datetime=2006-01-02"`
+	To        string `form:"to" binding:"omitempty,datetime=2006-01-02"`
+	Tz        string `form:"tz"`
+	GroupBy   string `form:"group_by,default=project"`
+	ProjectID *int64 `form:"project_id" binding:"omitempty,min=1"`
+	Format    string `form:"format,default=json" binding:"oneof=json csv"`
+}
+
+// TimeReportRow is one group of the time report. Only the fields named in
+// group_by are set.
+type TimeReportRow struct {
+	ProjectID   *int64  `json:"project_id,omitempty"`
+	ProjectName *string `json:"project_name,omitempty"`
+	Day         string  `json:"day,omitempty"`
+	UserID      *int64  `json:"user_id,omitempty"`
+	Seconds     int64   `json:"seconds"`
+	Entries     int64   `json:"entries"`
+}
+
+// TimeReportResponse is the JSON form of the time report.
+type TimeReportResponse struct {
+	From         string          `json:"from"`
+	To           string          `json:"to"`
+	Tz           string          `json:"tz"`
+	GroupBy      []string        `json:"group_by"`
+	Rows         []TimeReportRow `json:"rows"`
+	TotalSeconds int64           `json:"total_seconds"`
+}
@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/pavelc4/auriya-todolist-go/internal/cache"
+	db "github.com/pavelc4/auriya-todolist-go/internal/db/sqlc"
+	"github.com/pavelc4/auriya-todolist-go/internal/http/repository"
+)
+
+type TrashHandler struct {
+	Store *repository.Store
+	cache *cache.Service
+}
+
+func NewTrashHandler(store *repository.Store, cache *cache.Service) *TrashHandler {
+	return &TrashHandler{Store: store, cache: cache}
+}
+
+// List returns everything the user has in the trash, most recently deleted first.
+func (h *TrashHandler) List(c *gin.Context) {
+	userID := c.GetInt64("userID")
+	ctx := c.Request.Context()
+
+	tasks, err := h.Store.Queries.ListTrashedTasks(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		return
+	}
+	projects, err := h.Store.Queries.ListTrashedProjects(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		return
+	}
+
+	taskResponses := make([]TaskResponse, 0, len(tasks))
+	for _, t := range tasks {
+		taskResponses = append(taskResponses, newTaskResponse(t))
+	}
+	projectResponses := make([]ProjectResponse, 0, len(projects))
+	for _, p := range projects {
+		projectResponses = append(projectResponses, newProjectResponse(p))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tasks":    taskResponses,
+		"projects": projectResponses,
+	})
+}
+
+// PurgeTask permanently deletes a task that is already in the trash.
+func (h *TrashHandler) PurgeTask(c *gin.Context) {
+	var uri struct {
+		ID int64 `uri:"id" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "detail": err.Error()})
+		return
+	}
+
+	userID := c.GetInt64("userID")
+	ctx := c.Request.Context()
+
+	// A transaction of its own, so the user's sync lock is taken before the
+	// row is locked.
+	var purged int64
+	err := h.Store.ExecTx(ctx, func(q *db.Queries) error {
+		var err error
+		purged, err = q.PurgeTask(ctx, db.PurgeTaskParams{ID: uri.ID, UserID: userID})
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		return
+	}
+	if purged == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+		return
+	}
+
+	h.cache.Delete(fmt.Sprintf("task:%d", uri.ID))
+
+	c.Status(http.StatusNoContent)
+}
+
+// PurgeProject permanently deletes a trashed project and every trashed task
+// that still belongs to it.
+func (h *TrashHandler) PurgeProject(c *gin.Context) {
+	var uri struct {
+		ID int64 `uri:"id" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "detail": err.Error()})
+		return
+	}
+
+	userID := c.GetInt64("userID")
+	ctx := c.Request.Context()
+
+	// Tasks go first: deleting the project would otherwise detach them via
+	// the ON DELETE SET NULL foreign key and leave them behind in the trash.
+	err := h.Store.ExecTx(ctx, func(q *db.Queries) error {
+		if _, err := q.GetTrashedProject(ctx, db.GetTrashedProjectParams{ID: uri.ID, UserID: userID}); err != nil {
+			return err
+		}
+		err := q.PurgeProjectTasks(ctx, db.PurgeProjectTasksParams{
+			ProjectID: pgtype.Int8{Int64: uri.ID, Valid: true},
+			UserID:    userID,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = q.PurgeProject(ctx, db.PurgeProjectParams{ID: uri.ID, UserID: userID})
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Empty permanently deletes everything in the user's trash.
+func (h *TrashHandler) Empty(c *gin.Context) {
+	userID := c.GetInt64("userID")
+	ctx := c.Request.Context()
+
+	var tasks, projects int64
+	err := h.Store.ExecTx(ctx, func(q *db.Queries) error {
+		var err error
+		if tasks, err = q.EmptyTaskTrash(ctx, userID); err != nil {
+			return err
+		}
+		projects, err = q.EmptyProjectTrash(ctx, userID)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"purged_tasks":    tasks,
+		"purged_projects": projects,
+	})
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/pavelc4/auriya-todolist-go/internal/cache"
+	db "github.com/pavelc4/auriya-todolist-go/internal/db/sqlc"
+	"github.com/pavelc4/auriya-todolist-go/internal/http/repository"
+)
+
+type UndoHandler struct {
+	Store  *repository.Store
+	cache  *cache.Service
+	window time.Duration
+}
+
+func NewUndoHandler(store *repository.Store, cache *cache.Service, window time.Duration) *UndoHandler {
+	return &UndoHandler{Store: store, cache: cache, window: window}
+}
+
+// UndoTask reverts the most recent change of a task that has not been undone
+// yet. Calling it repeatedly steps further back through the task's history.
+func (h *UndoHandler) UndoTask(c *gin.Context) {
+	var uri struct {
+		ID int64 `uri:"id" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "detail": err.Error()})
+		return
+	}
+
+	userID := c.GetInt64("userID")
+
+	h.undo(c, func(q *db.Queries) (db.TaskEvent, error) {
+		ctx := c.Request.Context()
+		if _, err := q.LockTask(ctx, db.LockTaskParams{ID: uri.ID, UserID: userID}); err != nil {
+			return db.TaskEvent{}, err
+		}
+		event, err := q.GetLatestUndoableTaskEvent(ctx, db.GetLatestUndoableTaskEventParams{TaskID: uri.ID, UserID: userID})
+		if errors.Is(err, pgx.ErrNoRows) {
+			return event, fmt.Errorf("%w: task %d has nothing left to undo", repository.ErrUndoConflict, uri.ID)
+		}
+		return event, err
+	})
+}
+
+// UndoEvent reverts a specific history event, which must still be the latest
+// undoable event of its task.
+func (h *UndoHandler) UndoEvent(c *gin.Context) {
+	var uri struct {
+		EventID int64 `uri:"event_id" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "detail": err.Error()})
+		return
+	}
+
+	userID := c.GetInt64("userID")
+
+	h.undo(c, func(q *db.Queries) (db.TaskEvent, error) {
+		return q.GetTaskEvent(c.Request.Context(), db.GetTaskEventParams{ID: uri.EventID, UserID: userID})
+	})
+}
+
+// undo looks up the event to revert with find and undoes it in one transaction.
+func (h *UndoHandler) undo(c *gin.Context, find func(q *db.Queries) (db.TaskEvent, error)) {
+	userID := c.GetInt64("userID")
+	ctx := c.Request.Context()
+
+	var event db.TaskEvent
+	var task db.Task
+	err := h.Store.ExecTx(ctx, func(q *db.Queries) error {
+		var err error
+		if event, err = find(q); err != nil {
+			return err
+		}
+		if task, err = repository.UndoTaskEvent(ctx, q, userID, event, h.window); err != nil {
+			return err
+		}
+		event, err = q.GetTaskEvent(ctx, db.GetTaskEventParams{ID: event.ID, UserID: userID})
+		return err
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+		case errors.Is(err, repository.ErrUndoConflict):
+			c.JSON(http.StatusConflict, gin.H{"error": "undo_conflict", "detail": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		}
+		return
+	}
+
+	h.cache.Delete(fmt.Sprintf("task:%d", task.ID))
+
+	c.JSON(http.StatusOK, UndoResponse{
+		Task:        newTaskResponse(task),
+		UndoneEvent: newTaskEventResponse(event),
+	})
+}
@@ -0,0 +1,464 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/pavelc4/auriya-todolist-go/internal/db/sqlc"
+	"github.com/pavelc4/auriya-todolist-go/internal/http/repository"
+	"github.com/pavelc4/auriya-todolist-go/internal/webhooks"
+)
+
+type WebhookHandler struct {
+	Store *repository.Store
+	// AllowPrivate lets webhook URLs point at local and private addresses.
+	AllowPrivate bool
+}
+
+func NewWebhookHandler(store *repository.Store, allowPrivate bool) *WebhookHandler {
+	return &WebhookHandler{Store: store, AllowPrivate: allowPrivate}
+}
+
+// WebhookRenderer renders queued deliveries with the task and project
+// responses of the API, for jobs.WebhookDispatcher.
+func WebhookRenderer(resourceType string, row []byte) (json.RawMessage, error) {
+	switch resourceType {
+	case "task":
+		var t db.Task
+		if err := json.Unmarshal(row, &t); err != nil {
+			return nil, err
+		}
+		return json.Marshal(newTaskResponse(t))
+	case "project":
+		var p db.Project
+		if err := json.Unmarshal(row, &p); err != nil {
+			return nil, err
+		}
+		return json.Marshal(newProjectResponse(p))
+	default:
+		return row, nil
+	}
+}
+
+// newWebhookResponse converts a webhook to a JSON response model, without
+// its secret.
+func newWebhookResponse(w db.Webhook) WebhookResponse {
+	resp := WebhookResponse{
+		ID:           w.ID,
+		URL:          w.Url,
+		Events:       w.EventTypes,
+		Active:       w.Active,
+		FailureCount: w.FailureCount,
+		CreatedAt:    w.CreatedAt.Time,
+		UpdatedAt:    w.UpdatedAt.Time,
+	}
+	if resp.Events == nil {
+		resp.Events = []string{}
+	}
+	if w.ProjectID.Valid {
+		resp.ProjectID = &w.ProjectID.Int64
+	}
+	if w.DisabledAt.Valid {
+		resp.DisabledAt = &w.DisabledAt.Time
+	}
+	return resp
+}
+
+// newWebhookDeliveryResponse converts a delivery to a JSON response model.
+func newWebhookDeliveryResponse(d db.WebhookDelivery) WebhookDeliveryResponse {
+	resp := WebhookDeliveryResponse{
+		ID:           d.ID,
+		WebhookID:    d.WebhookID,
+		Event:        d.EventType,
+		Status:       d.Status,
+		AttemptCount: d.Attempts,
+		CreatedAt:    d.CreatedAt.Time,
+	}
+	if d.EventID.Valid {
+		id := d.EventID.String()
+		resp.EventID = &id
+	}
+	if d.Status == "pending" {
+		resp.NextAttemptAt = &d.NextAttemptAt.Time
+	}
+	if d.LastStatusCode.Valid {
+		resp.LastStatusCode = &d.LastStatusCode.Int32
+	}
+	if d.LastError.Valid {
+		resp.LastError = &d.LastError.String
+	}
+	if d.CompletedAt.Valid {
+		resp.CompletedAt = &d.CompletedAt.Time
+	}
+	return resp
+}
+
+func newWebhookAttemptResponse(a db.WebhookAttempt) WebhookAttemptResponse {
+	resp := WebhookAttemptResponse{AttemptedAt: a.AttemptedAt.Time, DurationMs: a.DurationMs}
+	if a.StatusCode.Valid {
+		resp.StatusCode = &a.StatusCode.Int32
+	}
+	if a.Error.Valid {
+		resp.Error = &a.Error.String
+	}
+	return resp
+}
+
+// checkWebhookEvents rejects unknown event names and drops duplicates.
+func checkWebhookEvents(events []string) ([]string, error) {
+	out := make([]string, 0, len(events))
+	for _, e := range events {
+		if !webhooks.ValidEvent(e) {
+			return nil, fmt.Errorf("unknown event %q", e)
+		}
+		if !slices.Contains(out, e) {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+// checkWebhookProject reports whether the user has a project, for webhooks
+// scoped to it. It writes the error response when not.
+func (h *WebhookHandler) checkWebhookProject(c *gin.Context, projectID int64) bool {
+	_, err := h.Store.Queries.GetProject(c.Request.Context(), db.GetProjectParams{ID: projectID, UserID: c.GetInt64("userID")})
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "unknown_project", "detail": "project not found"})
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		return false
+	}
+	return true
+}
+
+// loadWebhook reads the webhook named in the URL. It writes the error
+// response when that fails.
+func (h *WebhookHandler) loadWebhook(c *gin.Context) (db.Webhook, bool) {
+	var uri struct {
+		ID int64 `uri:"id" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "detail": err.Error()})
+		return db.Webhook{}, false
+	}
+	w, err := h.Store.Queries.GetWebhook(c.Request.Context(), db.GetWebhookParams{ID: uri.ID, UserID: c.GetInt64("userID")})
+	if err != nil {
+		writeWebhookError(c, err)
+		return db.Webhook{}, false
+	}
+	return w, true
+}
+
+// writeWebhookError maps errors from the webhook queries to responses.
+func writeWebhookError(c *gin.Context, err error) {
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+}
+
+// List returns the user's webhooks.
+func (h *WebhookHandler) List(c *gin.Context) {
+	hooks, err := h.Store.Queries.ListWebhooks(c.Request.Context(), c.GetInt64("userID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		return
+	}
+	resp := make([]WebhookResponse, 0, len(hooks))
+	for _, w := range hooks {
+		resp = append(resp, newWebhookResponse(w))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// Events lists the events a webhook can subscribe to.
+func (h *WebhookHandler) Events(c *gin.Context) {
+	c.JSON(http.StatusOK, webhooks.Events)
+}
+
+// Create adds a webhook. The response is the only one that includes a
+// generated secret.
+func (h *WebhookHandler) Create(c *gin.Context) {
+	var req CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "detail": err.Error()})
+		return
+	}
+	if err := webhooks.ValidateURL(req.URL, h.AllowPrivate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "detail": err.Error()})
+		return
+	}
+	events, err := checkWebhookEvents(req.Events)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "detail": err.Error()})
+		return
+	}
+	if req.ProjectID != nil && !h.checkWebhookProject(c, *req.ProjectID) {
+		return
+	}
+
+	secret := ""
+	if req.Secret != nil {
+		secret = *req.Secret
+	} else if secret, err = webhooks.NewSecret(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "detail": err.Error()})
+		return
+	}
+
+	w, err := h.Store.Queries.CreateWebhook(c.Request.Context(), db.CreateWebhookParams{
+		UserID:     c.GetInt64("userID"),
+		ProjectID:  toPgInt8(req.ProjectID),
+		Url:        req.URL,
+		Secret:     secret,
+		EventTypes: events,
+	})
+	if err != nil {
+		writeWebhookError(c, err)
+		return
+	}
+
+	resp := newWebhookResponse(w)
+	resp.Secret = w.Secret
+	c.JSON(http.StatusCreated, resp)
+}
+
+// Get returns one webhook.
+func (h *WebhookHandler) Get(c *gin.Context) {
+	w, ok := h.loadWebhook(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, newWebhookResponse(w))
+}
+
+// Update changes a webhook's URL, scope, events or whether it is active.
+func (h *WebhookHandler) Update(c *gin.Context) {
+	w, ok := h.loadWebhook(c)
+	if !ok {
+		return
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "detail": err.Error()})
+		return
+	}
+	var req UpdateWebhookRequest
+	nulls, err := readMergePatch(body, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "detail": err.Error()})
+		return
+	}
+
+	arg := db.SetWebhookFieldsParams{
+		Url:        w.Url,
+		ProjectID:  w.ProjectID,
+		EventTypes: w.EventTypes,
+		Active:     w.Active,
+		ID:         w.ID,
+		UserID:     w.UserID,
+	}
+	if req.URL != nil {
+		if err := webhooks.ValidateURL(*req.URL, h.AllowPrivate); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "detail": err.Error()})
+			return
+		}
+		arg.Url = *req.URL
+	}
+	if req.Events != nil || nulls["events"] {
+		if arg.EventTypes, err = checkWebhookEvents(req.Events); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "detail": err.Error()})
+			return
+		}
+	}
+	if nulls["project_id"] {
+		arg.ProjectID = pgtype.Int8{}
+	} else if req.ProjectID != nil {
+		if !h.checkWebhookProject(c, *req.ProjectID) {
+			return
+		}
+		arg.ProjectID = toPgInt8(req.ProjectID)
+	}
+	if req.Active != nil {
+		arg.Active = *req.Active
+	}
+
+	w, err = h.Store.Queries.SetWebhookFields(c.Request.Context(), arg)
+	if err != nil {
+		writeWebhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, newWebhookResponse(w))
+}
+
+// RotateSecret replaces a webhook's signing secret with a new random one and
+// returns it. Deliveries sent from then on, retries included, use it.
+func (h *WebhookHandler) RotateSecret(c *gin.Context) {
+	w, ok := h.loadWebhook(c)
+	if !ok {
+		return
+	}
+	secret, err := webhooks.NewSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "detail": err.Error()})
+		return
+	}
+	w, err = h.Store.Queries.SetWebhookSecret(c.Request.Context(), db.SetWebhookSecretParams{Secret: secret, ID: w.ID, UserID: w.UserID})
+	if err != nil {
+		writeWebhookError(c, err)
+		return
+	}
+	resp := newWebhookResponse(w)
+	resp.Secret = w.Secret
+	c.JSON(http.StatusOK, resp)
+}
+
+// Delete removes a webhook along with its pending deliveries and log.
+func (h *WebhookHandler) Delete(c *gin.Context) {
+	w, ok := h.loadWebhook(c)
+	if !ok {
+		return
+	}
+	if _, err := h.Store.Queries.DeleteWebhook(c.Request.Context(), db.DeleteWebhookParams{ID: w.ID, UserID: w.UserID}); err != nil {
+		writeWebhookError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Ping queues a ping delivery, to check that the receiver is reachable and
+// verifies signatures.
+func (h *WebhookHandler) Ping(c *gin.Context) {
+	w, ok := h.loadWebhook(c)
+	if !ok {
+		return
+	}
+	if !w.Active {
+		c.JSON(http.StatusConflict, gin.H{"error": "webhook_inactive", "detail": "activate the webhook first"})
+		return
+	}
+
+	payload, err := json.Marshal(gin.H{"webhook_id": w.ID, "url": w.Url, "events": newWebhookResponse(w).Events})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "detail": err.Error()})
+		return
+	}
+	d, err := h.Store.Queries.CreateWebhookDelivery(c.Request.Context(), db.CreateWebhookDeliveryParams{
+		WebhookID:    w.ID,
+		EventType:    webhooks.EventPing,
+		ResourceType: "webhook",
+		Payload:      payload,
+	})
+	if err != nil {
+		writeWebhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, newWebhookDeliveryResponse(d))
+}
+
+// Deliveries lists a webhook's deliveries, newest first.
+func (h *WebhookHandler) Deliveries(c *gin.Context) {
+	w, ok := h.loadWebhook(c)
+	if !ok {
+		return
+	}
+	var q WebhookDeliveriesQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_query", "detail": err.Error()})
+		return
+	}
+
+	arg := db.ListWebhookDeliveriesParams{WebhookID: w.ID, Limit: q.Limit}
+	if q.Status != "" {
+		arg.Status = pgtype.Text{String: q.Status, Valid: true}
+	}
+	if q.Before != 0 {
+		arg.BeforeID = pgtype.Int8{Int64: q.Before, Valid: true}
+	}
+	deliveries, err := h.Store.Queries.ListWebhookDeliveries(c.Request.Context(), arg)
+	if err != nil {
+		writeWebhookError(c, err)
+		return
+	}
+
+	resp := make([]WebhookDeliveryResponse, 0, len(deliveries))
+	for _, d := range deliveries {
+		resp = append(resp, newWebhookDeliveryResponse(d))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// loadDelivery reads the webhook and delivery named in the URL. It writes
+// the error response when that fails.
+func (h *WebhookHandler) loadDelivery(c *gin.Context) (db.Webhook, db.WebhookDelivery, bool) {
+	w, ok := h.loadWebhook(c)
+	if !ok {
+		return w, db.WebhookDelivery{}, false
+	}
+	var uri struct {
+		DeliveryID int64 `uri:"delivery_id" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "detail": err.Error()})
+		return w, db.WebhookDelivery{}, false
+	}
+	d, err := h.Store.Queries.GetWebhookDelivery(c.Request.Context(), db.GetWebhookDeliveryParams{ID: uri.DeliveryID, WebhookID: w.ID})
+	if err != nil {
+		writeWebhookError(c, err)
+		return w, d, false
+	}
+	return w, d, true
+}
+
+// Delivery returns one delivery with its payload and every attempt made.
+func (h *WebhookHandler) Delivery(c *gin.Context) {
+	_, d, ok := h.loadDelivery(c)
+	if !ok {
+		return
+	}
+	attempts, err := h.Store.Queries.ListWebhookAttempts(c.Request.Context(), d.ID)
+	if err != nil {
+		writeWebhookError(c, err)
+		return
+	}
+
+	resp := newWebhookDeliveryResponse(d)
+	if resp.Payload, err = WebhookRenderer(d.ResourceType, d.Payload); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal_error", "detail": err.Error()})
+		return
+	}
+	resp.Attempts = make([]WebhookAttemptResponse, 0, len(attempts))
+	for _, a := range attempts {
+		resp.Attempts = append(resp.Attempts, newWebhookAttemptResponse(a))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// Redeliver queues a new delivery of the same event and payload as an
+// earlier one, whatever became of it.
+func (h *WebhookHandler) Redeliver(c *gin.Context) {
+	w, d, ok := h.loadDelivery(c)
+	if !ok {
+		return
+	}
+	if !w.Active {
+		c.JSON(http.StatusConflict, gin.H{"error": "webhook_inactive", "detail": "activate the webhook first"})
+		return
+	}
+	d, err := h.Store.Queries.RedeliverWebhookDelivery(c.Request.Context(), db.RedeliverWebhookDeliveryParams{ID: d.ID, WebhookID: w.ID})
+	if err != nil {
+		writeWebhookError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, newWebhookDeliveryResponse(d))
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// CreateWebhookRequest defines the request body for adding a webhook.
+// ProjectID limits it to one project's tasks and the project itself. Events
+// picks the events it gets, all of them when empty. Secret signs the
+// deliveries; one is generated when it is not given.
+type CreateWebhookRequest struct {
+	URL       string   `json:"url" binding:"required,max=2000"`
+	ProjectID *int64   `json:"project_id" binding:"omitempty,min=1"`
+	Events    []string `json:"events" binding:"omitempty,max=20"`
+	Secret    *string  `json:"secret" binding:"omitempty,min=16,max=200"`
+}
+
+// UpdateWebhookRequest defines the request body for changing a webhook. It
+// is a merge patch: a null project_id makes the webhook cover all projects.
+// Setting active to true turns a webhook that was disabled after repeated
+// failures back on.
+type UpdateWebhookRequest struct {
+	URL       *string  `json:"url" binding:"omitempty,min=1,max=2000"`
+	ProjectID *int64   `json:"project_id" binding:"omitempty,min=1"`
+	Events    []string `json:"events" binding:"omitempty,max=20"`
+	Active    *bool    `json:"active"`
+}
+
+// WebhookResponse defines the standard response for a webhook. The secret is
+// only returned when the webhook is created and when it is rotated.
+// DisabledAt is set when the webhook was turned off because its deliveries
+// kept failing.
+type WebhookResponse struct {
+	ID           int64      `json:"id"`
+	URL          string     `json:"url"`
+	ProjectID    *int64     `json:"project_id"`
+	Events       []string   `json:"events"`
+	Active       bool       `json:"active"`
+	FailureCount int32      `json:"failure_count"`
+	DisabledAt   *time.Time `json:"disabled_at"`
+	Secret       string     `json:"secret,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// WebhookDeliveriesQuery defines the query parameters for listing
+// deliveries, newest first. Before pages back from a delivery ID.
+type WebhookDeliveriesQuery struct {
+	Status string `form:"status" binding:"omitempty,oneof=pending succeeded failed"`
+	Before int64  `form:"before" binding:"omitempty,min=1"`
+	Limit  int32  `form:"limit,default=50" binding:"min=1,max=100"`
+}
+
+// WebhookDeliveryResponse defines the response for a delivery. Status is
+// pending while it is being retried, then succeeded or failed. Payload is
+// the resource as it was when the event happened, as the API returns it.
+// EventID is the outbox event the delivery reports; pings and redeliveries
+// have none. Attempts is only included for a single delivery.
+type WebhookDeliveryResponse struct {
+	ID             int64                    `json:"id"`
+	WebhookID      int64                    `json:"webhook_id"`
+	EventID        *string                  `json:"event_id"`
+	Event          string                   `json:"event"`
+	Status         string                   `json:"status"`
+	AttemptCount   int32                    `json:"attempt_count"`
+	NextAttemptAt  *time.Time               `json:"next_attempt_at"`
+	LastStatusCode *int32                   `json:"last_status_code"`
+	LastError      *string                  `json:"last_error"`
+	Payload        json.RawMessage          `json:"payload,omitempty"`
+	CreatedAt      time.Time                `json:"created_at"`
+	CompletedAt    *time.Time               `json:"completed_at"`
+	Attempts       []WebhookAttemptResponse `json:"attempts,omitempty"`
+}
+
+// WebhookAttemptResponse defines the response for one delivery attempt.
+// StatusCode is null when the receiver could not be reached. The body of the
+// receiver's answer is not kept.
+type WebhookAttemptResponse struct {
+	AttemptedAt time.Time `json:"attempted_at"`
+	StatusCode  *int32    `json:"status_code"`
+	Error       *string   `json:"error"`
+	DurationMs  int32     `json:"duration_ms"`
+}
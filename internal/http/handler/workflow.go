@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/pavelc4/auriya-todolist-go/internal/db/sqlc"
+	"github.com/pavelc4/auriya-todolist-go/internal/http/repository"
+)
+
+func newWorkflowResponse(w repository.Workflow) WorkflowResponse {
+	resp := WorkflowResponse{
+		Statuses:    make([]WorkflowStatusResponse, len(w.Statuses)),
+		Default:     w.Default,
+		Transitions: w.Transitions,
+	}
+	for i, s := range w.Statuses {
+		resp.Statuses[i] = WorkflowStatusResponse{Key: s.Key, Name: s.Name, Category: s.Category}
+	}
+	if resp.Transitions == nil {
+		resp.Transitions = map[string][]string{}
+	}
+	return resp
+}
+
+// Workflow returns the statuses, default status and allowed transitions of a
+// project. Projects that have not defined their own get the built-in one.
+func (h *ProjectHandler) Workflow(c *gin.Context) {
+	var uri struct {
+		ID int64 `uri:"id" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "detail": err.Error()})
+		return
+	}
+
+	userID := c.GetInt64("userID")
+	ctx := c.Request.Context()
+
+	if _, err := h.Store.Queries.GetProject(ctx, db.GetProjectParams{ID: uri.ID, UserID: userID}); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		return
+	}
+
+	w, err := repository.LoadWorkflow(ctx, h.Store.Queries, pgtype.Int8{Int64: uri.ID, Valid: true})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, newWorkflowResponse(w))
+}
+
+// UpdateWorkflow replaces a project's workflow. Tasks with a removed status
+// are moved to the replacement given in remap.
+func (h *ProjectHandler) UpdateWorkflow(c *gin.Context) {
+	var uri struct {
+		ID int64 `uri:"id" binding:"required,min=1"`
+	}
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_id", "detail": err.Error()})
+		return
+	}
+
+	var req UpdateWorkflowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "detail": err.Error()})
+		return
+	}
+
+	w := repository.Workflow{Default: req.Default, Transitions: req.Transitions}
+	for _, s := range req.Statuses {
+		w.Statuses = append(w.Statuses, repository.WorkflowStatus{Key: s.Key, Name: s.Name, Category: s.Category})
+	}
+
+	userID := c.GetInt64("userID")
+	ctx := c.Request.Context()
+
+	var changed []int64
+	err := h.Store.ExecTx(ctx, func(q *db.Queries) error {
+		var err error
+		changed, err = repository.SaveWorkflow(ctx, q, userID, userID, uri.ID, w, req.Remap)
+		return err
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+		case errors.Is(err, repository.ErrInvalidWorkflow):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "invalid_workflow", "detail": err.Error()})
+		case errors.Is(err, repository.ErrStatusInUse):
+			c.JSON(http.StatusConflict, gin.H{"error": "status_in_use", "detail": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		}
+		return
+	}
+
+	for _, id := range changed {
+		h.cache.Delete(fmt.Sprintf("task:%d", id))
+	}
+
+	c.JSON(http.StatusOK, newWorkflowResponse(w))
+}
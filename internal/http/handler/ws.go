@@ -0,0 +1,263 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	db "github.com/pavelc4/auriya-todolist-go/internal/db/sqlc"
+	"github.com/pavelc4/auriya-todolist-go/internal/http/repository"
+	"github.com/pavelc4/auriya-todolist-go/internal/http/service"
+	"github.com/pavelc4/auriya-todolist-go/internal/realtime"
+	"golang.org/x/net/websocket"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// wsAuthTimeout is how long a client that did not send an Authorization
+	// header has to send its auth message.
+	wsAuthTimeout = 10 * time.Second
+	// Clients must answer the server's pings, so a connection that stays
+	// silent for wsReadTimeout is considered dead.
+	wsPingInterval = 25 * time.Second
+	wsReadTimeout  = 60 * time.Second
+	wsWriteTimeout = 10 * time.Second
+
+	wsMaxMessageBytes = 4 << 10
+	// wsReplyBuffer is how many replies may wait for the writer before the
+	// connection is closed for sending faster than it reads.
+	wsReplyBuffer  = 16
+	wsMessageRate  = 20
+	wsMessageBurst = 40
+)
+
+type WSHandler struct {
+	Store *repository.Store
+	hub   *realtime.Hub
+	jwt   *service.JWTService
+}
+
+func NewWSHandler(store *repository.Store, hub *realtime.Hub, jwt *service.JWTService) *WSHandler {
+	return &WSHandler{Store: store, hub: hub, jwt: jwt}
+}
+
+// Serve upgrades the request to a WebSocket for realtime project updates.
+// Clients authenticate with the usual bearer token, either in the
+// Authorization header or, for browsers, in an auth message sent first.
+// They then subscribe to projects they own and get their task and project
+// changes, the list of connections viewing them (presence) and typing
+// indicators. A connection that falls behind, floods the server or stops
+// answering pings is closed; clients reconnect and catch up through
+// GET /api/sync.
+func (h *WSHandler) Serve(c *gin.Context) {
+	var userID int64
+	if header := c.GetHeader("Authorization"); header != "" {
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid authorization header format"})
+			return
+		}
+		id, err := h.jwt.ValidateToken(token)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token", "detail": err.Error()})
+			return
+		}
+		userID = id
+	}
+
+	srv := websocket.Server{
+		// Clients authenticate with a token rather than cookies, so a page
+		// on another origin gains nothing by connecting.
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler: func(ws *websocket.Conn) {
+			h.serve(c.Request.Context(), ws, userID)
+		},
+	}
+	srv.ServeHTTP(c.Writer, c.Request)
+}
+
+func (h *WSHandler) serve(ctx context.Context, ws *websocket.Conn, userID int64) {
+	defer ws.Close()
+	ws.MaxPayloadBytes = wsMaxMessageBytes
+
+	// The connection is hijacked, so the server's timeouts no longer apply
+	// and every read and write sets its own deadline.
+	write := func(msg WSServerMessage) error {
+		if err := ws.SetWriteDeadline(time.Now().Add(wsWriteTimeout)); err != nil {
+			return err
+		}
+		return websocket.JSON.Send(ws, msg)
+	}
+	fail := func(code, detail string) {
+		write(WSServerMessage{Type: "error", Error: code, Detail: detail})
+	}
+
+	if userID == 0 {
+		var msg WSClientMessage
+		ws.SetReadDeadline(time.Now().Add(wsAuthTimeout))
+		if err := websocket.JSON.Receive(ws, &msg); err != nil || msg.Type != "auth" {
+			fail("unauthorized", "the first message must be an auth message")
+			return
+		}
+		id, err := h.jwt.ValidateToken(msg.Token)
+		if err != nil {
+			fail("unauthorized", err.Error())
+			return
+		}
+		userID = id
+	}
+
+	sub, err := h.hub.SubscribeProjects(userID)
+	if err != nil {
+		_, code := subscribeErrorStatus(err)
+		fail(code, err.Error())
+		return
+	}
+	defer sub.Close()
+
+	replies := make(chan WSServerMessage, wsReplyBuffer)
+	readDone := make(chan struct{})
+	go func() {
+		defer close(readDone)
+		h.read(ctx, ws, userID, sub, replies)
+	}()
+
+	if err := write(WSServerMessage{Type: "ready", ConnectionID: sub.ConnID()}); err != nil {
+		return
+	}
+
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+	for {
+		var msg WSServerMessage
+		select {
+		case <-readDone:
+			return
+		case <-sub.Done():
+			_, code := subscribeErrorStatus(sub.Err())
+			fail(code, sub.Err().Error())
+			return
+		case msg = <-replies:
+		case ev := <-sub.Events():
+			msg = wsEventMessage(ev)
+		case <-ping.C:
+			msg = WSServerMessage{Type: "ping"}
+		}
+		if err := write(msg); err != nil {
+			return
+		}
+	}
+}
+
+// read handles the client's messages until the connection fails, queueing
+// replies for the writer.
+func (h *WSHandler) read(ctx context.Context, ws *websocket.Conn, userID int64, sub *realtime.Subscription, replies chan<- WSServerMessage) {
+	limiter := rate.NewLimiter(wsMessageRate, wsMessageBurst)
+	for {
+		ws.SetReadDeadline(time.Now().Add(wsReadTimeout))
+		var msg WSClientMessage
+		err := websocket.JSON.Receive(ws, &msg)
+
+		var reply WSServerMessage
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &syntaxErr) || errors.As(err, &typeErr):
+			reply = WSServerMessage{Type: "error", Error: "invalid_message", Detail: err.Error()}
+		case err != nil:
+			return
+		case !limiter.Allow():
+			reply = WSServerMessage{Type: "error", Ref: msg.Ref, Error: "rate_limited", Detail: "too many messages"}
+		default:
+			reply = h.handle(ctx, userID, sub, msg)
+		}
+		if reply.Type == "" {
+			continue
+		}
+
+		select {
+		case replies <- reply:
+		default:
+			return
+		}
+	}
+}
+
+// handle runs one client message and returns the reply, if any.
+func (h *WSHandler) handle(ctx context.Context, userID int64, sub *realtime.Subscription, msg WSClientMessage) WSServerMessage {
+	reply := WSServerMessage{Ref: msg.Ref, ProjectID: msg.ProjectID}
+	fail := func(code, detail string) WSServerMessage {
+		reply.Type, reply.Error, reply.Detail = "error", code, detail
+		return reply
+	}
+	switch msg.Type {
+	case "subscribe", "unsubscribe", "typing":
+		if msg.ProjectID <= 0 {
+			return fail("invalid_message", "project_id is required")
+		}
+	}
+
+	switch msg.Type {
+	case "ping":
+		reply.Type = "pong"
+
+	case "pong":
+		return WSServerMessage{}
+
+	case "subscribe":
+		_, err := h.Store.Queries.GetProject(ctx, db.GetProjectParams{ID: msg.ProjectID, UserID: userID})
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fail("not_found", "")
+		}
+		if err != nil {
+			return fail("db_error", err.Error())
+		}
+		if err := sub.Watch(ctx, msg.ProjectID); err != nil {
+			if errors.Is(err, realtime.ErrTooManyProjects) {
+				return fail("too_many_projects", err.Error())
+			}
+			return fail("internal_error", err.Error())
+		}
+		reply.Type = "subscribed"
+		reply.Viewers = h.hub.Viewers(msg.ProjectID)
+
+	case "unsubscribe":
+		if err := sub.Unwatch(ctx, msg.ProjectID); err != nil {
+			return fail("internal_error", err.Error())
+		}
+		reply.Type = "unsubscribed"
+
+	case "typing":
+		err := sub.SetTyping(ctx, msg.ProjectID, msg.TaskID, msg.Typing)
+		if errors.Is(err, realtime.ErrNotWatching) {
+			return fail("not_subscribed", err.Error())
+		}
+		if err != nil {
+			return fail("internal_error", err.Error())
+		}
+		return WSServerMessage{}
+
+	default:
+		return fail("invalid_message", "unknown message type "+msg.Type)
+	}
+	return reply
+}
+
+func wsEventMessage(ev realtime.Event) WSServerMessage {
+	switch ev.Kind {
+	case realtime.KindPresence:
+		return WSServerMessage{Type: "presence", ProjectID: ev.Project, Viewers: ev.Viewers}
+	case realtime.KindTyping:
+		typing := ev.Typing
+		return WSServerMessage{Type: "typing", ProjectID: ev.Project, Typing: &typing}
+	case realtime.KindResync:
+		return WSServerMessage{Type: "resync"}
+	}
+	event := newChangeEvent(ev.Change, ev.Data)
+	return WSServerMessage{Type: "change", ProjectID: ev.Change.ProjectID, Event: &event}
+}
@@ -0,0 +1,32 @@
+package handler
+
+import "github.com/pavelc4/auriya-todolist-go/internal/realtime"
+
+// WSClientMessage is a message from a /ws client. Type is one of auth,
+// subscribe, unsubscribe, typing, ping and pong. Ref is copied into the
+// reply so that clients can match the two.
+type WSClientMessage struct {
+	Type      string `json:"type"`
+	Ref       string `json:"ref,omitempty"`
+	Token     string `json:"token,omitempty"`
+	ProjectID int64  `json:"project_id,omitempty"`
+	TaskID    int64  `json:"task_id,omitempty"`
+	Typing    bool   `json:"typing,omitempty"`
+}
+
+// WSServerMessage is a message to a /ws client. Type is one of ready,
+// subscribed, unsubscribed, change, presence, typing, resync, ping, pong
+// and error. A resync means changes were missed and the client should
+// reload what it shows.
+// Viewers is left out when nobody is viewing the project.
+type WSServerMessage struct {
+	Type         string            `json:"type"`
+	Ref          string            `json:"ref,omitempty"`
+	ConnectionID string            `json:"connection_id,omitempty"`
+	ProjectID    int64             `json:"project_id,omitempty"`
+	Event        *ChangeEvent      `json:"event,omitempty"`
+	Viewers      []realtime.Viewer `json:"viewers,omitempty"`
+	Typing       *realtime.Typing  `json:"typing,omitempty"`
+	Error        string            `json:"error,omitempty"`
+	Detail       string            `json:"detail,omitempty"`
+}
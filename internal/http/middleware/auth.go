@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/pavelc4/auriya-todolist-go/internal/http/repository"
 	"github.com/pavelc4/auriya-todolist-go/internal/http/service"
 )
 
@@ -30,6 +31,7 @@ func AuthMiddleware(jwtService *service.JWTService) gin.HandlerFunc {
 		}
 
 		c.Set("userID", userID)
+		c.Request = c.Request.WithContext(repository.WithUser(c.Request.Context(), userID))
 		c.Next()
 	}
 }
@@ -0,0 +1,202 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/pavelc4/auriya-todolist-go/internal/cache"
+	"github.com/pavelc4/auriya-todolist-go/internal/http/repository"
+	"github.com/pavelc4/auriya-todolist-go/internal/http/service"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/time/rate"
+)
+
+// davRealm is sent in the WWW-Authenticate challenge, which calendar apps
+// need before they ask the user for a password.
+const davRealm = `Basic realm="Auriya", charset="UTF-8"`
+
+const (
+	// davPasswordTTL is how long a checked email and password are accepted
+	// without running bcrypt again, so a syncing client's burst of requests
+	// costs one compare. A changed password takes this long to lock out
+	// clients that still send the old one.
+	davPasswordTTL = time.Minute
+
+	// Failed logins are limited per client IP and per account, each to a
+	// burst of davFailureBurst refilled at one every davFailureEvery. A
+	// limiter left alone for davFailureIdle is full again and is forgotten.
+	davFailureBurst = 10
+	davFailureEvery = 30 * time.Second
+	davFailureIdle  = davFailureBurst * davFailureEvery
+)
+
+// DAVAuth authenticates CalDAV clients, which cannot do the JWT login. It
+// takes HTTP basic auth with either the account's email and password or
+// any user name and a personal access token as the password, and a personal
+// access token as a bearer token. Accounts that sign in with OAuth have no
+// password and must use a token.
+//
+// Requests are not rate limited, since clients send bursts of them when
+// they sync, but failed logins are: a client IP with too many recent
+// failures gets 429 until its allowance refills. Wrong passwords for an
+// existing account are also counted against the account, and answered
+// with 429 once it has too many; the right password is still accepted, so
+// guessing cannot lock the owner's clients out.
+func DAVAuth(store *repository.Store, users *repository.UserRepository) gin.HandlerFunc {
+	ips := newFailureLimiter()
+	accounts := newFailureLimiter()
+	passwords := newDAVPasswordCache()
+
+	return func(c *gin.Context) {
+		ip := c.ClientIP()
+		if ips.exhausted(ip) {
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "too_many_requests"})
+			return
+		}
+
+		var userID int64
+		var err error
+		if username, password, ok := c.Request.BasicAuth(); ok {
+			if service.IsPersonalToken(password) {
+				userID, err = personalTokenUser(c, store, password)
+			} else {
+				userID, err = passwords.user(c, users, strings.TrimSpace(username), password)
+			}
+		} else if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok && service.IsPersonalToken(token) {
+			userID, err = personalTokenUser(c, store, token)
+		} else {
+			// No credentials yet; this is how clients ask for the challenge,
+			// so it does not count as a failure.
+			c.Header("WWW-Authenticate", davRealm)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid_credentials"})
+			return
+		}
+		if err != nil {
+			if !errors.Is(err, errBadCredentials) {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+				return
+			}
+			ips.fail(ip)
+			// userID is set when the account exists but the password
+			// is wrong.
+			if userID != 0 {
+				account := strconv.FormatInt(userID, 10)
+				locked := accounts.exhausted(account)
+				accounts.fail(account)
+				if locked {
+					c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "too_many_requests"})
+					return
+				}
+			}
+			c.Header("WWW-Authenticate", davRealm)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid_credentials"})
+			return
+		}
+		c.Set("userID", userID)
+		c.Request = c.Request.WithContext(repository.WithUser(c.Request.Context(), userID))
+		c.Next()
+	}
+}
+
+// failureLimiter limits failed logins by key. Only keys with recent
+// failures have a limiter, and idle ones expire, so keys made up by clients
+// cannot grow it without bound.
+type failureLimiter struct {
+	mu       sync.Mutex
+	limiters *cache.Service
+}
+
+func newFailureLimiter() *failureLimiter {
+	return &failureLimiter{limiters: cache.NewService(davFailureIdle, davFailureIdle)}
+}
+
+// exhausted reports whether key has no allowance left.
+func (f *failureLimiter) exhausted(key string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	l, ok := f.limiters.Get(key)
+	return ok && l.(*rate.Limiter).Tokens() < 1
+}
+
+// fail takes one failure from the allowance of key.
+func (f *failureLimiter) fail(key string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	l, ok := f.limiters.Get(key)
+	if !ok {
+		l = rate.NewLimiter(rate.Every(davFailureEvery), davFailureBurst)
+	}
+	l.(*rate.Limiter).Allow()
+	f.limiters.Set(key, l, davFailureIdle)
+}
+
+var errBadCredentials = errors.New("bad credentials")
+
+func personalTokenUser(c *gin.Context, store *repository.Store, token string) (int64, error) {
+	t, err := store.Queries.UsePersonalToken(c.Request.Context(), service.HashPersonalToken(token))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, errBadCredentials
+	}
+	if err != nil {
+		return 0, err
+	}
+	return t.UserID, nil
+}
+
+// davPasswordCache remembers email and password pairs that passed the
+// bcrypt check for davPasswordTTL. Pairs are keyed by a salted hash, so the
+// passwords themselves are not kept.
+type davPasswordCache struct {
+	salt     []byte
+	verified *cache.Service
+}
+
+func newDAVPasswordCache() *davPasswordCache {
+	salt := make([]byte, 32)
+	rand.Read(salt)
+	return &davPasswordCache{salt: salt, verified: cache.NewService(davPasswordTTL, 5*time.Minute)}
+}
+
+func (p *davPasswordCache) user(c *gin.Context, users *repository.UserRepository, email, password string) (int64, error) {
+	h := sha256.New()
+	h.Write(p.salt)
+	h.Write([]byte(email))
+	h.Write([]byte{0})
+	h.Write([]byte(password))
+	key := hex.EncodeToString(h.Sum(nil))
+	if id, ok := p.verified.Get(key); ok {
+		return id.(int64), nil
+	}
+
+	userID, err := passwordUser(c, users, email, password)
+	if err != nil {
+		return userID, err
+	}
+	p.verified.Set(key, userID, davPasswordTTL)
+	return userID, nil
+}
+
+// passwordUser checks an email and password. For a wrong password of an
+// existing account it returns the account's ID along with errBadCredentials.
+func passwordUser(c *gin.Context, users *repository.UserRepository, email, password string) (int64, error) {
+	user, err := users.GetByEmail(c.Request.Context(), email)
+	if err != nil {
+		return 0, err
+	}
+	if user == nil || user.Provider != "local" {
+		return 0, errBadCredentials
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) != nil {
+		return user.ID, errBadCredentials
+	}
+	return user.ID, nil
+}
@@ -0,0 +1,206 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/pavelc4/auriya-todolist-go/internal/db/sqlc"
+	"github.com/pavelc4/auriya-todolist-go/internal/http/repository"
+)
+
+const (
+	// IdempotencyKeyHeader names the header clients set to make a request
+	// safe to retry.
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on responses replayed from a stored
+	// request.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLen = 255
+	// maxIdempotentBody is the largest request body kept for hashing. It
+	// matches the largest body a handler accepts, an import file.
+	maxIdempotentBody = 20 << 20
+	// idempotencyStaleAfter is how long a request may stay in flight before
+	// its key is considered abandoned, e.g. because the server restarted
+	// while running it. It is well above the server's write timeout.
+	idempotencyStaleAfter = time.Minute
+)
+
+// Idempotency makes POST, PATCH and DELETE requests carrying an
+// Idempotency-Key header safe to retry. Keys are scoped to the user, so it
+// must run after AuthMiddleware. The first request with a key runs as usual
+// and its response is stored for the retention window; repeats get that
+// response back without running again. Reusing a key for a different
+// method, path or body is rejected with 422, and a repeat that arrives while
+// the first request is still running gets 409 and may retry shortly.
+// Responses with a 5xx status are not stored, so the request can be retried.
+func Idempotency(store *repository.Store, retention time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodPost, http.MethodPatch, http.MethodDelete:
+		default:
+			c.Next()
+			return
+		}
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLen {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid_idempotency_key", "detail": "Idempotency-Key must be at most 255 characters"})
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxIdempotentBody))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": "invalid_request", "detail": fmt.Sprintf("the body is larger than %d bytes", maxIdempotentBody)})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "detail": err.Error()})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		userID := c.GetInt64("userID")
+		hash := requestHash(c.Request, body)
+		ctx := c.Request.Context()
+		now := time.Now()
+
+		claim, err := store.Queries.ClaimIdempotencyKey(ctx, db.ClaimIdempotencyKeyParams{
+			UserID:        userID,
+			Key:           key,
+			RequestHash:   hash,
+			ExpiredBefore: pgtype.Timestamptz{Time: now.Add(-retention), Valid: true},
+			StaleBefore:   pgtype.Timestamptz{Time: now.Add(-idempotencyStaleAfter), Valid: true},
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			replayIdempotent(c, store, userID, key, hash)
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+			return
+		}
+
+		// The bookkeeping below must happen even if the client went away.
+		bg := context.WithoutCancel(ctx)
+		completed := false
+		defer func() {
+			if completed {
+				return
+			}
+			err := store.Queries.ReleaseIdempotencyKey(bg, db.ReleaseIdempotencyKeyParams{UserID: userID, Key: key, CreatedAt: claim.CreatedAt})
+			if err != nil {
+				log.Printf("idempotency: release key: %v", err)
+			}
+		}()
+
+		w := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+
+		status := w.Status()
+		if status >= http.StatusInternalServerError {
+			return
+		}
+		contentType := w.Header().Get("Content-Type")
+		headers := make(map[string]string)
+		for _, name := range replayedHeaders {
+			if v := w.Header().Get(name); v != "" {
+				headers[name] = v
+			}
+		}
+		headersJSON, _ := json.Marshal(headers)
+		err = store.Queries.CompleteIdempotencyKey(bg, db.CompleteIdempotencyKeyParams{
+			StatusCode:      pgtype.Int4{Int32: int32(status), Valid: true},
+			ContentType:     pgtype.Text{String: contentType, Valid: contentType != ""},
+			ResponseBody:    w.body.Bytes(),
+			ResponseHeaders: headersJSON,
+			UserID:          userID,
+			Key:             key,
+			CreatedAt:       claim.CreatedAt,
+		})
+		if err != nil {
+			log.Printf("idempotency: store response: %v", err)
+			return
+		}
+		completed = true
+	}
+}
+
+// replayedHeaders are the response headers stored with a response, besides
+// its content type, and sent again when it is replayed.
+var replayedHeaders = []string{"ETag", "Location"}
+
+// replayIdempotent answers a request whose key is already taken: with the
+// stored response, or with an error when the key belongs to a different
+// request or the original one is still running.
+func replayIdempotent(c *gin.Context, store *repository.Store, userID int64, key string, hash []byte) {
+	stored, err := store.Queries.GetIdempotencyKey(c.Request.Context(), db.GetIdempotencyKeyParams{UserID: userID, Key: key})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		return
+	}
+
+	switch {
+	case err == nil && !bytes.Equal(stored.RequestHash, hash):
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "idempotency_key_mismatch", "detail": "this Idempotency-Key was used for a different request"})
+	case err != nil || !stored.StatusCode.Valid:
+		// A missing row means the original request failed and released the
+		// key between our claim and this lookup; a retry will claim it.
+		c.Header("Retry-After", "1")
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "idempotency_key_in_use", "detail": "a request with this Idempotency-Key is still in progress"})
+	default:
+		var headers map[string]string
+		if err := json.Unmarshal(stored.ResponseHeaders, &headers); err != nil {
+			log.Printf("idempotency: stored headers: %v", err)
+		}
+		for name, v := range headers {
+			c.Header(name, v)
+		}
+		c.Header(IdempotentReplayedHeader, "true")
+		c.Data(int(stored.StatusCode.Int32), stored.ContentType.String, stored.ResponseBody)
+		c.Abort()
+	}
+}
+
+// requestHash identifies a request by its method, path with query and body.
+func requestHash(r *http.Request, body []byte) []byte {
+	h := sha256.New()
+	io.WriteString(h, r.Method)
+	h.Write([]byte{0})
+	io.WriteString(h, r.URL.RequestURI())
+	h.Write([]byte{0})
+	h.Write(body)
+	return h.Sum(nil)
+}
+
+// recordingWriter keeps a copy of the response body as it is written.
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	db "github.com/pavelc4/auriya-todolist-go/internal/db/sqlc"
+)
+
+// Checklist changes lock the task row, which serializes them per task, and
+// finish with RefreshTaskChecklist. That keeps the task's item counts current
+// and bumps its updated_at, so clients syncing tasks pick up the change.
+
+// ErrInvalidChecklistMove is returned when an item is placed next to items
+// that are not valid neighbours. The wrapped message says why.
+var ErrInvalidChecklistMove = errors.New("invalid checklist move")
+
+// AddChecklistItem adds an item to a live task, directly after afterID and/or
+// before beforeID, or at the end with neither.
+func AddChecklistItem(ctx context.Context, q *db.Queries, userID, taskID int64, text string, afterID, beforeID *int64) (db.ChecklistItem, db.Task, error) {
+	list, err := lockChecklist(ctx, q, userID, taskID, 0)
+	if err != nil {
+		return db.ChecklistItem{}, db.Task{}, err
+	}
+	slot, err := list.slot(0, afterID, beforeID, ErrInvalidChecklistMove)
+	if err != nil {
+		return db.ChecklistItem{}, db.Task{}, err
+	}
+	position, err := list.keyAt(slot)
+	if err != nil {
+		return db.ChecklistItem{}, db.Task{}, err
+	}
+
+	item, err := q.CreateChecklistItem(ctx, db.CreateChecklistItemParams{
+		TaskID:   taskID,
+		UserID:   userID,
+		Text:     text,
+		Position: position,
+	})
+	if err != nil {
+		return db.ChecklistItem{}, db.Task{}, err
+	}
+	task, err := q.RefreshTaskChecklist(ctx, taskID)
+	return item, task, err
+}
+
+// UpdateChecklistItem changes the text and/or checked state of an item.
+func UpdateChecklistItem(ctx context.Context, q *db.Queries, arg db.UpdateChecklistItemParams) (db.ChecklistItem, db.Task, error) {
+	if _, err := q.GetTaskForUpdate(ctx, db.GetTaskForUpdateParams{ID: arg.TaskID, UserID: arg.UserID}); err != nil {
+		return db.ChecklistItem{}, db.Task{}, err
+	}
+	item, err := q.UpdateChecklistItem(ctx, arg)
+	if err != nil {
+		return db.ChecklistItem{}, db.Task{}, err
+	}
+	task, err := q.RefreshTaskChecklist(ctx, arg.TaskID)
+	return item, task, err
+}
+
+// ToggleChecklistItem flips the checked state of an item.
+func ToggleChecklistItem(ctx context.Context, q *db.Queries, userID, taskID, itemID int64) (db.ChecklistItem, db.Task, error) {
+	if _, err := q.GetTaskForUpdate(ctx, db.GetTaskForUpdateParams{ID: taskID, UserID: userID}); err != nil {
+		return db.ChecklistItem{}, db.Task{}, err
+	}
+	item, err := q.ToggleChecklistItem(ctx, db.ToggleChecklistItemParams{ID: itemID, TaskID: taskID, UserID: userID})
+	if err != nil {
+		return db.ChecklistItem{}, db.Task{}, err
+	}
+	task, err := q.RefreshTaskChecklist(ctx, taskID)
+	return item, task, err
+}
+
+// MoveChecklistItem places an item directly after afterID and/or before
+// beforeID, or at the end with neither.
+func MoveChecklistItem(ctx context.Context, q *db.Queries, userID, taskID, itemID int64, afterID, beforeID *int64) (db.ChecklistItem, db.Task, error) {
+	list, err := lockChecklist(ctx, q, userID, taskID, itemID)
+	if err != nil {
+		return db.ChecklistItem{}, db.Task{}, err
+	}
+	slot, err := list.slot(itemID, afterID, beforeID, ErrInvalidChecklistMove)
+	if err != nil {
+		return db.ChecklistItem{}, db.Task{}, err
+	}
+	position, err := list.keyAt(slot)
+	if err != nil {
+		return db.ChecklistItem{}, db.Task{}, err
+	}
+	if err := q.SetChecklistItemPosition(ctx, db.SetChecklistItemPositionParams{ID: itemID, Position: position}); err != nil {
+		return db.ChecklistItem{}, db.Task{}, err
+	}
+
+	task, err := q.RefreshTaskChecklist(ctx, taskID)
+	if err != nil {
+		return db.ChecklistItem{}, db.Task{}, err
+	}
+	item, err := q.GetChecklistItem(ctx, db.GetChecklistItemParams{ID: itemID, TaskID: taskID, UserID: userID})
+	return item, task, err
+}
+
+// DeleteChecklistItem removes an item from a task.
+func DeleteChecklistItem(ctx context.Context, q *db.Queries, userID, taskID, itemID int64) (db.Task, error) {
+	if _, err := q.GetTaskForUpdate(ctx, db.GetTaskForUpdateParams{ID: taskID, UserID: userID}); err != nil {
+		return db.Task{}, err
+	}
+	n, err := q.DeleteChecklistItem(ctx, db.DeleteChecklistItemParams{ID: itemID, TaskID: taskID, UserID: userID})
+	if err != nil {
+		return db.Task{}, err
+	}
+	if n == 0 {
+		return db.Task{}, pgx.ErrNoRows
+	}
+	return q.RefreshTaskChecklist(ctx, taskID)
+}
+
+// lockChecklist locks a live task and returns its checklist as a rankedList,
+// leaving out excludeID. A non-zero excludeID must be one of the task's items.
+func lockChecklist(ctx context.Context, q *db.Queries, userID, taskID, excludeID int64) (*rankedList, error) {
+	if _, err := q.GetTaskForUpdate(ctx, db.GetTaskForUpdateParams{ID: taskID, UserID: userID}); err != nil {
+		return nil, err
+	}
+	items, err := q.ListChecklistItems(ctx, db.ListChecklistItemsParams{TaskID: taskID, UserID: userID})
+	if err != nil {
+		return nil, err
+	}
+
+	list := &rankedList{setKey: func(id int64, key string) error {
+		return q.SetChecklistItemPosition(ctx, db.SetChecklistItemPositionParams{ID: id, Position: key})
+	}}
+	found := excludeID == 0
+	for _, item := range items {
+		if item.ID == excludeID {
+			found = true
+			continue
+		}
+		list.ids = append(list.ids, item.ID)
+		list.keys = append(list.keys, item.Position)
+	}
+	if !found {
+		return nil, pgx.ErrNoRows
+	}
+	return list, nil
+}
@@ -0,0 +1,335 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/pavelc4/auriya-todolist-go/internal/db/sqlc"
+	"github.com/pavelc4/auriya-todolist-go/internal/rank"
+)
+
+// Task positions are rank keys scoped per user and project, with the inbox
+// (no project) as its own scope. Every write to positions within a scope
+// first takes LockTaskPositions for that scope, so concurrent moves are
+// serialized and always see each other's keys.
+
+// ErrInvalidMove is returned when a move does not describe a valid target
+// position. The wrapped message says why.
+var ErrInvalidMove = errors.New("invalid move")
+
+// TaskMove describes where a task should end up. AfterID and BeforeID name
+// the tasks that should directly precede and follow it; either or both may
+// be nil, and with neither the task goes to the end of the list. ProjectID
+// optionally moves the task to another project and must agree with the
+// neighbours' project when they are given.
+//
+// SectionID optionally moves the task into a section of the target project,
+// with 0 meaning "no section". When it is nil the section is taken from the
+// neighbours, or kept as long as the task stays in the same project.
+type TaskMove struct {
+	AfterID   *int64
+	BeforeID  *int64
+	ProjectID *int64
+	SectionID *int64
+}
+
+// MoveTask repositions a task, possibly into another project. Status changes
+// caused by entering a section are subject to the project's workflow.
+func MoveTask(ctx context.Context, q *db.Queries, actorID, userID, taskID int64, move TaskMove) (db.Task, error) {
+	current, err := q.GetTaskForUpdate(ctx, db.GetTaskForUpdateParams{ID: taskID, UserID: userID})
+	if err != nil {
+		return db.Task{}, err
+	}
+
+	scope := current.ProjectID
+	if move.ProjectID != nil {
+		scope = pgtype.Int8{Int64: *move.ProjectID, Valid: true}
+	}
+
+	neighbour := func(id *int64) (*db.Task, error) {
+		if id == nil {
+			return nil, nil
+		}
+		if *id == taskID {
+			return nil, fmt.Errorf("%w: a task cannot be moved next to itself", ErrInvalidMove)
+		}
+		t, err := q.GetTask(ctx, db.GetTaskParams{ID: *id, UserID: userID})
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%w: task %d not found", ErrInvalidMove, *id)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &t, nil
+	}
+	after, err := neighbour(move.AfterID)
+	if err != nil {
+		return db.Task{}, err
+	}
+	before, err := neighbour(move.BeforeID)
+	if err != nil {
+		return db.Task{}, err
+	}
+	if after != nil && before != nil && after.ProjectID != before.ProjectID {
+		return db.Task{}, fmt.Errorf("%w: tasks %d and %d are in different projects", ErrInvalidMove, after.ID, before.ID)
+	}
+	for _, n := range []*db.Task{after, before} {
+		if n == nil {
+			continue
+		}
+		if move.ProjectID != nil && n.ProjectID != scope {
+			return db.Task{}, fmt.Errorf("%w: task %d is not in project %d", ErrInvalidMove, n.ID, *move.ProjectID)
+		}
+		scope = n.ProjectID
+	}
+
+	if scope.Valid && scope != current.ProjectID {
+		if _, err := q.GetProject(ctx, db.GetProjectParams{ID: scope.Int64, UserID: userID}); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return db.Task{}, fmt.Errorf("%w: project %d not found", ErrInvalidMove, scope.Int64)
+			}
+			return db.Task{}, err
+		}
+	}
+
+	section, err := moveSection(ctx, q, userID, current, scope, move, after, before)
+	if err != nil {
+		return db.Task{}, err
+	}
+	// Within a section the board shows a filtered view of the project order,
+	// so neighbours only need to be in order rather than adjacent.
+	sectioned := section != nil
+
+	// Entering a section that maps to a status moves the task to it.
+	var requested pgtype.Text
+	var sectionID pgtype.Int8
+	if section != nil {
+		sectionID = pgtype.Int8{Int64: section.ID, Valid: true}
+		if sectionID != current.SectionID {
+			requested = section.Status
+		}
+	}
+	status, err := resolveStatus(ctx, q, &current, scope, requested)
+	if err != nil {
+		return db.Task{}, err
+	}
+
+	if err := q.LockTaskPositions(ctx, db.LockTaskPositionsParams{UserID: userID, ProjectID: scope}); err != nil {
+		return db.Task{}, err
+	}
+
+	// bounds reads the keys the new position has to fit between. It runs
+	// under the scope lock and re-reads the neighbours, since they may have
+	// moved between the checks above and taking the lock.
+	bounds := func() (string, string, error) {
+		reread := func(n *db.Task) (string, error) {
+			t, err := q.GetTask(ctx, db.GetTaskParams{ID: n.ID, UserID: userID})
+			if err != nil {
+				return "", err
+			}
+			if t.ProjectID != scope {
+				return "", fmt.Errorf("%w: task %d was moved concurrently", ErrInvalidMove, t.ID)
+			}
+			return t.Position, nil
+		}
+		arg := positionScope{userID: userID, projectID: scope, excludeID: taskID}
+
+		switch {
+		case after != nil && before != nil:
+			lower, err := reread(after)
+			if err != nil {
+				return "", "", err
+			}
+			upper, err := reread(before)
+			if err != nil {
+				return "", "", err
+			}
+			if sectioned {
+				if lower >= upper {
+					return "", "", fmt.Errorf("%w: task %d is not before task %d", ErrInvalidMove, after.ID, before.ID)
+				}
+				return lower, upper, nil
+			}
+			next, err := arg.next(ctx, q, lower)
+			if err != nil {
+				return "", "", err
+			}
+			if next != upper {
+				return "", "", fmt.Errorf("%w: tasks %d and %d are not adjacent", ErrInvalidMove, after.ID, before.ID)
+			}
+			return lower, upper, nil
+		case after != nil:
+			lower, err := reread(after)
+			if err != nil {
+				return "", "", err
+			}
+			upper, err := arg.next(ctx, q, lower)
+			return lower, upper, err
+		case before != nil:
+			upper, err := reread(before)
+			if err != nil {
+				return "", "", err
+			}
+			lower, err := arg.prev(ctx, q, upper)
+			return lower, upper, err
+		default:
+			lower, err := arg.last(ctx, q)
+			return lower, "", err
+		}
+	}
+
+	position, err := newPosition(ctx, q, positionScope{userID: userID, projectID: scope, excludeID: taskID}, bounds)
+	if err != nil {
+		return db.Task{}, err
+	}
+
+	task, err := q.MoveTask(ctx, db.MoveTaskParams{
+		ProjectID:      scope,
+		SectionID:      sectionID,
+		Position:       position,
+		Status:         status.Key,
+		StatusCategory: status.Category,
+		ID:             taskID,
+		UserID:         userID,
+	})
+	if err != nil {
+		return db.Task{}, err
+	}
+	if err := RecordTaskEvent(ctx, q, actorID, TaskUpdated, &current, task); err != nil {
+		return db.Task{}, err
+	}
+	return task, nil
+}
+
+// moveSection resolves the section a moved task ends up in, or nil for none.
+func moveSection(ctx context.Context, q *db.Queries, userID int64, current db.Task, scope pgtype.Int8, move TaskMove, after, before *db.Task) (*db.ProjectSection, error) {
+	var target pgtype.Int8
+	switch {
+	case move.SectionID != nil:
+		target = pgtype.Int8{Int64: *move.SectionID, Valid: *move.SectionID != 0}
+	case after != nil:
+		target = after.SectionID
+	case before != nil:
+		target = before.SectionID
+	case scope == current.ProjectID:
+		target = current.SectionID
+	}
+
+	for _, n := range []*db.Task{after, before} {
+		if n != nil && n.SectionID != target {
+			return nil, fmt.Errorf("%w: task %d is not in the target section", ErrInvalidMove, n.ID)
+		}
+	}
+	if !target.Valid {
+		return nil, nil
+	}
+	if !scope.Valid {
+		return nil, fmt.Errorf("%w: tasks without a project cannot be in a section", ErrInvalidMove)
+	}
+
+	section, err := q.GetSection(ctx, db.GetSectionParams{ID: target.Int64, ProjectID: scope.Int64, UserID: userID})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: section %d not found in project %d", ErrInvalidMove, target.Int64, scope.Int64)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &section, nil
+}
+
+// appendPosition locks the scope and returns a key after its last task.
+func appendPosition(ctx context.Context, q *db.Queries, userID int64, projectID pgtype.Int8, excludeID int64) (string, error) {
+	if err := q.LockTaskPositions(ctx, db.LockTaskPositionsParams{UserID: userID, ProjectID: projectID}); err != nil {
+		return "", err
+	}
+	scope := positionScope{userID: userID, projectID: projectID, excludeID: excludeID}
+	return newPosition(ctx, q, scope, func() (string, string, error) {
+		lower, err := scope.last(ctx, q)
+		return lower, "", err
+	})
+}
+
+// newPosition generates a key between the bounds returned by bounds. When
+// that is impossible (duplicate keys) or the key has grown too long, the
+// scope is rebalanced and the bounds are read again.
+func newPosition(ctx context.Context, q *db.Queries, scope positionScope, bounds func() (string, string, error)) (string, error) {
+	lower, upper, err := bounds()
+	if err != nil {
+		return "", err
+	}
+	key, err := rank.Between(lower, upper)
+	if err == nil && len(key) <= rank.MaxLen {
+		return key, nil
+	}
+
+	if err := scope.rebalance(ctx, q); err != nil {
+		return "", err
+	}
+	if lower, upper, err = bounds(); err != nil {
+		return "", err
+	}
+	return rank.Between(lower, upper)
+}
+
+// positionScope identifies one ordered list of tasks, optionally leaving out
+// the task that is being moved.
+type positionScope struct {
+	userID    int64
+	projectID pgtype.Int8
+	excludeID int64
+}
+
+func (s positionScope) last(ctx context.Context, q *db.Queries) (string, error) {
+	return noRowsAsEmpty(q.GetLastTaskPosition(ctx, db.GetLastTaskPositionParams{
+		UserID:    s.userID,
+		ProjectID: s.projectID,
+		ExcludeID: s.excludeID,
+	}))
+}
+
+func (s positionScope) next(ctx context.Context, q *db.Queries, position string) (string, error) {
+	return noRowsAsEmpty(q.GetNextTaskPosition(ctx, db.GetNextTaskPositionParams{
+		UserID:    s.userID,
+		ProjectID: s.projectID,
+		ExcludeID: s.excludeID,
+		Position:  position,
+	}))
+}
+
+func (s positionScope) prev(ctx context.Context, q *db.Queries, position string) (string, error) {
+	return noRowsAsEmpty(q.GetPrevTaskPosition(ctx, db.GetPrevTaskPositionParams{
+		UserID:    s.userID,
+		ProjectID: s.projectID,
+		ExcludeID: s.excludeID,
+		Position:  position,
+	}))
+}
+
+// rebalance rewrites every key in the scope with evenly spaced short keys,
+// keeping the current order.
+func (s positionScope) rebalance(ctx context.Context, q *db.Queries) error {
+	ids, err := q.ListTaskIDsByPosition(ctx, db.ListTaskIDsByPositionParams{
+		UserID:    s.userID,
+		ProjectID: s.projectID,
+		ExcludeID: s.excludeID,
+	})
+	if err != nil {
+		return err
+	}
+	for i, key := range rank.Spread(len(ids)) {
+		if err := q.SetTaskPosition(ctx, db.SetTaskPositionParams{Position: key, ID: ids[i], UserID: s.userID}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func noRowsAsEmpty(position string, err error) (string, error) {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	return position, err
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/pavelc4/auriya-todolist-go/internal/rank"
+)
+
+// rankedList is a short ordered list (sections of a project, checklist items
+// of a task) that is read whole and worked on in memory while its parent row
+// is locked. ids and keys are in order and exclude the item being placed.
+type rankedList struct {
+	ids  []int64
+	keys []string
+	// setKey persists a new key for an item when the list is rebalanced.
+	setKey func(id int64, key string) error
+}
+
+// slot returns the index in the list at which an item goes so that it
+// directly follows afterID and/or precedes beforeID, or the end with neither.
+// selfID is the item being placed; invalid is wrapped into the errors.
+func (l rankedList) slot(selfID int64, afterID, beforeID *int64, invalid error) (int, error) {
+	index := func(id *int64) (int, error) {
+		if id == nil {
+			return -1, nil
+		}
+		if *id == selfID {
+			return 0, fmt.Errorf("%w: an item cannot be placed next to itself", invalid)
+		}
+		for i, v := range l.ids {
+			if v == *id {
+				return i, nil
+			}
+		}
+		return 0, fmt.Errorf("%w: %d not found", invalid, *id)
+	}
+	after, err := index(afterID)
+	if err != nil {
+		return 0, err
+	}
+	before, err := index(beforeID)
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case afterID != nil && beforeID != nil:
+		if before != after+1 {
+			return 0, fmt.Errorf("%w: %d and %d are not adjacent", invalid, *afterID, *beforeID)
+		}
+		return before, nil
+	case afterID != nil:
+		return after + 1, nil
+	case beforeID != nil:
+		return before, nil
+	default:
+		return len(l.ids), nil
+	}
+}
+
+// keyAt returns a key for an item inserted at slot. When the neighbouring
+// keys leave no room or the key grows too long the list is rebalanced first.
+func (l *rankedList) keyAt(slot int) (string, error) {
+	bounds := func() (string, string) {
+		lower, upper := "", ""
+		if slot > 0 {
+			lower = l.keys[slot-1]
+		}
+		if slot < len(l.keys) {
+			upper = l.keys[slot]
+		}
+		return lower, upper
+	}
+
+	key, err := rank.Between(bounds())
+	if err == nil && len(key) <= rank.MaxLen {
+		return key, nil
+	}
+
+	l.keys = rank.Spread(len(l.ids))
+	for i, id := range l.ids {
+		if err := l.setKey(id, l.keys[i]); err != nil {
+			return "", err
+		}
+	}
+	return rank.Between(bounds())
+}
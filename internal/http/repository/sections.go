@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/pavelc4/auriya-todolist-go/internal/db/sqlc"
+)
+
+// Sections are ordered by rank keys within their project. A project has few
+// sections, so their order is worked out in memory while the project row is
+// locked with GetProjectForUpdate.
+
+// ErrInvalidSectionMove is returned when a section move names neighbours
+// that are not valid. The wrapped message says why.
+var ErrInvalidSectionMove = errors.New("invalid section move")
+
+// CreateSection adds a section at the end of its project's sections. Its
+// status, if any, must be part of the project's workflow.
+func CreateSection(ctx context.Context, q *db.Queries, arg db.CreateSectionParams) (db.ProjectSection, error) {
+	sections, err := lockSections(ctx, q, arg.ProjectID, arg.UserID)
+	if err != nil {
+		return db.ProjectSection{}, err
+	}
+	if err := checkSectionStatus(ctx, q, arg.ProjectID, arg.Status); err != nil {
+		return db.ProjectSection{}, err
+	}
+
+	list := sectionList(ctx, q, sections, 0)
+	if arg.Position, err = list.keyAt(len(list.ids)); err != nil {
+		return db.ProjectSection{}, err
+	}
+	return q.CreateSection(ctx, arg)
+}
+
+// UpdateSection renames a section or changes its status. An empty status
+// clears it; any other status must be part of the project's workflow.
+func UpdateSection(ctx context.Context, q *db.Queries, arg db.UpdateSectionParams) (db.ProjectSection, error) {
+	if _, err := q.GetProjectForUpdate(ctx, db.GetProjectForUpdateParams{ID: arg.ProjectID, UserID: arg.UserID}); err != nil {
+		return db.ProjectSection{}, err
+	}
+	if arg.Status.String != "" {
+		if err := checkSectionStatus(ctx, q, arg.ProjectID, arg.Status); err != nil {
+			return db.ProjectSection{}, err
+		}
+	}
+	return q.UpdateSection(ctx, arg)
+}
+
+// MoveSection places a section directly after afterID and/or before
+// beforeID. With neither it goes to the end.
+func MoveSection(ctx context.Context, q *db.Queries, userID, projectID, sectionID int64, afterID, beforeID *int64) (db.ProjectSection, error) {
+	sections, err := lockSections(ctx, q, projectID, userID)
+	if err != nil {
+		return db.ProjectSection{}, err
+	}
+	found := false
+	for _, s := range sections {
+		found = found || s.ID == sectionID
+	}
+	if !found {
+		return db.ProjectSection{}, pgx.ErrNoRows
+	}
+
+	list := sectionList(ctx, q, sections, sectionID)
+	slot, err := list.slot(sectionID, afterID, beforeID, ErrInvalidSectionMove)
+	if err != nil {
+		return db.ProjectSection{}, err
+	}
+	position, err := list.keyAt(slot)
+	if err != nil {
+		return db.ProjectSection{}, err
+	}
+
+	if err := q.SetSectionPosition(ctx, db.SetSectionPositionParams{ID: sectionID, Position: position}); err != nil {
+		return db.ProjectSection{}, err
+	}
+	return q.GetSection(ctx, db.GetSectionParams{ID: sectionID, ProjectID: projectID, UserID: userID})
+}
+
+func checkSectionStatus(ctx context.Context, q *db.Queries, projectID int64, status pgtype.Text) error {
+	if !status.Valid {
+		return nil
+	}
+	w, err := LoadWorkflow(ctx, q, pgtype.Int8{Int64: projectID, Valid: true})
+	if err != nil {
+		return err
+	}
+	_, err = w.Lookup(status.String)
+	return err
+}
+
+// lockSections locks the project and returns its sections in order.
+func lockSections(ctx context.Context, q *db.Queries, projectID, userID int64) ([]db.ProjectSection, error) {
+	if _, err := q.GetProjectForUpdate(ctx, db.GetProjectForUpdateParams{ID: projectID, UserID: userID}); err != nil {
+		return nil, err
+	}
+	return q.ListSections(ctx, db.ListSectionsParams{ProjectID: projectID, UserID: userID})
+}
+
+// sectionList returns the ordered sections, leaving out excludeID.
+func sectionList(ctx context.Context, q *db.Queries, sections []db.ProjectSection, excludeID int64) *rankedList {
+	list := &rankedList{setKey: func(id int64, key string) error {
+		return q.SetSectionPosition(ctx, db.SetSectionPositionParams{ID: id, Position: key})
+	}}
+	for _, s := range sections {
+		if s.ID != excludeID {
+			list.ids = append(list.ids, s.ID)
+			list.keys = append(list.keys, s.Position)
+		}
+	}
+	return list
+}
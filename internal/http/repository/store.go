@@ -2,6 +2,9 @@
 package repository
 
 import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	db "github.com/pavelc4/auriya-todolist-go/internal/db/sqlc"
 )
@@ -17,3 +20,101 @@ func NewStore(pool *pgxpool.Pool) *Store {
 		Queries: db.New(pool),
 	}
 }
+
+// userKey is the context key of the user on whose behalf work is done.
+type userKey struct{}
+
+// WithUser returns a copy of ctx for work done on behalf of userID. Write
+// transactions started with it take the user's sync lock first.
+func WithUser(ctx context.Context, userID int64) context.Context {
+	return context.WithValue(ctx, userKey{}, userID)
+}
+
+// lockSync takes the sync lock of the user of ctx, if it has one. The
+// record_change and record_tombstone triggers take the same lock when a
+// task or project is written, by which time the transaction holds row
+// locks; taking it before anything else keeps every transaction of a user
+// acquiring its locks in the same order, so they cannot deadlock.
+func lockSync(ctx context.Context, q *db.Queries) error {
+	userID, ok := ctx.Value(userKey{}).(int64)
+	if !ok {
+		return nil
+	}
+	return q.LockSyncChanges(ctx, userID)
+}
+
+// ExecTx runs fn inside a single transaction. The transaction is committed
+// when fn returns nil and rolled back otherwise.
+func (s *Store) ExecTx(ctx context.Context, fn func(q *db.Queries) error) error {
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	q := s.Queries.WithTx(tx)
+	if err := lockSync(ctx, q); err != nil {
+		return err
+	}
+	if err := fn(q); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ExecSnapshot runs fn inside a read-only transaction in which every query
+// sees the same snapshot of the database.
+func (s *Store) ExecSnapshot(ctx context.Context, fn func(q *db.Queries) error) error {
+	tx, err := s.DB.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(s.Queries.WithTx(tx)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// Tx is a transaction started by ExecTxSteps. Queries runs directly in the
+// transaction; Savepoint runs a step that may fail without aborting it.
+type Tx struct {
+	Queries *db.Queries
+	tx      pgx.Tx
+}
+
+// Savepoint runs fn inside a savepoint. When fn returns an error only the
+// changes it made are rolled back and the transaction can carry on.
+func (t *Tx) Savepoint(ctx context.Context, fn func(q *db.Queries) error) error {
+	sp, err := t.tx.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer sp.Rollback(ctx)
+
+	if err := fn(t.Queries.WithTx(sp)); err != nil {
+		return err
+	}
+	return sp.Commit(ctx)
+}
+
+// ExecTxSteps is ExecTx for work made of steps that may fail one by one, such
+// as bulk edits that report a result per item. The transaction is committed
+// when fn returns nil, keeping the steps that succeeded.
+func (s *Store) ExecTxSteps(ctx context.Context, fn func(tx *Tx) error) error {
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	q := s.Queries.WithTx(tx)
+	if err := lockSync(ctx, q); err != nil {
+		return err
+	}
+	if err := fn(&Tx{Queries: q, tx: tx}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/pavelc4/auriya-todolist-go/internal/db/sqlc"
+)
+
+// Actions recorded in task_events.
+const (
+	TaskCreated  = "created"
+	TaskUpdated  = "updated"
+	TaskDeleted  = "deleted"
+	TaskRestored = "restored"
+)
+
+// FieldChange is the before and after value of a single task field, encoded
+// the same way the field appears in a TaskSnapshot.
+type FieldChange struct {
+	Old json.RawMessage `json:"old"`
+	New json.RawMessage `json:"new"`
+}
+
+// TaskSnapshot is the part of a task that history tracks. Its JSON encoding
+// defines the field names and value formats used in task_events.changes.
+type TaskSnapshot struct {
+	Title       string     `json:"title"`
+	Description *string    `json:"description"`
+	Status      string     `json:"status"`
+	Priority    int32      `json:"priority"`
+	DueDate     *time.Time `json:"due_date"`
+	Estimate    *int32     `json:"estimate"`
+	Tags        []string   `json:"tags"`
+	Recurrence  *string    `json:"recurrence"`
+	ProjectID   *int64     `json:"project_id"`
+	SectionID   *int64     `json:"section_id"`
+	DeletedAt   *time.Time `json:"deleted_at"`
+}
+
+// SnapshotTask extracts the tracked fields of a task.
+func SnapshotTask(t db.Task) TaskSnapshot {
+	return TaskSnapshot{
+		Title:       t.Title,
+		Description: t.Description,
+		Status:      t.Status,
+		Priority:    t.Priority,
+		DueDate:     timePtr(t.DueDate),
+		Estimate:    int4Ptr(t.EstimateSeconds),
+		Tags:        tagsOrNil(t.Tags),
+		Recurrence:  t.Recurrence,
+		ProjectID:   int8Ptr(t.ProjectID),
+		SectionID:   int8Ptr(t.SectionID),
+		DeletedAt:   timePtr(t.DeletedAt),
+	}
+}
+
+// DiffTasks returns the fields that differ between before and after. A nil
+// before (a newly created task) reports every non-empty field of after.
+func DiffTasks(before *db.Task, after db.Task) (map[string]FieldChange, error) {
+	newFields, err := snapshotFields(after)
+	if err != nil {
+		return nil, err
+	}
+	oldFields := map[string]json.RawMessage{}
+	if before != nil {
+		if oldFields, err = snapshotFields(*before); err != nil {
+			return nil, err
+		}
+	}
+
+	changes := make(map[string]FieldChange)
+	for field, newValue := range newFields {
+		oldValue, ok := oldFields[field]
+		if !ok {
+			oldValue = json.RawMessage("null")
+		}
+		if bytes.Equal(oldValue, newValue) {
+			continue
+		}
+		changes[field] = FieldChange{Old: oldValue, New: newValue}
+	}
+	return changes, nil
+}
+
+// RecordTaskEvent stores a history entry for a task mutation. It must be
+// called with the same transaction-bound queries that performed the change.
+// Updates that did not change any tracked field are not recorded.
+func RecordTaskEvent(ctx context.Context, q *db.Queries, actorID int64, action string, before *db.Task, after db.Task) error {
+	return recordTaskEvent(ctx, q, actorID, action, before, after, 0)
+}
+
+// recordTaskEvent is RecordTaskEvent for undo, which links the new event to
+// the event it reverts.
+func recordTaskEvent(ctx context.Context, q *db.Queries, actorID int64, action string, before *db.Task, after db.Task, revertsEventID int64) error {
+	changes, err := DiffTasks(before, after)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 && action == TaskUpdated {
+		return nil
+	}
+
+	payload, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.CreateTaskEvent(ctx, db.CreateTaskEventParams{
+		TaskID:         after.ID,
+		UserID:         after.UserID,
+		ActorID:        pgtype.Int8{Int64: actorID, Valid: actorID != 0},
+		Action:         action,
+		Changes:        payload,
+		RevertsEventID: pgtype.Int8{Int64: revertsEventID, Valid: revertsEventID != 0},
+	})
+	return err
+}
+
+func snapshotFields(t db.Task) (map[string]json.RawMessage, error) {
+	raw, err := json.Marshal(SnapshotTask(t))
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(raw, &fields)
+	return fields, err
+}
+
+func timePtr(t pgtype.Timestamptz) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func int4Ptr(v pgtype.Int4) *int32 {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Int32
+}
+
+func int8Ptr(v pgtype.Int8) *int64 {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Int64
+}
+
+// tagsOrNil records "no tags" as null, so new tasks do not report a change.
+func tagsOrNil(tags []string) []string {
+	if len(tags) == 0 {
+		return nil
+	}
+	return tags
+}
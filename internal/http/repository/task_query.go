@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	db "github.com/pavelc4/auriya-todolist-go/internal/db/sqlc"
+	"github.com/pavelc4/auriya-todolist-go/internal/taskquery"
+)
+
+// ListTasksMatching is ListTasks further restricted by a condition compiled
+// with package taskquery. It is built at run time, so it cannot be one of
+// the generated queries; the condition's placeholders come first and the
+// ListTasks arguments are numbered after them.
+func ListTasksMatching(ctx context.Context, conn db.DBTX, cond taskquery.Condition, arg db.ListTasksParams) ([]db.Task, error) {
+	n := len(cond.Args)
+	sql := fmt.Sprintf(`SELECT * FROM tasks
+WHERE user_id = $%[1]d
+  AND deleted_at IS NULL
+  AND ($%[2]d::text IS NULL OR status = $%[2]d::text)
+  AND ($%[3]d::timestamptz IS NULL OR due_date <= $%[3]d::timestamptz)
+  AND %[7]s
+ORDER BY
+  CASE WHEN $%[4]d::text = 'position' THEN position END,
+  created_at DESC
+LIMIT $%[5]d OFFSET $%[6]d`, n+1, n+2, n+3, n+4, n+5, n+6, cond.SQL)
+
+	args := append(cond.Args[:n:n], arg.UserID, arg.Status, arg.DueBefore, arg.Sort, arg.Limit, arg.Offset)
+	rows, err := conn.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByName[db.Task])
+}
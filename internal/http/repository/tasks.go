@@ -0,0 +1,310 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/pavelc4/auriya-todolist-go/internal/db/sqlc"
+	"github.com/pavelc4/auriya-todolist-go/internal/recur"
+)
+
+var (
+	// ErrProjectInTrash is returned when a task cannot be restored on its own
+	// because the project it belongs to is still in the trash.
+	ErrProjectInTrash = errors.New("project is in the trash")
+	// ErrInvalidRecurrence is returned for repeat rules outside the subset
+	// package recur supports. The wrapped message says why.
+	ErrInvalidRecurrence = errors.New("invalid recurrence")
+	// ErrProjectNotFound is returned when a task is put into a project the
+	// user does not have, or that is in the trash. It wraps pgx.ErrNoRows.
+	ErrProjectNotFound = fmt.Errorf("project not found: %w", pgx.ErrNoRows)
+)
+
+// The functions below are the single write path for tasks. Each one performs
+// the mutation and records it in task_events using the caller's queries, so
+// running them inside Store.ExecTx keeps the change and its history atomic.
+
+// CreateTask inserts a task on behalf of actorID.
+// The task is placed at the end of its project's manual order. arg.Status may
+// be a status key of the project's workflow, or nil or empty for the
+// workflow's default status.
+func CreateTask(ctx context.Context, q *db.Queries, actorID int64, arg db.CreateTaskParams) (db.Task, error) {
+	arg.Tags = normalizeTags(arg.Tags)
+	recurrence, err := normalizeRecurrence(arg.Recurrence)
+	if err != nil {
+		return db.Task{}, err
+	}
+	arg.Recurrence = recurrence
+
+	if err := checkProject(ctx, q, arg.UserID, arg.ProjectID); err != nil {
+		return db.Task{}, err
+	}
+	var requested pgtype.Text
+	if s, ok := arg.Status.(string); ok && s != "" {
+		requested = pgtype.Text{String: s, Valid: true}
+	}
+	status, err := resolveStatus(ctx, q, nil, arg.ProjectID, requested)
+	if err != nil {
+		return db.Task{}, err
+	}
+	arg.Status = status.Key
+	arg.StatusCategory = status.Category
+
+	position, err := appendPosition(ctx, q, arg.UserID, arg.ProjectID, 0)
+	if err != nil {
+		return db.Task{}, err
+	}
+	arg.Position = position
+
+	task, err := q.CreateTask(ctx, arg)
+	if err != nil {
+		return db.Task{}, err
+	}
+	if err := RecordTaskEvent(ctx, q, actorID, TaskCreated, nil, task); err != nil {
+		return db.Task{}, err
+	}
+	return task, nil
+}
+
+// checkProject fails with ErrProjectNotFound unless project is empty or one
+// of the user's live projects, so that no other user's workflow or task
+// order is read or written.
+func checkProject(ctx context.Context, q *db.Queries, userID int64, project pgtype.Int8) error {
+	if !project.Valid {
+		return nil
+	}
+	_, err := q.GetProject(ctx, db.GetProjectParams{ID: project.Int64, UserID: userID})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrProjectNotFound
+	}
+	return err
+}
+
+// UpdateTask applies a partial update. The row is locked first so the
+// recorded "before" values are exactly the ones being replaced. A status
+// change must be allowed by the project's workflow. A task that changes
+// project is moved to the end of the new project's manual order, and its
+// status is mapped onto the new project's workflow; arg.ClearProject moves
+// it to the inbox. A non-nil, empty arg.Tags removes all tags and an empty
+// arg.Recurrence removes the rule.
+func UpdateTask(ctx context.Context, q *db.Queries, actorID int64, arg db.UpdateTaskParams) (db.Task, error) {
+	arg.Tags = normalizeTags(arg.Tags)
+	recurrence, err := normalizeRecurrence(arg.Recurrence)
+	if err != nil {
+		return db.Task{}, err
+	}
+	arg.Recurrence = recurrence
+
+	before, err := q.GetTaskForUpdate(ctx, db.GetTaskForUpdateParams{ID: arg.ID, UserID: arg.UserID})
+	if err != nil {
+		return db.Task{}, err
+	}
+	project := before.ProjectID
+	switch {
+	case arg.ClearProject:
+		project = pgtype.Int8{}
+	case arg.ProjectID.Valid:
+		project = arg.ProjectID
+	}
+	if project != before.ProjectID {
+		if err := checkProject(ctx, q, arg.UserID, project); err != nil {
+			return db.Task{}, err
+		}
+	}
+	status, err := resolveStatus(ctx, q, &before, project, arg.Status)
+	if err != nil {
+		return db.Task{}, err
+	}
+	arg.Status = pgtype.Text{String: status.Key, Valid: true}
+	arg.StatusCategory = pgtype.Text{String: status.Category, Valid: true}
+	task, err := q.UpdateTask(ctx, arg)
+	if err != nil {
+		return db.Task{}, err
+	}
+	if task.ProjectID != before.ProjectID {
+		position, err := appendPosition(ctx, q, task.UserID, task.ProjectID, task.ID)
+		if err != nil {
+			return db.Task{}, err
+		}
+		err = q.SetTaskPosition(ctx, db.SetTaskPositionParams{Position: position, ID: task.ID, UserID: task.UserID})
+		if err != nil {
+			return db.Task{}, err
+		}
+		task.Position = position
+	}
+	if err := RecordTaskEvent(ctx, q, actorID, TaskUpdated, &before, task); err != nil {
+		return db.Task{}, err
+	}
+	return task, nil
+}
+
+// CompleteTask sets a task to the first "done" status of its project's
+// workflow. A task that is already done is returned unchanged. Workflows
+// without a done status fail with ErrUnknownStatus.
+func CompleteTask(ctx context.Context, q *db.Queries, actorID, userID, taskID int64) (db.Task, error) {
+	task, err := q.GetTaskForUpdate(ctx, db.GetTaskForUpdateParams{ID: taskID, UserID: userID})
+	if err != nil {
+		return db.Task{}, err
+	}
+	if task.StatusCategory == CategoryDone {
+		return task, nil
+	}
+	w, err := LoadWorkflow(ctx, q, task.ProjectID)
+	if err != nil {
+		return db.Task{}, err
+	}
+	done, ok := w.First(CategoryDone)
+	if !ok {
+		return db.Task{}, fmt.Errorf("%w: the workflow has no done status", ErrUnknownStatus)
+	}
+	return UpdateTask(ctx, q, actorID, db.UpdateTaskParams{
+		ID:     taskID,
+		UserID: userID,
+		Status: pgtype.Text{String: done.Key, Valid: true},
+	})
+}
+
+// DeleteTask moves a live task to the trash, stopping its running timer.
+func DeleteTask(ctx context.Context, q *db.Queries, actorID int64, arg db.DeleteTaskParams) (db.Task, error) {
+	return deleteTask(ctx, q, actorID, arg, 0)
+}
+
+func deleteTask(ctx context.Context, q *db.Queries, actorID int64, arg db.DeleteTaskParams, revertsEventID int64) (db.Task, error) {
+	before, err := q.GetTaskForUpdate(ctx, db.GetTaskForUpdateParams{ID: arg.ID, UserID: arg.UserID})
+	if err != nil {
+		return db.Task{}, err
+	}
+	if err := stopTaskTimers(ctx, q, arg.ID); err != nil {
+		return db.Task{}, err
+	}
+	task, err := q.DeleteTask(ctx, arg)
+	if err != nil {
+		return db.Task{}, err
+	}
+	if err := recordTaskEvent(ctx, q, actorID, TaskDeleted, &before, task, revertsEventID); err != nil {
+		return db.Task{}, err
+	}
+	return task, nil
+}
+
+// RestoreTask brings a task back from the trash. It fails with
+// ErrProjectInTrash while the task's project is itself trashed.
+func RestoreTask(ctx context.Context, q *db.Queries, actorID int64, arg db.RestoreTaskParams) (db.Task, error) {
+	return restoreTask(ctx, q, actorID, arg, 0)
+}
+
+func restoreTask(ctx context.Context, q *db.Queries, actorID int64, arg db.RestoreTaskParams, revertsEventID int64) (db.Task, error) {
+	before, err := q.GetTrashedTask(ctx, db.GetTrashedTaskParams{ID: arg.ID, UserID: arg.UserID})
+	if err != nil {
+		return db.Task{}, err
+	}
+	if before.ProjectID.Valid {
+		_, err := q.GetTrashedProject(ctx, db.GetTrashedProjectParams{ID: before.ProjectID.Int64, UserID: arg.UserID})
+		if err == nil {
+			return db.Task{}, ErrProjectInTrash
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return db.Task{}, err
+		}
+	}
+	task, err := q.RestoreTask(ctx, arg)
+	if err != nil {
+		return db.Task{}, err
+	}
+	if err := recordTaskEvent(ctx, q, actorID, TaskRestored, &before, task, revertsEventID); err != nil {
+		return db.Task{}, err
+	}
+	return task, nil
+}
+
+// TrashProject moves a project and all of its live tasks to the trash using
+// one shared deleted_at, and returns the tasks that were trashed with it.
+func TrashProject(ctx context.Context, q *db.Queries, actorID int64, arg db.DeleteProjectParams) ([]db.Task, error) {
+	project, err := q.DeleteProject(ctx, arg)
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := q.TrashProjectTasks(ctx, db.TrashProjectTasksParams{
+		DeletedAt: project.DeletedAt,
+		ProjectID: pgtype.Int8{Int64: project.ID, Valid: true},
+		UserID:    arg.UserID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, task := range tasks {
+		before := task
+		before.DeletedAt = pgtype.Timestamptz{}
+		if err := RecordTaskEvent(ctx, q, actorID, TaskDeleted, &before, task); err != nil {
+			return nil, err
+		}
+		if err := stopTaskTimers(ctx, q, task.ID); err != nil {
+			return nil, err
+		}
+	}
+	return tasks, nil
+}
+
+// RestoreProject brings a project back from the trash together with exactly
+// the tasks that were trashed along with it.
+func RestoreProject(ctx context.Context, q *db.Queries, actorID int64, arg db.RestoreProjectParams) (db.Project, error) {
+	trashed, err := q.GetTrashedProject(ctx, db.GetTrashedProjectParams{ID: arg.ID, UserID: arg.UserID})
+	if err != nil {
+		return db.Project{}, err
+	}
+	project, err := q.RestoreProject(ctx, arg)
+	if err != nil {
+		return db.Project{}, err
+	}
+	tasks, err := q.RestoreProjectTasks(ctx, db.RestoreProjectTasksParams{
+		ProjectID: pgtype.Int8{Int64: project.ID, Valid: true},
+		UserID:    arg.UserID,
+		DeletedAt: trashed.DeletedAt,
+	})
+	if err != nil {
+		return db.Project{}, err
+	}
+	for _, task := range tasks {
+		before := task
+		before.DeletedAt = trashed.DeletedAt
+		if err := RecordTaskEvent(ctx, q, actorID, TaskRestored, &before, task); err != nil {
+			return db.Project{}, err
+		}
+	}
+	return project, nil
+}
+
+// normalizeTags lowercases and trims tags, dropping empty and repeated ones.
+// A nil slice stays nil so updates can tell "unchanged" from "no tags".
+func normalizeTags(tags []string) []string {
+	if tags == nil {
+		return nil
+	}
+	out := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(tag, "@")))
+		if tag != "" && !slices.Contains(out, tag) {
+			out = append(out, tag)
+		}
+	}
+	return out
+}
+
+// normalizeRecurrence validates a repeat rule and returns it in canonical
+// form. Nil and empty rules are returned as they are.
+func normalizeRecurrence(rule *string) (*string, error) {
+	if rule == nil || *rule == "" {
+		return rule, nil
+	}
+	r, err := recur.Parse(*rule)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRecurrence, err)
+	}
+	s := r.String()
+	return &s, nil
+}
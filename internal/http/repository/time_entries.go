@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/pavelc4/auriya-todolist-go/internal/db/sqlc"
+)
+
+// Every change to a task's finished time entries is followed by
+// RefreshTaskTimeSpent so tasks.time_spent_seconds stays the sum of them.
+
+var (
+	// ErrTimerRunning is returned when a timer is started while the user
+	// already has one running.
+	ErrTimerRunning = errors.New("a timer is already running")
+	// ErrNoRunningTimer is returned when stopping a task's timer that is
+	// not running.
+	ErrNoRunningTimer = errors.New("no timer is running for this task")
+	// ErrInvalidTimeEntry is returned for entries that end before they start
+	// or lie in the future.
+	ErrInvalidTimeEntry = errors.New("invalid time entry")
+)
+
+// TimerConflictError is returned by StartTimer when another timer is running.
+// It wraps ErrTimerRunning and carries the running entry.
+type TimerConflictError struct {
+	Running db.TimeEntry
+}
+
+func (e *TimerConflictError) Error() string {
+	return fmt.Sprintf("%s (entry %d on task %d)", ErrTimerRunning, e.Running.ID, e.Running.TaskID)
+}
+
+func (e *TimerConflictError) Unwrap() error { return ErrTimerRunning }
+
+// StartTimer starts a timer on a live task. If the user already has a timer
+// running it fails with a *TimerConflictError, unless stopRunning is set, in
+// which case that timer is stopped first and returned as stopped.
+func StartTimer(ctx context.Context, q *db.Queries, userID, taskID int64, note *string, stopRunning bool) (entry db.TimeEntry, stopped *db.TimeEntry, err error) {
+	if _, err := q.GetTaskForUpdate(ctx, db.GetTaskForUpdateParams{ID: taskID, UserID: userID}); err != nil {
+		return db.TimeEntry{}, nil, err
+	}
+
+	running, err := q.GetRunningTimeEntry(ctx, userID)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+	case err != nil:
+		return db.TimeEntry{}, nil, err
+	case !stopRunning:
+		return db.TimeEntry{}, nil, &TimerConflictError{Running: running}
+	default:
+		s, err := stopTimeEntry(ctx, q, running.ID)
+		if err != nil {
+			return db.TimeEntry{}, nil, err
+		}
+		stopped = &s
+	}
+
+	entry, err = q.StartTimeEntry(ctx, db.StartTimeEntryParams{TaskID: taskID, UserID: userID, Note: note})
+	if err != nil {
+		// A concurrent start won the race for the user's running slot.
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return db.TimeEntry{}, nil, ErrTimerRunning
+		}
+		return db.TimeEntry{}, nil, err
+	}
+	return entry, stopped, nil
+}
+
+// StopTimer stops the user's running timer on a task.
+func StopTimer(ctx context.Context, q *db.Queries, userID, taskID int64) (db.TimeEntry, error) {
+	running, err := q.GetRunningTimeEntry(ctx, userID)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && running.TaskID != taskID) {
+		return db.TimeEntry{}, ErrNoRunningTimer
+	}
+	if err != nil {
+		return db.TimeEntry{}, err
+	}
+	return stopTimeEntry(ctx, q, running.ID)
+}
+
+func stopTimeEntry(ctx context.Context, q *db.Queries, id int64) (db.TimeEntry, error) {
+	entry, err := q.StopTimeEntry(ctx, id)
+	if err != nil {
+		return db.TimeEntry{}, err
+	}
+	return entry, q.RefreshTaskTimeSpent(ctx, entry.TaskID)
+}
+
+// TimeEntryInput is a manually entered or edited time entry. A nil EndedAt
+// on an edit leaves a running timer running.
+type TimeEntryInput struct {
+	StartedAt time.Time
+	EndedAt   *time.Time
+	Note      *string
+}
+
+func (in TimeEntryInput) validate(now time.Time) error {
+	if in.StartedAt.After(now) {
+		return fmt.Errorf("%w: started_at is in the future", ErrInvalidTimeEntry)
+	}
+	if in.EndedAt != nil {
+		if in.EndedAt.Before(in.StartedAt) {
+			return fmt.Errorf("%w: ended_at is before started_at", ErrInvalidTimeEntry)
+		}
+		if in.EndedAt.After(now) {
+			return fmt.Errorf("%w: ended_at is in the future", ErrInvalidTimeEntry)
+		}
+	}
+	return nil
+}
+
+// CreateTimeEntry records time already spent on a live task.
+func CreateTimeEntry(ctx context.Context, q *db.Queries, userID, taskID int64, in TimeEntryInput) (db.TimeEntry, error) {
+	if in.EndedAt == nil {
+		return db.TimeEntry{}, fmt.Errorf("%w: ended_at is required", ErrInvalidTimeEntry)
+	}
+	if err := in.validate(time.Now()); err != nil {
+		return db.TimeEntry{}, err
+	}
+	if _, err := q.GetTaskForUpdate(ctx, db.GetTaskForUpdateParams{ID: taskID, UserID: userID}); err != nil {
+		return db.TimeEntry{}, err
+	}
+
+	entry, err := q.CreateTimeEntry(ctx, db.CreateTimeEntryParams{
+		TaskID:    taskID,
+		UserID:    userID,
+		StartedAt: pgtype.Timestamptz{Time: in.StartedAt, Valid: true},
+		EndedAt:   pgtype.Timestamptz{Time: *in.EndedAt, Valid: true},
+		Note:      in.Note,
+	})
+	if err != nil {
+		return db.TimeEntry{}, err
+	}
+	return entry, q.RefreshTaskTimeSpent(ctx, taskID)
+}
+
+// UpdateTimeEntry replaces the times and note of an entry. update receives
+// the current entry and returns the new values.
+func UpdateTimeEntry(ctx context.Context, q *db.Queries, userID, taskID, entryID int64, update func(db.TimeEntry) TimeEntryInput) (db.TimeEntry, error) {
+	current, err := q.GetTimeEntry(ctx, db.GetTimeEntryParams{ID: entryID, TaskID: taskID, UserID: userID})
+	if err != nil {
+		return db.TimeEntry{}, err
+	}
+
+	in := update(current)
+	if current.EndedAt.Valid && in.EndedAt == nil {
+		return db.TimeEntry{}, fmt.Errorf("%w: a finished entry cannot be restarted", ErrInvalidTimeEntry)
+	}
+	if err := in.validate(time.Now()); err != nil {
+		return db.TimeEntry{}, err
+	}
+
+	arg := db.UpdateTimeEntryParams{
+		StartedAt: pgtype.Timestamptz{Time: in.StartedAt, Valid: true},
+		Note:      in.Note,
+		ID:        entryID,
+	}
+	if in.EndedAt != nil {
+		arg.EndedAt = pgtype.Timestamptz{Time: *in.EndedAt, Valid: true}
+	}
+	entry, err := q.UpdateTimeEntry(ctx, arg)
+	if err != nil {
+		return db.TimeEntry{}, err
+	}
+	return entry, q.RefreshTaskTimeSpent(ctx, taskID)
+}
+
+// DeleteTimeEntry removes an entry, running or not.
+func DeleteTimeEntry(ctx context.Context, q *db.Queries, userID, taskID, entryID int64) error {
+	if _, err := q.DeleteTimeEntry(ctx, db.DeleteTimeEntryParams{ID: entryID, TaskID: taskID, UserID: userID}); err != nil {
+		return err
+	}
+	return q.RefreshTaskTimeSpent(ctx, taskID)
+}
+
+// StopStaleTimers ends the user's timers that have been running for longer
+// than max, crediting max to each, and returns the entries it stopped.
+func StopStaleTimers(ctx context.Context, q *db.Queries, userID int64, max time.Duration) ([]db.TimeEntry, error) {
+	entries, err := q.StopStaleTimeEntries(ctx, db.StopStaleTimeEntriesParams{MaxSeconds: int64(max / time.Second), UserID: userID})
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if err := q.RefreshTaskTimeSpent(ctx, e.TaskID); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// stopTaskTimers ends any timer running on a task, e.g. when it is trashed.
+func stopTaskTimers(ctx context.Context, q *db.Queries, taskID int64) error {
+	n, err := q.StopTaskTimeEntries(ctx, taskID)
+	if err != nil || n == 0 {
+		return err
+	}
+	return q.RefreshTaskTimeSpent(ctx, taskID)
+}
@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/pavelc4/auriya-todolist-go/internal/db/sqlc"
+)
+
+// ErrUndoConflict is returned when an event can no longer be undone. The
+// wrapped message says why.
+var ErrUndoConflict = errors.New("cannot undo")
+
+// UndoTaskEvent reverts a recorded task event:
+//
+//   - "updated" puts the changed fields back to their old values
+//   - "deleted" restores the task from the trash
+//   - "created" and "restored" move the task (back) to the trash
+//
+// Only the most recent event of a task that has not been undone yet can be
+// reverted, and only within window of it being recorded. Undos are recorded
+// as events of their own that point at the reverted event, so repeated undos
+// walk back through the history one step at a time.
+func UndoTaskEvent(ctx context.Context, q *db.Queries, actorID int64, event db.TaskEvent, window time.Duration) (db.Task, error) {
+	if event.RevertsEventID.Valid {
+		return db.Task{}, fmt.Errorf("%w: event %d is itself an undo", ErrUndoConflict, event.ID)
+	}
+	if event.UndoneAt.Valid {
+		return db.Task{}, fmt.Errorf("%w: event %d has already been undone", ErrUndoConflict, event.ID)
+	}
+	if time.Since(event.CreatedAt.Time) > window {
+		return db.Task{}, fmt.Errorf("%w: event %d is older than the %s undo window", ErrUndoConflict, event.ID, window)
+	}
+
+	// Lock the task so concurrent mutations or undos cannot interleave with
+	// the "is this still the latest event" check below.
+	current, err := q.LockTask(ctx, db.LockTaskParams{ID: event.TaskID, UserID: event.UserID})
+	if err != nil {
+		return db.Task{}, err
+	}
+	latest, err := q.GetLatestUndoableTaskEvent(ctx, db.GetLatestUndoableTaskEventParams{TaskID: event.TaskID, UserID: event.UserID})
+	if err != nil {
+		return db.Task{}, err
+	}
+	if latest.ID != event.ID {
+		return db.Task{}, fmt.Errorf("%w: task %d has changed since event %d", ErrUndoConflict, event.TaskID, event.ID)
+	}
+
+	var task db.Task
+	switch event.Action {
+	case TaskUpdated:
+		task, err = revertUpdate(ctx, q, actorID, current, event)
+	case TaskDeleted:
+		task, err = restoreTask(ctx, q, actorID, db.RestoreTaskParams{ID: event.TaskID, UserID: event.UserID}, event.ID)
+		if errors.Is(err, ErrProjectInTrash) {
+			err = fmt.Errorf("%w: %v", ErrUndoConflict, err)
+		}
+	case TaskCreated, TaskRestored:
+		task, err = deleteTask(ctx, q, actorID, db.DeleteTaskParams{ID: event.TaskID, UserID: event.UserID}, event.ID)
+	default:
+		err = fmt.Errorf("%w: unknown action %q", ErrUndoConflict, event.Action)
+	}
+	if err != nil {
+		return db.Task{}, err
+	}
+
+	if err := q.MarkTaskEventUndone(ctx, event.ID); err != nil {
+		return db.Task{}, err
+	}
+	return task, nil
+}
+
+// revertUpdate writes the old values recorded in event back onto current.
+func revertUpdate(ctx context.Context, q *db.Queries, actorID int64, current db.Task, event db.TaskEvent) (db.Task, error) {
+	if current.DeletedAt.Valid {
+		return db.Task{}, fmt.Errorf("%w: task %d is in the trash", ErrUndoConflict, current.ID)
+	}
+
+	var changes map[string]FieldChange
+	if err := json.Unmarshal(event.Changes, &changes); err != nil {
+		return db.Task{}, err
+	}
+
+	// Overlay the old values on the current snapshot and decode it back, so
+	// each field is parsed with the same type it was recorded with.
+	fields, err := snapshotFields(current)
+	if err != nil {
+		return db.Task{}, err
+	}
+	for field, change := range changes {
+		fields[field] = change.Old
+	}
+	raw, err := json.Marshal(fields)
+	if err != nil {
+		return db.Task{}, err
+	}
+	var old TaskSnapshot
+	if err := json.Unmarshal(raw, &old); err != nil {
+		return db.Task{}, err
+	}
+
+	arg := db.SetTaskFieldsParams{
+		Title:       old.Title,
+		Description: old.Description,
+		Status:      old.Status,
+		Priority:    old.Priority,
+		Tags:        old.Tags,
+		Recurrence:  old.Recurrence,
+		ID:          current.ID,
+		UserID:      current.UserID,
+	}
+	if old.DueDate != nil {
+		arg.DueDate = pgtype.Timestamptz{Time: *old.DueDate, Valid: true}
+	}
+	if arg.Tags == nil {
+		arg.Tags = []string{}
+	}
+	if old.Estimate != nil {
+		arg.EstimateSeconds = pgtype.Int4{Int32: *old.Estimate, Valid: true}
+	}
+	if old.ProjectID != nil {
+		if _, err := q.GetProject(ctx, db.GetProjectParams{ID: *old.ProjectID, UserID: current.UserID}); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return db.Task{}, fmt.Errorf("%w: project %d is no longer available", ErrUndoConflict, *old.ProjectID)
+			}
+			return db.Task{}, err
+		}
+		arg.ProjectID = pgtype.Int8{Int64: *old.ProjectID, Valid: true}
+	}
+	if old.SectionID != nil && old.ProjectID != nil {
+		_, err := q.GetSection(ctx, db.GetSectionParams{ID: *old.SectionID, ProjectID: *old.ProjectID, UserID: current.UserID})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return db.Task{}, fmt.Errorf("%w: section %d no longer exists", ErrUndoConflict, *old.SectionID)
+			}
+			return db.Task{}, err
+		}
+		arg.SectionID = pgtype.Int8{Int64: *old.SectionID, Valid: true}
+	}
+
+	w, err := LoadWorkflow(ctx, q, arg.ProjectID)
+	if err != nil {
+		return db.Task{}, err
+	}
+	status, ok := w.Status(old.Status)
+	if !ok {
+		return db.Task{}, fmt.Errorf("%w: status %q is no longer part of the workflow", ErrUndoConflict, old.Status)
+	}
+	arg.StatusCategory = status.Category
+
+	task, err := q.SetTaskFields(ctx, arg)
+	if err != nil {
+		return db.Task{}, err
+	}
+	if err := recordTaskEvent(ctx, q, actorID, TaskUpdated, &current, task, event.ID); err != nil {
+		return db.Task{}, err
+	}
+	return task, nil
+}
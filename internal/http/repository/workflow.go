@@ -0,0 +1,344 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/pavelc4/auriya-todolist-go/internal/db/sqlc"
+)
+
+// Status categories group the statuses of a workflow by meaning, so features
+// that only care whether a task is open or finished work with any workflow.
+const (
+	CategoryTodo  = "todo"
+	CategoryDoing = "doing"
+	CategoryDone  = "done"
+)
+
+var (
+	// ErrUnknownStatus is returned when a task is given a status that is not
+	// part of its project's workflow.
+	ErrUnknownStatus = errors.New("unknown status")
+	// ErrTransitionNotAllowed is returned when a workflow does not allow a
+	// task to go from its current status to the requested one.
+	ErrTransitionNotAllowed = errors.New("status transition not allowed")
+	// ErrInvalidWorkflow is returned when a workflow definition is malformed.
+	ErrInvalidWorkflow = errors.New("invalid workflow")
+	// ErrStatusInUse is returned when a workflow change would remove a status
+	// that tasks still have and no replacement was given.
+	ErrStatusInUse = errors.New("status in use")
+)
+
+// WorkflowStatus is one status of a workflow.
+type WorkflowStatus struct {
+	Key      string `json:"key"`
+	Name     string `json:"name"`
+	Category string `json:"category"`
+}
+
+// Workflow is the set of statuses tasks in a project can have. Transitions
+// maps a status to the statuses a task may move to from it; a workflow
+// without any transitions allows every change.
+type Workflow struct {
+	Statuses    []WorkflowStatus    `json:"statuses"`
+	Default     string              `json:"default"`
+	Transitions map[string][]string `json:"transitions"`
+}
+
+// DefaultWorkflow is used for tasks without a project and for projects that
+// have not defined their own statuses.
+func DefaultWorkflow() Workflow {
+	return Workflow{
+		Statuses: []WorkflowStatus{
+			{Key: "pending", Name: "Pending", Category: CategoryTodo},
+			{Key: "in_progress", Name: "In progress", Category: CategoryDoing},
+			{Key: "done", Name: "Done", Category: CategoryDone},
+		},
+		Default:     "pending",
+		Transitions: map[string][]string{},
+	}
+}
+
+// Status looks up a status by key.
+func (w Workflow) Status(key string) (WorkflowStatus, bool) {
+	for _, s := range w.Statuses {
+		if s.Key == key {
+			return s, true
+		}
+	}
+	return WorkflowStatus{}, false
+}
+
+// keys returns the status keys in workflow order.
+func (w Workflow) keys() []string {
+	keys := make([]string, len(w.Statuses))
+	for i, s := range w.Statuses {
+		keys[i] = s.Key
+	}
+	return keys
+}
+
+// First returns the first status of the given category.
+func (w Workflow) First(category string) (WorkflowStatus, bool) {
+	for _, s := range w.Statuses {
+		if s.Category == category {
+			return s, true
+		}
+	}
+	return WorkflowStatus{}, false
+}
+
+// Lookup returns the status with the given key or ErrUnknownStatus listing
+// the valid keys.
+func (w Workflow) Lookup(key string) (WorkflowStatus, error) {
+	s, ok := w.Status(key)
+	if !ok {
+		return WorkflowStatus{}, fmt.Errorf("%w: %q is not one of %s", ErrUnknownStatus, key, strings.Join(w.keys(), ", "))
+	}
+	return s, nil
+}
+
+// CheckTransition reports whether a task may go from one status to another.
+func (w Workflow) CheckTransition(from, to string) error {
+	if from == to || len(w.Transitions) == 0 {
+		return nil
+	}
+	allowed := w.Transitions[from]
+	if slices.Contains(allowed, to) {
+		return nil
+	}
+	if len(allowed) == 0 {
+		return fmt.Errorf("%w: no transitions are allowed from %q", ErrTransitionNotAllowed, from)
+	}
+	return fmt.Errorf("%w: %q can only move to %s", ErrTransitionNotAllowed, from, strings.Join(allowed, ", "))
+}
+
+// Validate checks that the workflow is well formed.
+func (w Workflow) Validate() error {
+	if len(w.Statuses) == 0 {
+		return fmt.Errorf("%w: at least one status is required", ErrInvalidWorkflow)
+	}
+	seen := make(map[string]bool, len(w.Statuses))
+	for _, s := range w.Statuses {
+		if s.Key == "" {
+			return fmt.Errorf("%w: status keys must not be empty", ErrInvalidWorkflow)
+		}
+		if seen[s.Key] {
+			return fmt.Errorf("%w: duplicate status %q", ErrInvalidWorkflow, s.Key)
+		}
+		seen[s.Key] = true
+		switch s.Category {
+		case CategoryTodo, CategoryDoing, CategoryDone:
+		default:
+			return fmt.Errorf("%w: status %q has unknown category %q", ErrInvalidWorkflow, s.Key, s.Category)
+		}
+	}
+	if !seen[w.Default] {
+		return fmt.Errorf("%w: default status %q is not in the workflow", ErrInvalidWorkflow, w.Default)
+	}
+	for from, targets := range w.Transitions {
+		if !seen[from] {
+			return fmt.Errorf("%w: transition from unknown status %q", ErrInvalidWorkflow, from)
+		}
+		for i, to := range targets {
+			if !seen[to] {
+				return fmt.Errorf("%w: transition from %q to unknown status %q", ErrInvalidWorkflow, from, to)
+			}
+			if slices.Contains(targets[:i], to) {
+				return fmt.Errorf("%w: duplicate transition from %q to %q", ErrInvalidWorkflow, from, to)
+			}
+		}
+	}
+	return nil
+}
+
+// LoadWorkflow returns the workflow of a project, or DefaultWorkflow for
+// tasks without a project and projects without statuses of their own.
+func LoadWorkflow(ctx context.Context, q *db.Queries, projectID pgtype.Int8) (Workflow, error) {
+	if !projectID.Valid {
+		return DefaultWorkflow(), nil
+	}
+	statuses, err := q.ListProjectStatuses(ctx, projectID.Int64)
+	if err != nil {
+		return Workflow{}, err
+	}
+	if len(statuses) == 0 {
+		return DefaultWorkflow(), nil
+	}
+	transitions, err := q.ListProjectTransitions(ctx, projectID.Int64)
+	if err != nil {
+		return Workflow{}, err
+	}
+
+	w := Workflow{Transitions: make(map[string][]string)}
+	for _, s := range statuses {
+		w.Statuses = append(w.Statuses, WorkflowStatus{Key: s.Key, Name: s.Name, Category: s.Category})
+		if s.IsDefault {
+			w.Default = s.Key
+		}
+	}
+	for _, t := range transitions {
+		w.Transitions[t.FromStatus] = append(w.Transitions[t.FromStatus], t.ToStatus)
+	}
+	return w, nil
+}
+
+// SaveWorkflow replaces a project's workflow. Tasks and sections whose status
+// is removed are moved to remap[status]; removing a status that tasks still
+// use without a replacement fails with ErrStatusInUse. Remapped tasks get a
+// history entry like any other update. It returns the IDs of tasks whose
+// status or status category changed.
+func SaveWorkflow(ctx context.Context, q *db.Queries, actorID, userID, projectID int64, w Workflow, remap map[string]string) ([]int64, error) {
+	if err := w.Validate(); err != nil {
+		return nil, err
+	}
+	if _, err := q.GetProjectForUpdate(ctx, db.GetProjectForUpdateParams{ID: projectID, UserID: userID}); err != nil {
+		return nil, err
+	}
+	for from, to := range remap {
+		if _, ok := w.Status(to); !ok {
+			return nil, fmt.Errorf("%w: %q is remapped to unknown status %q", ErrInvalidWorkflow, from, to)
+		}
+	}
+
+	if err := q.DeleteProjectStatuses(ctx, projectID); err != nil {
+		return nil, err
+	}
+	for i, s := range w.Statuses {
+		err := q.CreateProjectStatus(ctx, db.CreateProjectStatusParams{
+			ProjectID: projectID,
+			Key:       s.Key,
+			Name:      s.Name,
+			Category:  s.Category,
+			Position:  int32(i),
+			IsDefault: s.Key == w.Default,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	for from, targets := range w.Transitions {
+		for _, to := range targets {
+			err := q.CreateProjectTransition(ctx, db.CreateProjectTransitionParams{ProjectID: projectID, FromStatus: from, ToStatus: to})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	project := pgtype.Int8{Int64: projectID, Valid: true}
+	var changed []int64
+	used, err := q.ListProjectTaskStatuses(ctx, project)
+	if err != nil {
+		return nil, err
+	}
+	for _, status := range used {
+		if _, ok := w.Status(status); ok {
+			continue
+		}
+		to, ok := remap[status]
+		if !ok {
+			return nil, fmt.Errorf("%w: tasks still have status %q; give a replacement for it", ErrStatusInUse, status)
+		}
+		target, _ := w.Status(to)
+		tasks, err := q.RemapProjectTaskStatus(ctx, db.RemapProjectTaskStatusParams{
+			ToStatus:       target.Key,
+			StatusCategory: target.Category,
+			ProjectID:      project,
+			FromStatus:     status,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, task := range tasks {
+			changed = append(changed, task.ID)
+			before := task
+			before.Status = status
+			if err := RecordTaskEvent(ctx, q, actorID, TaskUpdated, &before, task); err != nil {
+				return nil, err
+			}
+		}
+	}
+	// Statuses that were kept may have changed category.
+	synced, err := q.SyncProjectTaskCategories(ctx, project)
+	if err != nil {
+		return nil, err
+	}
+	changed = append(changed, synced...)
+
+	sections, err := q.ListSections(ctx, db.ListSectionsParams{ProjectID: projectID, UserID: userID})
+	if err != nil {
+		return nil, err
+	}
+	for _, section := range sections {
+		if !section.Status.Valid {
+			continue
+		}
+		if _, ok := w.Status(section.Status.String); ok {
+			continue
+		}
+		// An empty status clears the mapping when there is no replacement.
+		_, err := q.UpdateSection(ctx, db.UpdateSectionParams{
+			Status:    pgtype.Text{String: remap[section.Status.String], Valid: true},
+			ID:        section.ID,
+			ProjectID: projectID,
+			UserID:    userID,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return changed, nil
+}
+
+// resolveStatus works out the status a task ends up with when it is written
+// to project. requested is the status asked for, if any. Within the same
+// project the change must be allowed by the workflow; a task that changes
+// project keeps its status when the new workflow has it and otherwise takes
+// the first status of the same category, falling back to the default.
+func resolveStatus(ctx context.Context, q *db.Queries, before *db.Task, project pgtype.Int8, requested pgtype.Text) (WorkflowStatus, error) {
+	w, err := LoadWorkflow(ctx, q, project)
+	if err != nil {
+		return WorkflowStatus{}, err
+	}
+
+	if before == nil {
+		if !requested.Valid {
+			return w.Lookup(w.Default)
+		}
+		return w.Lookup(requested.String)
+	}
+
+	if before.ProjectID == project {
+		if !requested.Valid {
+			if s, ok := w.Status(before.Status); ok {
+				return s, nil
+			}
+			return WorkflowStatus{Key: before.Status, Category: before.StatusCategory}, nil
+		}
+		s, err := w.Lookup(requested.String)
+		if err != nil {
+			return WorkflowStatus{}, err
+		}
+		if err := w.CheckTransition(before.Status, s.Key); err != nil {
+			return WorkflowStatus{}, err
+		}
+		return s, nil
+	}
+
+	if requested.Valid {
+		return w.Lookup(requested.String)
+	}
+	if s, ok := w.Status(before.Status); ok {
+		return s, nil
+	}
+	if s, ok := w.First(before.StatusCategory); ok {
+		return s, nil
+	}
+	return w.Lookup(w.Default)
+}
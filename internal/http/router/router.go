@@ -6,16 +6,17 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 	_ "github.com/pavelc4/auriya-todolist-go/docs" // docs is generated by Swag CLI
 	"github.com/pavelc4/auriya-todolist-go/internal/cache"
+	"github.com/pavelc4/auriya-todolist-go/internal/config"
 	"github.com/pavelc4/auriya-todolist-go/internal/http/handler"
 	"github.com/pavelc4/auriya-todolist-go/internal/http/middleware"
 	"github.com/pavelc4/auriya-todolist-go/internal/http/repository"
 	"github.com/pavelc4/auriya-todolist-go/internal/http/service"
+	"github.com/pavelc4/auriya-todolist-go/internal/realtime"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
-	"golang.org/x/oauth2"
 )
 
-func New(db *pgxpool.Pool, googleConf, githubConf *oauth2.Config, userRepo *repository.UserRepository, jwtService *service.JWTService, cacheSvc *cache.Service) *gin.Engine {
+func New(cfg *config.Config, db *pgxpool.Pool, userRepo *repository.UserRepository, jwtService *service.JWTService, cacheSvc *cache.Service, hub *realtime.Hub) *gin.Engine {
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.New()
 	r.Use(gin.Logger(), gin.Recovery())
@@ -36,8 +37,24 @@ func New(db *pgxpool.Pool, googleConf, githubConf *oauth2.Config, userRepo *repo
 
 	store := repository.NewStore(db)
 	task := handler.NewTaskHandler(store, cacheSvc)
-	auth := handler.NewAuthHandler(googleConf, githubConf, userRepo, jwtService)
-	project := handler.NewProjectHandler(store)
+	auth := handler.NewAuthHandler(cfg.GoogleOAuthConfig, cfg.GitHubOAuthConfig, userRepo, jwtService)
+	project := handler.NewProjectHandler(store, cacheSvc)
+	trash := handler.NewTrashHandler(store, cacheSvc)
+	undo := handler.NewUndoHandler(store, cacheSvc, cfg.UndoWindow)
+	section := handler.NewSectionHandler(store)
+	timeEntry := handler.NewTimeEntryHandler(store, cacheSvc)
+	checklist := handler.NewChecklistHandler(store, cacheSvc)
+	filter := handler.NewFilterHandler(store)
+	batch := handler.NewBatchHandler(store, cacheSvc)
+	sync := handler.NewSyncHandler(store, cfg.SyncTombstoneRetention)
+	events := handler.NewEventsHandler(store, hub)
+	ws := handler.NewWSHandler(store, hub, jwtService)
+	webhook := handler.NewWebhookHandler(store, cfg.WebhookAllowPrivate)
+	calendar := handler.NewCalendarHandler(store)
+	calDAV := handler.NewCalDAVHandler(store, cacheSvc, cfg.SyncTombstoneRetention)
+	personalToken := handler.NewPersonalTokenHandler(store)
+	export := handler.NewExportHandler(store, cfg.ExportAsyncThreshold)
+	imports := handler.NewImportHandler(store)
 
 	// auth routes
 	// Google
@@ -47,21 +64,83 @@ func New(db *pgxpool.Pool, googleConf, githubConf *oauth2.Config, userRepo *repo
 	r.GET("/auth/github/login", auth.GitHubLogin)
 	r.GET("/auth/github/callback", auth.GitHubCallback)
 
+	// Realtime WebSocket; it authenticates its own clients
+	r.GET("/ws", ws.Serve)
+
+	// Calendar feed; the secret token in the URL identifies the user
+	r.GET("/ical/:file", middleware.RateLimiter(), calendar.Feed)
+
+	// CalDAV server for task apps; basic auth or a personal access token.
+	// Only failed logins are limited: clients send bursts of requests when
+	// they sync.
+	r.GET("/.well-known/caldav", calDAV.WellKnown)
+	r.Handle("PROPFIND", "/.well-known/caldav", calDAV.WellKnown)
+	dav := r.Group("/dav")
+	dav.Use(middleware.DAVAuth(store, userRepo))
+	for _, method := range handler.DAVMethods {
+		dav.Handle(method, "/*path", calDAV.Serve)
+	}
+
 	api := r.Group("/api")
 	api.Use(middleware.RateLimiter()) // Apply rate limiter middleware
 	{
 		api.POST("/register", auth.Register)
 		api.POST("/login", auth.Login)
 
-		protected := api.Group("/")
-		protected.Use(middleware.AuthMiddleware(jwtService))
+		authed := api.Group("/")
+		authed.Use(middleware.AuthMiddleware(jwtService))
+
+		// Routes that issue credentials are kept out of the idempotency
+		// group, which would store their responses, and with them the
+		// secrets that are otherwise only kept as hashes.
+		credentials := authed.Group("/")
+		{
+			// Personal access tokens, for clients such as CalDAV apps
+			credentials.GET("/tokens", personalToken.List)
+			credentials.POST("/tokens", personalToken.Create)
+			credentials.DELETE("/tokens/:id", personalToken.Delete)
+
+			// Calendar feed settings; the feed URL holds a secret
+			credentials.GET("/ical", calendar.FeedInfo)
+			credentials.POST("/ical/token", calendar.RegenerateFeed)
+			credentials.DELETE("/ical/token", calendar.RevokeFeed)
+		}
+
+		protected := authed.Group("/")
+		// Login and registration are left out too, for the same reason.
+		protected.Use(middleware.Idempotency(store, cfg.IdempotencyRetention))
 		{
 			// Task routes
 			protected.POST("/tasks", task.Create)
+			protected.POST("/tasks/quick", task.QuickAdd)
+			protected.POST("/tasks/bulk", task.Bulk)
 			protected.GET("/tasks/:id", task.Get)
 			protected.GET("/tasks", task.List)
 			protected.PATCH("/tasks/:id", task.Update)
 			protected.DELETE("/tasks/:id", task.Delete)
+			protected.POST("/tasks/:id/restore", task.Restore)
+			protected.POST("/tasks/:id/move", task.Move)
+			protected.GET("/tasks/:id/history", task.History)
+			protected.POST("/tasks/:id/undo", undo.UndoTask)
+			protected.POST("/undo/:event_id", undo.UndoEvent)
+
+			// Checklist routes
+			protected.GET("/tasks/:id/checklist", checklist.List)
+			protected.POST("/tasks/:id/checklist", checklist.Add)
+			protected.PATCH("/tasks/:id/checklist/:item_id", checklist.Update)
+			protected.DELETE("/tasks/:id/checklist/:item_id", checklist.Delete)
+			protected.POST("/tasks/:id/checklist/:item_id/toggle", checklist.Toggle)
+			protected.POST("/tasks/:id/checklist/:item_id/move", checklist.Move)
+
+			// Time tracking routes
+			protected.GET("/tasks/:id/time-entries", timeEntry.List)
+			protected.POST("/tasks/:id/time-entries", timeEntry.Create)
+			protected.POST("/tasks/:id/time-entries/start", timeEntry.Start)
+			protected.POST("/tasks/:id/time-entries/stop", timeEntry.Stop)
+			protected.PATCH("/tasks/:id/time-entries/:entry_id", timeEntry.Update)
+			protected.DELETE("/tasks/:id/time-entries/:entry_id", timeEntry.Delete)
+			protected.GET("/time-entries/running", timeEntry.Running)
+			protected.GET("/reports/time", timeEntry.Report)
 
 			// Project routes
 			protected.POST("/projects", project.Create)
@@ -69,7 +148,62 @@ func New(db *pgxpool.Pool, googleConf, githubConf *oauth2.Config, userRepo *repo
 			protected.GET("/projects/:id", project.Get)
 			protected.PATCH("/projects/:id", project.Update)
 			protected.DELETE("/projects/:id", project.Delete)
+			protected.POST("/projects/:id/restore", project.Restore)
 			protected.GET("/projects/:id/tasks", task.ListByProject) // New route
+			protected.GET("/projects/:id/board", section.Board)
+			protected.GET("/projects/:id/workflow", project.Workflow)
+			protected.PUT("/projects/:id/workflow", project.UpdateWorkflow)
+
+			// Section routes
+			protected.POST("/projects/:id/sections", section.Create)
+			protected.GET("/projects/:id/sections", section.List)
+			protected.PATCH("/projects/:id/sections/:section_id", section.Update)
+			protected.DELETE("/projects/:id/sections/:section_id", section.Delete)
+			protected.POST("/projects/:id/sections/:section_id/move", section.Move)
+
+			// Filter routes
+			protected.GET("/filters", filter.List)
+			protected.POST("/filters", filter.Create)
+			protected.GET("/filters/:id", filter.Get)
+			protected.PATCH("/filters/:id", filter.Update)
+			protected.DELETE("/filters/:id", filter.Delete)
+			protected.GET("/filters/:id/tasks", filter.Tasks)
+
+			// Batch route for replaying offline changes
+			protected.POST("/batch", batch.Run)
+
+			// Incremental sync for offline clients
+			protected.GET("/sync", sync.Sync)
+
+			// Live task and project changes (Server-Sent Events)
+			protected.GET("/events", events.Stream)
+
+			// Webhook routes
+			protected.GET("/webhooks", webhook.List)
+			protected.POST("/webhooks", webhook.Create)
+			protected.GET("/webhooks/events", webhook.Events)
+			protected.GET("/webhooks/:id", webhook.Get)
+			protected.PATCH("/webhooks/:id", webhook.Update)
+			protected.DELETE("/webhooks/:id", webhook.Delete)
+			protected.POST("/webhooks/:id/secret", webhook.RotateSecret)
+			protected.POST("/webhooks/:id/ping", webhook.Ping)
+			protected.GET("/webhooks/:id/deliveries", webhook.Deliveries)
+			protected.GET("/webhooks/:id/deliveries/:delivery_id", webhook.Delivery)
+			protected.POST("/webhooks/:id/deliveries/:delivery_id/redeliver", webhook.Redeliver)
+
+			// Data export; large exports are written in the background
+			protected.GET("/export", export.Export)
+			protected.GET("/exports/:id", export.GetExport)
+			protected.GET("/exports/:id/download", export.Download)
+
+			// Import from Todoist, Trello and CSV
+			protected.POST("/import", imports.Import)
+
+			// Trash routes
+			protected.GET("/trash", trash.List)
+			protected.DELETE("/trash", trash.Empty)
+			protected.DELETE("/trash/tasks/:id", trash.PurgeTask)
+			protected.DELETE("/trash/projects/:id", trash.PurgeProject)
 		}
 	}
 
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+)
+
+// PersonalTokenPrefix starts every personal access token, so they are easy
+// to tell from passwords and JWTs and to spot when leaked.
+const PersonalTokenPrefix = "pat_"
+
+// NewPersonalToken returns a new random personal access token.
+func NewPersonalToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return PersonalTokenPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// IsPersonalToken reports whether s looks like a personal access token.
+func IsPersonalToken(s string) bool {
+	return strings.HasPrefix(s, PersonalTokenPrefix)
+}
+
+// HashPersonalToken returns what is stored for a personal access token.
+func HashPersonalToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
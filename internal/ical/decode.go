@@ -0,0 +1,241 @@
+package ical
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ErrMalformed is returned by Decode for data that is not iCalendar. The
+// wrapped message says where.
+var ErrMalformed = errors.New("malformed iCalendar data")
+
+// Decode reads one component, usually a VCALENDAR, with everything in it.
+// Lines are unfolded and split into names, parameters and values; names
+// are upper-cased and values are kept as they are, so use ParseText and
+// ParseDateTime on them.
+func Decode(r io.Reader) (*Component, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	lines := unfold(string(data))
+
+	var root *Component
+	var stack []*Component
+	for n, line := range lines {
+		if line == "" {
+			continue
+		}
+		p, err := parseLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrMalformed, n+1, err)
+		}
+		switch p.Name {
+		case "BEGIN":
+			if root != nil && len(stack) == 0 {
+				return nil, fmt.Errorf("%w: line %d: data after the end", ErrMalformed, n+1)
+			}
+			c := NewComponent(strings.ToUpper(p.Value))
+			if len(stack) > 0 {
+				stack[len(stack)-1].Append(c)
+			} else {
+				root = c
+			}
+			stack = append(stack, c)
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1].Name != strings.ToUpper(p.Value) {
+				return nil, fmt.Errorf("%w: line %d: unexpected END:%s", ErrMalformed, n+1, p.Value)
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("%w: line %d: property outside a component", ErrMalformed, n+1)
+			}
+			c := stack[len(stack)-1]
+			c.Props = append(c.Props, p)
+		}
+	}
+	if root == nil {
+		return nil, fmt.Errorf("%w: no component", ErrMalformed)
+	}
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("%w: %s is not closed", ErrMalformed, stack[len(stack)-1].Name)
+	}
+	return root, nil
+}
+
+// unfold splits data into content lines, joining continuation lines that
+// start with a space or tab. Bare LF line ends are accepted too.
+func unfold(data string) []string {
+	var lines []string
+	for _, raw := range strings.Split(data, "\n") {
+		raw = strings.TrimSuffix(raw, "\r")
+		if len(lines) > 0 && raw != "" && (raw[0] == ' ' || raw[0] == '\t') {
+			lines[len(lines)-1] += raw[1:]
+			continue
+		}
+		lines = append(lines, raw)
+	}
+	return lines
+}
+
+// parseLine splits a content line such as
+// DUE;TZID="Europe/Berlin":20261020T090000 into its parts. Quoted parameter
+// values lose their quotes.
+func parseLine(line string) (Property, error) {
+	var p Property
+	i := strings.IndexAny(line, ";:")
+	if i <= 0 {
+		return p, errors.New("missing name or value")
+	}
+	p.Name = strings.ToUpper(line[:i])
+	for line[i] == ';' {
+		line = line[i+1:]
+		eq := strings.IndexByte(line, '=')
+		if eq <= 0 {
+			return p, fmt.Errorf("bad parameter in %s", p.Name)
+		}
+		param := Param{Name: strings.ToUpper(line[:eq])}
+		j := eq + 1
+		var value strings.Builder
+		for {
+			if j < len(line) && line[j] == '"' {
+				end := strings.IndexByte(line[j+1:], '"')
+				if end < 0 {
+					return p, fmt.Errorf("unterminated quote in %s", p.Name)
+				}
+				value.WriteString(line[j+1 : j+1+end])
+				j += end + 2
+			} else {
+				end := strings.IndexAny(line[j:], ",;:")
+				if end < 0 {
+					return p, fmt.Errorf("missing value in %s", p.Name)
+				}
+				value.WriteString(line[j : j+end])
+				j += end
+			}
+			if j < len(line) && line[j] == ',' {
+				value.WriteByte(',')
+				j++
+				continue
+			}
+			break
+		}
+		if j >= len(line) {
+			return p, fmt.Errorf("missing value in %s", p.Name)
+		}
+		param.Value = value.String()
+		p.Params = append(p.Params, param)
+		i = j
+	}
+	p.Value = line[i+1:]
+	return p, nil
+}
+
+// Prop returns the first property with the given name, or nil.
+func (c *Component) Prop(name string) *Property {
+	for i := range c.Props {
+		if c.Props[i].Name == name {
+			return &c.Props[i]
+		}
+	}
+	return nil
+}
+
+// PropsNamed returns all properties with the given name.
+func (c *Component) PropsNamed(name string) []Property {
+	var out []Property
+	for _, p := range c.Props {
+		if p.Name == name {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// Children returns the child components with the given name.
+func (c *Component) Children(name string) []*Component {
+	var out []*Component
+	for _, child := range c.Components {
+		if child.Name == name {
+			out = append(out, child)
+		}
+	}
+	return out
+}
+
+// Param returns the value of a parameter, or "" when it is not set.
+func (p Property) Param(name string) string {
+	for _, param := range p.Params {
+		if param.Name == name {
+			return param.Value
+		}
+	}
+	return ""
+}
+
+// ParseText undoes the escaping of a TEXT value.
+func ParseText(v string) string {
+	if !strings.Contains(v, `\`) {
+		return v
+	}
+	var b strings.Builder
+	for i := 0; i < len(v); i++ {
+		if v[i] != '\\' || i+1 == len(v) {
+			b.WriteByte(v[i])
+			continue
+		}
+		i++
+		switch v[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(v[i])
+		}
+	}
+	return b.String()
+}
+
+// ParseTextList splits a list of TEXT values, such as CATEGORIES, on the
+// commas that are not escaped.
+func ParseTextList(v string) []string {
+	var out []string
+	start := 0
+	for i := 0; i < len(v); i++ {
+		switch v[i] {
+		case '\\':
+			i++
+		case ',':
+			out = append(out, ParseText(v[start:i]))
+			start = i + 1
+		}
+	}
+	return append(out, ParseText(v[start:]))
+}
+
+// ParseDateTime reads a DATE or DATE-TIME property. Times with a TZID that
+// is an IANA zone name are read in that zone; floating times and unknown
+// zones are taken as UTC. isDate reports a DATE value, which is returned as
+// midnight UTC.
+func ParseDateTime(p Property) (t time.Time, isDate bool, err error) {
+	v := p.Value
+	if strings.EqualFold(p.Param("VALUE"), "DATE") || len(v) == 8 {
+		t, err = time.Parse("20060102", v)
+		return t, true, err
+	}
+	if s, ok := strings.CutSuffix(v, "Z"); ok {
+		t, err = time.Parse("20060102T150405", s)
+		return t, false, err
+	}
+	loc := time.UTC
+	if tzid := p.Param("TZID"); tzid != "" {
+		if l, err := time.LoadLocation(strings.TrimPrefix(tzid, "/")); err == nil {
+			loc = l
+		}
+	}
+	t, err = time.ParseInLocation("20060102T150405", v, loc)
+	return t, false, err
+}
@@ -0,0 +1,157 @@
+// Package ical reads and writes iCalendar (RFC 5545) data.
+//
+// It works on a generic tree of components and properties and leaves their
+// meaning to the caller; the helpers only take care of the text format:
+// escaping, date and duration values, and folding lines longer than 75
+// octets.
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Param is a property parameter, such as VALUE=DATE.
+type Param struct {
+	Name  string
+	Value string
+}
+
+// Property is one content line. Value is written as is; use Text for free
+// text.
+type Property struct {
+	Name   string
+	Params []Param
+	Value  string
+}
+
+// Component is a BEGIN/END block such as VCALENDAR or VTODO.
+type Component struct {
+	Name       string
+	Props      []Property
+	Components []*Component
+}
+
+// NewComponent returns an empty component.
+func NewComponent(name string) *Component {
+	return &Component{Name: name}
+}
+
+// Add appends a property with a value in its final form.
+func (c *Component) Add(name, value string, params ...Param) {
+	c.Props = append(c.Props, Property{Name: name, Params: params, Value: value})
+}
+
+// AddText appends a TEXT property, escaping its value.
+func (c *Component) AddText(name, text string, params ...Param) {
+	c.Add(name, Text(text), params...)
+}
+
+// Append adds a child component.
+func (c *Component) Append(child *Component) {
+	c.Components = append(c.Components, child)
+}
+
+// Text escapes s as a TEXT value.
+func Text(s string) string {
+	return textEscaper.Replace(s)
+}
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// DateTime formats t as a UTC DATE-TIME value.
+func DateTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// Date formats the day of t as a DATE value.
+func Date(t time.Time) string {
+	return t.Format("20060102")
+}
+
+// Duration formats d as a DURATION value such as "PT1H30M" or "-PT15M",
+// dropping fractions of a second.
+func Duration(d time.Duration) string {
+	var b strings.Builder
+	if d < 0 {
+		b.WriteByte('-')
+		d = -d
+	}
+	b.WriteString("P")
+	days := d / (24 * time.Hour)
+	d -= days * 24 * time.Hour
+	if days > 0 {
+		fmt.Fprintf(&b, "%dD", days)
+	}
+	if d >= time.Second || days == 0 {
+		b.WriteByte('T')
+		h, m, s := d/time.Hour, d%time.Hour/time.Minute, d%time.Minute/time.Second
+		if h > 0 {
+			fmt.Fprintf(&b, "%dH", h)
+		}
+		if m > 0 {
+			fmt.Fprintf(&b, "%dM", m)
+		}
+		if s > 0 || (h == 0 && m == 0) {
+			fmt.Fprintf(&b, "%dS", s)
+		}
+	}
+	return b.String()
+}
+
+// Encode writes c and its children.
+func Encode(w io.Writer, c *Component) error {
+	bw := bufio.NewWriter(w)
+	encode(bw, c)
+	return bw.Flush()
+}
+
+func encode(w *bufio.Writer, c *Component) {
+	writeLine(w, "BEGIN:"+c.Name)
+	for _, p := range c.Props {
+		var line strings.Builder
+		line.WriteString(p.Name)
+		for _, param := range p.Params {
+			line.WriteString(";" + param.Name + "=" + paramValue(param.Value))
+		}
+		line.WriteString(":" + p.Value)
+		writeLine(w, line.String())
+	}
+	for _, child := range c.Components {
+		encode(w, child)
+	}
+	writeLine(w, "END:"+c.Name)
+}
+
+// paramValue quotes a parameter value that contains characters with a
+// meaning in content lines. Double quotes cannot be escaped and are dropped.
+func paramValue(v string) string {
+	v = strings.ReplaceAll(v, `"`, "")
+	if strings.ContainsAny(v, ";:,") {
+		return `"` + v + `"`
+	}
+	return v
+}
+
+// writeLine writes one content line, folded so that no line is longer than
+// 75 octets, without splitting a UTF-8 sequence.
+func writeLine(w *bufio.Writer, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		w.WriteString(line[:cut])
+		w.WriteString("\r\n ")
+		line = line[cut:]
+		// Continuation lines start with the space, which counts.
+		limit = 74
+	}
+	w.WriteString(line)
+	w.WriteString("\r\n")
+}
@@ -0,0 +1,127 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// parseCSV reads the generic CSV described in the package documentation.
+func parseCSV(r io.Reader, opts Options) (*Plan, error) {
+	rows, err := readCSV(r)
+	if err != nil {
+		return nil, err
+	}
+	col := func(name string) int { return rows.column(name) }
+	titleCol := col("title")
+	if titleCol < 0 {
+		return nil, fmt.Errorf("%w: the header has no title column", ErrInvalidFile)
+	}
+	idCol := col("external_id")
+	if idCol < 0 {
+		idCol = col("id")
+	}
+	descCol, projectCol, sectionCol := col("description"), col("project"), col("section")
+	statusCol, categoryCol, prioCol := col("status"), col("status_category"), col("priority")
+	dueCol, estimateCol, tagsCol := col("due_date"), col("estimate"), col("tags")
+	recurrenceCol, checklistCol := col("recurrence"), col("checklist")
+
+	plan := &Plan{}
+	ids := contentIDs{}
+	// projects maps lower-cased project names to their index in the plan.
+	projects := make(map[string]int)
+	sections := make(map[string]map[string]bool)
+	for _, rec := range rows.records {
+		field := func(i int) string { return unescapeCSV(strings.TrimSpace(rec.get(i))) }
+
+		projectID := ""
+		if name := field(projectCol); name != "" {
+			key := strings.ToLower(name)
+			projectID = "project:name:" + key
+			if _, ok := projects[key]; !ok {
+				projects[key] = len(plan.Projects)
+				sections[key] = make(map[string]bool)
+				plan.Projects = append(plan.Projects, Project{Row: rec.line, ExternalID: projectID, Name: name, MatchByName: true})
+			}
+			if section := field(sectionCol); section != "" && !sections[key][strings.ToLower(section)] {
+				sections[key][strings.ToLower(section)] = true
+				p := &plan.Projects[projects[key]]
+				p.Sections = append(p.Sections, section)
+			}
+		}
+
+		t := Task{
+			Row:         rec.line,
+			Project:     projectID,
+			Section:     field(sectionCol),
+			Title:       field(titleCol),
+			Description: unescapeCSV(rec.get(descCol)),
+			Status:      strings.ToLower(field(statusCol)),
+			Category:    strings.ToLower(field(categoryCol)),
+			Recurrence:  field(recurrenceCol),
+		}
+		if id := field(idCol); id != "" {
+			t.ExternalID = "task:" + id
+		} else {
+			t.ExternalID = ids.next("task", projectID, t.Section, t.Title, t.Description, field(dueCol))
+		}
+		if s := field(prioCol); s != "" {
+			n, err := strconv.ParseInt(s, 10, 32)
+			if err != nil {
+				t.fail("priority %q is not a number", s)
+			}
+			t.Priority = int32(n)
+		}
+		if s := field(estimateCol); s != "" {
+			n, err := strconv.ParseInt(s, 10, 32)
+			if err != nil || n < 0 {
+				t.fail("estimate %q is not a number of seconds", s)
+			} else {
+				estimate := int32(n)
+				t.Estimate = &estimate
+			}
+		}
+		if s := field(dueCol); s != "" {
+			d, err := parseDate(s, opts.Location)
+			if err != nil {
+				t.fail("%v", err)
+			} else {
+				t.Due = &d
+			}
+		}
+		if s := field(tagsCol); s != "" {
+			t.Tags = strings.Split(s, ",")
+		}
+		for _, line := range strings.Split(unescapeCSV(rec.get(checklistCol)), "\n") {
+			t.Checklist = append(t.Checklist, checklistLine(line))
+		}
+		plan.Tasks = append(plan.Tasks, t)
+	}
+	if len(plan.Tasks) == 0 {
+		return nil, fmt.Errorf("%w: no tasks found", ErrInvalidFile)
+	}
+	return plan, nil
+}
+
+// checklistLine reads a line of the checklist column, which may start with
+// "[ ]" or "[x]".
+func checklistLine(line string) ChecklistItem {
+	line = strings.TrimSpace(line)
+	switch {
+	case strings.HasPrefix(line, "[x]"), strings.HasPrefix(line, "[X]"):
+		return ChecklistItem{Text: line[3:], Checked: true}
+	case strings.HasPrefix(line, "[ ]"):
+		return ChecklistItem{Text: line[3:]}
+	}
+	return ChecklistItem{Text: line}
+}
+
+// unescapeCSV undoes the quote the CSV export puts in front of values a
+// spreadsheet would read as a formula.
+func unescapeCSV(s string) string {
+	if len(s) > 1 && s[0] == '\'' && strings.ContainsRune("=+-@\t\r", rune(s[1])) {
+		return s[1:]
+	}
+	return s
+}
@@ -0,0 +1,323 @@
+// Package importer reads exports of other task managers, and a generic CSV
+// layout, into a Plan of the projects, sections and tasks to create. It only
+// parses; checking the plan against the account and saving it is left to
+// the caller.
+//
+// The sources are:
+//
+//	todoist_json  a Todoist backup or Sync API dump with projects, sections,
+//	              items and notes
+//	todoist_csv   the CSV Todoist exports for one project
+//	trello        the JSON export of a Trello board
+//	csv           the generic CSV described below
+//
+// Subtasks in Todoist and checklists in Trello become checklist items of
+// their task, and comments are added to the task's description.
+//
+// The generic CSV has a header row naming its columns, in any order and
+// case. Only title is required; unknown columns are ignored, so the CSV data
+// export can be imported again.
+//
+//	external_id      a stable ID for the row, so that importing the file
+//	                 again skips it; "id" is used when it is missing
+//	title            the task title
+//	description      the task description
+//	project          the project name; matched against existing projects
+//	                 and created when there is none, or empty for the inbox
+//	section          the section name within the project
+//	status           a status key of the project's workflow, or todo,
+//	                 doing or done for the first status of that category
+//	status_category  todo, doing or done, used when status is not known
+//	priority         1 to 5, 5 being the most urgent
+//	due_date         RFC 3339, "2006-01-02 15:04" or "2006-01-02"
+//	estimate         in seconds
+//	tags             separated by commas
+//	recurrence       an RRULE value such as FREQ=WEEKLY;BYDAY=MO
+//	checklist        one item per line, each optionally starting with
+//	                 "[ ]" or "[x]"
+package importer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Sources this package reads.
+const (
+	TodoistJSON = "todoist_json"
+	TodoistCSV  = "todoist_csv"
+	Trello      = "trello"
+	CSV         = "csv"
+)
+
+// Limits of the fields tasks and projects are imported into. Longer values
+// are cut short with a warning.
+const (
+	maxTitle   = 255
+	maxName    = 100
+	maxTags    = 20
+	maxTagSize = 50
+	maxItem    = 500
+)
+
+// MaxTasks is the number of tasks one file may hold.
+const MaxTasks = 10000
+
+// ErrInvalidFile is returned for input that cannot be read as its source's
+// format at all. The wrapped message says why. Problems with single items
+// are reported on the items instead.
+var ErrInvalidFile = errors.New("invalid import file")
+
+// Options are the settings of one import.
+type Options struct {
+	// Project names the project of sources that have none, such as the
+	// Todoist CSV of one project, and renames the board of a Trello export.
+	Project string
+	// Location is where dates without a time zone are read. Dates without a
+	// time are due at the end of that day.
+	Location *time.Location
+	// Now resolves relative dates in Todoist due strings.
+	Now time.Time
+}
+
+// Plan is what an import holds, in the order it should be created. Tasks
+// refer to their project by its ExternalID.
+type Plan struct {
+	Projects []Project
+	Tasks    []Task
+}
+
+// Project is a project to import. Sections are the names of its sections
+// in order. MatchByName lets an existing project with the same name take
+// its place, for sources without stable IDs.
+type Project struct {
+	Row         int
+	ExternalID  string
+	Name        string
+	MatchByName bool
+	Sections    []string
+	Warnings    []string
+}
+
+// Task is a task to import. Row is the line of a CSV file or the position
+// in a JSON list, from 1. Project is the ExternalID of its project, or ""
+// for the inbox, and Section the name of its section.
+//
+// Status is a status key of the project's workflow, or a status category;
+// Category is used when Status is empty or not known. Priority is 0 for the
+// default. A task with Skip set is left out on purpose, such as an archived
+// card; one with Err set cannot be imported.
+type Task struct {
+	Row         int
+	ExternalID  string
+	Project     string
+	Section     string
+	Title       string
+	Description string
+	Status      string
+	Category    string
+	Priority    int32
+	Due         *time.Time
+	Tags        []string
+	Recurrence  string
+	Estimate    *int32
+	Checklist   []ChecklistItem
+	Skip        string
+	Err         error
+	Warnings    []string
+}
+
+// ChecklistItem is an item of a task's checklist.
+type ChecklistItem struct {
+	Text    string
+	Checked bool
+}
+
+// Parse reads a file of the given source.
+func Parse(source string, r io.Reader, opts Options) (*Plan, error) {
+	if opts.Location == nil {
+		opts.Location = time.UTC
+	}
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+	opts.Now = opts.Now.In(opts.Location)
+
+	var plan *Plan
+	var err error
+	switch source {
+	case TodoistJSON:
+		plan, err = parseTodoistJSON(r, opts)
+	case TodoistCSV:
+		plan, err = parseTodoistCSV(r, opts)
+	case Trello:
+		plan, err = parseTrello(r, opts)
+	case CSV:
+		plan, err = parseCSV(r, opts)
+	default:
+		return nil, fmt.Errorf("unknown source %q", source)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(plan.Tasks) > MaxTasks {
+		return nil, fmt.Errorf("%w: more than %d tasks", ErrInvalidFile, MaxTasks)
+	}
+	for i := range plan.Projects {
+		plan.Projects[i].finish()
+	}
+	for i := range plan.Tasks {
+		plan.Tasks[i].finish()
+	}
+	return plan, nil
+}
+
+func (p *Project) warn(format string, args ...any) {
+	p.Warnings = append(p.Warnings, fmt.Sprintf(format, args...))
+}
+
+// finish cuts the project's names down to size.
+func (p *Project) finish() {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		p.Name = "Imported"
+	}
+	if s, cut := truncate(p.Name, maxName); cut {
+		p.warn("name cut to %d characters", maxName)
+		p.Name = s
+	}
+	for i, s := range p.Sections {
+		if short, cut := truncate(s, maxName); cut {
+			p.warn("section %q cut to %d characters", short, maxName)
+			p.Sections[i] = short
+		}
+	}
+}
+
+func (t *Task) warn(format string, args ...any) {
+	t.Warnings = append(t.Warnings, fmt.Sprintf(format, args...))
+}
+
+// fail marks the task as not importable, keeping the first reason.
+func (t *Task) fail(format string, args ...any) {
+	if t.Err == nil {
+		t.Err = fmt.Errorf(format, args...)
+	}
+}
+
+// finish checks the task's fields and cuts them down to size.
+func (t *Task) finish() {
+	t.Title = strings.TrimSpace(t.Title)
+	t.Description = strings.TrimSpace(t.Description)
+	t.Section, _ = truncate(strings.TrimSpace(t.Section), maxName)
+	if t.Title == "" {
+		t.fail("title is empty")
+	}
+	if s, cut := truncate(t.Title, maxTitle); cut {
+		t.warn("title cut to %d characters", maxTitle)
+		t.Title = s
+	}
+	if t.Priority != 0 && (t.Priority < 1 || t.Priority > 5) {
+		t.fail("priority %d is not between 1 and 5", t.Priority)
+	}
+
+	var tags []string
+	for _, tag := range t.Tags {
+		tag = strings.TrimSpace(tag)
+		switch {
+		case tag == "":
+		case utf8.RuneCountInString(tag) > maxTagSize:
+			t.warn("tag %q is longer than %d characters and was left out", tag, maxTagSize)
+		default:
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) > maxTags {
+		t.warn("only the first %d of %d tags were kept", maxTags, len(tags))
+		tags = tags[:maxTags]
+	}
+	t.Tags = tags
+
+	items := t.Checklist[:0]
+	for _, item := range t.Checklist {
+		item.Text = strings.TrimSpace(item.Text)
+		if item.Text == "" {
+			continue
+		}
+		if s, cut := truncate(item.Text, maxItem); cut {
+			t.warn("checklist item cut to %d characters", maxItem)
+			item.Text = s
+		}
+		items = append(items, item)
+	}
+	t.Checklist = items
+}
+
+// addComment adds a comment from the source to the task's description,
+// since tasks have no comments of their own.
+func (t *Task) addComment(text string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+	if t.Description != "" {
+		t.Description += "\n\n"
+	}
+	t.Description += "Comment: " + text
+}
+
+// truncate cuts s to at most n characters and reports whether it did.
+func truncate(s string, n int) (string, bool) {
+	if utf8.RuneCountInString(s) <= n {
+		return s, false
+	}
+	return strings.TrimSpace(string([]rune(s)[:n])), true
+}
+
+// contentIDs makes external IDs for items that have no ID in their source,
+// from their content. The same content seen again gets a counter, so
+// duplicate rows stay apart.
+type contentIDs map[string]int
+
+func (ids contentIDs) next(kind string, parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	id := kind + ":" + hex.EncodeToString(sum[:10])
+	ids[id]++
+	if n := ids[id]; n > 1 {
+		id = fmt.Sprintf("%s#%d", id, n)
+	}
+	return id
+}
+
+// dateLayouts are the layouts parseDate accepts, besides RFC 3339.
+var dateLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+}
+
+// parseDate reads a date with an optional time. Times without a zone are
+// read in loc, and a date on its own is due at the end of that day.
+func parseDate(s string, loc *time.Location) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	d, err := time.ParseInLocation(time.DateOnly, s, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("date %q is not understood", s)
+	}
+	return time.Date(d.Year(), d.Month(), d.Day(), 23, 59, 59, 0, loc), nil
+}
@@ -0,0 +1,422 @@
+package importer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pavelc4/auriya-todolist-go/internal/quickadd"
+)
+
+// todoistID is an ID from Todoist, which older exports write as numbers and
+// newer ones as strings.
+type todoistID string
+
+func (id *todoistID) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*id = todoistID(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(b, &n); err != nil {
+		return fmt.Errorf("id must be a string or a number")
+	}
+	*id = todoistID(n.String())
+	return nil
+}
+
+// todoistBackup is the part of a Todoist JSON export this package reads.
+// Sync API dumps list tasks as items; REST dumps call them tasks.
+type todoistBackup struct {
+	Projects []todoistProject `json:"projects"`
+	Sections []todoistSection `json:"sections"`
+	Items    []todoistItem    `json:"items"`
+	Tasks    []todoistItem    `json:"tasks"`
+	Notes    []todoistNote    `json:"notes"`
+}
+
+type todoistProject struct {
+	ID             todoistID `json:"id"`
+	Name           string    `json:"name"`
+	InboxProject   bool      `json:"inbox_project"`
+	IsInboxProject bool      `json:"is_inbox_project"`
+	IsDeleted      bool      `json:"is_deleted"`
+}
+
+type todoistSection struct {
+	ID        todoistID `json:"id"`
+	ProjectID todoistID `json:"project_id"`
+	Name      string    `json:"name"`
+	IsDeleted bool      `json:"is_deleted"`
+}
+
+type todoistItem struct {
+	ID          todoistID     `json:"id"`
+	ProjectID   todoistID     `json:"project_id"`
+	SectionID   todoistID     `json:"section_id"`
+	ParentID    todoistID     `json:"parent_id"`
+	Content     string        `json:"content"`
+	Description string        `json:"description"`
+	Priority    int           `json:"priority"`
+	Due         *todoistDue   `json:"due"`
+	Labels      []string      `json:"labels"`
+	Checked     bool          `json:"checked"`
+	IsCompleted bool          `json:"is_completed"`
+	IsDeleted   bool          `json:"is_deleted"`
+	Duration    *todoistSpan  `json:"duration"`
+	ChildOrder  int           `json:"child_order"`
+	row         int           `json:"-"`
+	children    []todoistItem `json:"-"`
+}
+
+type todoistDue struct {
+	Date        string `json:"date"`
+	String      string `json:"string"`
+	IsRecurring bool   `json:"is_recurring"`
+	Timezone    string `json:"timezone"`
+}
+
+type todoistSpan struct {
+	Amount int    `json:"amount"`
+	Unit   string `json:"unit"`
+}
+
+type todoistNote struct {
+	ItemID    todoistID `json:"item_id"`
+	Content   string    `json:"content"`
+	IsDeleted bool      `json:"is_deleted"`
+}
+
+// todoistPriorities maps Todoist's API priorities, where 4 is the most
+// urgent (shown as p1), to ours.
+var todoistPriorities = map[int]int32{1: 1, 2: 3, 3: 4, 4: 5}
+
+func parseTodoistJSON(r io.Reader, opts Options) (*Plan, error) {
+	var backup todoistBackup
+	if err := json.NewDecoder(r).Decode(&backup); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	items := append(backup.Items, backup.Tasks...)
+	if len(backup.Projects) == 0 && len(items) == 0 {
+		return nil, fmt.Errorf("%w: no projects or tasks found", ErrInvalidFile)
+	}
+
+	plan := &Plan{}
+	// projects maps Todoist project IDs to external IDs; the inbox maps to "".
+	projects := make(map[todoistID]string)
+	projectIndex := make(map[todoistID]int)
+	for i, p := range backup.Projects {
+		if p.IsDeleted {
+			continue
+		}
+		if p.InboxProject || p.IsInboxProject {
+			projects[p.ID] = ""
+			continue
+		}
+		ext := "project:" + string(p.ID)
+		projects[p.ID] = ext
+		projectIndex[p.ID] = len(plan.Projects)
+		plan.Projects = append(plan.Projects, Project{Row: i + 1, ExternalID: ext, Name: p.Name})
+	}
+
+	sections := make(map[todoistID]string)
+	for _, s := range backup.Sections {
+		if s.IsDeleted {
+			continue
+		}
+		sections[s.ID] = s.Name
+		if i, ok := projectIndex[s.ProjectID]; ok {
+			plan.Projects[i].Sections = append(plan.Projects[i].Sections, s.Name)
+		}
+	}
+
+	// Subtasks become checklist items of the top task above them.
+	byID := make(map[todoistID]*todoistItem)
+	for i := range items {
+		items[i].row = i + 1
+		byID[items[i].ID] = &items[i]
+	}
+	root := func(it *todoistItem) *todoistItem {
+		for depth := 0; it.ParentID != "" && depth < 100; depth++ {
+			parent, ok := byID[it.ParentID]
+			if !ok {
+				break
+			}
+			it = parent
+		}
+		return it
+	}
+	var tops []*todoistItem
+	for i := range items {
+		it := &items[i]
+		if it.IsDeleted {
+			continue
+		}
+		if top := root(it); top != it {
+			if !top.IsDeleted {
+				top.children = append(top.children, *it)
+			}
+			continue
+		}
+		tops = append(tops, it)
+	}
+
+	notes := make(map[todoistID][]string)
+	for _, n := range backup.Notes {
+		if !n.IsDeleted {
+			notes[n.ItemID] = append(notes[n.ItemID], n.Content)
+		}
+	}
+
+	for _, it := range tops {
+		t := Task{
+			Row:         it.row,
+			ExternalID:  "task:" + string(it.ID),
+			Section:     sections[it.SectionID],
+			Title:       it.Content,
+			Description: it.Description,
+			Priority:    todoistPriorities[it.Priority],
+			Tags:        it.Labels,
+		}
+		project, ok := projects[it.ProjectID]
+		switch {
+		case ok:
+			t.Project = project
+		case len(backup.Projects) > 0:
+			t.fail("project %s is not in the file", it.ProjectID)
+		}
+		if it.Checked || it.IsCompleted {
+			t.Category = "done"
+		}
+		if it.Due != nil {
+			todoistDueDate(&t, *it.Due, opts)
+		}
+		if it.Duration != nil && it.Duration.Amount > 0 {
+			seconds := int32(it.Duration.Amount * 60)
+			if it.Duration.Unit == "day" {
+				seconds = int32(it.Duration.Amount * 24 * 3600)
+			}
+			t.Estimate = &seconds
+		}
+		sort.SliceStable(it.children, func(i, j int) bool { return it.children[i].ChildOrder < it.children[j].ChildOrder })
+		for _, child := range it.children {
+			t.Checklist = append(t.Checklist, ChecklistItem{Text: child.Content, Checked: child.Checked || child.IsCompleted})
+		}
+		for _, note := range notes[it.ID] {
+			t.addComment(note)
+		}
+		plan.Tasks = append(plan.Tasks, t)
+	}
+	return plan, nil
+}
+
+// todoistDueDate sets the due date and recurrence of a task from a Todoist
+// due object. Repeating dates are read from their text, such as
+// "every monday", the same way quick add reads them.
+func todoistDueDate(t *Task, due todoistDue, opts Options) {
+	loc := opts.Location
+	if due.Timezone != "" {
+		if l, err := time.LoadLocation(due.Timezone); err == nil {
+			loc = l
+		}
+	}
+	if due.Date != "" {
+		d, err := parseDate(due.Date, loc)
+		if err != nil {
+			t.warn("%v; imported without a due date", err)
+		} else {
+			t.Due = &d
+		}
+	}
+	if due.IsRecurring {
+		res := quickadd.Parse(due.String, opts.Now.In(loc))
+		if res.Recurrence == "" {
+			t.warn("repeating due date %q is not supported; imported as a one-off", due.String)
+		} else {
+			t.Recurrence = res.Recurrence
+		}
+	}
+}
+
+// todoistCSVPriorities maps the priorities of Todoist's CSV files, where 1
+// is the most urgent, to ours.
+var todoistCSVPriorities = map[string]int32{"1": 5, "2": 4, "3": 3, "4": 1}
+
+func parseTodoistCSV(r io.Reader, opts Options) (*Plan, error) {
+	if strings.TrimSpace(opts.Project) == "" {
+		return nil, fmt.Errorf("%w: a Todoist CSV file needs a project name", ErrInvalidFile)
+	}
+	rows, err := readCSV(r)
+	if err != nil {
+		return nil, err
+	}
+	col := func(name string) int { return rows.column(name) }
+	typeCol, contentCol := col("TYPE"), col("CONTENT")
+	if typeCol < 0 || contentCol < 0 {
+		return nil, fmt.Errorf("%w: not a Todoist CSV file, it has no TYPE and CONTENT columns", ErrInvalidFile)
+	}
+	descCol, prioCol, indentCol := col("DESCRIPTION"), col("PRIORITY"), col("INDENT")
+	dateCol, tzCol := col("DATE"), col("TIMEZONE")
+
+	projectID := "project:name:" + strings.ToLower(strings.TrimSpace(opts.Project))
+	plan := &Plan{Projects: []Project{{Row: 1, ExternalID: projectID, Name: opts.Project, MatchByName: true}}}
+	ids := contentIDs{}
+	section := ""
+	var last *Task
+	for _, rec := range rows.records {
+		content := strings.TrimSpace(rec.get(contentCol))
+		switch strings.ToLower(strings.TrimSpace(rec.get(typeCol))) {
+		case "section":
+			section = content
+			plan.Projects[0].Sections = append(plan.Projects[0].Sections, content)
+			last = nil
+		case "note":
+			if last != nil {
+				last.addComment(content)
+			}
+		case "task":
+			indent, _ := strconv.Atoi(rec.get(indentCol))
+			if indent > 1 && last != nil {
+				title, _ := todoistLabels(content)
+				last.Checklist = append(last.Checklist, ChecklistItem{Text: title})
+				continue
+			}
+			title, labels := todoistLabels(content)
+			t := Task{
+				Row:         rec.line,
+				ExternalID:  ids.next("task", projectID, section, content),
+				Project:     projectID,
+				Section:     section,
+				Title:       title,
+				Description: rec.get(descCol),
+				Priority:    todoistCSVPriorities[strings.TrimSpace(rec.get(prioCol))],
+				Tags:        labels,
+			}
+			if date := strings.TrimSpace(rec.get(dateCol)); date != "" {
+				loc := opts.Location
+				if tz := strings.TrimSpace(rec.get(tzCol)); tz != "" {
+					if l, err := time.LoadLocation(tz); err == nil {
+						loc = l
+					}
+				}
+				todoistDateText(&t, date, opts.Now.In(loc))
+			}
+			plan.Tasks = append(plan.Tasks, t)
+			last = &plan.Tasks[len(plan.Tasks)-1]
+		}
+	}
+	if len(plan.Tasks) == 0 && len(plan.Projects[0].Sections) == 0 {
+		return nil, fmt.Errorf("%w: no tasks found", ErrInvalidFile)
+	}
+	return plan, nil
+}
+
+// todoistDateText sets a task's due date from the DATE column of a Todoist
+// CSV file, which holds what the user typed, such as "every monday" or
+// "Oct 20".
+func todoistDateText(t *Task, text string, now time.Time) {
+	if d, err := parseDate(text, now.Location()); err == nil {
+		t.Due = &d
+		return
+	}
+	res := quickadd.Parse(text, now)
+	if res.Due == nil {
+		t.warn("due date %q is not understood; imported without one", text)
+		return
+	}
+	t.Due = res.Due
+	t.Recurrence = res.Recurrence
+	if res.Title != "" {
+		t.warn("only part of the due date %q was understood", text)
+	}
+}
+
+// todoistLabels splits the @labels Todoist CSV files add to the end of a
+// task's content from its title.
+func todoistLabels(content string) (string, []string) {
+	words := strings.Fields(content)
+	end := len(words)
+	for end > 0 && len(words[end-1]) > 1 && words[end-1][0] == '@' {
+		end--
+	}
+	if end == len(words) || end == 0 {
+		return content, nil
+	}
+	var labels []string
+	for _, w := range words[end:] {
+		labels = append(labels, w[1:])
+	}
+	return strings.Join(words[:end], " "), labels
+}
+
+// csvRows is a CSV file with a header row.
+type csvRows struct {
+	header  []string
+	records []csvRecord
+}
+
+// csvRecord is a row of a CSV file and the line it starts on.
+type csvRecord struct {
+	line   int
+	fields []string
+}
+
+// readCSV reads a CSV file with a header row. Rows may have fewer fields
+// than the header.
+func readCSV(r io.Reader) (*csvRows, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: the file is empty", ErrInvalidFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+	rows := &csvRows{header: header}
+	for {
+		fields, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+		}
+		line, _ := cr.FieldPos(0)
+		rows.records = append(rows.records, csvRecord{line: line, fields: fields})
+		if len(rows.records) > MaxTasks*2 {
+			return nil, fmt.Errorf("%w: more than %d tasks", ErrInvalidFile, MaxTasks)
+		}
+	}
+	return rows, nil
+}
+
+// column returns the index of a header column, ignoring case, or -1.
+func (rows *csvRows) column(name string) int {
+	for i, h := range rows.header {
+		if strings.EqualFold(strings.TrimSpace(h), name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// get returns the field at index i, or "" when the row is shorter or i is
+// -1.
+func (rec csvRecord) get(i int) string {
+	if i < 0 || i >= len(rec.fields) {
+		return ""
+	}
+	return rec.fields[i]
+}
@@ -0,0 +1,184 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// trelloBoard is the part of a Trello board export this package reads.
+type trelloBoard struct {
+	ID         string            `json:"id"`
+	Name       string            `json:"name"`
+	Lists      []trelloList      `json:"lists"`
+	Cards      []trelloCard      `json:"cards"`
+	Checklists []trelloChecklist `json:"checklists"`
+	Actions    []trelloAction    `json:"actions"`
+}
+
+type trelloList struct {
+	ID     string  `json:"id"`
+	Name   string  `json:"name"`
+	Closed bool    `json:"closed"`
+	Pos    float64 `json:"pos"`
+}
+
+type trelloCard struct {
+	ID          string        `json:"id"`
+	Name        string        `json:"name"`
+	Desc        string        `json:"desc"`
+	IDList      string        `json:"idList"`
+	Closed      bool          `json:"closed"`
+	Due         *string       `json:"due"`
+	DueComplete bool          `json:"dueComplete"`
+	Labels      []trelloLabel `json:"labels"`
+	Pos         float64       `json:"pos"`
+}
+
+type trelloLabel struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+type trelloChecklist struct {
+	ID         string            `json:"id"`
+	IDCard     string            `json:"idCard"`
+	Name       string            `json:"name"`
+	Pos        float64           `json:"pos"`
+	CheckItems []trelloCheckItem `json:"checkItems"`
+}
+
+type trelloCheckItem struct {
+	Name  string  `json:"name"`
+	State string  `json:"state"`
+	Pos   float64 `json:"pos"`
+}
+
+type trelloAction struct {
+	Type string `json:"type"`
+	Data struct {
+		Text string `json:"text"`
+		Card struct {
+			ID string `json:"id"`
+		} `json:"card"`
+	} `json:"data"`
+}
+
+// parseTrello reads a board export into one project, with a section for
+// every open list. Cards keep the order of the board.
+func parseTrello(r io.Reader, opts Options) (*Plan, error) {
+	var board trelloBoard
+	if err := json.NewDecoder(r).Decode(&board); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	if board.ID == "" && len(board.Cards) == 0 {
+		return nil, fmt.Errorf("%w: not a Trello board export", ErrInvalidFile)
+	}
+
+	projectID := "project:" + board.ID
+	project := Project{Row: 1, ExternalID: projectID, Name: board.Name}
+	if name := strings.TrimSpace(opts.Project); name != "" {
+		project.Name = name
+	}
+
+	lists := make(map[string]trelloList, len(board.Lists))
+	for _, l := range board.Lists {
+		lists[l.ID] = l
+	}
+	open := make([]trelloList, 0, len(board.Lists))
+	for _, l := range board.Lists {
+		if !l.Closed {
+			open = append(open, l)
+		}
+	}
+	sort.SliceStable(open, func(i, j int) bool { return open[i].Pos < open[j].Pos })
+	for _, l := range open {
+		project.Sections = append(project.Sections, l.Name)
+	}
+
+	checklists := make(map[string][]trelloChecklist)
+	for _, cl := range board.Checklists {
+		checklists[cl.IDCard] = append(checklists[cl.IDCard], cl)
+	}
+	// Actions are listed newest first; comments are added oldest first.
+	comments := make(map[string][]string)
+	for i := len(board.Actions) - 1; i >= 0; i-- {
+		a := board.Actions[i]
+		if a.Type == "commentCard" {
+			comments[a.Data.Card.ID] = append(comments[a.Data.Card.ID], a.Data.Text)
+		}
+	}
+
+	order := make([]int, len(board.Cards))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := board.Cards[order[i]], board.Cards[order[j]]
+		if la, lb := lists[a.IDList].Pos, lists[b.IDList].Pos; la != lb {
+			return la < lb
+		}
+		return a.Pos < b.Pos
+	})
+
+	plan := &Plan{Projects: []Project{project}}
+	for _, i := range order {
+		c := board.Cards[i]
+		list, ok := lists[c.IDList]
+		t := Task{
+			Row:         i + 1,
+			ExternalID:  "task:" + c.ID,
+			Project:     projectID,
+			Title:       c.Name,
+			Description: c.Desc,
+		}
+		switch {
+		case c.Closed:
+			t.Skip = "archived in Trello"
+		case !ok:
+			t.warn("list %s is not in the file; imported without a section", c.IDList)
+		case list.Closed:
+			t.Skip = "its list is archived in Trello"
+		default:
+			t.Section = list.Name
+		}
+		if c.DueComplete {
+			t.Category = "done"
+		}
+		if c.Due != nil && *c.Due != "" {
+			if d, err := time.Parse(time.RFC3339, *c.Due); err == nil {
+				t.Due = &d
+			} else {
+				t.warn("due date %q is not understood; imported without one", *c.Due)
+			}
+		}
+		for _, l := range c.Labels {
+			if l.Name != "" {
+				t.Tags = append(t.Tags, l.Name)
+			} else if l.Color != "" {
+				t.Tags = append(t.Tags, l.Color)
+			}
+		}
+
+		cls := checklists[c.ID]
+		sort.SliceStable(cls, func(i, j int) bool { return cls[i].Pos < cls[j].Pos })
+		for _, cl := range cls {
+			items := cl.CheckItems
+			sort.SliceStable(items, func(i, j int) bool { return items[i].Pos < items[j].Pos })
+			for _, item := range items {
+				t.Checklist = append(t.Checklist, ChecklistItem{Text: item.Name, Checked: item.State == "complete"})
+			}
+		}
+		if len(cls) > 1 {
+			t.warn("%d checklists were merged into one", len(cls))
+		}
+		for _, text := range comments[c.ID] {
+			t.addComment(text)
+		}
+		plan.Tasks = append(plan.Tasks, t)
+	}
+	return plan, nil
+}
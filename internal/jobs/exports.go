@@ -0,0 +1,161 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/pavelc4/auriya-todolist-go/internal/db/sqlc"
+	"github.com/pavelc4/auriya-todolist-go/internal/http/repository"
+)
+
+const (
+	// exportLease is how long a worker has to write an export before another
+	// one may take it over.
+	exportLease         = time.Hour
+	exportPurgeBatch    = 100
+	exportPurgeInterval = time.Hour
+)
+
+// ExportFunc writes the file of a background export.
+type ExportFunc func(ctx context.Context, w io.Writer, export db.Export) error
+
+// ExportWorker writes queued data exports to files in Dir, which every
+// instance serving downloads must share. Finished exports, failed ones
+// included, are deleted with their files Retention after they finish.
+type ExportWorker struct {
+	Store     *repository.Store
+	Write     ExportFunc
+	Dir       string
+	Retention time.Duration
+	Interval  time.Duration
+}
+
+func NewExportWorker(store *repository.Store, write ExportFunc, dir string, retention, interval time.Duration) *ExportWorker {
+	return &ExportWorker{Store: store, Write: write, Dir: dir, Retention: retention, Interval: interval}
+}
+
+// Run writes queued exports once immediately and then on every tick until
+// ctx is cancelled.
+func (w *ExportWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	var purged time.Time
+	for {
+		if err := w.work(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("exports: %v", err)
+		}
+		if time.Since(purged) >= exportPurgeInterval {
+			if err := w.purge(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("exports: purge: %v", err)
+			}
+			purged = time.Now()
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// work writes queued exports one at a time until none are left.
+func (w *ExportWorker) work(ctx context.Context) error {
+	for ctx.Err() == nil {
+		lease := pgtype.Timestamptz{Time: time.Now().Add(exportLease), Valid: true}
+		e, err := w.Store.Queries.ClaimExport(ctx, lease)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		arg := db.FinishExportParams{ID: e.ID, Status: "succeeded"}
+		path, size, err := w.writeFile(ctx, e)
+		if err != nil {
+			if ctx.Err() != nil {
+				// Shutting down; the lease runs out and the export is
+				// picked up again.
+				return nil
+			}
+			log.Printf("exports: export %d: %v", e.ID, err)
+			arg.Status = "failed"
+			arg.Error = pgtype.Text{String: err.Error(), Valid: true}
+		} else {
+			arg.FilePath = pgtype.Text{String: path, Valid: true}
+			arg.SizeBytes = pgtype.Int8{Int64: size, Valid: true}
+		}
+		arg.ExpiresAt = pgtype.Timestamptz{Time: time.Now().Add(w.Retention), Valid: true}
+		if err := w.Store.Queries.FinishExport(ctx, arg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFile writes an export to a temporary file and moves it into place
+// once it is complete, returning its path and size.
+func (w *ExportWorker) writeFile(ctx context.Context, e db.Export) (string, int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, exportLease)
+	defer cancel()
+
+	if err := os.MkdirAll(w.Dir, 0o700); err != nil {
+		return "", 0, err
+	}
+	f, err := os.CreateTemp(w.Dir, fmt.Sprintf("export-%d-*.tmp", e.ID))
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(f.Name())
+
+	if err := w.Write(ctx, f, e); err != nil {
+		f.Close()
+		return "", 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return "", 0, err
+	}
+	if err := f.Close(); err != nil {
+		return "", 0, err
+	}
+	path := filepath.Join(w.Dir, fmt.Sprintf("export-%d", e.ID))
+	if err := os.Rename(f.Name(), path); err != nil {
+		return "", 0, err
+	}
+	return path, info.Size(), nil
+}
+
+// purge deletes expired exports and their files.
+func (w *ExportWorker) purge(ctx context.Context) error {
+	for {
+		expired, err := w.Store.Queries.ListExpiredExports(ctx, exportPurgeBatch)
+		if err != nil {
+			return err
+		}
+		for _, e := range expired {
+			if e.FilePath.Valid {
+				if err := os.Remove(e.FilePath.String); err != nil && !errors.Is(err, fs.ErrNotExist) {
+					return err
+				}
+			}
+			if err := w.Store.Queries.DeleteExport(ctx, e.ID); err != nil {
+				return err
+			}
+		}
+		if len(expired) < exportPurgeBatch {
+			return nil
+		}
+	}
+}
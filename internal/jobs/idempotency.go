@@ -0,0 +1,51 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/pavelc4/auriya-todolist-go/internal/http/repository"
+)
+
+// IdempotencyPurger deletes stored Idempotency-Key responses once they are
+// older than the retention window.
+type IdempotencyPurger struct {
+	Store     *repository.Store
+	Retention time.Duration
+	Interval  time.Duration
+}
+
+func NewIdempotencyPurger(store *repository.Store, retention, interval time.Duration) *IdempotencyPurger {
+	return &IdempotencyPurger{Store: store, Retention: retention, Interval: interval}
+}
+
+// Run purges once immediately and then on every tick until ctx is cancelled.
+func (p *IdempotencyPurger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := p.purge(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("idempotency purge: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *IdempotencyPurger) purge(ctx context.Context) error {
+	before := pgtype.Timestamptz{Time: time.Now().Add(-p.Retention), Valid: true}
+	n, err := p.Store.Queries.DeleteExpiredIdempotencyKeys(ctx, before)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		log.Printf("idempotency purge: removed %d keys", n)
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package jobs
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/pavelc4/auriya-todolist-go/internal/db/sqlc"
+	"github.com/pavelc4/auriya-todolist-go/internal/http/repository"
+	"github.com/pavelc4/auriya-todolist-go/internal/outbox"
+)
+
+const (
+	outboxBatch         = 100
+	outboxLease         = 2 * time.Minute
+	outboxSinkTimeout   = 10 * time.Second
+	outboxPurgeInterval = time.Hour
+	// outboxGiveUpAfter is how old an event may get before a sink that
+	// still fails it is given up on and the event marked dead.
+	outboxGiveUpAfter = 24 * time.Hour
+)
+
+// OutboxRelay publishes outbox events to its sinks, oldest first, at least
+// once: an event is marked published when every sink has it, and retried
+// with outbox.Backoff for the sinks that failed. Each sink gets events in
+// order: one that missed an event gets no newer ones, in this batch or
+// later ones, until it has it. Events still missing a sink after
+// outboxGiveUpAfter are marked dead, which unblocks the sink. Published and
+// dead events are deleted once they are older than Retention.
+//
+// Several instances may run a relay; a claimed batch is leased to one of
+// them for outboxLease.
+type OutboxRelay struct {
+	Store     *repository.Store
+	Sinks     []outbox.Sink
+	Retention time.Duration
+	Interval  time.Duration
+}
+
+func NewOutboxRelay(store *repository.Store, sinks []outbox.Sink, retention, interval time.Duration) *OutboxRelay {
+	return &OutboxRelay{Store: store, Sinks: sinks, Retention: retention, Interval: interval}
+}
+
+// Run relays due events once immediately and then on every tick until ctx
+// is cancelled.
+func (r *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	var purged time.Time
+	for {
+		if err := r.relay(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("outbox: %v", err)
+		}
+		if time.Since(purged) >= outboxPurgeInterval {
+			if err := r.purge(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("outbox: purge: %v", err)
+			}
+			purged = time.Now()
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// relay publishes batches of due events until none are left.
+func (r *OutboxRelay) relay(ctx context.Context) error {
+	for ctx.Err() == nil {
+		lease := pgtype.Timestamptz{Time: time.Now().Add(outboxLease), Valid: true}
+		batch, err := r.Store.Queries.ClaimOutboxEvents(ctx, db.ClaimOutboxEventsParams{LeaseUntil: lease, Limit: outboxBatch})
+		if err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+		// UPDATE ... RETURNING does not keep the order of the subquery.
+		slices.SortFunc(batch, func(a, b db.Outbox) int { return cmp.Compare(a.ID, b.ID) })
+
+		down, err := r.behind(ctx, batch[0].ID)
+		if err != nil {
+			return err
+		}
+		for _, row := range batch {
+			if err := r.publish(ctx, row, down); err != nil {
+				return err
+			}
+		}
+		if len(batch) < outboxBatch || len(down) > 0 {
+			return nil
+		}
+	}
+	return nil
+}
+
+// errOutboxWaiting is the error of an event held back from a sink that
+// has not got an older event yet.
+var errOutboxWaiting = errors.New("waiting for an earlier event")
+
+// behind returns the sinks that still miss a live event older than id,
+// which must wait for it.
+func (r *OutboxRelay) behind(ctx context.Context, id int64) (map[string]error, error) {
+	down := make(map[string]error)
+	for _, sink := range r.Sinks {
+		name := sink.Name()
+		behind, err := r.Store.Queries.OutboxSinkBehind(ctx, db.OutboxSinkBehindParams{BeforeID: id, Sink: name})
+		if err != nil {
+			return nil, err
+		}
+		if behind {
+			down[name] = fmt.Errorf("%s: %w", name, errOutboxWaiting)
+		}
+	}
+	return down, nil
+}
+
+// publish hands one event to the sinks that do not have it yet and records
+// the outcome. down holds the sinks that failed or were behind earlier in
+// the batch.
+func (r *OutboxRelay) publish(ctx context.Context, row db.Outbox, down map[string]error) error {
+	ev := outbox.NewEvent(row)
+	done := row.PublishedSinks
+	var errs []error
+	failed := false
+	for _, sink := range r.Sinks {
+		name := sink.Name()
+		if slices.Contains(done, name) {
+			continue
+		}
+		if err, ok := down[name]; ok {
+			errs = append(errs, err)
+			failed = failed || !errors.Is(err, errOutboxWaiting)
+			continue
+		}
+
+		sinkCtx, cancel := context.WithTimeout(ctx, outboxSinkTimeout)
+		err := sink.Publish(sinkCtx, ev)
+		cancel()
+		if ctx.Err() != nil {
+			// Shutting down; the lease runs out and the event is tried again.
+			return ctx.Err()
+		}
+		if err != nil {
+			err = errors.New(name + ": " + err.Error())
+			log.Printf("outbox: event %s: %v", ev.ID, err)
+			down[name] = err
+			errs = append(errs, err)
+			failed = true
+			continue
+		}
+		done = append(done, name)
+	}
+
+	if len(errs) == 0 {
+		return r.Store.Queries.MarkOutboxPublished(ctx, db.MarkOutboxPublishedParams{PublishedSinks: done, ID: row.ID})
+	}
+	lastError := pgtype.Text{String: errors.Join(errs...).Error(), Valid: true}
+	if time.Since(row.CreatedAt.Time) > outboxGiveUpAfter {
+		log.Printf("outbox: event %s: giving up after %s: %s", ev.ID, outboxGiveUpAfter, lastError.String)
+		return r.Store.Queries.MarkOutboxDead(ctx, db.MarkOutboxDeadParams{PublishedSinks: done, LastError: lastError, ID: row.ID})
+	}
+	// Waiting for an earlier event is not a failed attempt, so it does
+	// not lengthen the backoff.
+	attempts := row.Attempts
+	if failed {
+		attempts++
+	}
+	return r.Store.Queries.MarkOutboxFailed(ctx, db.MarkOutboxFailedParams{
+		PublishedSinks: done,
+		Attempts:       attempts,
+		LastError:      lastError,
+		NextAttemptAt:  pgtype.Timestamptz{Time: time.Now().Add(outbox.Backoff(int(attempts) + 1)), Valid: true},
+		ID:             row.ID,
+	})
+}
+
+func (r *OutboxRelay) purge(ctx context.Context) error {
+	before := pgtype.Timestamptz{Time: time.Now().Add(-r.Retention), Valid: true}
+	n, err := r.Store.Queries.DeleteExpiredOutboxEvents(ctx, before)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		log.Printf("outbox: purged %d published or dead events", n)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/pavelc4/auriya-todolist-go/internal/cache"
+	db "github.com/pavelc4/auriya-todolist-go/internal/db/sqlc"
+	"github.com/pavelc4/auriya-todolist-go/internal/http/repository"
+)
+
+// TimerStopper ends timers that were left running for longer than MaxRunning.
+// Each stopped entry is credited exactly MaxRunning and marked auto_stopped so
+// it can be reviewed and corrected.
+type TimerStopper struct {
+	Store      *repository.Store
+	Cache      *cache.Service
+	MaxRunning time.Duration
+	Interval   time.Duration
+}
+
+func NewTimerStopper(store *repository.Store, cacheSvc *cache.Service, maxRunning, interval time.Duration) *TimerStopper {
+	return &TimerStopper{Store: store, Cache: cacheSvc, MaxRunning: maxRunning, Interval: interval}
+}
+
+// Run checks once immediately and then on every tick until ctx is cancelled.
+func (s *TimerStopper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.stop(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("timer auto-stop: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// stop ends stale timers one user at a time, each in a transaction that
+// holds the user's sync lock, like the user's own writes. A user whose
+// timers cannot be stopped is logged and tried again on the next tick.
+func (s *TimerStopper) stop(ctx context.Context) error {
+	users, err := s.Store.Queries.ListStaleTimerUsers(ctx, int64(s.MaxRunning/time.Second))
+	if err != nil {
+		return err
+	}
+	var total int
+	for _, userID := range users {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var stopped []db.TimeEntry
+		err := s.Store.ExecTx(repository.WithUser(ctx, userID), func(q *db.Queries) error {
+			var err error
+			stopped, err = repository.StopStaleTimers(ctx, q, userID, s.MaxRunning)
+			return err
+		})
+		if err != nil {
+			log.Printf("timer auto-stop: user %d: %v", userID, err)
+			continue
+		}
+		for _, e := range stopped {
+			s.Cache.Delete(fmt.Sprintf("task:%d", e.TaskID))
+		}
+		total += len(stopped)
+	}
+	if total > 0 {
+		log.Printf("timer auto-stop: stopped %d timers", total)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/pavelc4/auriya-todolist-go/internal/http/repository"
+)
+
+// TombstonePurger deletes the sync tombstones of hard deletes once they are
+// older than the retention window. Sync tokens issued before then are
+// answered with a full resync. The CalDAV names of the purged tasks go with
+// them.
+type TombstonePurger struct {
+	Store     *repository.Store
+	Retention time.Duration
+	Interval  time.Duration
+}
+
+func NewTombstonePurger(store *repository.Store, retention, interval time.Duration) *TombstonePurger {
+	return &TombstonePurger{Store: store, Retention: retention, Interval: interval}
+}
+
+// Run purges once immediately and then on every tick until ctx is cancelled.
+func (p *TombstonePurger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := p.purge(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("tombstone purge: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *TombstonePurger) purge(ctx context.Context) error {
+	before := pgtype.Timestamptz{Time: time.Now().Add(-p.Retention), Valid: true}
+	n, err := p.Store.Queries.DeleteExpiredSyncTombstones(ctx, before)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		log.Printf("tombstone purge: removed %d tombstones", n)
+	}
+	if _, err := p.Store.Queries.DeleteOrphanedCaldavObjects(ctx); err != nil {
+		return err
+	}
+	return nil
+}
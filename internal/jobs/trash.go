@@ -0,0 +1,79 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/pavelc4/auriya-todolist-go/internal/db/sqlc"
+	"github.com/pavelc4/auriya-todolist-go/internal/http/repository"
+)
+
+// TrashPurger permanently removes tasks and projects that have been in the
+// trash for longer than the retention window.
+type TrashPurger struct {
+	Store     *repository.Store
+	Retention time.Duration
+	Interval  time.Duration
+}
+
+func NewTrashPurger(store *repository.Store, retention, interval time.Duration) *TrashPurger {
+	return &TrashPurger{Store: store, Retention: retention, Interval: interval}
+}
+
+// Run purges once immediately and then on every tick until ctx is cancelled.
+func (p *TrashPurger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := p.purge(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("trash purge: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purge removes expired trash one user at a time, each in a transaction
+// that holds the user's sync lock, like the user's own writes. A user whose
+// purge fails is logged and tried again on the next tick.
+func (p *TrashPurger) purge(ctx context.Context) error {
+	before := pgtype.Timestamptz{Time: time.Now().Add(-p.Retention), Valid: true}
+	users, err := p.Store.Queries.ListExpiredTrashUsers(ctx, before)
+	if err != nil {
+		return err
+	}
+
+	var tasks, projects int64
+	for _, userID := range users {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var t, pr int64
+		err := p.Store.ExecTx(repository.WithUser(ctx, userID), func(q *db.Queries) error {
+			// Tasks first so a project's trashed tasks go with it instead
+			// of being detached by the ON DELETE SET NULL foreign key.
+			var err error
+			if t, err = q.PurgeExpiredTasks(ctx, db.PurgeExpiredTasksParams{UserID: userID, Before: before}); err != nil {
+				return err
+			}
+			pr, err = q.PurgeExpiredProjects(ctx, db.PurgeExpiredProjectsParams{UserID: userID, DeletedAt: before})
+			return err
+		})
+		if err != nil {
+			log.Printf("trash purge: user %d: %v", userID, err)
+			continue
+		}
+		tasks += t
+		projects += pr
+	}
+	if tasks > 0 || projects > 0 {
+		log.Printf("trash purge: removed %d tasks and %d projects", tasks, projects)
+	}
+	return nil
+}
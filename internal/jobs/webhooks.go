@@ -0,0 +1,265 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/pavelc4/auriya-todolist-go/internal/db/sqlc"
+	"github.com/pavelc4/auriya-todolist-go/internal/http/repository"
+	"github.com/pavelc4/auriya-todolist-go/internal/webhooks"
+)
+
+const (
+	webhookBatch       = 50
+	webhookConcurrency = 8
+	// webhookDrainLimit is how much of a receiver's answer is read, and
+	// thrown away, so that the connection can be reused.
+	webhookDrainLimit    = 64 << 10
+	webhookPurgeInterval = time.Hour
+)
+
+// WebhookDispatcher sends queued webhook deliveries. A delivery that fails
+// is retried with webhooks.Backoff until it succeeds or has been tried
+// webhooks.MaxAttempts times, and a webhook that keeps failing is turned
+// off. Every attempt is logged with the receiver's status code. Finished
+// deliveries are deleted once they are older than Retention.
+//
+// Receivers are dialled directly, never through a proxy, and unless
+// AllowPrivate is set only on public addresses; see webhooks.DialControl.
+//
+// Several instances may run a dispatcher; a claimed delivery is leased to
+// one of them for Timeout plus a minute, after which it is claimed again if
+// its instance went away without recording the attempt.
+type WebhookDispatcher struct {
+	Store     *repository.Store
+	Render    webhooks.RenderFunc
+	Client    *http.Client
+	Timeout   time.Duration
+	Retention time.Duration
+	Interval  time.Duration
+}
+
+func NewWebhookDispatcher(store *repository.Store, render webhooks.RenderFunc, timeout, retention, interval time.Duration, allowPrivate bool) *WebhookDispatcher {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if !allowPrivate {
+		dialer.Control = webhooks.DialControl
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+		// A redirect is an answer like any other; following it would send the
+		// signed payload somewhere the user did not configure.
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return &WebhookDispatcher{Store: store, Render: render, Client: client, Timeout: timeout, Retention: retention, Interval: interval}
+}
+
+// Run sends due deliveries once immediately and then on every tick until ctx
+// is cancelled.
+func (d *WebhookDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.Interval)
+	defer ticker.Stop()
+
+	var purged time.Time
+	for {
+		if err := d.dispatch(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("webhooks: %v", err)
+		}
+		if time.Since(purged) >= webhookPurgeInterval {
+			if err := d.purge(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("webhooks: purge: %v", err)
+			}
+			purged = time.Now()
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// dispatch sends batches of due deliveries until none are left.
+func (d *WebhookDispatcher) dispatch(ctx context.Context) error {
+	for ctx.Err() == nil {
+		lease := pgtype.Timestamptz{Time: time.Now().Add(d.Timeout + time.Minute), Valid: true}
+		batch, err := d.Store.Queries.ClaimWebhookDeliveries(ctx, db.ClaimWebhookDeliveriesParams{LeaseUntil: lease, Limit: webhookBatch})
+		if err != nil {
+			return err
+		}
+
+		sem := make(chan struct{}, webhookConcurrency)
+		var wg sync.WaitGroup
+		for _, delivery := range batch {
+			sem <- struct{}{}
+			wg.Add(1)
+			go func() {
+				defer func() { <-sem; wg.Done() }()
+				if err := d.deliver(ctx, delivery); err != nil && ctx.Err() == nil {
+					log.Printf("webhooks: delivery %d: %v", delivery.ID, err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		if len(batch) < webhookBatch {
+			return nil
+		}
+	}
+	return nil
+}
+
+// deliver makes one attempt at a delivery and records its outcome.
+func (d *WebhookDispatcher) deliver(ctx context.Context, delivery db.WebhookDelivery) error {
+	q := d.Store.Queries
+	hook, err := q.GetWebhookByID(ctx, delivery.WebhookID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil // deleted meanwhile, along with its deliveries
+	}
+	if err != nil {
+		return err
+	}
+
+	body, err := d.payload(delivery)
+	if err != nil {
+		// Retrying cannot fix a payload that does not render.
+		err = fmt.Errorf("render payload: %w", err)
+		_, finishErr := q.FinishWebhookAttempt(ctx, db.FinishWebhookAttemptParams{
+			Status:        "failed",
+			LastError:     pgtype.Text{String: err.Error(), Valid: true},
+			NextAttemptAt: delivery.NextAttemptAt,
+			ID:            delivery.ID,
+		})
+		return errors.Join(err, finishErr)
+	}
+
+	start := time.Now()
+	status, sendErr := d.send(ctx, hook, delivery, body)
+	if ctx.Err() != nil {
+		// Shutting down; the lease runs out and the delivery is tried again.
+		return nil
+	}
+	ok := sendErr == nil && status >= 200 && status < 300
+
+	attempt := db.RecordWebhookAttemptParams{
+		DeliveryID: delivery.ID,
+		DurationMs: int32(time.Since(start).Milliseconds()),
+	}
+	finish := db.FinishWebhookAttemptParams{ID: delivery.ID, Status: "succeeded", NextAttemptAt: delivery.NextAttemptAt}
+	if status != 0 {
+		attempt.StatusCode = pgtype.Int4{Int32: int32(status), Valid: true}
+		finish.LastStatusCode = attempt.StatusCode
+	}
+	if !ok {
+		msg := "unexpected status " + strconv.Itoa(status)
+		if sendErr != nil {
+			msg = sendErr.Error()
+		}
+		attempt.Error = pgtype.Text{String: msg, Valid: true}
+		finish.LastError = attempt.Error
+		finish.Status = "failed"
+		if attempts := int(delivery.Attempts) + 1; attempts < webhooks.MaxAttempts {
+			finish.Status = "pending"
+			finish.NextAttemptAt = pgtype.Timestamptz{Time: time.Now().Add(webhooks.Backoff(attempts)), Valid: true}
+		}
+	}
+
+	return d.Store.ExecTx(ctx, func(q *db.Queries) error {
+		if err := q.RecordWebhookAttempt(ctx, attempt); err != nil {
+			return err
+		}
+		if _, err := q.FinishWebhookAttempt(ctx, finish); err != nil {
+			return err
+		}
+		if ok {
+			return q.ResetWebhookFailures(ctx, hook.ID)
+		}
+		after, err := q.CountWebhookFailure(ctx, db.CountWebhookFailureParams{MaxFailures: webhooks.MaxConsecutiveFailures, ID: hook.ID})
+		if err != nil {
+			return err
+		}
+		if hook.Active && !after.Active {
+			log.Printf("webhooks: disabled webhook %d after %d failed attempts", hook.ID, after.FailureCount)
+		}
+		return nil
+	})
+}
+
+// payload renders the body of a delivery.
+func (d *WebhookDispatcher) payload(delivery db.WebhookDelivery) ([]byte, error) {
+	data, err := d.Render(delivery.ResourceType, delivery.Payload)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(webhooks.Payload{
+		ID:        delivery.ID,
+		EventID:   eventID(delivery),
+		Event:     delivery.EventType,
+		WebhookID: delivery.WebhookID,
+		CreatedAt: delivery.CreatedAt.Time,
+		Data:      data,
+	})
+}
+
+// eventID returns the outbox event a delivery was queued for, or "" for
+// pings.
+func eventID(delivery db.WebhookDelivery) string {
+	if !delivery.EventID.Valid {
+		return ""
+	}
+	return delivery.EventID.String()
+}
+
+// send posts a signed body to the webhook. It returns the status code, or
+// zero when no response arrived. The body of the response is discarded.
+func (d *WebhookDispatcher) send(ctx context.Context, hook db.Webhook, delivery db.WebhookDelivery, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", webhooks.UserAgent)
+	req.Header.Set(webhooks.HeaderDelivery, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(webhooks.HeaderEvent, delivery.EventType)
+	if id := eventID(delivery); id != "" {
+		req.Header.Set(webhooks.HeaderEventID, id)
+	}
+	req.Header.Set(webhooks.HeaderSignature, webhooks.Sign(hook.Secret, time.Now(), body))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.CopyN(io.Discard, resp.Body, webhookDrainLimit)
+	return resp.StatusCode, nil
+}
+
+func (d *WebhookDispatcher) purge(ctx context.Context) error {
+	before := pgtype.Timestamptz{Time: time.Now().Add(-d.Retention), Valid: true}
+	n, err := d.Store.Queries.DeleteExpiredWebhookDeliveries(ctx, before)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		log.Printf("webhooks: purged %d old deliveries", n)
+	}
+	return nil
+}
@@ -0,0 +1,352 @@
+// Package jsonpatch applies RFC 6902 JSON Patch documents.
+//
+// A patch is applied to a whole document at once: if any operation fails,
+// Apply returns an error and no result, as the RFC requires.
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalid is returned by Decode for documents that are not a valid
+	// JSON Patch. The wrapped message says why.
+	ErrInvalid = errors.New("jsonpatch: invalid patch")
+	// ErrFailed is returned by Apply when an operation cannot be applied to
+	// the document, for example because its path does not exist.
+	ErrFailed = errors.New("jsonpatch: patch cannot be applied")
+	// ErrTestFailed is returned by Apply when a "test" operation does not
+	// match. It wraps ErrFailed.
+	ErrTestFailed = fmt.Errorf("%w: test failed", ErrFailed)
+)
+
+// Operation is one step of a patch. From is only used by "move" and "copy".
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+
+	path, from []string
+	value      any
+}
+
+// Patch is a decoded JSON Patch document.
+type Patch []Operation
+
+// Decode parses a JSON Patch document and checks every operation.
+func Decode(b []byte) (Patch, error) {
+	var p Patch
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+
+	for i := range p {
+		op := &p[i]
+		fail := func(format string, args ...any) error {
+			return fmt.Errorf("%w: operation %d: %s", ErrInvalid, i, fmt.Sprintf(format, args...))
+		}
+
+		var err error
+		if op.path, err = parsePointer(op.Path); err != nil {
+			return nil, fail("path: %v", err)
+		}
+		switch op.Op {
+		case "add", "replace", "test":
+			// A missing value stays nil, while "value": null is kept as
+			// the literal null.
+			if op.Value == nil {
+				return nil, fail("%q needs a value", op.Op)
+			}
+			if op.value, err = decodeValue(op.Value); err != nil {
+				return nil, fail("value: %v", err)
+			}
+		case "move", "copy":
+			if op.from, err = parsePointer(op.From); err != nil {
+				return nil, fail("from: %v", err)
+			}
+			if op.Op == "move" && isPrefix(op.from, op.path) && len(op.from) < len(op.path) {
+				return nil, fail("cannot move a value into itself")
+			}
+		case "remove":
+		default:
+			return nil, fail("unknown op %q", op.Op)
+		}
+	}
+	return p, nil
+}
+
+// Apply applies the patch to a JSON document and returns the result.
+func (p Patch) Apply(doc []byte) ([]byte, error) {
+	v, err := decodeValue(doc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: document: %v", ErrFailed, err)
+	}
+	for i, op := range p {
+		if v, err = op.apply(v); err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+	return json.Marshal(v)
+}
+
+func (op Operation) apply(doc any) (any, error) {
+	switch op.Op {
+	case "add":
+		return add(doc, op.path, clone(op.value))
+	case "remove":
+		if len(op.path) == 0 {
+			return nil, fmt.Errorf("%w: cannot remove the whole document", ErrFailed)
+		}
+		return edit(doc, op.path, removeAt)
+	case "replace":
+		if len(op.path) == 0 {
+			return clone(op.value), nil
+		}
+		return edit(doc, op.path, func(c any, key string) (any, error) {
+			if _, err := get(c, []string{key}); err != nil {
+				return nil, err
+			}
+			return setAt(c, key, clone(op.value))
+		})
+	case "test":
+		v, err := get(doc, op.path)
+		if err != nil {
+			return nil, err
+		}
+		if !equal(v, op.value) {
+			return nil, ErrTestFailed
+		}
+		return doc, nil
+	case "move":
+		v, err := get(doc, op.from)
+		if err != nil {
+			return nil, err
+		}
+		if len(op.from) == 0 {
+			return v, nil
+		}
+		if doc, err = edit(doc, op.from, removeAt); err != nil {
+			return nil, err
+		}
+		return add(doc, op.path, v)
+	default: // copy
+		v, err := get(doc, op.from)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, op.path, clone(v))
+	}
+}
+
+func add(doc any, path []string, v any) (any, error) {
+	if len(path) == 0 {
+		return v, nil
+	}
+	return edit(doc, path, func(c any, key string) (any, error) {
+		if a, ok := c.([]any); ok {
+			i := len(a)
+			if key != "-" {
+				var err error
+				if i, err = index(key, len(a)+1); err != nil {
+					return nil, err
+				}
+			}
+			a = append(a, nil)
+			copy(a[i+1:], a[i:])
+			a[i] = v
+			return a, nil
+		}
+		return setAt(c, key, v)
+	})
+}
+
+// edit walks to the container holding the last token of path and replaces
+// it with what fn returns, rebuilding the containers above it.
+func edit(node any, path []string, fn func(container any, key string) (any, error)) (any, error) {
+	if len(path) == 1 {
+		return fn(node, path[0])
+	}
+	child, err := get(node, path[:1])
+	if err != nil {
+		return nil, err
+	}
+	if child, err = edit(child, path[1:], fn); err != nil {
+		return nil, err
+	}
+	return setAt(node, path[0], child)
+}
+
+func get(node any, path []string) (any, error) {
+	for _, key := range path {
+		switch n := node.(type) {
+		case map[string]any:
+			v, ok := n[key]
+			if !ok {
+				return nil, fmt.Errorf("%w: member %q does not exist", ErrFailed, key)
+			}
+			node = v
+		case []any:
+			i, err := index(key, len(n))
+			if err != nil {
+				return nil, err
+			}
+			node = n[i]
+		default:
+			return nil, fmt.Errorf("%w: %q is not inside an object or array", ErrFailed, key)
+		}
+	}
+	return node, nil
+}
+
+func setAt(c any, key string, v any) (any, error) {
+	switch n := c.(type) {
+	case map[string]any:
+		n[key] = v
+		return n, nil
+	case []any:
+		i, err := index(key, len(n))
+		if err != nil {
+			return nil, err
+		}
+		n[i] = v
+		return n, nil
+	}
+	return nil, fmt.Errorf("%w: %q is not inside an object or array", ErrFailed, key)
+}
+
+func removeAt(c any, key string) (any, error) {
+	switch n := c.(type) {
+	case map[string]any:
+		if _, ok := n[key]; !ok {
+			return nil, fmt.Errorf("%w: member %q does not exist", ErrFailed, key)
+		}
+		delete(n, key)
+		return n, nil
+	case []any:
+		i, err := index(key, len(n))
+		if err != nil {
+			return nil, err
+		}
+		return append(n[:i], n[i+1:]...), nil
+	}
+	return nil, fmt.Errorf("%w: %q is not inside an object or array", ErrFailed, key)
+}
+
+// index parses an array index that must be below n. Leading zeros and "-"
+// are not allowed here; "-" is handled by add.
+func index(key string, n int) (int, error) {
+	i, err := strconv.Atoi(key)
+	if err != nil || i < 0 || (len(key) > 1 && key[0] == '0') || key[0] == '+' {
+		return 0, fmt.Errorf("%w: %q is not an array index", ErrFailed, key)
+	}
+	if i >= n {
+		return 0, fmt.Errorf("%w: index %d is out of range", ErrFailed, i)
+	}
+	return i, nil
+}
+
+// parsePointer splits an RFC 6901 JSON Pointer into unescaped tokens.
+func parsePointer(s string) ([]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if s[0] != '/' {
+		return nil, fmt.Errorf("%q must be empty or start with /", s)
+	}
+	tokens := strings.Split(s[1:], "/")
+	for i, t := range tokens {
+		if strings.Contains(strings.NewReplacer("~0", "", "~1", "").Replace(t), "~") {
+			return nil, fmt.Errorf("%q has an invalid ~ escape", s)
+		}
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(t)
+	}
+	return tokens, nil
+}
+
+func isPrefix(prefix, path []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func decodeValue(b []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func clone(v any) any {
+	switch n := v.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(n))
+		for k, e := range n {
+			m[k] = clone(e)
+		}
+		return m
+	case []any:
+		a := make([]any, len(n))
+		for i, e := range n {
+			a[i] = clone(e)
+		}
+		return a
+	}
+	return v
+}
+
+// equal compares JSON values as RFC 6902 "test" does, with numbers equal
+// when their values are.
+func equal(a, b any) bool {
+	switch x := a.(type) {
+	case map[string]any:
+		y, ok := b.(map[string]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k, v := range x {
+			w, ok := y[k]
+			if !ok || !equal(v, w) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		y, ok := b.([]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case json.Number:
+		y, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		if x == y {
+			return true
+		}
+		fx, errx := x.Float64()
+		fy, erry := y.Float64()
+		return errx == nil && erry == nil && fx == fy
+	}
+	return a == b
+}
@@ -0,0 +1,129 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// The cases follow the examples of RFC 6902 appendix A.
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+	}{
+		{"add member", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`},
+		{"add array element", `{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{"add to array end", `{"foo":[1,2]}`, `[{"op":"add","path":"/foo/-","value":3}]`, `{"foo":[1,2,3]}`},
+		{"add at array length", `{"foo":[1,2]}`, `[{"op":"add","path":"/foo/2","value":3}]`, `{"foo":[1,2,3]}`},
+		{"add replaces member", `{"foo":1}`, `[{"op":"add","path":"/foo","value":2}]`, `{"foo":2}`},
+		{"add null", `{}`, `[{"op":"add","path":"/foo","value":null}]`, `{"foo":null}`},
+		{"add nested", `{"foo":{"bar":{}}}`, `[{"op":"add","path":"/foo/bar/baz","value":[1]}]`, `{"foo":{"bar":{"baz":[1]}}}`},
+		{"add whole document", `{"foo":1}`, `[{"op":"add","path":"","value":[1]}]`, `[1]`},
+		{"remove member", `{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		{"remove array element", `{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{"replace", `{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{"replace whole document", `{"foo":1}`, `[{"op":"replace","path":"","value":{"bar":2}}]`, `{"bar":2}`},
+		{"move member", `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`, `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{"move array element", `{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`},
+		{"copy", `{"foo":{"bar":[1]}}`, `[{"op":"copy","from":"/foo/bar","path":"/baz"},{"op":"add","path":"/baz/-","value":2}]`, `{"baz":[1,2],"foo":{"bar":[1]}}`},
+		{"test passes", `{"baz":"qux","foo":["a",2,"c"]}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`, `{"baz":"qux","foo":["a",2,"c"]}`},
+		{"test compares numbers by value", `{"n":1}`, `[{"op":"test","path":"/n","value":1.0}]`, `{"n":1}`},
+		{"test compares objects ignoring order", `{"o":{"a":1,"b":2}}`, `[{"op":"test","path":"/o","value":{"b":2,"a":1}}]`, `{"o":{"a":1,"b":2}}`},
+		{"escaped pointer", `{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":10},{"op":"remove","path":"/~1"}]`, `{"~1":10}`},
+		{"empty patch", `{"foo":1}`, `[]`, `{"foo":1}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Decode([]byte(tt.patch))
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			got, err := p.Apply([]byte(tt.doc))
+			if err != nil {
+				t.Fatalf("Apply: %v", err)
+			}
+			if !sameJSON(t, got, []byte(tt.want)) {
+				t.Errorf("Apply(%s) = %s, want %s", tt.doc, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		err   error
+	}{
+		{"add to missing parent", `{"foo":"bar"}`, `[{"op":"add","path":"/baz/bat","value":"qux"}]`, ErrFailed},
+		{"add past array end", `{"foo":[1]}`, `[{"op":"add","path":"/foo/2","value":3}]`, ErrFailed},
+		{"index with leading zero", `{"foo":[1,2]}`, `[{"op":"remove","path":"/foo/01"}]`, ErrFailed},
+		{"remove missing member", `{"foo":1}`, `[{"op":"remove","path":"/bar"}]`, ErrFailed},
+		{"remove whole document", `{"foo":1}`, `[{"op":"remove","path":""}]`, ErrFailed},
+		{"replace missing member", `{"foo":1}`, `[{"op":"replace","path":"/bar","value":2}]`, ErrFailed},
+		{"move from missing member", `{"foo":1}`, `[{"op":"move","from":"/bar","path":"/baz"}]`, ErrFailed},
+		{"test fails", `{"baz":"qux"}`, `[{"op":"test","path":"/baz","value":"bar"}]`, ErrTestFailed},
+		{"test of string and number", `{"n":"1"}`, `[{"op":"test","path":"/n","value":1}]`, ErrTestFailed},
+		{"later operation fails", `{"foo":1}`, `[{"op":"add","path":"/bar","value":2},{"op":"test","path":"/bar","value":3}]`, ErrTestFailed},
+		{"path through a scalar", `{"foo":1}`, `[{"op":"add","path":"/foo/bar","value":2}]`, ErrFailed},
+		{"document is not JSON", `{"foo":`, `[]`, ErrFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Decode([]byte(tt.patch))
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			got, err := p.Apply([]byte(tt.doc))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Apply = %s, %v; want %v", got, err, tt.err)
+			}
+			if got != nil {
+				t.Errorf("Apply returned %s along with an error", got)
+			}
+		})
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+	}{
+		{"not JSON", `[{"op":`},
+		{"not a list", `{"op":"add","path":"/a","value":1}`},
+		{"unknown op", `[{"op":"merge","path":"/a"}]`},
+		{"missing op", `[{"path":"/a"}]`},
+		{"add without value", `[{"op":"add","path":"/a"}]`},
+		{"test without value", `[{"op":"test","path":"/a"}]`},
+		{"path without slash", `[{"op":"remove","path":"a"}]`},
+		{"bad escape", `[{"op":"remove","path":"/a~2"}]`},
+		{"move without from", `[{"op":"move","path":"/a"}]`},
+		{"bad from", `[{"op":"copy","from":"a","path":"/b"}]`},
+		{"move into itself", `[{"op":"move","from":"/a","path":"/a/b"}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Decode([]byte(tt.patch)); !errors.Is(err, ErrInvalid) {
+				t.Errorf("Decode(%s) = %v, want ErrInvalid", tt.patch, err)
+			}
+		})
+	}
+}
+
+func sameJSON(t *testing.T, a, b []byte) bool {
+	t.Helper()
+	var x, y any
+	if err := json.Unmarshal(a, &x); err != nil {
+		t.Fatalf("result %s: %v", a, err)
+	}
+	if err := json.Unmarshal(b, &y); err != nil {
+		t.Fatalf("want %s: %v", b, err)
+	}
+	return reflect.DeepEqual(x, y)
+}
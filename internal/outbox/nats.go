@@ -0,0 +1,164 @@
+package outbox
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const natsDialTimeout = 5 * time.Second
+
+// NATSSink publishes events to a message broker that speaks the NATS client
+// protocol, such as nats-server or a stand-in for it in development, on the
+// subject "<prefix>.<event type>", e.g. "auriya.events.task.updated". The
+// body is the Event as JSON and the Nats-Msg-Id header carries its ID, which
+// JetStream uses to drop duplicates. Each publish waits for the broker to
+// answer a PING, so that an event counts as published only once the broker
+// has read it.
+type NATSSink struct {
+	URL    string
+	Prefix string
+
+	mu   sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func NewNATSSink(rawURL, prefix string) *NATSSink {
+	return &NATSSink{URL: rawURL, Prefix: prefix}
+}
+
+func (s *NATSSink) Name() string { return "nats" }
+
+func (s *NATSSink) Publish(ctx context.Context, ev Event) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	headers := "NATS/1.0\r\nNats-Msg-Id: " + ev.ID + "\r\nContent-Type: application/json\r\n\r\n"
+	msg := fmt.Sprintf("HPUB %s.%s %d %d\r\n%s%s\r\nPING\r\n",
+		s.Prefix, ev.Type, len(headers), len(headers)+len(body), headers, body)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		if err := s.connect(ctx); err != nil {
+			return err
+		}
+	}
+	if err := s.roundTrip(ctx, msg); err != nil {
+		s.close()
+		return err
+	}
+	return nil
+}
+
+// Close drops the connection to the broker.
+func (s *NATSSink) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.close()
+}
+
+func (s *NATSSink) close() {
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn, s.r = nil, nil
+	}
+}
+
+// connect dials the broker and completes the handshake: the server's INFO,
+// then CONNECT and a PING that must be answered. s.mu must be held.
+func (s *NATSSink) connect(ctx context.Context) error {
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		return err
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "4222")
+	}
+
+	dialer := net.Dialer{Timeout: natsDialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return err
+	}
+	s.conn, s.r = conn, bufio.NewReader(conn)
+
+	if err := s.setDeadline(ctx); err != nil {
+		s.close()
+		return err
+	}
+	line, err := s.r.ReadString('\n')
+	if err != nil {
+		s.close()
+		return err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		s.close()
+		return fmt.Errorf("nats: unexpected greeting %q", strings.TrimSpace(line))
+	}
+
+	opts := map[string]any{"verbose": false, "pedantic": false, "headers": true, "name": "auriya-outbox", "lang": "go"}
+	if u.User != nil {
+		opts["user"] = u.User.Username()
+		opts["pass"], _ = u.User.Password()
+	}
+	connect, err := json.Marshal(opts)
+	if err != nil {
+		s.close()
+		return err
+	}
+	if err := s.roundTrip(ctx, "CONNECT "+string(connect)+"\r\nPING\r\n"); err != nil {
+		s.close()
+		return err
+	}
+	return nil
+}
+
+// roundTrip writes msg, which ends in a PING, and waits for the PONG.
+// s.mu must be held.
+func (s *NATSSink) roundTrip(ctx context.Context, msg string) error {
+	if err := s.setDeadline(ctx); err != nil {
+		return err
+	}
+	if _, err := s.conn.Write([]byte(msg)); err != nil {
+		return err
+	}
+	for {
+		line, err := s.r.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err := s.conn.Write([]byte("PONG\r\n")); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return errors.New("nats: " + strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+		// +OK and INFO updates need no answer.
+	}
+}
+
+// setDeadline bounds the next exchange by ctx, or by the dial timeout when
+// ctx has no deadline.
+func (s *NATSSink) setDeadline(ctx context.Context) error {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(natsDialTimeout)
+	}
+	return s.conn.SetDeadline(deadline)
+}
@@ -0,0 +1,71 @@
+// Package outbox publishes domain events recorded in the outbox table.
+//
+// Every task and project change writes an event to the outbox in the same
+// transaction (see migration 0021), so events are neither lost when the
+// process dies mid-request nor published for changes that were rolled
+// back. jobs.OutboxRelay reads them and hands each one to every Sink. An
+// event is retried until all sinks took it, so a sink may see it more than
+// once; Event.ID stays the same across retries and is what sinks and their
+// consumers deduplicate on.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	db "github.com/pavelc4/auriya-todolist-go/internal/db/sqlc"
+)
+
+// Event is one domain event, such as "task.updated". Payload is the task or
+// project as a database row, as it was after the change, or just before it
+// for deletes. ProjectID is the project the resource belongs to, if any.
+type Event struct {
+	ID            string          `json:"id"`
+	Type          string          `json:"type"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   int64           `json:"aggregate_id"`
+	UserID        int64           `json:"user_id"`
+	ProjectID     int64           `json:"project_id,omitempty"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	Payload       json.RawMessage `json:"payload"`
+}
+
+// NewEvent converts an outbox row to an Event.
+func NewEvent(row db.Outbox) Event {
+	return Event{
+		ID:            row.EventID.String(),
+		Type:          row.EventType,
+		AggregateType: row.AggregateType,
+		AggregateID:   row.AggregateID,
+		UserID:        row.UserID,
+		ProjectID:     row.ProjectID.Int64,
+		OccurredAt:    row.CreatedAt.Time,
+		Payload:       row.Payload,
+	}
+}
+
+// Sink is somewhere events are published. Publish returns once the sink has
+// the event for good; an error makes the relay try again later. Name
+// identifies the sink in the outbox's record of where an event went, so it
+// must not change between releases.
+type Sink interface {
+	Name() string
+	Publish(ctx context.Context, ev Event) error
+}
+
+const (
+	firstRetryDelay = time.Second
+	maxRetryDelay   = 5 * time.Minute
+)
+
+// Backoff returns how long to wait before publishing an event again after
+// attempts failed tries: a second after the first, doubling up to five
+// minutes.
+func Backoff(attempts int) time.Duration {
+	d := firstRetryDelay
+	for i := 1; i < attempts && d < maxRetryDelay; i++ {
+		d *= 2
+	}
+	return min(d, maxRetryDelay)
+}
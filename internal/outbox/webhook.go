@@ -0,0 +1,40 @@
+package outbox
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	db "github.com/pavelc4/auriya-todolist-go/internal/db/sqlc"
+)
+
+// WebhookSink queues events for the user's webhooks that want them, for
+// jobs.WebhookDispatcher to send. A webhook gets each event once, however
+// often it is published.
+type WebhookSink struct {
+	Queries *db.Queries
+}
+
+func NewWebhookSink(queries *db.Queries) *WebhookSink {
+	return &WebhookSink{Queries: queries}
+}
+
+func (s *WebhookSink) Name() string { return "webhooks" }
+
+func (s *WebhookSink) Publish(ctx context.Context, ev Event) error {
+	var id pgtype.UUID
+	if err := id.Scan(ev.ID); err != nil {
+		return err
+	}
+	arg := db.EnqueueWebhookEventParams{
+		EventID:      id,
+		EventType:    ev.Type,
+		ResourceType: ev.AggregateType,
+		Payload:      ev.Payload,
+		UserID:       ev.UserID,
+	}
+	if ev.ProjectID != 0 {
+		arg.ProjectID = pgtype.Int8{Int64: ev.ProjectID, Valid: true}
+	}
+	_, err := s.Queries.EnqueueWebhookEvent(ctx, arg)
+	return err
+}
@@ -0,0 +1,610 @@
+// Package quickadd parses a single line of text into the fields of a task,
+// e.g. "Pay rent every month on the 1st #home !3 tomorrow 9am".
+//
+// Recognised anywhere in the line:
+//
+//	!1 … !5              priority
+//	#name                project, matched by name
+//	@tag                 tag (repeatable)
+//	every …, daily, …    recurrence ("every 2 weeks", "every mon and thu",
+//	                     "every weekday", "every month on the 15th")
+//	today, tomorrow, …   due date ("friday", "next week", "in 3 days",
+//	                     "nov 1", "2026-11-01"), optionally after on/due/by
+//	9am, 14:30, noon     due time, optionally after "at"; "at 9" also works
+//
+// Everything else becomes the title. Text in double quotes is always kept in
+// the title, so "Read \"next week\"" does not set a date. Only the first
+// date, time, priority, project and recurrence are used; later ones are left
+// in the title.
+package quickadd
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pavelc4/auriya-todolist-go/internal/recur"
+)
+
+// Result is a parsed line. Due is nil when no date, time or recurrence was
+// given; a date without a time is due at the end of that day and sets AllDay.
+// Priority is 0 and Project and Recurrence are empty when not given.
+type Result struct {
+	Title      string
+	Due        *time.Time
+	AllDay     bool
+	Priority   int32
+	Project    string
+	Tags       []string
+	Recurrence string
+}
+
+// Parse parses text. Relative dates are resolved against now, in now's
+// location.
+func Parse(text string, now time.Time) Result {
+	p := &parser{toks: tokenize(text), now: now, clock: -1}
+	for p.i < len(p.toks) {
+		if !p.toks[p.i].literal {
+			if n := p.match(); n > 0 {
+				p.i += n
+				continue
+			}
+		}
+		p.title = append(p.title, p.toks[p.i].text)
+		p.i++
+	}
+	return p.result()
+}
+
+type token struct {
+	text    string
+	literal bool
+}
+
+// tokenize splits text on whitespace, keeping double-quoted runs together as
+// literal tokens. An unterminated quote runs to the end of the text.
+func tokenize(text string) []token {
+	var toks []token
+	for {
+		text = strings.TrimLeft(text, " \t\r\n")
+		if text == "" {
+			return toks
+		}
+		if text[0] == '"' {
+			quoted, rest, _ := strings.Cut(text[1:], `"`)
+			if quoted = strings.TrimSpace(quoted); quoted != "" {
+				toks = append(toks, token{text: quoted, literal: true})
+			}
+			text = rest
+			continue
+		}
+		end := strings.IndexAny(text, " \t\r\n")
+		if end < 0 {
+			end = len(text)
+		}
+		toks = append(toks, token{text: text[:end]})
+		text = text[end:]
+	}
+}
+
+type parser struct {
+	toks  []token
+	i     int
+	now   time.Time
+	title []string
+
+	priority int32
+	project  string
+	tags     []string
+	rule     *recur.Rule
+	date     *time.Time // midnight of the due day
+	exact    *time.Time // "in 3 hours"
+	clock    int        // minutes after midnight, -1 when not given
+	tonight  bool
+}
+
+// word returns the i-th token from the current one, lowercased and without
+// trailing punctuation, or "" past the end or for a quoted token.
+func (p *parser) word(i int) string {
+	if p.i+i >= len(p.toks) || p.toks[p.i+i].literal {
+		return ""
+	}
+	return strings.TrimRight(strings.ToLower(p.toks[p.i+i].text), ",.;:!?")
+}
+
+// match tries each kind of field at the current token and returns the
+// number of tokens it consumed, or 0.
+func (p *parser) match() int {
+	raw := strings.TrimRight(p.toks[p.i].text, ",.;:?")
+	switch {
+	case p.priority == 0 && len(raw) == 2 && raw[0] == '!' && raw[1] >= '1' && raw[1] <= '5':
+		p.priority = int32(raw[1] - '0')
+		return 1
+	case p.project == "" && len(raw) > 1 && raw[0] == '#':
+		p.project = raw[1:]
+		return 1
+	case len(raw) > 1 && raw[0] == '@':
+		if tag := strings.ToLower(raw[1:]); !slices.Contains(p.tags, tag) {
+			p.tags = append(p.tags, tag)
+		}
+		return 1
+	}
+
+	if p.rule == nil {
+		if n := p.matchRecurrence(); n > 0 {
+			return n
+		}
+	}
+	if p.date == nil && p.exact == nil {
+		n := 0
+		if w := p.word(0); w == "on" || w == "due" || w == "by" {
+			n = 1
+		}
+		if k := p.matchDate(n); k > 0 {
+			return n + k
+		}
+	}
+	if p.clock < 0 {
+		if p.word(0) == "at" {
+			if k := p.matchClock(1, true); k > 0 {
+				return 1 + k
+			}
+		} else if k := p.matchClock(0, false); k > 0 {
+			return k
+		}
+	}
+	return 0
+}
+
+// matchRecurrence matches "daily" style words and "every …" phrases.
+func (p *parser) matchRecurrence() int {
+	switch p.word(0) {
+	case "daily":
+		p.rule = &recur.Rule{Freq: recur.Daily, Interval: 1}
+		return 1
+	case "weekly":
+		p.rule = &recur.Rule{Freq: recur.Weekly, Interval: 1}
+		return 1
+	case "monthly":
+		p.rule = &recur.Rule{Freq: recur.Monthly, Interval: 1}
+		return 1
+	case "yearly", "annually":
+		p.rule = &recur.Rule{Freq: recur.Yearly, Interval: 1}
+		return 1
+	case "every":
+	default:
+		return 0
+	}
+
+	n := 1
+	r := recur.Rule{Interval: 1}
+	if p.word(n) == "other" {
+		r.Interval = 2
+		n++
+	} else if k, ok := number(p.word(n)); ok && k > 1 {
+		r.Interval = k
+		n++
+	}
+
+	unit := p.word(n)
+	switch {
+	case unit == "day" || unit == "days":
+		r.Freq = recur.Daily
+		n++
+	case unit == "weekday" || unit == "weekdays" || unit == "workday" || unit == "workdays":
+		r.Freq = recur.Weekly
+		r.ByDay = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+		n++
+	case unit == "week" || unit == "weeks":
+		r.Freq = recur.Weekly
+		n++
+		if p.word(n) == "on" {
+			if days, k := p.weekdays(n + 1); k > 0 {
+				r.ByDay = days
+				n += 1 + k
+			}
+		}
+	case unit == "month" || unit == "months":
+		r.Freq = recur.Monthly
+		n++
+		// "on the 1st", "on the 15", "on 15th"
+		k := n
+		if p.word(k) == "on" {
+			k++
+			if p.word(k) == "the" {
+				k++
+			}
+			if day, ok := monthDay(p.word(k)); ok {
+				r.ByMonthDay = day
+				n = k + 1
+			}
+		}
+	case unit == "year" || unit == "years":
+		r.Freq = recur.Yearly
+		n++
+	default:
+		if days, k := p.weekdays(n); k > 0 {
+			r.Freq = recur.Weekly
+			r.ByDay = days
+			n += k
+		} else if day, ok := ordinal(unit); ok && r.Interval == 1 {
+			r.Freq = recur.Monthly
+			r.ByMonthDay = day
+			n++
+		} else {
+			return 0
+		}
+	}
+	p.rule = &r
+	return n
+}
+
+// weekdays matches a list of weekday names starting i tokens ahead, such as
+// "mon", "monday and thursday" or "mon, wed, fri", and returns the days and
+// the number of tokens consumed.
+func (p *parser) weekdays(i int) ([]time.Weekday, int) {
+	d, ok := weekday(p.word(i))
+	if !ok {
+		return nil, 0
+	}
+	days := []time.Weekday{d}
+	n := 1
+	for {
+		if d, ok := weekday(p.word(i + n)); ok {
+			days = append(days, d)
+			n++
+			continue
+		}
+		if p.word(i+n) == "and" {
+			if d, ok := weekday(p.word(i + n + 1)); ok {
+				days = append(days, d)
+				n += 2
+				continue
+			}
+		}
+		return days, n
+	}
+}
+
+var isoDate = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+
+// matchDate matches a date starting i tokens ahead.
+func (p *parser) matchDate(i int) int {
+	today := midnight(p.now)
+	w := p.word(i)
+	switch w {
+	case "today":
+		p.date = &today
+		return 1
+	case "tonight":
+		p.date = &today
+		p.tonight = true
+		return 1
+	case "tomorrow", "tmr", "tmrw":
+		d := today.AddDate(0, 0, 1)
+		p.date = &d
+		return 1
+	case "next":
+		switch next := p.word(i + 1); next {
+		case "week":
+			d := nextWeekday(today, time.Monday)
+			p.date = &d
+			return 2
+		case "month":
+			d := time.Date(today.Year(), today.Month()+1, 1, 0, 0, 0, 0, today.Location())
+			p.date = &d
+			return 2
+		default:
+			if wd, ok := weekday(next); ok {
+				d := nextWeekday(today, wd)
+				p.date = &d
+				return 2
+			}
+		}
+		return 0
+	case "this":
+		if wd, ok := weekday(p.word(i + 1)); ok {
+			d := today
+			if d.Weekday() != wd {
+				d = nextWeekday(today, wd)
+			}
+			p.date = &d
+			return 2
+		}
+		return 0
+	case "in":
+		return p.matchIn(i + 1)
+	}
+
+	parse := fullWeekday
+	if i > 0 {
+		parse = weekday // after on/due/by
+	}
+	if wd, ok := parse(w); ok {
+		d := nextWeekday(today, wd)
+		p.date = &d
+		return 1
+	}
+	if isoDate.MatchString(w) {
+		if d, err := time.ParseInLocation(time.DateOnly, w, p.now.Location()); err == nil {
+			p.date = &d
+			return 1
+		}
+		return 0
+	}
+	return p.matchDayMonth(i)
+}
+
+// matchIn matches the rest of "in 3 days", "in a week" or "in 2 hours".
+func (p *parser) matchIn(i int) int {
+	k, ok := number(p.word(i))
+	if !ok || k < 1 {
+		return 0
+	}
+	today := midnight(p.now)
+	var d time.Time
+	switch p.word(i + 1) {
+	case "day", "days":
+		d = today.AddDate(0, 0, k)
+	case "week", "weeks":
+		d = today.AddDate(0, 0, 7*k)
+	case "month", "months":
+		d = today.AddDate(0, k, 0)
+	case "year", "years":
+		d = today.AddDate(k, 0, 0)
+	case "hour", "hours", "hr", "hrs":
+		t := p.now.Add(time.Duration(k) * time.Hour).Truncate(time.Minute)
+		p.exact = &t
+		return 3
+	case "minute", "minutes", "min", "mins":
+		t := p.now.Add(time.Duration(k) * time.Minute).Truncate(time.Minute)
+		p.exact = &t
+		return 3
+	default:
+		return 0
+	}
+	p.date = &d
+	return 3
+}
+
+// matchDayMonth matches "nov 1", "november 1st", "1 nov" or "1st november",
+// each optionally followed by a year. Without a year the next such day is
+// used.
+func (p *parser) matchDayMonth(i int) int {
+	var (
+		m   time.Month
+		day int
+		ok  bool
+	)
+	if m, ok = month(p.word(i)); ok {
+		if day, ok = monthDay(p.word(i + 1)); !ok {
+			return 0
+		}
+	} else if day, ok = monthDay(p.word(i)); ok {
+		if m, ok = month(p.word(i + 1)); !ok {
+			return 0
+		}
+	} else {
+		return 0
+	}
+	n := 2
+
+	today := midnight(p.now)
+	year := today.Year()
+	if y, err := strconv.Atoi(p.word(i + 2)); err == nil && len(p.word(i+2)) == 4 {
+		year = y
+		n++
+	}
+	d := time.Date(year, m, day, 0, 0, 0, 0, today.Location())
+	if d.Day() != day {
+		return 0 // e.g. feb 30
+	}
+	if n == 2 && d.Before(today) {
+		d = d.AddDate(1, 0, 0)
+	}
+	p.date = &d
+	return n
+}
+
+var clockPattern = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(am|pm|a\.m\.|p\.m\.)?$`)
+
+// matchClock matches a time of day starting i tokens ahead. A bare hour such
+// as "9" only counts after "at".
+func (p *parser) matchClock(i int, afterAt bool) int {
+	w := p.word(i)
+	switch w {
+	case "noon", "midday":
+		p.clock = 12 * 60
+		return 1
+	case "midnight":
+		p.clock = 0
+		return 1
+	}
+
+	m := clockPattern.FindStringSubmatch(w)
+	if m == nil {
+		return 0
+	}
+	n := 1
+	hour, _ := strconv.Atoi(m[1])
+	minute, _ := strconv.Atoi(m[2])
+	suffix := m[3]
+	if suffix == "" {
+		if s := p.word(i + 1); s == "am" || s == "pm" || s == "a.m" || s == "p.m" {
+			suffix = s
+			n++
+		}
+	}
+
+	switch {
+	case suffix != "":
+		if hour < 1 || hour > 12 {
+			return 0
+		}
+		hour %= 12
+		if suffix[0] == 'p' {
+			hour += 12
+		}
+	case m[2] == "" && !afterAt:
+		return 0
+	case hour > 23:
+		return 0
+	}
+	if minute > 59 {
+		return 0
+	}
+	p.clock = hour*60 + minute
+	return n
+}
+
+func (p *parser) result() Result {
+	res := Result{
+		Title:    strings.Join(p.title, " "),
+		Priority: p.priority,
+		Project:  p.project,
+		Tags:     p.tags,
+	}
+	if p.rule != nil {
+		res.Recurrence = p.rule.String()
+	}
+
+	if p.exact != nil {
+		res.Due = p.exact
+		return res
+	}
+	if p.date == nil && p.clock < 0 && p.rule == nil {
+		return res
+	}
+
+	today := midnight(p.now)
+	day := today
+	switch {
+	case p.date != nil:
+		day = *p.date
+	case p.rule != nil:
+		day = p.rule.First(today)
+	}
+
+	clock := p.clock
+	if clock < 0 && p.tonight {
+		clock = 20 * 60
+	}
+	if clock < 0 {
+		due := time.Date(day.Year(), day.Month(), day.Day(), 23, 59, 59, 0, day.Location())
+		res.Due = &due
+		res.AllDay = true
+		return res
+	}
+
+	due := at(day, clock)
+	if p.date == nil && !due.After(p.now) {
+		// A time on its own, or with only a recurrence, means the next one.
+		next := day.AddDate(0, 0, 1)
+		if p.rule != nil {
+			next = p.rule.First(next)
+		}
+		due = at(next, clock)
+	}
+	res.Due = &due
+	return res
+}
+
+func midnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+func at(day time.Time, clock int) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), clock/60, clock%60, 0, 0, day.Location())
+}
+
+// nextWeekday returns the first day after from that falls on wd.
+func nextWeekday(from time.Time, wd time.Weekday) time.Time {
+	days := (int(wd) - int(from.Weekday()) + 6) % 7
+	return from.AddDate(0, 0, days+1)
+}
+
+var weekdayAbbrevs = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "tues": time.Tuesday,
+	"wed": time.Wednesday, "thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday,
+	"fri": time.Friday, "sat": time.Saturday,
+}
+
+// weekday parses a weekday name, singular or plural, or its abbreviation.
+func weekday(w string) (time.Weekday, bool) {
+	if d, ok := weekdayAbbrevs[w]; ok {
+		return d, true
+	}
+	return fullWeekday(w)
+}
+
+// fullWeekday is weekday without abbreviations, for words standing on their
+// own where "sun" or "wed" are more likely meant literally.
+func fullWeekday(w string) (time.Weekday, bool) {
+	w = strings.TrimSuffix(w, "s")
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if w == strings.ToLower(d.String()) {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+func month(w string) (time.Month, bool) {
+	if len(w) < 3 {
+		return 0, false
+	}
+	for m := time.January; m <= time.December; m++ {
+		name := strings.ToLower(m.String())
+		if w == name || w == name[:3] || (m == time.September && w == "sept") {
+			return m, true
+		}
+	}
+	return 0, false
+}
+
+// ordinal parses "1st" through "31st".
+func ordinal(w string) (int, bool) {
+	if len(w) < 3 {
+		return 0, false
+	}
+	suffix := w[len(w)-2:]
+	if suffix != "st" && suffix != "nd" && suffix != "rd" && suffix != "th" {
+		return 0, false
+	}
+	n, err := strconv.Atoi(w[:len(w)-2])
+	if err != nil || n < 1 || n > 31 {
+		return 0, false
+	}
+	return n, true
+}
+
+// monthDay parses a day of the month written as "15" or "15th".
+func monthDay(w string) (int, bool) {
+	if n, ok := ordinal(w); ok {
+		return n, true
+	}
+	n, err := strconv.Atoi(w)
+	if err != nil || len(w) > 2 || n < 1 || n > 31 {
+		return 0, false
+	}
+	return n, true
+}
+
+var numberWords = map[string]int{
+	"a": 1, "an": 1, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6,
+	"seven": 7, "eight": 8, "nine": 9, "ten": 10, "eleven": 11, "twelve": 12,
+}
+
+// number parses a small count written in digits or words.
+func number(w string) (int, bool) {
+	if n, ok := numberWords[w]; ok {
+		return n, true
+	}
+	n, err := strconv.Atoi(w)
+	if err != nil || len(w) > 3 {
+		return 0, false
+	}
+	return n, true
+}
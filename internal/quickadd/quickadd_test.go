@@ -0,0 +1,163 @@
+package quickadd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+	_ "time/tzdata" // America/New_York and Asia/Tokyo, even without zoneinfo
+)
+
+// testNow is a Monday afternoon.
+var testNow = time.Date(2026, 10, 19, 15, 30, 0, 0, time.UTC)
+
+func TestParse(t *testing.T) {
+	day := func(m time.Month, d int) *time.Time {
+		t := time.Date(2026, m, d, 23, 59, 59, 0, time.UTC)
+		return &t
+	}
+	at := func(m time.Month, d, hour, min int) *time.Time {
+		t := time.Date(2026, m, d, hour, min, 0, 0, time.UTC)
+		return &t
+	}
+	tests := []struct {
+		name string
+		text string
+		want Result
+	}{
+		// Relative dates
+		{"today", "Call mom today", Result{Title: "Call mom", Due: day(10, 19), AllDay: true}},
+		{"tomorrow", "Call mom tomorrow", Result{Title: "Call mom", Due: day(10, 20), AllDay: true}},
+		{"tmrw", "Call mom tmrw", Result{Title: "Call mom", Due: day(10, 20), AllDay: true}},
+		{"tonight", "Take out trash tonight", Result{Title: "Take out trash", Due: at(10, 19, 20, 0)}},
+		{"in days", "Renew passport in 3 days", Result{Title: "Renew passport", Due: day(10, 22), AllDay: true}},
+		{"in a week", "Renew passport in a week", Result{Title: "Renew passport", Due: day(10, 26), AllDay: true}},
+		{"in hours", "Check oven in 2 hours", Result{Title: "Check oven", Due: at(10, 19, 17, 30)}},
+		{"in minutes", "Check oven in 45 mins", Result{Title: "Check oven", Due: at(10, 19, 16, 15)}},
+		{"next week", "Plan sprint next week", Result{Title: "Plan sprint", Due: day(10, 26), AllDay: true}},
+		{"next month", "Pay invoice next month", Result{Title: "Pay invoice", Due: day(11, 1), AllDay: true}},
+		{"due prefix", "Report due tomorrow", Result{Title: "Report", Due: day(10, 20), AllDay: true}},
+
+		// Weekdays
+		{"weekday", "Gym friday", Result{Title: "Gym", Due: day(10, 23), AllDay: true}},
+		{"same weekday is next week", "Gym monday", Result{Title: "Gym", Due: day(10, 26), AllDay: true}},
+		{"this weekday is today", "Gym this monday", Result{Title: "Gym", Due: day(10, 19), AllDay: true}},
+		{"next weekday", "Gym next fri", Result{Title: "Gym", Due: day(10, 23), AllDay: true}},
+		{"abbreviation after on", "Gym on wed", Result{Title: "Gym", Due: day(10, 21), AllDay: true}},
+		{"abbreviation on its own", "Wear a hat in the sun", Result{Title: "Wear a hat in the sun"}},
+
+		// Absolute dates
+		{"month day", "Vote nov 3", Result{Title: "Vote", Due: day(11, 3), AllDay: true}},
+		{"day month", "Vote 3rd november", Result{Title: "Vote", Due: day(11, 3), AllDay: true}},
+		{"past month day is next year", "Party jan 5", Result{Title: "Party", Due: ptr(time.Date(2027, 1, 5, 23, 59, 59, 0, time.UTC)), AllDay: true}},
+		{"month day with year", "Party jan 5 2026", Result{Title: "Party", Due: day(1, 5), AllDay: true}},
+		{"iso date", "Ship 2026-12-24", Result{Title: "Ship", Due: day(12, 24), AllDay: true}},
+		{"no such day", "Ship feb 30", Result{Title: "Ship feb 30"}},
+
+		// Times
+		{"date and time", "Dentist tomorrow 9am", Result{Title: "Dentist", Due: at(10, 20, 9, 0)}},
+		{"time before date", "Dentist at 9:15 pm friday", Result{Title: "Dentist", Due: at(10, 23, 21, 15)}},
+		{"24-hour time", "Standup 16:45", Result{Title: "Standup", Due: at(10, 19, 16, 45)}},
+		{"passed time is tomorrow", "Standup 10:00", Result{Title: "Standup", Due: at(10, 20, 10, 0)}},
+		{"bare hour after at", "Lunch at 1 pm", Result{Title: "Lunch", Due: at(10, 20, 13, 0)}},
+		{"bare hour after at, 24-hour", "Lunch at 18", Result{Title: "Lunch", Due: at(10, 19, 18, 0)}},
+		{"bare hour is a number", "Buy 9 eggs", Result{Title: "Buy 9 eggs"}},
+		{"noon", "Lunch today noon", Result{Title: "Lunch", Due: at(10, 19, 12, 0)}},
+		{"not a time", "Read 13pm", Result{Title: "Read 13pm"}},
+
+		// Recurrence
+		{"monthly on a day", "Pay rent every month on the 1st", Result{Title: "Pay rent", Due: day(11, 1), AllDay: true, Recurrence: "FREQ=MONTHLY;BYMONTHDAY=1"}},
+		{"every weekday", "Standup every weekday 9:30", Result{Title: "Standup", Due: at(10, 20, 9, 30), Recurrence: "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR"}},
+		{"every listed days", "Gym every mon and thu", Result{Title: "Gym", Due: day(10, 19), AllDay: true, Recurrence: "FREQ=WEEKLY;BYDAY=MO,TH"}},
+		{"every other week", "Clean every other week", Result{Title: "Clean", Due: day(10, 19), AllDay: true, Recurrence: "FREQ=WEEKLY;INTERVAL=2"}},
+
+		// Other fields
+		{
+			"everything at once",
+			"Pay rent every month on the 1st #home !3 tomorrow 9am @bills @Bills",
+			Result{Title: "Pay rent", Due: at(10, 20, 9, 0), Priority: 3, Project: "home", Tags: []string{"bills"}, Recurrence: "FREQ=MONTHLY;BYMONTHDAY=1"},
+		},
+		{"quotes stay in the title", `Read "next week" today`, Result{Title: "Read next week", Due: day(10, 19), AllDay: true}},
+		{"only the first of each", "Call !2 !4 #a #b today friday", Result{Title: "Call !4 #b friday", Due: day(10, 19), AllDay: true, Priority: 2, Project: "a"}},
+		{"nothing to parse", "Just a title", Result{Title: "Just a title"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkParse(t, tt.text, testNow, tt.want)
+		})
+	}
+}
+
+// Dates and times are read in now's location, not UTC.
+func TestParseTimeZones(t *testing.T) {
+	newYork := load(t, "America/New_York")
+	tokyo := load(t, "Asia/Tokyo")
+	tests := []struct {
+		name string
+		text string
+		now  time.Time
+		want Result
+	}{
+		{
+			"today is the local day",
+			"Call today",
+			time.Date(2026, 10, 19, 2, 0, 0, 0, time.UTC).In(newYork), // still the 18th there
+			Result{Title: "Call", Due: ptr(time.Date(2026, 10, 18, 23, 59, 59, 0, newYork)), AllDay: true},
+		},
+		{
+			"weekday from the local day",
+			"Call friday",
+			time.Date(2026, 10, 22, 16, 0, 0, 0, time.UTC).In(tokyo), // already Friday there
+			Result{Title: "Call", Due: ptr(time.Date(2026, 10, 30, 23, 59, 59, 0, tokyo)), AllDay: true},
+		},
+		{
+			"local clock time",
+			"Dentist tomorrow 9am",
+			time.Date(2026, 10, 19, 12, 0, 0, 0, newYork),
+			Result{Title: "Dentist", Due: ptr(time.Date(2026, 10, 20, 9, 0, 0, 0, newYork))},
+		},
+		{
+			"clock time across the end of daylight saving",
+			"Dentist tomorrow 9am",
+			time.Date(2026, 10, 31, 12, 0, 0, 0, newYork),
+			Result{Title: "Dentist", Due: ptr(time.Date(2026, 11, 1, 14, 0, 0, 0, time.UTC).In(newYork))},
+		},
+		{
+			"passed local time is tomorrow",
+			"Standup 8:00",
+			time.Date(2026, 10, 19, 9, 0, 0, 0, tokyo),
+			Result{Title: "Standup", Due: ptr(time.Date(2026, 10, 20, 8, 0, 0, 0, tokyo))},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkParse(t, tt.text, tt.now, tt.want)
+		})
+	}
+}
+
+func checkParse(t *testing.T, text string, now time.Time, want Result) {
+	t.Helper()
+	got := Parse(text, now)
+	if got.Due != nil && want.Due != nil {
+		if !got.Due.Equal(*want.Due) || got.Due.Location().String() != want.Due.Location().String() {
+			t.Errorf("Parse(%q).Due = %v, want %v", text, got.Due, want.Due)
+		}
+	} else if got.Due != want.Due {
+		t.Errorf("Parse(%q).Due = %v, want %v", text, got.Due, want.Due)
+	}
+	got.Due, want.Due = nil, nil
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse(%q) = %+v, want %+v", text, got, want)
+	}
+}
+
+func load(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func ptr(t time.Time) *time.Time { return &t }
@@ -0,0 +1,98 @@
+// Package rank generates string keys for manually ordered lists.
+//
+// Keys are base-62 fractions that sort with plain byte-wise comparison
+// (COLLATE "C" in Postgres), so an item can be moved between two neighbours
+// by writing a single new key without touching the rest of the list.
+package rank
+
+import (
+	"errors"
+	"strings"
+)
+
+const digits = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+const base = len(digits)
+
+// MaxLen is the key length past which a list should be rebalanced with Spread.
+// Repeatedly inserting into the same gap grows keys by about one character
+// every few insertions.
+const MaxLen = 32
+
+// ErrOrder is returned by Between when lower does not sort before upper.
+var ErrOrder = errors.New("rank: lower key must sort before upper key")
+
+// ErrInvalidKey is returned for keys containing characters outside the alphabet.
+var ErrInvalidKey = errors.New("rank: invalid key")
+
+// Between returns a key that sorts strictly between lower and upper. An empty
+// lower means the start of the list and an empty upper means the end.
+func Between(lower, upper string) (string, error) {
+	if !valid(lower) || !valid(upper) {
+		return "", ErrInvalidKey
+	}
+	if upper != "" && lower >= upper {
+		return "", ErrOrder
+	}
+
+	var b strings.Builder
+	for i := 0; ; i++ {
+		lo := 0
+		if i < len(lower) {
+			lo = strings.IndexByte(digits, lower[i])
+		}
+		hi := base
+		if upper != "" {
+			if i >= len(upper) {
+				// lower < upper and upper is exhausted, so upper consisted of
+				// lower's prefix followed by trailing zeros; no key fits.
+				return "", ErrOrder
+			}
+			hi = strings.IndexByte(digits, upper[i])
+		}
+
+		if hi-lo > 1 {
+			b.WriteByte(digits[(lo+hi)/2])
+			return b.String(), nil
+		}
+
+		b.WriteByte(digits[lo])
+		if hi > lo {
+			// The digits are adjacent: keep lower's digit and continue with
+			// no upper bound, anything longer still sorts below upper.
+			upper = ""
+		}
+	}
+}
+
+// Spread returns n keys of equal length, evenly spaced across the key space
+// and in ascending order. It is used to rebalance a list whose keys have
+// grown too long.
+func Spread(n int) []string {
+	width, space := 1, base
+	for space <= n {
+		width++
+		space *= base
+	}
+
+	keys := make([]string, n)
+	for i := range keys {
+		v := (i + 1) * space / (n + 1)
+		key := make([]byte, width)
+		for j := width - 1; j >= 0; j-- {
+			key[j] = digits[v%base]
+			v /= base
+		}
+		keys[i] = string(key)
+	}
+	return keys
+}
+
+func valid(key string) bool {
+	for i := 0; i < len(key); i++ {
+		if strings.IndexByte(digits, key[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,503 @@
+// Package realtime fans task and project changes out to live connections.
+//
+// Every write to a task or project sends a NOTIFY on the "changes" channel
+// (see migrations 0018 and 0019). A Hub holds one LISTEN connection per
+// server instance and passes each change to the subscriptions of the user
+// it belongs to, so changes made through any instance reach every client.
+// Presence and typing signals travel the same way on the "presence"
+// channel.
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	changesChannel = "changes"
+
+	// subscriptionBuffer is how many events a subscription may fall behind
+	// before its events are replaced with a resync; see send.
+	subscriptionBuffer = 64
+	// maxPending is how many distinct resources a user may have changes
+	// waiting to be loaded for before their subscriptions are told to
+	// resync instead.
+	maxPending = 1000
+	// MaxSubscriptionsPerUser caps a user's live connections on one
+	// instance.
+	MaxSubscriptionsPerUser = 20
+	// maxLoads caps how many resources the hub loads at once, across all
+	// users.
+	maxLoads      = 16
+	loadTimeout   = 5 * time.Second
+	maxRetryDelay = 30 * time.Second
+)
+
+var (
+	// ErrClosed is returned by Subscribe once the hub is shutting down, and
+	// is the Err of the subscriptions it closed.
+	ErrClosed = errors.New("realtime: hub closed")
+	// ErrListenerReset is the Err of subscriptions dropped because the
+	// LISTEN connection was lost, during which changes may have been missed.
+	ErrListenerReset = errors.New("realtime: change listener reconnected")
+	// ErrTooManySubscriptions is returned by Subscribe when the user already
+	// has MaxSubscriptionsPerUser subscriptions.
+	ErrTooManySubscriptions = errors.New("realtime: too many connections")
+)
+
+// Change identifies one change as sent by the database. Seq is its change
+// number, the same one GET /api/sync uses. Op is "created", "updated" or
+// "deleted". ProjectID is the project the change belongs to, if any, and
+// FromProjectID the one a task moved out of.
+type Change struct {
+	Seq           int64  `json:"seq"`
+	UserID        int64  `json:"user_id"`
+	Type          string `json:"type"`
+	ID            int64  `json:"id"`
+	Op            string `json:"op"`
+	ProjectID     int64  `json:"project_id"`
+	FromProjectID int64  `json:"from_project_id"`
+}
+
+// Event kinds. Only subscriptions that watch projects get presence and
+// typing events. A resync event says the subscription missed events and
+// should reload what it shows.
+const (
+	KindChange   = "change"
+	KindPresence = "presence"
+	KindTyping   = "typing"
+	KindResync   = "resync"
+)
+
+// Event is one message for a subscription. A change comes with the resource
+// as it is after the change in Data, which is nil for deletes and when the
+// resource is already gone again. Presence events list everyone viewing
+// Project, and typing events say who is typing in it.
+type Event struct {
+	Kind    string
+	Change  Change
+	Data    json.RawMessage
+	Project int64
+	Viewers []Viewer
+	Typing  Typing
+}
+
+// LoadFunc reads the resource a change is about, for Event.Data. It returns
+// nil data when the resource no longer exists.
+type LoadFunc func(ctx context.Context, ch Change) (json.RawMessage, error)
+
+// Hub distributes changes to subscriptions. The zero value is not usable;
+// create hubs with NewHub.
+type Hub struct {
+	pool *pgxpool.Pool
+	load LoadFunc
+
+	mu       sync.Mutex
+	subs     map[int64]map[*Subscription]struct{}
+	watchers map[int64]map[*Subscription]struct{}
+	viewers  map[int64]map[string]*viewer
+	closed   bool
+
+	// pending holds the changes waiting to be delivered, by user. A user
+	// has an entry while a goroutine is delivering their changes; see
+	// dispatch. loads limits concurrent loads to maxLoads.
+	pending map[int64][]Change
+	loads   chan struct{}
+
+	// observers are told about every change; see Observe.
+	observers []observer
+}
+
+type observer struct {
+	changed func(Change)
+	reset   func()
+}
+
+func NewHub(pool *pgxpool.Pool, load LoadFunc) *Hub {
+	return &Hub{
+		pool:     pool,
+		load:     load,
+		subs:     make(map[int64]map[*Subscription]struct{}),
+		watchers: make(map[int64]map[*Subscription]struct{}),
+		viewers:  make(map[int64]map[string]*viewer),
+		pending:  make(map[int64][]Change),
+		loads:    make(chan struct{}, maxLoads),
+	}
+}
+
+// Observe registers changed to be called with every change this instance
+// hears of, whether or not anyone subscribes to it, and reset after the
+// listener reconnects, when changes may have been missed. Both run in the
+// LISTEN loop and must not block. Observe must be called before Run.
+func (h *Hub) Observe(changed func(Change), reset func()) {
+	h.observers = append(h.observers, observer{changed: changed, reset: reset})
+}
+
+// Subscription receives the events of one user until it is closed, either
+// by its owner or by the hub.
+type Subscription struct {
+	hub    *Hub
+	userID int64
+	connID string
+	events chan Event
+	done   chan struct{}
+	once   sync.Once
+	err    error
+
+	// resyncing is set while the last event queued is a resync; see
+	// resync. It is guarded by hub.mu.
+	resyncing bool
+
+	// watching is nil for subscriptions that get all of the user's changes.
+	// Otherwise it holds the projects whose changes, presence and typing
+	// the subscription gets. It is guarded by hub.mu.
+	watching map[int64]bool
+}
+
+// Subscribe starts delivering all of the user's changes. The caller must
+// Close the subscription when done with it.
+func (h *Hub) Subscribe(userID int64) (*Subscription, error) {
+	return h.subscribe(userID, nil)
+}
+
+// SubscribeProjects is Subscribe for a connection that picks the projects it
+// follows with Watch and takes part in their presence.
+func (h *Hub) SubscribeProjects(userID int64) (*Subscription, error) {
+	return h.subscribe(userID, make(map[int64]bool))
+}
+
+func (h *Hub) subscribe(userID int64, watching map[int64]bool) (*Subscription, error) {
+	connID, err := newConnID()
+	if err != nil {
+		return nil, err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil, ErrClosed
+	}
+	if len(h.subs[userID]) >= MaxSubscriptionsPerUser {
+		return nil, ErrTooManySubscriptions
+	}
+	s := &Subscription{
+		hub:      h,
+		userID:   userID,
+		connID:   connID,
+		events:   make(chan Event, subscriptionBuffer),
+		done:     make(chan struct{}),
+		watching: watching,
+	}
+	if h.subs[userID] == nil {
+		h.subs[userID] = make(map[*Subscription]struct{})
+	}
+	h.subs[userID][s] = struct{}{}
+	return s, nil
+}
+
+// ConnID identifies the subscription in presence lists.
+func (s *Subscription) ConnID() string { return s.connID }
+
+// Events delivers the user's events in change order.
+func (s *Subscription) Events() <-chan Event { return s.events }
+
+// Done is closed when the subscription ends.
+func (s *Subscription) Done() <-chan struct{} { return s.done }
+
+// Err says why the hub ended the subscription, after Done is closed. It is
+// nil when the owner closed it.
+func (s *Subscription) Err() error {
+	select {
+	case <-s.done:
+		return s.err
+	default:
+		return nil
+	}
+}
+
+// Close ends the subscription. It is safe to call more than once.
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s, nil)
+}
+
+// remove ends s with err and announces that it stopped watching its
+// projects. h.mu must be held.
+func (h *Hub) remove(s *Subscription, err error) {
+	s.once.Do(func() {
+		s.err = err
+		close(s.done)
+		if subs := h.subs[s.userID]; subs != nil {
+			delete(subs, s)
+			if len(subs) == 0 {
+				delete(h.subs, s.userID)
+			}
+		}
+		for project := range s.watching {
+			h.unwatch(s, project)
+			go h.publish(signal{Kind: signalLeave, Project: project, UserID: s.userID, ConnID: s.connID})
+		}
+	})
+}
+
+// dropAll ends every subscription with err.
+func (h *Hub) dropAll(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, subs := range h.subs {
+		for s := range subs {
+			h.remove(s, err)
+		}
+	}
+	clear(h.viewers)
+}
+
+// Close ends every subscription with ErrClosed and refuses new ones. It is
+// meant to run when the server shuts down, so that long-lived streams
+// return.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	h.closed = true
+	h.mu.Unlock()
+	h.dropAll(ErrClosed)
+}
+
+// Run listens for changes and signals until ctx is cancelled, reconnecting
+// with backoff when the connection is lost. After a reconnect every
+// subscription is dropped with ErrListenerReset, as changes may have been
+// missed meanwhile. It also keeps presence fresh; see announce.
+func (h *Hub) Run(ctx context.Context) {
+	go h.announce(ctx)
+
+	delay := time.Second
+	for connected := false; ; {
+		err := h.listen(ctx, func() {
+			if connected {
+				h.dropAll(ErrListenerReset)
+				for _, o := range h.observers {
+					o.reset()
+				}
+			}
+			connected = true
+			delay = time.Second
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("realtime: listen: %v; retrying in %s", err, delay)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxRetryDelay)
+	}
+}
+
+// listen holds a LISTEN connection and dispatches its notifications. It
+// calls ready once the connection is listening.
+func (h *Hub) listen(ctx context.Context, ready func()) error {
+	pooled, err := h.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// The connection stays in LISTEN state, so it must not go back to the
+	// pool.
+	conn := pooled.Hijack()
+	defer conn.Close(context.WithoutCancel(ctx))
+
+	for _, channel := range []string{changesChannel, presenceChannel} {
+		if _, err := conn.Exec(ctx, "LISTEN "+channel); err != nil {
+			return err
+		}
+	}
+	ready()
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		if n.Channel == presenceChannel {
+			var sig signal
+			if err := json.Unmarshal([]byte(n.Payload), &sig); err != nil {
+				log.Printf("realtime: bad signal %q: %v", n.Payload, err)
+				continue
+			}
+			h.receive(sig)
+			continue
+		}
+		var ch Change
+		if err := json.Unmarshal([]byte(n.Payload), &ch); err != nil {
+			log.Printf("realtime: bad notification %q: %v", n.Payload, err)
+			continue
+		}
+		for _, o := range h.observers {
+			o.changed(ch)
+		}
+		h.dispatch(ctx, ch)
+	}
+}
+
+// dispatch queues a change for the subscriptions of its user that follow
+// it. Loading the resource takes a query, which must not hold up the LISTEN
+// loop, so each user's changes are delivered in order by a goroutine of
+// their own that runs while the user has changes pending. A change to a
+// resource that already has one waiting replaces it, so a transaction that
+// touches many rows, or the same rows many times, queues each row once.
+// Should a user still have maxPending rows waiting, the queue is dropped
+// and their subscriptions get a resync.
+func (h *Hub) dispatch(ctx context.Context, ch Change) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.listening(ch) {
+		return
+	}
+	queue, running := h.pending[ch.UserID]
+	for i, old := range queue {
+		if old.Type == ch.Type && old.ID == ch.ID {
+			ch = coalesce(old, ch)
+			queue = append(queue[:i], queue[i+1:]...)
+			break
+		}
+	}
+	if len(queue) >= maxPending {
+		for s := range h.subs[ch.UserID] {
+			h.resync(s)
+		}
+		h.pending[ch.UserID] = queue[:0]
+		return
+	}
+	h.pending[ch.UserID] = append(queue, ch)
+	if !running {
+		go h.drain(ctx, ch.UserID)
+	}
+}
+
+// coalesce merges two changes to the same resource into one that leaves a
+// subscriber where both would have: the later state, created if the
+// earlier change created it, and still naming the project it moved out of.
+func coalesce(earlier, later Change) Change {
+	if earlier.Op == "created" && later.Op == "updated" {
+		later.Op = "created"
+	}
+	if later.FromProjectID == 0 && earlier.FromProjectID != later.ProjectID {
+		later.FromProjectID = earlier.FromProjectID
+	}
+	return later
+}
+
+// drain delivers the pending changes of a user until there are none left.
+func (h *Hub) drain(ctx context.Context, userID int64) {
+	for {
+		h.mu.Lock()
+		queue := h.pending[userID]
+		if len(queue) == 0 {
+			delete(h.pending, userID)
+			h.mu.Unlock()
+			return
+		}
+		ch := queue[0]
+		h.pending[userID] = queue[1:]
+		h.mu.Unlock()
+
+		h.deliver(ctx, ch)
+	}
+}
+
+// listening reports whether any subscription follows ch. h.mu must be held.
+func (h *Hub) listening(ch Change) bool {
+	for s := range h.subs[ch.UserID] {
+		if s.follows(ch) {
+			return true
+		}
+	}
+	return false
+}
+
+// deliver sends a change to the subscriptions that follow it. The resource
+// is loaded once, and only when someone is still listening.
+func (h *Hub) deliver(ctx context.Context, ch Change) {
+	h.mu.Lock()
+	listening := h.listening(ch)
+	h.mu.Unlock()
+	if !listening {
+		return
+	}
+
+	ev := Event{Kind: KindChange, Change: ch}
+	if ch.Op != "deleted" && h.load != nil {
+		select {
+		case h.loads <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+		loadCtx, cancel := context.WithTimeout(ctx, loadTimeout)
+		data, err := h.load(loadCtx, ch)
+		cancel()
+		<-h.loads
+		if err != nil {
+			log.Printf("realtime: load %s %d: %v", ch.Type, ch.ID, err)
+		}
+		ev.Data = data
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for s := range h.subs[ch.UserID] {
+		if s.follows(ch) {
+			h.send(s, ev)
+		}
+	}
+}
+
+// follows reports whether s gets ch. h.mu must be held.
+func (s *Subscription) follows(ch Change) bool {
+	if s.watching == nil {
+		return true
+	}
+	return s.watching[ch.ProjectID] || (ch.FromProjectID != 0 && s.watching[ch.FromProjectID])
+}
+
+// send queues ev for s. Rather than wait when its buffer is full, so that
+// one slow client cannot hold up the others, the queued events are replaced
+// with a resync; see resync. h.mu must be held.
+func (h *Hub) send(s *Subscription, ev Event) {
+	if s.resyncing {
+		if len(s.events) > 0 {
+			// The resync has not been read yet and stands for ev too.
+			return
+		}
+		s.resyncing = false
+	}
+	select {
+	case s.events <- ev:
+	default:
+		h.resync(s)
+	}
+}
+
+// resync replaces the events queued for s with one resync event, which
+// stands for them and for any others until s reads it. h.mu must be held.
+func (h *Hub) resync(s *Subscription) {
+	if s.resyncing && len(s.events) > 0 {
+		return
+	}
+	for drained := false; !drained; {
+		select {
+		case <-s.events:
+		default:
+			drained = true
+		}
+	}
+	// Only the hub adds events, and it holds h.mu, so there is room.
+	s.events <- Event{Kind: KindResync}
+	s.resyncing = true
+}
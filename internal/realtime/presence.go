@@ -0,0 +1,295 @@
+package realtime
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"sort"
+	"time"
+)
+
+// Presence is shared between instances as signals on the "presence"
+// channel. A connection announces itself when it starts watching a project
+// and when it stops; every instance keeps the resulting list of viewers per
+// project and tells its own watchers when it changes. Viewers are
+// re-announced every presenceInterval and forgotten after presenceTTL
+// without one, which covers instances that stopped without a goodbye.
+const (
+	presenceChannel  = "presence"
+	presenceInterval = 30 * time.Second
+	presenceTTL      = 75 * time.Second
+	publishTimeout   = 5 * time.Second
+
+	// MaxWatchedProjects caps the projects one subscription follows.
+	MaxWatchedProjects = 50
+)
+
+var (
+	// ErrTooManyProjects is returned by Watch when the subscription already
+	// follows MaxWatchedProjects projects.
+	ErrTooManyProjects = errors.New("realtime: watching too many projects")
+	// ErrNotWatching is returned for signals about a project the
+	// subscription does not watch.
+	ErrNotWatching = errors.New("realtime: project is not watched")
+	// ErrNotWatchable is returned by Watch for subscriptions made with
+	// Subscribe, which already get every change.
+	ErrNotWatchable = errors.New("realtime: subscription cannot watch projects")
+)
+
+const (
+	signalJoin   = "join"
+	signalHere   = "here"
+	signalLeave  = "leave"
+	signalTyping = "typing"
+)
+
+type signal struct {
+	Kind    string `json:"kind"`
+	Project int64  `json:"project_id"`
+	UserID  int64  `json:"user_id"`
+	ConnID  string `json:"conn_id"`
+	TaskID  int64  `json:"task_id,omitempty"`
+	Typing  bool   `json:"typing,omitempty"`
+}
+
+// Viewer is a connection viewing a project.
+type Viewer struct {
+	UserID int64     `json:"user_id"`
+	ConnID string    `json:"connection_id"`
+	Since  time.Time `json:"since"`
+}
+
+// Typing says that a connection started or stopped typing in a project, on
+// a task when TaskID is set.
+type Typing struct {
+	UserID int64  `json:"user_id"`
+	ConnID string `json:"connection_id"`
+	TaskID int64  `json:"task_id,omitempty"`
+	Typing bool   `json:"typing"`
+}
+
+type viewer struct {
+	Viewer
+	seen time.Time
+}
+
+func newConnID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Watch makes s follow a project's changes and announces it as a viewer.
+// The caller checks that the user may see the project.
+func (s *Subscription) Watch(ctx context.Context, project int64) error {
+	h := s.hub
+	h.mu.Lock()
+	select {
+	case <-s.done:
+		h.mu.Unlock()
+		return ErrClosed
+	default:
+	}
+	switch {
+	case s.watching == nil:
+		h.mu.Unlock()
+		return ErrNotWatchable
+	case s.watching[project]:
+		h.mu.Unlock()
+		return nil
+	case len(s.watching) >= MaxWatchedProjects:
+		h.mu.Unlock()
+		return ErrTooManyProjects
+	}
+	s.watching[project] = true
+	if h.watchers[project] == nil {
+		h.watchers[project] = make(map[*Subscription]struct{})
+	}
+	h.watchers[project][s] = struct{}{}
+	h.mu.Unlock()
+
+	return h.publishCtx(ctx, signal{Kind: signalJoin, Project: project, UserID: s.userID, ConnID: s.connID})
+}
+
+// Unwatch stops following a project and leaves its presence.
+func (s *Subscription) Unwatch(ctx context.Context, project int64) error {
+	h := s.hub
+	h.mu.Lock()
+	if !s.watching[project] {
+		h.mu.Unlock()
+		return nil
+	}
+	h.unwatch(s, project)
+	h.mu.Unlock()
+
+	return h.publishCtx(ctx, signal{Kind: signalLeave, Project: project, UserID: s.userID, ConnID: s.connID})
+}
+
+// SetTyping tells the project's other viewers that s started or stopped
+// typing. Clients should repeat it every few seconds while typing and treat
+// an indicator that is not repeated as stopped.
+func (s *Subscription) SetTyping(ctx context.Context, project, taskID int64, typing bool) error {
+	h := s.hub
+	h.mu.Lock()
+	watching := s.watching[project]
+	h.mu.Unlock()
+	if !watching {
+		return ErrNotWatching
+	}
+	return h.publishCtx(ctx, signal{Kind: signalTyping, Project: project, UserID: s.userID, ConnID: s.connID, TaskID: taskID, Typing: typing})
+}
+
+// Viewers returns who is viewing a project, as far as this instance knows.
+func (h *Hub) Viewers(project int64) []Viewer {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.viewerList(project)
+}
+
+// unwatch removes s from a project's watchers. h.mu must be held.
+func (h *Hub) unwatch(s *Subscription, project int64) {
+	delete(s.watching, project)
+	if subs := h.watchers[project]; subs != nil {
+		delete(subs, s)
+		if len(subs) == 0 {
+			delete(h.watchers, project)
+		}
+	}
+}
+
+func (h *Hub) publishCtx(ctx context.Context, sig signal) error {
+	payload, err := json.Marshal(sig)
+	if err != nil {
+		return err
+	}
+	_, err = h.pool.Exec(ctx, "SELECT pg_notify($1, $2)", presenceChannel, string(payload))
+	return err
+}
+
+// publish sends a signal that nobody waits for, such as a leave when a
+// connection ends.
+func (h *Hub) publish(sig signal) {
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancel()
+	if err := h.publishCtx(ctx, sig); err != nil {
+		log.Printf("realtime: publish %s: %v", sig.Kind, err)
+	}
+}
+
+// receive applies a signal from any instance, this one included.
+func (h *Hub) receive(sig signal) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	switch sig.Kind {
+	case signalJoin, signalHere:
+		now := time.Now()
+		if v, ok := h.viewers[sig.Project][sig.ConnID]; ok {
+			v.seen = now
+			return
+		}
+		if h.viewers[sig.Project] == nil {
+			h.viewers[sig.Project] = make(map[string]*viewer)
+		}
+		h.viewers[sig.Project][sig.ConnID] = &viewer{Viewer: Viewer{UserID: sig.UserID, ConnID: sig.ConnID, Since: now}, seen: now}
+		h.sendPresence(sig.Project)
+
+	case signalLeave:
+		viewers := h.viewers[sig.Project]
+		if _, ok := viewers[sig.ConnID]; !ok {
+			return
+		}
+		delete(viewers, sig.ConnID)
+		if len(viewers) == 0 {
+			delete(h.viewers, sig.Project)
+		}
+		h.sendPresence(sig.Project)
+
+	case signalTyping:
+		ev := Event{Kind: KindTyping, Project: sig.Project, Typing: Typing{UserID: sig.UserID, ConnID: sig.ConnID, TaskID: sig.TaskID, Typing: sig.Typing}}
+		for s := range h.watchers[sig.Project] {
+			if s.connID != sig.ConnID {
+				h.send(s, ev)
+			}
+		}
+	}
+}
+
+// sendPresence tells a project's watchers on this instance who is viewing
+// it. h.mu must be held.
+func (h *Hub) sendPresence(project int64) {
+	if len(h.watchers[project]) == 0 {
+		return
+	}
+	ev := Event{Kind: KindPresence, Project: project, Viewers: h.viewerList(project)}
+	for s := range h.watchers[project] {
+		h.send(s, ev)
+	}
+}
+
+// viewerList returns a project's viewers, longest present first. h.mu must
+// be held.
+func (h *Hub) viewerList(project int64) []Viewer {
+	list := make([]Viewer, 0, len(h.viewers[project]))
+	for _, v := range h.viewers[project] {
+		list = append(list, v.Viewer)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].Since.Equal(list[j].Since) {
+			return list[i].Since.Before(list[j].Since)
+		}
+		return list[i].ConnID < list[j].ConnID
+	})
+	return list
+}
+
+// announce re-announces this instance's viewers and forgets viewers that
+// have not been announced within presenceTTL, until ctx is cancelled.
+func (h *Hub) announce(ctx context.Context) {
+	ticker := time.NewTicker(presenceInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		h.mu.Lock()
+		var sigs []signal
+		for project, subs := range h.watchers {
+			for s := range subs {
+				sigs = append(sigs, signal{Kind: signalHere, Project: project, UserID: s.userID, ConnID: s.connID})
+			}
+		}
+		cutoff := time.Now().Add(-presenceTTL)
+		for project, viewers := range h.viewers {
+			changed := false
+			for id, v := range viewers {
+				if v.seen.Before(cutoff) {
+					delete(viewers, id)
+					changed = true
+				}
+			}
+			if len(viewers) == 0 {
+				delete(h.viewers, project)
+			}
+			if changed {
+				h.sendPresence(project)
+			}
+		}
+		h.mu.Unlock()
+
+		for _, sig := range sigs {
+			if err := h.publishCtx(ctx, sig); err != nil && ctx.Err() == nil {
+				log.Printf("realtime: announce: %v", err)
+			}
+		}
+	}
+}
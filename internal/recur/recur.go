@@ -0,0 +1,142 @@
+// Package recur parses and formats task repeat rules.
+//
+// Rules are a small subset of RFC 5545 RRULE values (FREQ, INTERVAL, BYDAY
+// without ordinals and BYMONTHDAY), which is enough for "every 2 weeks on
+// Monday" style rules and can be exported to calendars unchanged.
+package recur
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequencies a rule can repeat at.
+const (
+	Daily   = "DAILY"
+	Weekly  = "WEEKLY"
+	Monthly = "MONTHLY"
+	Yearly  = "YEARLY"
+)
+
+// ErrInvalid is returned by Parse for rules outside the supported subset.
+// The wrapped message says which part is wrong.
+var ErrInvalid = errors.New("recur: invalid rule")
+
+var dayCodes = [7]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// Rule is a parsed repeat rule. Interval is at least 1 and ByMonthDay is 0
+// when not set.
+type Rule struct {
+	Freq       string
+	Interval   int
+	ByDay      []time.Weekday
+	ByMonthDay int
+}
+
+// Parse parses an RRULE value such as "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH".
+// An optional "RRULE:" prefix is accepted.
+func Parse(s string) (Rule, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	r := Rule{Interval: 1}
+	seen := map[string]bool{}
+	for _, part := range strings.Split(s, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		name = strings.ToUpper(name)
+		if !ok || value == "" {
+			return Rule{}, fmt.Errorf("%w: %q is not NAME=VALUE", ErrInvalid, part)
+		}
+		if seen[name] {
+			return Rule{}, fmt.Errorf("%w: %s given twice", ErrInvalid, name)
+		}
+		seen[name] = true
+
+		switch name {
+		case "FREQ":
+			r.Freq = strings.ToUpper(value)
+			if !slices.Contains([]string{Daily, Weekly, Monthly, Yearly}, r.Freq) {
+				return Rule{}, fmt.Errorf("%w: unsupported FREQ %q", ErrInvalid, value)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 || n > 999 {
+				return Rule{}, fmt.Errorf("%w: INTERVAL must be between 1 and 999", ErrInvalid)
+			}
+			r.Interval = n
+		case "BYDAY":
+			for _, code := range strings.Split(strings.ToUpper(value), ",") {
+				i := slices.Index(dayCodes[:], code)
+				if i < 0 {
+					return Rule{}, fmt.Errorf("%w: unsupported BYDAY value %q", ErrInvalid, code)
+				}
+				if !slices.Contains(r.ByDay, time.Weekday(i)) {
+					r.ByDay = append(r.ByDay, time.Weekday(i))
+				}
+			}
+		case "BYMONTHDAY":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 || n > 31 {
+				return Rule{}, fmt.Errorf("%w: BYMONTHDAY must be between 1 and 31", ErrInvalid)
+			}
+			r.ByMonthDay = n
+		default:
+			return Rule{}, fmt.Errorf("%w: unsupported part %s", ErrInvalid, name)
+		}
+	}
+	if r.Freq == "" {
+		return Rule{}, fmt.Errorf("%w: FREQ is required", ErrInvalid)
+	}
+	if len(r.ByDay) > 0 && r.Freq != Weekly {
+		return Rule{}, fmt.Errorf("%w: BYDAY is only supported with FREQ=WEEKLY", ErrInvalid)
+	}
+	if r.ByMonthDay != 0 && r.Freq != Monthly {
+		return Rule{}, fmt.Errorf("%w: BYMONTHDAY is only supported with FREQ=MONTHLY", ErrInvalid)
+	}
+	return r, nil
+}
+
+// String formats the rule as a canonical RRULE value, without the prefix.
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := slices.Clone(r.ByDay)
+		// Weeks start on Monday, as in RFC 5545.
+		slices.SortFunc(days, func(a, b time.Weekday) int { return (int(a)+6)%7 - (int(b)+6)%7 })
+		codes := make([]string, len(days))
+		for i, d := range days {
+			codes[i] = dayCodes[d]
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	if r.ByMonthDay != 0 {
+		parts = append(parts, "BYMONTHDAY="+strconv.Itoa(r.ByMonthDay))
+	}
+	return strings.Join(parts, ";")
+}
+
+// First returns the first day on or after from that matches the rule, at the
+// same time of day as from. Rules that only fix the frequency match any day.
+func (r Rule) First(from time.Time) time.Time {
+	switch {
+	case len(r.ByDay) > 0:
+		for d := from; ; d = d.AddDate(0, 0, 1) {
+			if slices.Contains(r.ByDay, d.Weekday()) {
+				return d
+			}
+		}
+	case r.ByMonthDay != 0:
+		// Months too short for the day are skipped.
+		for d := from; ; d = d.AddDate(0, 0, 1) {
+			if d.Day() == r.ByMonthDay {
+				return d
+			}
+		}
+	}
+	return from
+}
@@ -0,0 +1,104 @@
+package taskquery
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+// token is a lexeme with the 1-based column it starts at.
+type token struct {
+	kind tokenKind
+	text string
+	col  int
+}
+
+// describe names the token for error messages.
+func (t token) describe() string {
+	if t.kind == tokEOF {
+		return "end of input"
+	}
+	return strconv.Quote(t.text)
+}
+
+// is reports whether t is the given keyword, ignoring case. Quoted strings
+// are never keywords.
+func (t token) is(keyword string) bool {
+	return t.kind == tokWord && strings.EqualFold(t.text, keyword)
+}
+
+// wordBreaks are the characters that end a bare word.
+const wordBreaks = `()=!<>~:"`
+
+func lex(src string) ([]token, error) {
+	var toks []token
+	col := func(off int) int { return utf8.RuneCountInString(src[:off]) + 1 }
+
+	for off := 0; off < len(src); {
+		r, size := utf8.DecodeRuneInString(src[off:])
+		switch {
+		case unicode.IsSpace(r):
+			off += size
+		case r == '(':
+			toks = append(toks, token{kind: tokLParen, text: "(", col: col(off)})
+			off++
+		case r == ')':
+			toks = append(toks, token{kind: tokRParen, text: ")", col: col(off)})
+			off++
+		case r == '"':
+			start := off
+			var b strings.Builder
+			off++
+			for {
+				if off >= len(src) {
+					return nil, &Error{Column: col(start), Token: src[start:], Msg: "unterminated string"}
+				}
+				if src[off] == '\\' && off+1 < len(src) && (src[off+1] == '"' || src[off+1] == '\\') {
+					b.WriteByte(src[off+1])
+					off += 2
+					continue
+				}
+				if src[off] == '"' {
+					off++
+					break
+				}
+				b.WriteByte(src[off])
+				off++
+			}
+			toks = append(toks, token{kind: tokString, text: b.String(), col: col(start)})
+		case strings.ContainsRune("=!<>~:", r):
+			op := string(r)
+			if off+1 < len(src) && src[off+1] == '=' && strings.ContainsRune("!<>", r) {
+				op += "="
+			}
+			if op == "!" {
+				return nil, &Error{Column: col(off), Token: op, Msg: `unexpected "!": use "!=" or "not"`}
+			}
+			toks = append(toks, token{kind: tokOp, text: op, col: col(off)})
+			off += len(op)
+		default:
+			start := off
+			for off < len(src) {
+				r, size := utf8.DecodeRuneInString(src[off:])
+				if unicode.IsSpace(r) || strings.ContainsRune(wordBreaks, r) {
+					break
+				}
+				off += size
+			}
+			toks = append(toks, token{kind: tokWord, text: src[start:off], col: col(start)})
+		}
+	}
+	return append(toks, token{kind: tokEOF, col: utf8.RuneCountInString(src) + 1}), nil
+}
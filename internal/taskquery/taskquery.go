@@ -0,0 +1,421 @@
+// Package taskquery compiles a small filter language over tasks to a
+// parameterized SQL condition, e.g.
+//
+//	priority >= 3 and (status = pending or due < 2026-11-01)
+//	  and project = "Work" and not tag:someday
+//
+// A query is comparisons combined with and, or, not and parentheses; and
+// binds tighter than or. A comparison is field, operator and value, where a
+// value is a bare word or a double-quoted string. Fields:
+//
+//	title, description  = != ~ (contains, ignoring case)
+//	status              = !=
+//	category            = != with todo, doing, done or open
+//	priority            = != < <= > >= with 1 to 5
+//	due, created,       = != < <= > >= with YYYY-MM-DD, a quoted RFC 3339
+//	updated               time, today, tomorrow, yesterday or now; due
+//	                      also takes none
+//	project             = != with a name, an ID or none
+//	tag                 = != with a tag or none
+//
+// "field:value" is shorthand for "field = value", except for title and
+// description where it means contains. Dates compare by calendar day in the
+// time zone given to Compile, so "due <= 2026-11-01" includes that whole day.
+// Keywords, field names and the category value are case-insensitive.
+//
+// Every comparison is true or false, never NULL, so "not due < today" also
+// matches tasks without a due date.
+package taskquery
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxDepth limits how deeply parentheses and not may nest.
+const MaxDepth = 32
+
+// Error is a syntax or type error in a query. Column is the 1-based position
+// of the offending token, counted in characters.
+type Error struct {
+	Column int
+	Token  string
+	Msg    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("column %d: %s", e.Column, e.Msg)
+}
+
+func errorAt(t token, format string, args ...any) *Error {
+	return &Error{Column: t.col, Token: t.text, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Condition is a compiled query: a boolean SQL expression over the columns
+// of the tasks table, with placeholders $1 to $len(Args).
+type Condition struct {
+	SQL  string
+	Args []any
+}
+
+// Compile parses src and compiles it to a Condition. Dates are read in now's
+// location and relative ones (today, …) resolved against now.
+func Compile(src string, now time.Time) (Condition, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return Condition{}, err
+	}
+	p := &parser{toks: toks}
+	if p.peek().kind == tokEOF {
+		return Condition{}, errorAt(p.peek(), "empty query")
+	}
+	n, err := p.parseOr(0)
+	if err != nil {
+		return Condition{}, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		if t.kind == tokRParen {
+			return Condition{}, errorAt(t, `unexpected ")" without a matching "("`)
+		}
+		return Condition{}, errorAt(t, `unexpected %s: expected "and", "or" or the end of the query`, t.describe())
+	}
+
+	c := &compiler{now: now}
+	sql, err := c.compile(n)
+	if err != nil {
+		return Condition{}, err
+	}
+	return Condition{SQL: sql, Args: c.args}, nil
+}
+
+type node interface{}
+
+type logical struct {
+	op          string // "AND" or "OR"
+	left, right node
+}
+
+type negation struct {
+	x node
+}
+
+type comparison struct {
+	field, op, value token
+}
+
+type parser struct {
+	toks []token
+	i    int
+}
+
+func (p *parser) peek() token { return p.toks[p.i] }
+
+func (p *parser) next() token {
+	t := p.toks[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *parser) parseOr(depth int) (node, error) {
+	left, err := p.parseAnd(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().is("or") {
+		p.next()
+		right, err := p.parseAnd(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = logical{op: "OR", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd(depth int) (node, error) {
+	left, err := p.parseNot(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().is("and") {
+		p.next()
+		right, err := p.parseNot(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = logical{op: "AND", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot(depth int) (node, error) {
+	if depth > MaxDepth {
+		return nil, errorAt(p.peek(), "query is nested too deeply")
+	}
+	if p.peek().is("not") {
+		p.next()
+		x, err := p.parseNot(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return negation{x: x}, nil
+	}
+	return p.parsePrimary(depth)
+}
+
+func (p *parser) parsePrimary(depth int) (node, error) {
+	t := p.next()
+	switch {
+	case t.kind == tokLParen:
+		n, err := p.parseOr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, errorAt(closing, `unexpected %s: expected ")" to close the "(" at column %d`, closing.describe(), t.col)
+		}
+		return n, nil
+	case t.kind == tokWord && !t.is("and") && !t.is("or"):
+		op := p.next()
+		if op.kind != tokOp {
+			return nil, errorAt(op, "unexpected %s: expected an operator after %q", op.describe(), t.text)
+		}
+		value := p.next()
+		if value.kind != tokWord && value.kind != tokString {
+			return nil, errorAt(value, "unexpected %s: expected a value after %q", value.describe(), op.text)
+		}
+		return comparison{field: t, op: op, value: value}, nil
+	default:
+		return nil, errorAt(t, `unexpected %s: expected a field name, "not" or "("`, t.describe())
+	}
+}
+
+type compiler struct {
+	now  time.Time
+	args []any
+}
+
+// arg adds a placeholder for v and returns it.
+func (c *compiler) arg(v any) string {
+	c.args = append(c.args, v)
+	return "$" + strconv.Itoa(len(c.args))
+}
+
+// text is arg for strings, typed so Postgres need not infer it.
+func (c *compiler) text(s string) string {
+	return c.arg(s) + "::text"
+}
+
+func (c *compiler) compile(n node) (string, error) {
+	switch n := n.(type) {
+	case logical:
+		left, err := c.compile(n.left)
+		if err != nil {
+			return "", err
+		}
+		right, err := c.compile(n.right)
+		if err != nil {
+			return "", err
+		}
+		return "(" + left + " " + n.op + " " + right + ")", nil
+	case negation:
+		x, err := c.compile(n.x)
+		if err != nil {
+			return "", err
+		}
+		return "NOT " + x, nil
+	case comparison:
+		sql, err := c.comparison(n)
+		if err != nil {
+			return "", err
+		}
+		// Comparisons against NULL columns count as false, so not is the
+		// complement of its operand.
+		return "COALESCE(" + sql + ", false)", nil
+	}
+	return "", fmt.Errorf("taskquery: unknown node %T", n)
+}
+
+var fields = []string{"title", "description", "status", "category", "priority", "due", "created", "updated", "project", "tag"}
+
+var sqlOps = map[string]string{"=": "=", ":": "=", "!=": "<>", "<": "<", "<=": "<=", ">": ">", ">=": ">="}
+
+func (c *compiler) comparison(n comparison) (string, error) {
+	field := strings.ToLower(n.field.text)
+	op := n.op.text
+	value := n.value.text
+	unquoted := n.value.kind == tokWord
+
+	allow := func(ops ...string) error {
+		for _, o := range ops {
+			if o == op {
+				return nil
+			}
+		}
+		return errorAt(n.op, "operator %q cannot be used with %s; use %s", op, field, strings.Join(ops, " "))
+	}
+
+	switch field {
+	case "title", "description":
+		if err := allow("=", "!=", "~", ":"); err != nil {
+			return "", err
+		}
+		col := "title"
+		if field == "description" {
+			col = "coalesce(description, '')"
+		}
+		switch op {
+		case "~", ":":
+			return col + ` ILIKE '%' || ` + c.text(escapeLike(value)) + ` || '%'`, nil
+		case "=":
+			return "lower(" + col + ") = lower(" + c.text(value) + ")", nil
+		default:
+			return "lower(" + col + ") <> lower(" + c.text(value) + ")", nil
+		}
+
+	case "status":
+		if err := allow("=", "!=", ":"); err != nil {
+			return "", err
+		}
+		return "status " + sqlOps[op] + " " + c.text(value), nil
+
+	case "category":
+		if err := allow("=", "!=", ":"); err != nil {
+			return "", err
+		}
+		v := strings.ToLower(value)
+		switch v {
+		case "todo", "doing", "done":
+			return "status_category " + sqlOps[op] + " " + c.text(v), nil
+		case "open":
+			if op == "!=" {
+				return "status_category = 'done'", nil
+			}
+			return "status_category <> 'done'", nil
+		}
+		return "", errorAt(n.value, "invalid category %s: expected todo, doing, done or open", n.value.describe())
+
+	case "priority":
+		if err := allow("=", "!=", "<", "<=", ">", ">=", ":"); err != nil {
+			return "", err
+		}
+		p, err := strconv.Atoi(value)
+		if err != nil || !unquoted || p < 1 || p > 5 {
+			return "", errorAt(n.value, "invalid priority %s: expected a number from 1 to 5", n.value.describe())
+		}
+		return "priority " + sqlOps[op] + " " + c.arg(p), nil
+
+	case "due", "created", "updated":
+		if err := allow("=", "!=", "<", "<=", ">", ">=", ":"); err != nil {
+			return "", err
+		}
+		col := map[string]string{"due": "due_date", "created": "created_at", "updated": "updated_at"}[field]
+		if field == "due" && unquoted && strings.EqualFold(value, "none") {
+			if err := allow("=", "!=", ":"); err != nil {
+				return "", err
+			}
+			if op == "!=" {
+				return "due_date IS NOT NULL", nil
+			}
+			return "due_date IS NULL", nil
+		}
+		return c.timeComparison(n, col)
+
+	case "project":
+		if err := allow("=", "!=", ":"); err != nil {
+			return "", err
+		}
+		var sql string
+		if id, err := strconv.ParseInt(value, 10, 64); err == nil && unquoted {
+			sql = "project_id = " + c.arg(id)
+		} else if unquoted && strings.EqualFold(value, "none") {
+			sql = "project_id IS NULL"
+		} else {
+			sql = "project_id IN (SELECT p.id FROM projects p WHERE p.user_id = tasks.user_id AND lower(p.name) = lower(" + c.text(value) + "))"
+		}
+		if op == "!=" {
+			// project_id IS NULL makes the comparison false, not NULL.
+			return "NOT COALESCE(" + sql + ", false)", nil
+		}
+		return sql, nil
+
+	case "tag":
+		if err := allow("=", "!=", ":"); err != nil {
+			return "", err
+		}
+		sql := "cardinality(tags) = 0"
+		if !unquoted || !strings.EqualFold(value, "none") {
+			sql = "tags @> ARRAY[" + c.text(strings.ToLower(value)) + "]"
+		}
+		if op == "!=" {
+			return "NOT " + sql, nil
+		}
+		return sql, nil
+	}
+
+	return "", errorAt(n.field, "unknown field %s: expected one of %s", n.field.describe(), strings.Join(fields, ", "))
+}
+
+// timeComparison compiles a comparison of a timestamp column. A date stands
+// for the whole day: "= D" is [D, D+1), "<= D" is before D+1 and so on.
+func (c *compiler) timeComparison(n comparison, col string) (string, error) {
+	start, day, ok := c.parseTime(n.value)
+	if !ok {
+		return "", errorAt(n.value, "invalid date %s: expected YYYY-MM-DD, a quoted RFC 3339 time, today, tomorrow, yesterday or now", n.value.describe())
+	}
+	if !day {
+		return col + " " + sqlOps[n.op.text] + " " + c.arg(start), nil
+	}
+
+	end := start.AddDate(0, 0, 1)
+	switch n.op.text {
+	case "=", ":":
+		return "(" + col + " >= " + c.arg(start) + " AND " + col + " < " + c.arg(end) + ")", nil
+	case "!=":
+		return "(" + col + " < " + c.arg(start) + " OR " + col + " >= " + c.arg(end) + ")", nil
+	case "<":
+		return col + " < " + c.arg(start), nil
+	case "<=":
+		return col + " < " + c.arg(end), nil
+	case ">":
+		return col + " >= " + c.arg(end), nil
+	default: // ">="
+		return col + " >= " + c.arg(start), nil
+	}
+}
+
+// parseTime reads a date or time value. day reports whether it names a
+// whole day, in which case t is its start.
+func (c *compiler) parseTime(v token) (t time.Time, day bool, ok bool) {
+	loc := c.now.Location()
+	today := time.Date(c.now.Year(), c.now.Month(), c.now.Day(), 0, 0, 0, 0, loc)
+	if v.kind == tokWord {
+		switch strings.ToLower(v.text) {
+		case "now":
+			return c.now, false, true
+		case "today":
+			return today, true, true
+		case "tomorrow":
+			return today.AddDate(0, 0, 1), true, true
+		case "yesterday":
+			return today.AddDate(0, 0, -1), true, true
+		}
+	}
+	if d, err := time.ParseInLocation(time.DateOnly, v.text, loc); err == nil {
+		return d, true, true
+	}
+	if ts, err := time.Parse(time.RFC3339, v.text); err == nil {
+		return ts, false, true
+	}
+	return time.Time{}, false, false
+}
+
+// escapeLike escapes the LIKE wildcards in s, using the default escape
+// character.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,166 @@
+package taskquery
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2026, 10, 19, 15, 30, 0, 0, time.UTC)
+
+func TestCompile(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 10, d, 0, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		name string
+		src  string
+		sql  string
+		args []any
+	}{
+		{
+			name: "and binds tighter than or",
+			src:  "status = a or status = b and priority > 2",
+			sql:  "(COALESCE(status = $1::text, false) OR (COALESCE(status = $2::text, false) AND COALESCE(priority > $3, false)))",
+			args: []any{"a", "b", 2},
+		},
+		{
+			name: "parentheses",
+			src:  "(status = a or status = b) and priority > 2",
+			sql:  "((COALESCE(status = $1::text, false) OR COALESCE(status = $2::text, false)) AND COALESCE(priority > $3, false))",
+			args: []any{"a", "b", 2},
+		},
+		{
+			name: "not binds tighter than and",
+			src:  "not status = a and tag:x",
+			sql:  "(NOT COALESCE(status = $1::text, false) AND COALESCE(tags @> ARRAY[$2::text], false))",
+			args: []any{"a", "x"},
+		},
+		{
+			name: "or is left-associative",
+			src:  "priority = 1 OR priority = 2 Or priority = 3",
+			sql:  "((COALESCE(priority = $1, false) OR COALESCE(priority = $2, false)) OR COALESCE(priority = $3, false))",
+			args: []any{1, 2, 3},
+		},
+		{
+			name: "quoted value with spaces and escapes",
+			src:  `project = "Work \"A\" \\ B"`,
+			sql:  "COALESCE(project_id IN (SELECT p.id FROM projects p WHERE p.user_id = tasks.user_id AND lower(p.name) = lower($1::text)), false)",
+			args: []any{`Work "A" \ B`},
+		},
+		{
+			name: "quoted number is a project name",
+			src:  `project = "12"`,
+			sql:  "COALESCE(project_id IN (SELECT p.id FROM projects p WHERE p.user_id = tasks.user_id AND lower(p.name) = lower($1::text)), false)",
+			args: []any{"12"},
+		},
+		{
+			name: "bare number is a project ID",
+			src:  "project:12",
+			sql:  "COALESCE(project_id = $1, false)",
+			args: []any{int64(12)},
+		},
+		{
+			name: "quoted none is a tag name",
+			src:  `tag = "none"`,
+			sql:  "COALESCE(tags @> ARRAY[$1::text], false)",
+			args: []any{"none"},
+		},
+		{
+			name: "bare none",
+			src:  "tag != none and due = none",
+			sql:  "(COALESCE(NOT cardinality(tags) = 0, false) AND COALESCE(due_date IS NULL, false))",
+		},
+		{
+			name: "contains escapes wildcards",
+			src:  `title ~ "50% off_now"`,
+			sql:  `COALESCE(title ILIKE '%' || $1::text || '%', false)`,
+			args: []any{`50\% off\_now`},
+		},
+		{
+			name: "shorthand on description",
+			src:  "description:milk",
+			sql:  `COALESCE(coalesce(description, '') ILIKE '%' || $1::text || '%', false)`,
+			args: []any{"milk"},
+		},
+		{
+			name: "date covers the whole day",
+			src:  "due <= 2026-11-01",
+			sql:  "COALESCE(due_date < $1, false)",
+			args: []any{time.Date(2026, 11, 2, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			name: "relative date",
+			src:  "due = today",
+			sql:  "COALESCE((due_date >= $1 AND due_date < $2), false)",
+			args: []any{day(19), day(20)},
+		},
+		{
+			name: "quoted time",
+			src:  `updated > "2026-10-01T08:00:00Z"`,
+			sql:  "COALESCE(updated_at > $1, false)",
+			args: []any{time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)},
+		},
+		{
+			name: "keywords and fields ignore case",
+			src:  "NOT Category = OPEN",
+			sql:  "NOT COALESCE(status_category <> 'done', false)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Compile(tt.src, testNow)
+			if err != nil {
+				t.Fatalf("Compile(%q): %v", tt.src, err)
+			}
+			if got.SQL != tt.sql {
+				t.Errorf("Compile(%q).SQL =\n\t%s\nwant\n\t%s", tt.src, got.SQL, tt.sql)
+			}
+			if !reflect.DeepEqual(got.Args, tt.args) {
+				t.Errorf("Compile(%q).Args = %#v, want %#v", tt.src, got.Args, tt.args)
+			}
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		src    string
+		column int
+		msg    string
+	}{
+		{"", 1, "empty query"},
+		{"   ", 4, "empty query"},
+		{"status", 7, "expected an operator"},
+		{"status =", 9, "expected a value"},
+		{"status = a and", 15, "expected a field name"},
+		{"status = a b = c", 12, `expected "and", "or"`},
+		{`title = "abc`, 9, "unterminated string"},
+		{"(status = a", 12, `to close the "(" at column 1`},
+		{"status = a)", 11, "without a matching"},
+		{"status ! a", 8, `use "!=" or "not"`},
+		{"and = 1", 1, "expected a field name"},
+		{"colour = red", 1, "unknown field"},
+		{"priority = 9", 12, "invalid priority"},
+		{`priority = "3"`, 12, "invalid priority"},
+		{"status < a", 8, "cannot be used with status"},
+		{"title < a", 7, "cannot be used with title"},
+		{"due < none", 5, "cannot be used with due"},
+		{"due < someday", 7, "invalid date"},
+		{"category = later", 12, "invalid category"},
+		{"tag ~ x", 5, "cannot be used with tag"},
+		{strings.Repeat("(", MaxDepth+2) + "status = a" + strings.Repeat(")", MaxDepth+2), MaxDepth + 2, "nested too deeply"},
+		{strings.Repeat("not ", MaxDepth+2) + "status = a", 4*(MaxDepth+1) + 1, "nested too deeply"},
+	}
+	for _, tt := range tests {
+		_, err := Compile(tt.src, testNow)
+		var qerr *Error
+		if !errors.As(err, &qerr) {
+			t.Errorf("Compile(%q) = %v, want an *Error", tt.src, err)
+			continue
+		}
+		if qerr.Column != tt.column || !strings.Contains(qerr.Msg, tt.msg) {
+			t.Errorf("Compile(%q) = %q at column %d, want %q at column %d", tt.src, qerr.Msg, qerr.Column, tt.msg, tt.column)
+		}
+	}
+}
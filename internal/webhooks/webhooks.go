@@ -0,0 +1,246 @@
+// Package webhooks holds what outgoing webhooks and their receivers agree
+// on: the events, the request headers and the signature.
+//
+// Deliveries are queued from the outbox by outbox.WebhookSink and sent by
+// jobs.WebhookDispatcher. Each delivery is a POST with a JSON Payload. Its
+// X-Webhook-Signature header has the form
+//
+//	t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>" keyed by the secret>
+//
+// and receivers should check it with Verify. A delivery is retried with
+// exponential backoff until the receiver answers with a 2xx status, so it
+// may arrive more than once; X-Webhook-Delivery identifies it for
+// deduplication. X-Webhook-Event-ID names the outbox event the delivery
+// reports; pings and manual redeliveries have none.
+//
+// Receivers must be on public addresses: loopback, private, link-local
+// (which holds cloud metadata services) and other reserved ranges are
+// refused, both when a URL is saved and, through DialControl, whenever a
+// delivery connects. Only the receiver's status code is kept.
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Request headers of a delivery.
+const (
+	HeaderSignature = "X-Webhook-Signature"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderEventID   = "X-Webhook-Event-ID"
+	UserAgent       = "auriya-webhooks/1"
+)
+
+// EventPing is sent on request to check that a receiver is reachable. It is
+// delivered whatever events the webhook subscribed to.
+const EventPing = "ping"
+
+// Events lists the events a webhook can subscribe to.
+var Events = []string{
+	"task.created",
+	"task.updated",
+	"task.deleted",
+	"project.created",
+	"project.updated",
+	"project.deleted",
+}
+
+// ValidEvent reports whether name is one of Events.
+func ValidEvent(name string) bool {
+	return slices.Contains(Events, name)
+}
+
+const (
+	// MaxAttempts is how often a delivery is tried before it is given up.
+	MaxAttempts = 10
+	// MaxConsecutiveFailures is how many attempts in a row may fail, over
+	// all of a webhook's deliveries, before the webhook is turned off.
+	MaxConsecutiveFailures = 15
+
+	firstRetryDelay = time.Minute
+	maxRetryDelay   = 6 * time.Hour
+)
+
+// Backoff returns how long to wait before retrying a delivery that has
+// failed attempts times: a minute after the first failure, doubling up to
+// six hours.
+func Backoff(attempts int) time.Duration {
+	d := firstRetryDelay
+	for i := 1; i < attempts && d < maxRetryDelay; i++ {
+		d *= 2
+	}
+	return min(d, maxRetryDelay)
+}
+
+// Payload is the body of a delivery. Data is the task or project as the API
+// returns it, as it was when the event happened; deletes carry the resource
+// as it was just before.
+type Payload struct {
+	ID        int64           `json:"id"`
+	EventID   string          `json:"event_id,omitempty"`
+	Event     string          `json:"event"`
+	WebhookID int64           `json:"webhook_id"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// RenderFunc turns the database row a delivery was queued with into
+// Payload.Data. resourceType is "task", "project", or "webhook" for pings.
+type RenderFunc func(resourceType string, row []byte) (json.RawMessage, error)
+
+// NewSecret returns a random signing secret.
+func NewSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// ValidateURL checks that raw is an absolute http or https URL. Unless
+// allowPrivate is set, its host must not be localhost or an address
+// PublicAddr rejects; host names are checked again when they are dialled.
+func ValidateURL(raw string, allowPrivate bool) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.New("url must use http or https")
+	}
+	if u.Host == "" {
+		return errors.New("url must have a host")
+	}
+	if u.User != nil {
+		return errors.New("url must not contain credentials")
+	}
+	if allowPrivate {
+		return nil
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return errors.New("url must not point to a local or private address")
+	}
+	if ip, err := netip.ParseAddr(host); err == nil && !PublicAddr(ip) {
+		return errors.New("url must not point to a local or private address")
+	}
+	return nil
+}
+
+// ErrForbiddenAddress is returned by DialControl for addresses PublicAddr
+// rejects.
+var ErrForbiddenAddress = errors.New("webhooks: address not allowed")
+
+// reservedPrefixes are ranges that are neither private nor public, which
+// the netip predicates do not cover.
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+}
+
+// PublicAddr reports whether ip is a public unicast address a receiver may
+// use: not loopback, private, link-local, multicast, unspecified or
+// reserved. IPv4-mapped IPv6 addresses are judged by their IPv4 address.
+func PublicAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return false
+	}
+	for _, p := range reservedPrefixes {
+		if p.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// DialControl is a net.Dialer Control function that refuses to connect to
+// addresses PublicAddr rejects. It runs on the resolved address, so host
+// names that resolve, or are rebound, to internal addresses are refused as
+// well.
+func DialControl(network, address string, _ syscall.RawConn) error {
+	ap, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !PublicAddr(ap.Addr()) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, ap.Addr())
+	}
+	return nil
+}
+
+// Sign returns the X-Webhook-Signature value for body sent at t.
+func Sign(secret string, t time.Time, body []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	return "t=" + ts + ",v1=" + signature(secret, ts, body)
+}
+
+func signature(secret, ts string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+var (
+	// ErrBadSignature is returned by Verify when the header is malformed or
+	// no signature in it matches.
+	ErrBadSignature = errors.New("webhooks: signature mismatch")
+	// ErrStale is returned by Verify when the signature is older than the
+	// tolerance, which guards against replayed requests.
+	ErrStale = errors.New("webhooks: signature too old")
+)
+
+// Verify checks a received X-Webhook-Signature header against body. A
+// tolerance of zero skips the age check.
+func Verify(secret, header string, body []byte, tolerance time.Duration) error {
+	var ts string
+	var sigs []string
+	for _, part := range strings.Split(header, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return ErrBadSignature
+		}
+		switch k {
+		case "t":
+			ts = v
+		case "v1":
+			sigs = append(sigs, v)
+		}
+	}
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || len(sigs) == 0 {
+		return ErrBadSignature
+	}
+
+	want := []byte(signature(secret, ts, body))
+	matched := false
+	for _, sig := range sigs {
+		matched = matched || hmac.Equal(want, []byte(sig))
+	}
+	if !matched {
+		return ErrBadSignature
+	}
+	if age := time.Since(time.Unix(sec, 0)); tolerance > 0 && (age > tolerance || age < -tolerance) {
+		return fmt.Errorf("%w: signed %s ago", ErrStale, age.Round(time.Second))
+	}
+	return nil
+}
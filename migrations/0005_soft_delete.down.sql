@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_projects_deleted_at;
+DROP INDEX IF EXISTS idx_tasks_deleted_at;
+DROP INDEX IF EXISTS idx_projects_user_live;
+DROP INDEX IF EXISTS idx_tasks_user_live;
+
+ALTER TABLE "projects" DROP COLUMN IF EXISTS "deleted_at";
+ALTER TABLE "tasks" DROP COLUMN IF EXISTS "deleted_at";
@@ -0,0 +1,12 @@
+-- Soft delete for tasks and projects. Rows with deleted_at set live in the
+-- trash until they are restored or purged.
+ALTER TABLE "tasks" ADD COLUMN "deleted_at" timestamptz;
+ALTER TABLE "projects" ADD COLUMN "deleted_at" timestamptz;
+
+-- Most queries only look at live rows
+CREATE INDEX IF NOT EXISTS idx_tasks_user_live ON "tasks" ("user_id") WHERE "deleted_at" IS NULL;
+CREATE INDEX IF NOT EXISTS idx_projects_user_live ON "projects" ("user_id") WHERE "deleted_at" IS NULL;
+
+-- Used by the trash listing and the purge job
+CREATE INDEX IF NOT EXISTS idx_tasks_deleted_at ON "tasks" ("deleted_at") WHERE "deleted_at" IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_projects_deleted_at ON "projects" ("deleted_at") WHERE "deleted_at" IS NOT NULL;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_task_events_task_id;
+DROP TABLE IF EXISTS "task_events";
@@ -0,0 +1,22 @@
+-- Audit trail of task mutations. Each row records who changed a task and a
+-- field-level before/after diff of what changed.
+CREATE TABLE "task_events" (
+  "id" bigserial PRIMARY KEY,
+  "task_id" bigint NOT NULL,
+  "user_id" bigint NOT NULL,
+  "actor_id" bigint,
+  "action" varchar(20) NOT NULL,
+  "changes" jsonb NOT NULL DEFAULT '{}',
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "task_events" ADD FOREIGN KEY ("task_id") REFERENCES "tasks" ("id") ON DELETE CASCADE;
+ALTER TABLE "task_events" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+ALTER TABLE "task_events" ADD FOREIGN KEY ("actor_id") REFERENCES "users" ("id") ON DELETE SET NULL;
+
+ALTER TABLE "task_events"
+    ADD CONSTRAINT task_events_action_check
+    CHECK (action IN ('created', 'updated', 'deleted', 'restored'));
+
+-- History is always read newest first for a single task
+CREATE INDEX IF NOT EXISTS idx_task_events_task_id ON "task_events" ("task_id", "id" DESC);
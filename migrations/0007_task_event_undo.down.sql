@@ -0,0 +1,3 @@
+ALTER TABLE "task_events" DROP CONSTRAINT IF EXISTS task_events_reverts_event_id_fkey;
+ALTER TABLE "task_events" DROP COLUMN IF EXISTS "reverts_event_id";
+ALTER TABLE "task_events" DROP COLUMN IF EXISTS "undone_at";
@@ -0,0 +1,6 @@
+-- Undo support for task history. An undo is itself recorded as a new event
+-- pointing at the event it reverts, and the reverted event is marked undone.
+ALTER TABLE "task_events" ADD COLUMN "undone_at" timestamptz;
+ALTER TABLE "task_events" ADD COLUMN "reverts_event_id" bigint;
+
+ALTER TABLE "task_events" ADD FOREIGN KEY ("reverts_event_id") REFERENCES "task_events" ("id") ON DELETE SET NULL;
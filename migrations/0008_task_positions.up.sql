@@ -0,0 +1,19 @@
+-- Manual ordering of tasks. position is a fractional key (see internal/rank)
+-- compared byte-wise, scoped per user and project (NULL project = inbox).
+ALTER TABLE "tasks" ADD COLUMN "position" text COLLATE "C" NOT NULL DEFAULT '';
+
+-- Existing tasks keep their creation order. The backfill is not a user
+-- change, so keep updated_at as it is.
+ALTER TABLE "tasks" DISABLE TRIGGER trg_set_updated_at;
+
+UPDATE "tasks" t
+SET "position" = lpad(o.rn::text, 10, '0')
+FROM (
+  SELECT id, row_number() OVER (PARTITION BY user_id, project_id ORDER BY created_at, id) AS rn
+  FROM "tasks"
+) o
+WHERE t.id = o.id;
+
+ALTER TABLE "tasks" ENABLE TRIGGER trg_set_updated_at;
+
+CREATE INDEX IF NOT EXISTS idx_tasks_position ON "tasks" ("user_id", "project_id", "position") WHERE "deleted_at" IS NULL;
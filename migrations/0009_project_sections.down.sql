@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_tasks_section_id;
+ALTER TABLE "tasks" DROP CONSTRAINT IF EXISTS tasks_section_id_fkey;
+ALTER TABLE "tasks" DROP COLUMN IF EXISTS "section_id";
+
+DROP INDEX IF EXISTS idx_project_sections_project;
+DROP TABLE IF EXISTS "project_sections";
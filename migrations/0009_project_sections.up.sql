@@ -0,0 +1,23 @@
+-- Sections split a project into ordered columns (e.g. Backlog, Doing, Review).
+CREATE TABLE "project_sections" (
+  "id" bigserial PRIMARY KEY,
+  "project_id" bigint NOT NULL,
+  "user_id" bigint NOT NULL,
+  "name" varchar NOT NULL,
+  "position" text COLLATE "C" NOT NULL,
+  -- Optional status a task takes when it is moved into the section
+  "status" varchar(50),
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "project_sections" ADD FOREIGN KEY ("project_id") REFERENCES "projects" ("id") ON DELETE CASCADE;
+ALTER TABLE "project_sections" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_project_sections_project ON "project_sections" ("project_id", "position");
+
+-- Tasks fall back to "no section" when their section is deleted
+ALTER TABLE "tasks" ADD COLUMN "section_id" bigint;
+ALTER TABLE "tasks" ADD FOREIGN KEY ("section_id") REFERENCES "project_sections" ("id") ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_tasks_section_id ON "tasks" ("section_id");
@@ -0,0 +1,23 @@
+DROP TABLE IF EXISTS "project_status_transitions";
+DROP TABLE IF EXISTS "project_statuses";
+
+-- Fold custom statuses back into the fixed ones by category.
+ALTER TABLE "tasks" DISABLE TRIGGER trg_set_updated_at;
+
+UPDATE "tasks"
+SET "status" = CASE "status_category"
+  WHEN 'doing' THEN 'in_progress'
+  WHEN 'done' THEN 'done'
+  ELSE 'pending'
+END
+WHERE "status" NOT IN ('pending', 'in_progress', 'done');
+
+ALTER TABLE "tasks" ENABLE TRIGGER trg_set_updated_at;
+
+DROP INDEX IF EXISTS idx_tasks_status_category;
+ALTER TABLE "tasks" DROP CONSTRAINT IF EXISTS tasks_status_category_check;
+ALTER TABLE "tasks" DROP COLUMN IF EXISTS "status_category";
+
+ALTER TABLE "tasks"
+    ADD CONSTRAINT status_check
+    CHECK (status IN ('pending','in_progress','done'));
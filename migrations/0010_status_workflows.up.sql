@@ -0,0 +1,49 @@
+-- Statuses are defined per project (project_statuses) instead of by a fixed
+-- check constraint. Projects without statuses of their own use the built-in
+-- pending / in_progress / done workflow.
+ALTER TABLE "tasks" DROP CONSTRAINT IF EXISTS status_check;
+
+-- The category of a task's status is kept on the task so queries such as
+-- "open tasks" do not depend on each project's workflow.
+ALTER TABLE "tasks" ADD COLUMN "status_category" varchar(10) NOT NULL DEFAULT 'todo';
+ALTER TABLE "tasks" ADD CONSTRAINT tasks_status_category_check
+  CHECK ("status_category" IN ('todo', 'doing', 'done'));
+
+-- Backfill from the old fixed statuses without touching updated_at.
+ALTER TABLE "tasks" DISABLE TRIGGER trg_set_updated_at;
+
+UPDATE "tasks"
+SET "status_category" = CASE "status"
+  WHEN 'in_progress' THEN 'doing'
+  WHEN 'done' THEN 'done'
+  ELSE 'todo'
+END;
+
+ALTER TABLE "tasks" ENABLE TRIGGER trg_set_updated_at;
+
+CREATE INDEX IF NOT EXISTS idx_tasks_status_category ON "tasks" ("user_id", "status_category") WHERE "deleted_at" IS NULL;
+
+CREATE TABLE "project_statuses" (
+  "project_id" bigint NOT NULL,
+  "key" varchar(50) NOT NULL,
+  "name" varchar(100) NOT NULL,
+  "category" varchar(10) NOT NULL,
+  "position" int NOT NULL,
+  "is_default" boolean NOT NULL DEFAULT false,
+  PRIMARY KEY ("project_id", "key"),
+  CONSTRAINT project_statuses_category_check CHECK ("category" IN ('todo', 'doing', 'done'))
+);
+
+ALTER TABLE "project_statuses" ADD FOREIGN KEY ("project_id") REFERENCES "projects" ("id") ON DELETE CASCADE;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_project_statuses_default ON "project_statuses" ("project_id") WHERE "is_default";
+
+-- A workflow without transitions allows any status change.
+CREATE TABLE "project_status_transitions" (
+  "project_id" bigint NOT NULL,
+  "from_status" varchar(50) NOT NULL,
+  "to_status" varchar(50) NOT NULL,
+  PRIMARY KEY ("project_id", "from_status", "to_status"),
+  FOREIGN KEY ("project_id", "from_status") REFERENCES "project_statuses" ("project_id", "key") ON DELETE CASCADE,
+  FOREIGN KEY ("project_id", "to_status") REFERENCES "project_statuses" ("project_id", "key") ON DELETE CASCADE
+);
@@ -0,0 +1,8 @@
+ALTER TABLE "tasks" DROP COLUMN IF EXISTS "time_spent_seconds";
+ALTER TABLE "tasks" DROP CONSTRAINT IF EXISTS tasks_estimate_check;
+ALTER TABLE "tasks" DROP COLUMN IF EXISTS "estimate_seconds";
+
+DROP INDEX IF EXISTS idx_time_entries_running;
+DROP INDEX IF EXISTS idx_time_entries_user_started;
+DROP INDEX IF EXISTS idx_time_entries_task;
+DROP TABLE IF EXISTS "time_entries";
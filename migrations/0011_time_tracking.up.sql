@@ -0,0 +1,29 @@
+-- Time logged against tasks. A row with no ended_at is a running timer.
+CREATE TABLE "time_entries" (
+  "id" bigserial PRIMARY KEY,
+  "task_id" bigint NOT NULL,
+  "user_id" bigint NOT NULL,
+  "started_at" timestamptz NOT NULL,
+  "ended_at" timestamptz,
+  "note" text,
+  -- Set when the auto-stop job ended a timer that was left running
+  "auto_stopped" boolean NOT NULL DEFAULT false,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz NOT NULL DEFAULT (now()),
+  CONSTRAINT time_entries_range_check CHECK ("ended_at" IS NULL OR "ended_at" >= "started_at")
+);
+
+ALTER TABLE "time_entries" ADD FOREIGN KEY ("task_id") REFERENCES "tasks" ("id") ON DELETE CASCADE;
+ALTER TABLE "time_entries" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_time_entries_task ON "time_entries" ("task_id", "started_at");
+CREATE INDEX IF NOT EXISTS idx_time_entries_user_started ON "time_entries" ("user_id", "started_at");
+
+-- At most one running timer per user
+CREATE UNIQUE INDEX IF NOT EXISTS idx_time_entries_running ON "time_entries" ("user_id") WHERE "ended_at" IS NULL;
+
+-- Estimates and the total of finished entries, both in seconds. The total is
+-- kept up to date by the application whenever entries change.
+ALTER TABLE "tasks" ADD COLUMN "estimate_seconds" int;
+ALTER TABLE "tasks" ADD CONSTRAINT tasks_estimate_check CHECK ("estimate_seconds" >= 0);
+ALTER TABLE "tasks" ADD COLUMN "time_spent_seconds" bigint NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+ALTER TABLE "tasks" DROP COLUMN IF EXISTS "checklist_checked";
+ALTER TABLE "tasks" DROP COLUMN IF EXISTS "checklist_total";
+
+DROP INDEX IF EXISTS idx_checklist_items_task;
+DROP TABLE IF EXISTS "checklist_items";
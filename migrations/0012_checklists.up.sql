@@ -0,0 +1,20 @@
+-- Lightweight checklist items inside a task, ordered by rank key.
+CREATE TABLE "checklist_items" (
+  "id" bigserial PRIMARY KEY,
+  "task_id" bigint NOT NULL,
+  "user_id" bigint NOT NULL,
+  "text" varchar(500) NOT NULL,
+  "checked" boolean NOT NULL DEFAULT false,
+  "position" text COLLATE "C" NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "checklist_items" ADD FOREIGN KEY ("task_id") REFERENCES "tasks" ("id") ON DELETE CASCADE;
+ALTER TABLE "checklist_items" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_checklist_items_task ON "checklist_items" ("task_id", "position");
+
+-- Item counts kept on the task so lists can show progress without a join.
+ALTER TABLE "tasks" ADD COLUMN "checklist_total" int NOT NULL DEFAULT 0;
+ALTER TABLE "tasks" ADD COLUMN "checklist_checked" int NOT NULL DEFAULT 0;
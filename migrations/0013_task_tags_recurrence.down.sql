@@ -0,0 +1,4 @@
+ALTER TABLE "tasks" DROP COLUMN IF EXISTS "recurrence";
+
+DROP INDEX IF EXISTS idx_tasks_tags;
+ALTER TABLE "tasks" DROP COLUMN IF EXISTS "tags";
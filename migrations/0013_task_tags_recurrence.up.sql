@@ -0,0 +1,6 @@
+-- Free-form tags, stored lowercased and without duplicates.
+ALTER TABLE "tasks" ADD COLUMN "tags" text[] NOT NULL DEFAULT '{}';
+CREATE INDEX IF NOT EXISTS idx_tasks_tags ON "tasks" USING gin ("tags");
+
+-- Repeat rule as an RFC 5545 RRULE value, e.g. FREQ=MONTHLY;BYMONTHDAY=1.
+ALTER TABLE "tasks" ADD COLUMN "recurrence" text;
@@ -0,0 +1,14 @@
+-- Named task filters ("smart lists"). The definition is the JSON form of the
+-- filter handler's FilterDefinition.
+CREATE TABLE "saved_filters" (
+  "id" bigserial PRIMARY KEY,
+  "user_id" bigint NOT NULL,
+  "name" varchar(100) NOT NULL,
+  "definition" jsonb NOT NULL DEFAULT '{}',
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "saved_filters" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_saved_filters_user_name ON "saved_filters" ("user_id", lower("name"));
@@ -0,0 +1,19 @@
+-- Requests made with an Idempotency-Key header. A row is claimed before the
+-- request runs and holds its response once it has finished; status_code is
+-- NULL while the request is still in flight. request_hash covers the
+-- method, path and body, so a key cannot be reused for a different request.
+CREATE TABLE "idempotency_keys" (
+  "user_id" bigint NOT NULL,
+  "key" varchar(255) NOT NULL,
+  "request_hash" bytea NOT NULL,
+  "status_code" int,
+  "content_type" text,
+  "response_body" bytea,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "completed_at" timestamptz,
+  PRIMARY KEY ("user_id", "key")
+);
+
+ALTER TABLE "idempotency_keys" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_created_at ON "idempotency_keys" ("created_at");
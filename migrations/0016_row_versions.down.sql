@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS trg_bump_version ON projects;
+DROP TRIGGER IF EXISTS trg_bump_version ON tasks;
+DROP FUNCTION IF EXISTS bump_version();
+ALTER TABLE "projects" DROP COLUMN IF EXISTS "version";
+ALTER TABLE "tasks" DROP COLUMN IF EXISTS "version";
@@ -0,0 +1,23 @@
+-- Row versions for optimistic concurrency, exposed by the API as ETags.
+-- Every UPDATE bumps the version through a trigger, so all write paths are
+-- covered, including position and checklist bookkeeping.
+ALTER TABLE "tasks" ADD COLUMN "version" bigint NOT NULL DEFAULT 1;
+ALTER TABLE "projects" ADD COLUMN "version" bigint NOT NULL DEFAULT 1;
+
+CREATE OR REPLACE FUNCTION bump_version()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.version = OLD.version + 1;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_bump_version
+BEFORE UPDATE ON tasks
+FOR EACH ROW
+EXECUTE FUNCTION bump_version();
+
+CREATE TRIGGER trg_bump_version
+BEFORE UPDATE ON projects
+FOR EACH ROW
+EXECUTE FUNCTION bump_version();
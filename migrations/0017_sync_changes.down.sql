@@ -0,0 +1,12 @@
+DROP TRIGGER IF EXISTS trg_record_tombstone ON projects;
+DROP TRIGGER IF EXISTS trg_record_tombstone ON tasks;
+DROP TRIGGER IF EXISTS trg_record_change ON projects;
+DROP TRIGGER IF EXISTS trg_record_change ON tasks;
+DROP FUNCTION IF EXISTS record_tombstone();
+DROP FUNCTION IF EXISTS record_change();
+DROP TABLE IF EXISTS "sync_tombstones";
+DROP INDEX IF EXISTS idx_projects_user_change_seq;
+DROP INDEX IF EXISTS idx_tasks_user_change_seq;
+ALTER TABLE "projects" DROP COLUMN IF EXISTS "change_seq";
+ALTER TABLE "tasks" DROP COLUMN IF EXISTS "change_seq";
+DROP SEQUENCE IF EXISTS change_seq;
@@ -0,0 +1,65 @@
+-- Change numbers for incremental sync. Every insert or update of a task or
+-- project takes the next value of change_seq, and hard deletes leave a
+-- tombstone with a number of its own, so GET /api/sync can return
+-- everything after the number a client has seen. Writes take a per-user
+-- advisory lock first, so one user's changes commit in number order and a
+-- client that has seen change N cannot miss an earlier one.
+CREATE SEQUENCE IF NOT EXISTS change_seq;
+
+ALTER TABLE "tasks" ADD COLUMN "change_seq" bigint NOT NULL DEFAULT nextval('change_seq');
+ALTER TABLE "projects" ADD COLUMN "change_seq" bigint NOT NULL DEFAULT nextval('change_seq');
+
+CREATE INDEX IF NOT EXISTS idx_tasks_user_change_seq ON "tasks" ("user_id", "change_seq");
+CREATE INDEX IF NOT EXISTS idx_projects_user_change_seq ON "projects" ("user_id", "change_seq");
+
+CREATE TABLE "sync_tombstones" (
+  "change_seq" bigint PRIMARY KEY DEFAULT nextval('change_seq'),
+  "user_id" bigint NOT NULL,
+  "entity_type" varchar(20) NOT NULL,
+  "entity_id" bigint NOT NULL,
+  "deleted_at" timestamptz NOT NULL DEFAULT (clock_timestamp())
+);
+
+ALTER TABLE "sync_tombstones" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_sync_tombstones_user ON "sync_tombstones" ("user_id", "change_seq");
+CREATE INDEX IF NOT EXISTS idx_sync_tombstones_deleted_at ON "sync_tombstones" ("deleted_at");
+
+CREATE OR REPLACE FUNCTION record_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM pg_advisory_xact_lock(hashtextextended('sync_changes:' || NEW.user_id, 0));
+    NEW.change_seq = nextval('change_seq');
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION record_tombstone()
+RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM pg_advisory_xact_lock(hashtextextended('sync_changes:' || OLD.user_id, 0));
+    INSERT INTO sync_tombstones (user_id, entity_type, entity_id)
+    VALUES (OLD.user_id, TG_ARGV[0], OLD.id);
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_record_change
+BEFORE INSERT OR UPDATE ON tasks
+FOR EACH ROW
+EXECUTE FUNCTION record_change();
+
+CREATE TRIGGER trg_record_change
+BEFORE INSERT OR UPDATE ON projects
+FOR EACH ROW
+EXECUTE FUNCTION record_change();
+
+CREATE TRIGGER trg_record_tombstone
+AFTER DELETE ON tasks
+FOR EACH ROW
+EXECUTE FUNCTION record_tombstone('task');
+
+CREATE TRIGGER trg_record_tombstone
+AFTER DELETE ON projects
+FOR EACH ROW
+EXECUTE FUNCTION record_tombstone('project');
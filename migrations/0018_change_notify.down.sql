@@ -0,0 +1,31 @@
+DROP TRIGGER IF EXISTS trg_record_change ON tasks;
+DROP TRIGGER IF EXISTS trg_record_change ON projects;
+
+CREATE OR REPLACE FUNCTION record_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM pg_advisory_xact_lock(hashtextextended('sync_changes:' || NEW.user_id, 0));
+    NEW.change_seq = nextval('change_seq');
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION record_tombstone()
+RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM pg_advisory_xact_lock(hashtextextended('sync_changes:' || OLD.user_id, 0));
+    INSERT INTO sync_tombstones (user_id, entity_type, entity_id)
+    VALUES (OLD.user_id, TG_ARGV[0], OLD.id);
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_record_change
+BEFORE INSERT OR UPDATE ON tasks
+FOR EACH ROW
+EXECUTE FUNCTION record_change();
+
+CREATE TRIGGER trg_record_change
+BEFORE INSERT OR UPDATE ON projects
+FOR EACH ROW
+EXECUTE FUNCTION record_change();
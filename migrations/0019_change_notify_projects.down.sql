@@ -0,0 +1,47 @@
+CREATE OR REPLACE FUNCTION record_change()
+RETURNS TRIGGER AS $$
+DECLARE
+    op text;
+BEGIN
+    PERFORM pg_advisory_xact_lock(hashtextextended('sync_changes:' || NEW.user_id, 0));
+    NEW.change_seq = nextval('change_seq');
+
+    IF NEW.deleted_at IS NOT NULL THEN
+        op := 'deleted';
+    ELSIF TG_OP = 'INSERT' OR OLD.deleted_at IS NOT NULL THEN
+        op := 'created';
+    ELSE
+        op := 'updated';
+    END IF;
+
+    PERFORM pg_notify('changes', json_build_object(
+        'seq', NEW.change_seq,
+        'user_id', NEW.user_id,
+        'type', TG_ARGV[0],
+        'id', NEW.id,
+        'op', op
+    )::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION record_tombstone()
+RETURNS TRIGGER AS $$
+DECLARE
+    seq bigint;
+BEGIN
+    PERFORM pg_advisory_xact_lock(hashtextextended('sync_changes:' || OLD.user_id, 0));
+    INSERT INTO sync_tombstones (user_id, entity_type, entity_id)
+    VALUES (OLD.user_id, TG_ARGV[0], OLD.id)
+    RETURNING change_seq INTO seq;
+
+    PERFORM pg_notify('changes', json_build_object(
+        'seq', seq,
+        'user_id', OLD.user_id,
+        'type', TG_ARGV[0],
+        'id', OLD.id,
+        'op', 'deleted'
+    )::text);
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
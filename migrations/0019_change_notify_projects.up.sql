@@ -0,0 +1,73 @@
+-- Name the project a task change belongs to in its notification, and the
+-- project it left when it moved, so that live connections watching a
+-- project get its tasks' changes, deletes included. A project change
+-- belongs to the project itself. Trigger functions are compiled per table,
+-- so the task-only branch never touches projects rows.
+CREATE OR REPLACE FUNCTION record_change()
+RETURNS TRIGGER AS $$
+DECLARE
+    op text;
+    project_id bigint;
+    from_project_id bigint;
+BEGIN
+    PERFORM pg_advisory_xact_lock(hashtextextended('sync_changes:' || NEW.user_id, 0));
+    NEW.change_seq = nextval('change_seq');
+
+    IF NEW.deleted_at IS NOT NULL THEN
+        op := 'deleted';
+    ELSIF TG_OP = 'INSERT' OR OLD.deleted_at IS NOT NULL THEN
+        op := 'created';
+    ELSE
+        op := 'updated';
+    END IF;
+
+    IF TG_ARGV[0] = 'project' THEN
+        project_id := NEW.id;
+    ELSE
+        project_id := NEW.project_id;
+        IF TG_OP = 'UPDATE' AND OLD.project_id IS DISTINCT FROM NEW.project_id THEN
+            from_project_id := OLD.project_id;
+        END IF;
+    END IF;
+
+    PERFORM pg_notify('changes', json_build_object(
+        'seq', NEW.change_seq,
+        'user_id', NEW.user_id,
+        'type', TG_ARGV[0],
+        'id', NEW.id,
+        'op', op,
+        'project_id', project_id,
+        'from_project_id', from_project_id
+    )::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION record_tombstone()
+RETURNS TRIGGER AS $$
+DECLARE
+    seq bigint;
+    project_id bigint;
+BEGIN
+    PERFORM pg_advisory_xact_lock(hashtextextended('sync_changes:' || OLD.user_id, 0));
+    INSERT INTO sync_tombstones (user_id, entity_type, entity_id)
+    VALUES (OLD.user_id, TG_ARGV[0], OLD.id)
+    RETURNING change_seq INTO seq;
+
+    IF TG_ARGV[0] = 'project' THEN
+        project_id := OLD.id;
+    ELSE
+        project_id := OLD.project_id;
+    END IF;
+
+    PERFORM pg_notify('changes', json_build_object(
+        'seq', seq,
+        'user_id', OLD.user_id,
+        'type', TG_ARGV[0],
+        'id', OLD.id,
+        'op', 'deleted',
+        'project_id', project_id
+    )::text);
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
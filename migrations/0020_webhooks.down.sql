@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS trg_enqueue_webhooks ON projects;
+DROP TRIGGER IF EXISTS trg_enqueue_webhooks ON tasks;
+DROP FUNCTION IF EXISTS enqueue_webhooks();
+DROP TABLE IF EXISTS "webhook_attempts";
+DROP TABLE IF EXISTS "webhook_deliveries";
+DROP TABLE IF EXISTS "webhooks";
@@ -0,0 +1,119 @@
+-- Outgoing webhooks. A webhook belongs to a user and optionally narrows to
+-- one project; event_types lists the events it wants, or is empty for all
+-- of them. failure_count counts failed attempts since the last success and
+-- disables the webhook once it gets too high.
+CREATE TABLE "webhooks" (
+  "id" bigserial PRIMARY KEY,
+  "user_id" bigint NOT NULL,
+  "project_id" bigint,
+  "url" text NOT NULL,
+  "secret" text NOT NULL,
+  "event_types" text[] NOT NULL DEFAULT '{}',
+  "active" boolean NOT NULL DEFAULT true,
+  "failure_count" int NOT NULL DEFAULT 0,
+  "disabled_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "webhooks" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+ALTER TABLE "webhooks" ADD FOREIGN KEY ("project_id") REFERENCES "projects" ("id") ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_webhooks_user ON "webhooks" ("user_id");
+
+-- The delivery queue and log. payload is the resource as it was when the
+-- event happened, as a database row; status is pending until a delivery
+-- succeeds or runs out of attempts.
+CREATE TABLE "webhook_deliveries" (
+  "id" bigserial PRIMARY KEY,
+  "webhook_id" bigint NOT NULL,
+  "event_type" varchar(50) NOT NULL,
+  "resource_type" varchar(20) NOT NULL,
+  "payload" jsonb NOT NULL,
+  "status" varchar(20) NOT NULL DEFAULT 'pending',
+  "attempts" int NOT NULL DEFAULT 0,
+  "next_attempt_at" timestamptz NOT NULL DEFAULT (now()),
+  "last_status_code" int,
+  "last_error" text,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "completed_at" timestamptz
+);
+
+ALTER TABLE "webhook_deliveries" ADD FOREIGN KEY ("webhook_id") REFERENCES "webhooks" ("id") ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON "webhook_deliveries" ("next_attempt_at") WHERE "status" = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON "webhook_deliveries" ("webhook_id", "created_at" DESC);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_completed ON "webhook_deliveries" ("completed_at");
+
+-- One row per attempt, with what the receiver answered.
+CREATE TABLE "webhook_attempts" (
+  "id" bigserial PRIMARY KEY,
+  "delivery_id" bigint NOT NULL,
+  "status_code" int,
+  "error" text,
+  "response_body" text,
+  "duration_ms" int NOT NULL,
+  "attempted_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "webhook_attempts" ADD FOREIGN KEY ("delivery_id") REFERENCES "webhook_deliveries" ("id") ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_webhook_attempts_delivery ON "webhook_attempts" ("delivery_id", "attempted_at");
+
+-- Queue a delivery for every webhook that wants the event, in the same
+-- transaction as the change. Moving to the trash is a delete; changes to
+-- rows already in the trash, including purging them, are not reported.
+CREATE OR REPLACE FUNCTION enqueue_webhooks()
+RETURNS TRIGGER AS $$
+DECLARE
+    rec record;
+    event text;
+    project bigint;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        IF OLD.deleted_at IS NOT NULL THEN
+            RETURN NULL;
+        END IF;
+        rec := OLD;
+        event := 'deleted';
+    ELSE
+        rec := NEW;
+        IF NEW.deleted_at IS NOT NULL THEN
+            IF TG_OP = 'UPDATE' AND OLD.deleted_at IS NOT NULL THEN
+                RETURN NULL;
+            END IF;
+            event := 'deleted';
+        ELSIF TG_OP = 'INSERT' OR OLD.deleted_at IS NOT NULL THEN
+            event := 'created';
+        ELSE
+            event := 'updated';
+        END IF;
+    END IF;
+    event := TG_ARGV[0] || '.' || event;
+
+    IF TG_ARGV[0] = 'project' THEN
+        project := rec.id;
+    ELSE
+        project := rec.project_id;
+    END IF;
+
+    INSERT INTO webhook_deliveries (webhook_id, event_type, resource_type, payload)
+    SELECT w.id, event, TG_ARGV[0], to_jsonb(rec)
+    FROM webhooks w
+    WHERE w.user_id = rec.user_id
+      AND w.active
+      AND (w.project_id IS NULL OR w.project_id = project)
+      AND (cardinality(w.event_types) = 0 OR event = ANY (w.event_types));
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_enqueue_webhooks
+AFTER INSERT OR UPDATE OR DELETE ON tasks
+FOR EACH ROW
+EXECUTE FUNCTION enqueue_webhooks('task');
+
+CREATE TRIGGER trg_enqueue_webhooks
+AFTER INSERT OR UPDATE OR DELETE ON projects
+FOR EACH ROW
+EXECUTE FUNCTION enqueue_webhooks('project');
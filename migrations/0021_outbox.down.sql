@@ -0,0 +1,66 @@
+DROP TRIGGER IF EXISTS trg_write_outbox ON projects;
+DROP TRIGGER IF EXISTS trg_write_outbox ON tasks;
+DROP FUNCTION IF EXISTS write_outbox();
+
+DROP INDEX IF EXISTS idx_webhook_deliveries_event;
+ALTER TABLE "webhook_deliveries" DROP COLUMN IF EXISTS "event_id";
+
+DROP TABLE IF EXISTS "outbox";
+
+-- Queue a delivery for every webhook that wants the event, in the same
+-- transaction as the change. Moving to the trash is a delete; changes to
+-- rows already in the trash, including purging them, are not reported.
+CREATE OR REPLACE FUNCTION enqueue_webhooks()
+RETURNS TRIGGER AS $$
+DECLARE
+    rec record;
+    event text;
+    project bigint;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        IF OLD.deleted_at IS NOT NULL THEN
+            RETURN NULL;
+        END IF;
+        rec := OLD;
+        event := 'deleted';
+    ELSE
+        rec := NEW;
+        IF NEW.deleted_at IS NOT NULL THEN
+            IF TG_OP = 'UPDATE' AND OLD.deleted_at IS NOT NULL THEN
+                RETURN NULL;
+            END IF;
+            event := 'deleted';
+        ELSIF TG_OP = 'INSERT' OR OLD.deleted_at IS NOT NULL THEN
+            event := 'created';
+        ELSE
+            event := 'updated';
+        END IF;
+    END IF;
+    event := TG_ARGV[0] || '.' || event;
+
+    IF TG_ARGV[0] = 'project' THEN
+        project := rec.id;
+    ELSE
+        project := rec.project_id;
+    END IF;
+
+    INSERT INTO webhook_deliveries (webhook_id, event_type, resource_type, payload)
+    SELECT w.id, event, TG_ARGV[0], to_jsonb(rec)
+    FROM webhooks w
+    WHERE w.user_id = rec.user_id
+      AND w.active
+      AND (w.project_id IS NULL OR w.project_id = project)
+      AND (cardinality(w.event_types) = 0 OR event = ANY (w.event_types));
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_enqueue_webhooks
+AFTER INSERT OR UPDATE OR DELETE ON tasks
+FOR EACH ROW
+EXECUTE FUNCTION enqueue_webhooks('task');
+
+CREATE TRIGGER trg_enqueue_webhooks
+AFTER INSERT OR UPDATE OR DELETE ON projects
+FOR EACH ROW
+EXECUTE FUNCTION enqueue_webhooks('project');
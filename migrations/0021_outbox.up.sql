@@ -0,0 +1,87 @@
+-- Domain events, written by triggers in the same transaction as the task or
+-- project change they describe, so that an event exists if and only if its
+-- change was committed. The outbox relay publishes them to its sinks at
+-- least once; event_id stays the same across retries so that consumers can
+-- drop duplicates. published_sinks lists the sinks that already have the
+-- event, which a retry skips.
+CREATE TABLE "outbox" (
+  "id" bigserial PRIMARY KEY,
+  "event_id" uuid NOT NULL DEFAULT gen_random_uuid(),
+  "event_type" varchar(50) NOT NULL,
+  "aggregate_type" varchar(20) NOT NULL,
+  "aggregate_id" bigint NOT NULL,
+  "user_id" bigint NOT NULL,
+  "project_id" bigint,
+  "payload" jsonb NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "published_at" timestamptz,
+  "published_sinks" text[] NOT NULL DEFAULT '{}',
+  "attempts" int NOT NULL DEFAULT 0,
+  "next_attempt_at" timestamptz NOT NULL DEFAULT (now()),
+  "last_error" text
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_outbox_event_id ON "outbox" ("event_id");
+CREATE INDEX IF NOT EXISTS idx_outbox_due ON "outbox" ("next_attempt_at", "id") WHERE "published_at" IS NULL;
+CREATE INDEX IF NOT EXISTS idx_outbox_published ON "outbox" ("published_at");
+
+-- Webhook deliveries are now queued by the relay's webhook sink. event_id
+-- keeps it from queueing an event twice for the same webhook; redeliveries
+-- and pings have none.
+ALTER TABLE "webhook_deliveries" ADD COLUMN "event_id" uuid;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_deliveries_event ON "webhook_deliveries" ("webhook_id", "event_id");
+
+DROP TRIGGER IF EXISTS trg_enqueue_webhooks ON tasks;
+DROP TRIGGER IF EXISTS trg_enqueue_webhooks ON projects;
+DROP FUNCTION IF EXISTS enqueue_webhooks();
+
+-- Moving to the trash is a delete and restoring a create; changes to rows
+-- already in the trash, including purging them, are not events.
+CREATE OR REPLACE FUNCTION write_outbox()
+RETURNS TRIGGER AS $$
+DECLARE
+    rec record;
+    event text;
+    project bigint;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        IF OLD.deleted_at IS NOT NULL THEN
+            RETURN NULL;
+        END IF;
+        rec := OLD;
+        event := 'deleted';
+    ELSE
+        rec := NEW;
+        IF NEW.deleted_at IS NOT NULL THEN
+            IF TG_OP = 'UPDATE' AND OLD.deleted_at IS NOT NULL THEN
+                RETURN NULL;
+            END IF;
+            event := 'deleted';
+        ELSIF TG_OP = 'INSERT' OR OLD.deleted_at IS NOT NULL THEN
+            event := 'created';
+        ELSE
+            event := 'updated';
+        END IF;
+    END IF;
+
+    IF TG_ARGV[0] = 'project' THEN
+        project := rec.id;
+    ELSE
+        project := rec.project_id;
+    END IF;
+
+    INSERT INTO outbox (event_type, aggregate_type, aggregate_id, user_id, project_id, payload)
+    VALUES (TG_ARGV[0] || '.' || event, TG_ARGV[0], rec.id, rec.user_id, project, to_jsonb(rec));
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_write_outbox
+AFTER INSERT OR UPDATE OR DELETE ON tasks
+FOR EACH ROW
+EXECUTE FUNCTION write_outbox('task');
+
+CREATE TRIGGER trg_write_outbox
+AFTER INSERT OR UPDATE OR DELETE ON projects
+FOR EACH ROW
+EXECUTE FUNCTION write_outbox('project');
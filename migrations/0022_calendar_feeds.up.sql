@@ -0,0 +1,12 @@
+-- Secret calendar feed URLs. Only a SHA-256 hash of the token is stored; a
+-- user has at most one token, and regenerating it revokes the old URL.
+CREATE TABLE "calendar_feeds" (
+  "user_id" bigint PRIMARY KEY,
+  "token_hash" bytea NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "last_used_at" timestamptz
+);
+
+ALTER TABLE "calendar_feeds" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_calendar_feeds_token ON "calendar_feeds" ("token_hash");
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "caldav_objects";
+DROP TABLE IF EXISTS "personal_tokens";
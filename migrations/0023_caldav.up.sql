@@ -0,0 +1,32 @@
+-- Personal access tokens let clients that cannot do the OAuth or JWT login,
+-- such as CalDAV apps, sign in. Only a SHA-256 hash of the token is stored.
+CREATE TABLE "personal_tokens" (
+  "id" bigserial PRIMARY KEY,
+  "user_id" bigint NOT NULL,
+  "name" varchar(100) NOT NULL,
+  "token_hash" bytea NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "last_used_at" timestamptz
+);
+
+ALTER TABLE "personal_tokens" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_personal_tokens_token ON "personal_tokens" ("token_hash");
+CREATE INDEX IF NOT EXISTS idx_personal_tokens_user ON "personal_tokens" ("user_id");
+
+-- CalDAV clients pick the resource name and UID of the to-dos they create
+-- and expect to find them under those again. Tasks made any other way are
+-- served as task-<id>.ics and have no row here. There is deliberately no
+-- foreign key to tasks: the name is still needed to report a purged task as
+-- gone to sync-collection, so rows are removed with the task's tombstone.
+CREATE TABLE "caldav_objects" (
+  "task_id" bigint PRIMARY KEY,
+  "user_id" bigint NOT NULL,
+  "name" varchar(255) NOT NULL,
+  "uid" text NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "caldav_objects" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_caldav_objects_name ON "caldav_objects" ("user_id", "name");
@@ -0,0 +1,26 @@
+-- Data exports that are too big to stream in the request are written to a
+-- file by a background job. project_ids and inbox are the filters the
+-- export was asked for; status goes from pending to running to succeeded or
+-- failed. Finished exports and their files are removed after expires_at.
+CREATE TABLE "exports" (
+  "id" bigserial PRIMARY KEY,
+  "user_id" bigint NOT NULL,
+  "format" varchar(20) NOT NULL,
+  "project_ids" bigint[],
+  "inbox" boolean NOT NULL DEFAULT false,
+  "status" varchar(20) NOT NULL DEFAULT 'pending',
+  "file_path" text,
+  "size_bytes" bigint,
+  "error" text,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "started_at" timestamptz,
+  "lease_until" timestamptz,
+  "completed_at" timestamptz,
+  "expires_at" timestamptz
+);
+
+ALTER TABLE "exports" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_exports_user ON "exports" ("user_id", "created_at" DESC);
+CREATE INDEX IF NOT EXISTS idx_exports_pending ON "exports" ("created_at") WHERE "status" IN ('pending', 'running');
+CREATE INDEX IF NOT EXISTS idx_exports_expires ON "exports" ("expires_at");
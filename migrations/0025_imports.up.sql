@@ -0,0 +1,22 @@
+-- Imports remember which projects and tasks they made from which item of
+-- the source, so importing the same file again skips what is already
+-- there. external_id is the item's ID in the source, prefixed with its
+-- kind. Purging the project or task forgets it, and a later import brings
+-- it back.
+CREATE TABLE "import_records" (
+  "user_id" bigint NOT NULL,
+  "source" varchar(20) NOT NULL,
+  "external_id" text NOT NULL,
+  "project_id" bigint,
+  "task_id" bigint,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("user_id", "source", "external_id"),
+  CHECK (("project_id" IS NULL) <> ("task_id" IS NULL))
+);
+
+ALTER TABLE "import_records" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+ALTER TABLE "import_records" ADD FOREIGN KEY ("project_id") REFERENCES "projects" ("id") ON DELETE CASCADE;
+ALTER TABLE "import_records" ADD FOREIGN KEY ("task_id") REFERENCES "tasks" ("id") ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_import_records_project ON "import_records" ("project_id");
+CREATE INDEX IF NOT EXISTS idx_import_records_task ON "import_records" ("task_id");
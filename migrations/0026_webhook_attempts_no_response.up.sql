@@ -0,0 +1,3 @@
+-- Receivers' answers are no longer kept: a webhook aimed at an internal
+-- service would otherwise hand its replies to the webhook's owner.
+ALTER TABLE "webhook_attempts" DROP COLUMN IF EXISTS "response_body";
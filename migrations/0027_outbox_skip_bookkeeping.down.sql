@@ -0,0 +1,40 @@
+-- Moving to the trash is a delete and restoring a create; changes to rows
+-- already in the trash, including purging them, are not events.
+CREATE OR REPLACE FUNCTION write_outbox()
+RETURNS TRIGGER AS $$
+DECLARE
+    rec record;
+    event text;
+    project bigint;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        IF OLD.deleted_at IS NOT NULL THEN
+            RETURN NULL;
+        END IF;
+        rec := OLD;
+        event := 'deleted';
+    ELSE
+        rec := NEW;
+        IF NEW.deleted_at IS NOT NULL THEN
+            IF TG_OP = 'UPDATE' AND OLD.deleted_at IS NOT NULL THEN
+                RETURN NULL;
+            END IF;
+            event := 'deleted';
+        ELSIF TG_OP = 'INSERT' OR OLD.deleted_at IS NOT NULL THEN
+            event := 'created';
+        ELSE
+            event := 'updated';
+        END IF;
+    END IF;
+
+    IF TG_ARGV[0] = 'project' THEN
+        project := rec.id;
+    ELSE
+        project := rec.project_id;
+    END IF;
+
+    INSERT INTO outbox (event_type, aggregate_type, aggregate_id, user_id, project_id, payload)
+    VALUES (TG_ARGV[0] || '.' || event, TG_ARGV[0], rec.id, rec.user_id, project, to_jsonb(rec));
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
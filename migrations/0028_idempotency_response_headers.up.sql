@@ -0,0 +1,3 @@
+-- Headers a replayed response needs besides its content type, such as
+-- ETag and Location, by name.
+ALTER TABLE "idempotency_keys" ADD COLUMN IF NOT EXISTS "response_headers" jsonb NOT NULL DEFAULT '{}';
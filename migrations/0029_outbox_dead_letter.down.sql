@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_outbox_dead;
+DROP INDEX IF EXISTS idx_outbox_due;
+CREATE INDEX IF NOT EXISTS idx_outbox_due ON "outbox" ("next_attempt_at", "id") WHERE "published_at" IS NULL;
+
+DELETE FROM "outbox" WHERE "dead_at" IS NOT NULL;
+ALTER TABLE "outbox" DROP COLUMN IF EXISTS "dead_at";
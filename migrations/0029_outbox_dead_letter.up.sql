@@ -0,0 +1,8 @@
+-- Events that could not be published for too long are given up on rather
+-- than retried forever: dead_at marks them, the relay skips them, and they
+-- are deleted after the same retention as published events.
+ALTER TABLE "outbox" ADD COLUMN IF NOT EXISTS "dead_at" timestamptz;
+
+DROP INDEX IF EXISTS idx_outbox_due;
+CREATE INDEX IF NOT EXISTS idx_outbox_due ON "outbox" ("next_attempt_at", "id") WHERE "published_at" IS NULL AND "dead_at" IS NULL;
+CREATE INDEX IF NOT EXISTS idx_outbox_dead ON "outbox" ("dead_at") WHERE "dead_at" IS NOT NULL;
@@ -0,0 +1,28 @@
+-- name: GetCaldavObjectByName :one
+SELECT * FROM caldav_objects
+WHERE user_id = $1 AND name = $2;
+
+-- name: ListCaldavObjects :many
+SELECT * FROM caldav_objects
+WHERE user_id = sqlc.arg('user_id') AND task_id = ANY(sqlc.arg('task_ids')::bigint[]);
+
+-- name: SetCaldavObject :exec
+-- SetCaldavObject records the resource name and UID a client gave a task.
+INSERT INTO caldav_objects (task_id, user_id, name, uid)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (task_id) DO UPDATE
+SET name = EXCLUDED.name, uid = EXCLUDED.uid;
+
+-- name: DeleteCaldavObjectByName :exec
+DELETE FROM caldav_objects
+WHERE user_id = $1 AND name = $2;
+
+-- name: DeleteOrphanedCaldavObjects :execrows
+-- DeleteOrphanedCaldavObjects removes the names of purged tasks once their
+-- tombstones have expired too.
+DELETE FROM caldav_objects o
+WHERE NOT EXISTS (SELECT 1 FROM tasks t WHERE t.id = o.task_id)
+  AND NOT EXISTS (
+    SELECT 1 FROM sync_tombstones s
+    WHERE s.entity_type = 'task' AND s.entity_id = o.task_id
+  );
@@ -0,0 +1,24 @@
+-- name: SetCalendarFeedToken :one
+-- SetCalendarFeedToken gives the user a new feed token, replacing any
+-- earlier one.
+INSERT INTO calendar_feeds (user_id, token_hash)
+VALUES ($1, $2)
+ON CONFLICT (user_id) DO UPDATE
+SET token_hash = EXCLUDED.token_hash, created_at = now(), last_used_at = NULL
+RETURNING *;
+
+-- name: GetCalendarFeed :one
+SELECT * FROM calendar_feeds
+WHERE user_id = $1;
+
+-- name: UseCalendarFeedToken :one
+-- UseCalendarFeedToken looks up the feed a token belongs to and notes that
+-- it was used.
+UPDATE calendar_feeds
+SET last_used_at = now()
+WHERE token_hash = $1
+RETURNING *;
+
+-- name: DeleteCalendarFeed :execrows
+DELETE FROM calendar_feeds
+WHERE user_id = $1;
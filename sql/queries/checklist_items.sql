@@ -0,0 +1,41 @@
+-- name: CreateChecklistItem :one
+INSERT INTO checklist_items (task_id, user_id, text, position)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: ListChecklistItems :many
+SELECT * FROM checklist_items
+WHERE task_id = $1 AND user_id = $2
+ORDER BY position, id;
+
+-- name: ListChecklistItemsByTasks :many
+SELECT * FROM checklist_items
+WHERE user_id = sqlc.arg('user_id') AND task_id = ANY(sqlc.arg('task_ids')::bigint[])
+ORDER BY task_id, position, id;
+
+-- name: GetChecklistItem :one
+SELECT * FROM checklist_items
+WHERE id = $1 AND task_id = $2 AND user_id = $3;
+
+-- name: UpdateChecklistItem :one
+UPDATE checklist_items
+SET
+  text       = COALESCE(sqlc.narg('text'), text),
+  checked    = COALESCE(sqlc.narg('checked'), checked),
+  updated_at = now()
+WHERE id = sqlc.arg('id') AND task_id = sqlc.arg('task_id') AND user_id = sqlc.arg('user_id')
+RETURNING *;
+
+-- name: ToggleChecklistItem :one
+UPDATE checklist_items
+SET checked = NOT checked, updated_at = now()
+WHERE id = $1 AND task_id = $2 AND user_id = $3
+RETURNING *;
+
+-- name: SetChecklistItemPosition :exec
+UPDATE checklist_items SET position = $2, updated_at = now()
+WHERE id = $1;
+
+-- name: DeleteChecklistItem :execrows
+DELETE FROM checklist_items
+WHERE id = $1 AND task_id = $2 AND user_id = $3;
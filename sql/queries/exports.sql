@@ -0,0 +1,46 @@
+-- name: CreateExport :one
+INSERT INTO exports (user_id, format, project_ids, inbox)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetExport :one
+SELECT * FROM exports
+WHERE id = $1 AND user_id = $2;
+
+-- name: ClaimExport :one
+-- ClaimExport picks the oldest pending export, or a running one whose
+-- worker let its lease run out, and leases it until lease_until.
+UPDATE exports
+SET status = 'running', started_at = now(), lease_until = sqlc.arg('lease_until')
+WHERE id = (
+  SELECT id FROM exports
+  WHERE status = 'pending' OR (status = 'running' AND lease_until < now())
+  ORDER BY created_at
+  LIMIT 1
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: FinishExport :exec
+-- FinishExport records the outcome of a running export. Failed exports have
+-- no file and keep their error until they expire like the others.
+UPDATE exports
+SET
+  status       = sqlc.arg('status'),
+  file_path    = sqlc.narg('file_path'),
+  size_bytes   = sqlc.narg('size_bytes'),
+  error        = sqlc.narg('error'),
+  lease_until  = NULL,
+  completed_at = now(),
+  expires_at   = sqlc.arg('expires_at')
+WHERE id = sqlc.arg('id');
+
+-- name: ListExpiredExports :many
+SELECT * FROM exports
+WHERE expires_at < now()
+ORDER BY expires_at
+LIMIT $1;
+
+-- name: DeleteExport :exec
+DELETE FROM exports
+WHERE id = $1;
@@ -0,0 +1,45 @@
+-- name: ClaimIdempotencyKey :one
+-- ClaimIdempotencyKey records an in-flight request for a key. A key whose
+-- record expired, or whose request never finished, is taken over. When the
+-- key is held by a live record no row is returned.
+INSERT INTO idempotency_keys (user_id, key, request_hash)
+VALUES (sqlc.arg('user_id'), sqlc.arg('key'), sqlc.arg('request_hash'))
+ON CONFLICT (user_id, key) DO UPDATE
+SET
+  request_hash  = EXCLUDED.request_hash,
+  status_code   = NULL,
+  content_type  = NULL,
+  response_body = NULL,
+  created_at    = now(),
+  completed_at  = NULL
+WHERE idempotency_keys.created_at < sqlc.arg('expired_before')
+   OR (idempotency_keys.completed_at IS NULL AND idempotency_keys.created_at < sqlc.arg('stale_before'))
+RETURNING *;
+
+-- name: GetIdempotencyKey :one
+SELECT * FROM idempotency_keys
+WHERE user_id = $1 AND key = $2;
+
+-- name: CompleteIdempotencyKey :exec
+-- CompleteIdempotencyKey stores the response of a claimed request. The claim
+-- is identified by its created_at, so a request whose claim was taken over
+-- does not overwrite the new one.
+UPDATE idempotency_keys
+SET
+  status_code   = sqlc.arg('status_code'),
+  content_type  = sqlc.arg('content_type'),
+  response_body = sqlc.arg('response_body'),
+  response_headers = sqlc.arg('response_headers'),
+  completed_at  = now()
+WHERE user_id = sqlc.arg('user_id') AND key = sqlc.arg('key')
+  AND created_at = sqlc.arg('created_at') AND completed_at IS NULL;
+
+-- name: ReleaseIdempotencyKey :exec
+-- ReleaseIdempotencyKey drops an unfinished claim so the request can be
+-- retried.
+DELETE FROM idempotency_keys
+WHERE user_id = $1 AND key = $2 AND created_at = $3 AND completed_at IS NULL;
+
+-- name: DeleteExpiredIdempotencyKeys :execrows
+DELETE FROM idempotency_keys
+WHERE created_at < $1;
@@ -0,0 +1,10 @@
+-- name: GetImportRecord :one
+SELECT * FROM import_records
+WHERE user_id = $1 AND source = $2 AND external_id = $3;
+
+-- name: CreateImportRecord :execrows
+-- CreateImportRecord affects no row when another import of the same item
+-- got there first.
+INSERT INTO import_records (user_id, source, external_id, project_id, task_id)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (user_id, source, external_id) DO NOTHING;
@@ -0,0 +1,55 @@
+-- name: ClaimOutboxEvents :many
+-- ClaimOutboxEvents picks unpublished events that are due, oldest first, and
+-- moves them out of reach until lease_until so that other relays skip them.
+-- Events whose relay dies mid-publish are picked up again once the lease
+-- runs out.
+UPDATE outbox
+SET next_attempt_at = sqlc.arg('lease_until')
+WHERE id IN (
+  SELECT id FROM outbox
+  WHERE published_at IS NULL AND dead_at IS NULL AND next_attempt_at <= now()
+  ORDER BY id
+  LIMIT sqlc.arg('limit')
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: MarkOutboxPublished :exec
+UPDATE outbox
+SET published_at = now(), published_sinks = $1, last_error = NULL
+WHERE id = $2;
+
+-- name: MarkOutboxFailed :exec
+-- MarkOutboxFailed records a publish that failed for some sinks. The event
+-- is retried at next_attempt_at for the sinks not in published_sinks.
+UPDATE outbox
+SET
+  published_sinks = sqlc.arg('published_sinks'),
+  attempts        = sqlc.arg('attempts'),
+  last_error      = sqlc.arg('last_error'),
+  next_attempt_at = sqlc.arg('next_attempt_at')
+WHERE id = sqlc.arg('id');
+
+-- name: MarkOutboxDead :exec
+-- MarkOutboxDead gives up on an event that still misses some sinks.
+UPDATE outbox
+SET
+  published_sinks = sqlc.arg('published_sinks'),
+  attempts        = attempts + 1,
+  last_error      = sqlc.arg('last_error'),
+  dead_at         = now()
+WHERE id = sqlc.arg('id');
+
+-- name: OutboxSinkBehind :one
+-- OutboxSinkBehind reports whether a live event older than before_id has
+-- not reached sink yet, in which case sink must not get newer events.
+SELECT EXISTS (
+  SELECT 1 FROM outbox
+  WHERE published_at IS NULL AND dead_at IS NULL
+    AND id < sqlc.arg('before_id')
+    AND NOT (sqlc.arg('sink')::text = ANY(published_sinks))
+) AS behind;
+
+-- name: DeleteExpiredOutboxEvents :execrows
+DELETE FROM outbox
+WHERE published_at < sqlc.arg('before') OR dead_at < sqlc.arg('before');
@@ -0,0 +1,21 @@
+-- name: CreatePersonalToken :one
+INSERT INTO personal_tokens (user_id, name, token_hash)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: ListPersonalTokens :many
+SELECT * FROM personal_tokens
+WHERE user_id = $1
+ORDER BY id;
+
+-- name: UsePersonalToken :one
+-- UsePersonalToken looks up the token a hash belongs to and notes that it
+-- was used.
+UPDATE personal_tokens
+SET last_used_at = now()
+WHERE token_hash = $1
+RETURNING *;
+
+-- name: DeletePersonalToken :execrows
+DELETE FROM personal_tokens
+WHERE id = $1 AND user_id = $2;
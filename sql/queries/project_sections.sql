@@ -0,0 +1,31 @@
+-- name: CreateSection :one
+INSERT INTO project_sections (project_id, user_id, name, position, status)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetSection :one
+SELECT * FROM project_sections
+WHERE id = $1 AND project_id = $2 AND user_id = $3;
+
+-- name: ListSections :many
+SELECT * FROM project_sections
+WHERE project_id = $1 AND user_id = $2
+ORDER BY position, id;
+
+-- name: UpdateSection :one
+-- An empty status clears the section's status mapping.
+UPDATE project_sections
+SET
+  name       = COALESCE(sqlc.narg('name'), name),
+  status     = NULLIF(COALESCE(sqlc.narg('status'), status), ''),
+  updated_at = now()
+WHERE id = sqlc.arg('id') AND project_id = sqlc.arg('project_id') AND user_id = sqlc.arg('user_id')
+RETURNING *;
+
+-- name: SetSectionPosition :exec
+UPDATE project_sections SET position = $2, updated_at = now()
+WHERE id = $1;
+
+-- name: DeleteSection :execrows
+DELETE FROM project_sections
+WHERE id = $1 AND project_id = $2 AND user_id = $3;
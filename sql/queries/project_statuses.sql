@@ -0,0 +1,22 @@
+-- name: ListProjectStatuses :many
+SELECT * FROM project_statuses
+WHERE project_id = $1
+ORDER BY position;
+
+-- name: ListProjectTransitions :many
+SELECT * FROM project_status_transitions
+WHERE project_id = $1
+ORDER BY from_status, to_status;
+
+-- name: CreateProjectStatus :exec
+INSERT INTO project_statuses (project_id, key, name, category, position, is_default)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: CreateProjectTransition :exec
+INSERT INTO project_status_transitions (project_id, from_status, to_status)
+VALUES ($1, $2, $3);
+
+-- name: DeleteProjectStatuses :exec
+-- Transitions are removed with the statuses they reference.
+DELETE FROM project_statuses
+WHERE project_id = $1;
@@ -8,20 +8,53 @@ INSERT INTO projects (
 
 -- name: GetProject :one
 SELECT * FROM projects
-WHERE id = $1 AND user_id = $2
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
 LIMIT 1;
 
+-- name: GetProjectForUpdate :one
+SELECT * FROM projects
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+FOR UPDATE;
+
 -- name: ListProjects :many
 SELECT * FROM projects
-WHERE user_id = $1
+WHERE user_id = $1 AND deleted_at IS NULL
 ORDER BY created_at DESC;
 
 -- name: UpdateProject :one
 UPDATE projects
 SET name = $3, updated_at = now()
-WHERE id = $1 AND user_id = $2
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+RETURNING *;
+
+-- name: DeleteProject :one
+UPDATE projects
+SET deleted_at = now()
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
 RETURNING *;
 
--- name: DeleteProject :exec
+-- name: GetTrashedProject :one
+SELECT * FROM projects
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL
+LIMIT 1;
+
+-- name: ListTrashedProjects :many
+SELECT * FROM projects
+WHERE user_id = $1 AND deleted_at IS NOT NULL
+ORDER BY deleted_at DESC;
+
+-- name: RestoreProject :one
+UPDATE projects
+SET deleted_at = NULL, updated_at = now()
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL
+RETURNING *;
+
+-- name: PurgeProject :execrows
 DELETE FROM projects
-WHERE id = $1 AND user_id = $2;
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL;
+
+-- name: EmptyProjectTrash :execrows
+DELETE FROM projects WHERE user_id = $1 AND deleted_at IS NOT NULL;
+
+-- name: PurgeExpiredProjects :execrows
+DELETE FROM projects WHERE user_id = $1 AND deleted_at IS NOT NULL AND deleted_at < $2;
@@ -0,0 +1,26 @@
+-- name: CreateSavedFilter :one
+INSERT INTO saved_filters (user_id, name, definition)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: GetSavedFilter :one
+SELECT * FROM saved_filters
+WHERE id = $1 AND user_id = $2;
+
+-- name: ListSavedFilters :many
+SELECT * FROM saved_filters
+WHERE user_id = $1
+ORDER BY lower(name), id;
+
+-- name: UpdateSavedFilter :one
+UPDATE saved_filters
+SET
+  name       = COALESCE(sqlc.narg('name'), name),
+  definition = COALESCE(sqlc.narg('definition'), definition),
+  updated_at = now()
+WHERE id = sqlc.arg('id') AND user_id = sqlc.arg('user_id')
+RETURNING *;
+
+-- name: DeleteSavedFilter :execrows
+DELETE FROM saved_filters
+WHERE id = $1 AND user_id = $2;
@@ -0,0 +1,36 @@
+-- name: LockSyncChanges :exec
+-- LockSyncChanges takes the per-user lock the record_change and
+-- record_tombstone triggers take, until the end of the transaction.
+SELECT pg_advisory_xact_lock(hashtextextended('sync_changes:' || sqlc.arg('user_id')::bigint, 0));
+
+-- name: ListTaskChanges :many
+-- ListTaskChanges returns the user's tasks changed after a change number,
+-- trashed ones included, oldest change first.
+SELECT * FROM tasks
+WHERE user_id = sqlc.arg('user_id') AND change_seq > sqlc.arg('after')
+ORDER BY change_seq
+LIMIT sqlc.arg('limit');
+
+-- name: ListProjectChanges :many
+SELECT * FROM projects
+WHERE user_id = sqlc.arg('user_id') AND change_seq > sqlc.arg('after')
+ORDER BY change_seq
+LIMIT sqlc.arg('limit');
+
+-- name: ListSyncTombstones :many
+SELECT * FROM sync_tombstones
+WHERE user_id = sqlc.arg('user_id') AND change_seq > sqlc.arg('after')
+ORDER BY change_seq
+LIMIT sqlc.arg('limit');
+
+-- name: DeleteExpiredSyncTombstones :execrows
+DELETE FROM sync_tombstones WHERE deleted_at < sqlc.arg('before');
+
+-- name: GetLatestChangeSeq :one
+-- GetLatestChangeSeq returns the highest change number among the user's
+-- tasks, projects and tombstones, or 0 when there are none.
+SELECT GREATEST(
+  (SELECT COALESCE(MAX(change_seq), 0) FROM tasks WHERE tasks.user_id = sqlc.arg('user_id')),
+  (SELECT COALESCE(MAX(change_seq), 0) FROM projects WHERE projects.user_id = sqlc.arg('user_id')),
+  (SELECT COALESCE(MAX(change_seq), 0) FROM sync_tombstones WHERE sync_tombstones.user_id = sqlc.arg('user_id'))
+)::bigint AS change_seq;
@@ -0,0 +1,24 @@
+-- name: CreateTaskEvent :one
+INSERT INTO task_events (task_id, user_id, actor_id, action, changes, reverts_event_id)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetTaskEvent :one
+SELECT * FROM task_events
+WHERE id = $1 AND user_id = $2;
+
+-- name: ListTaskEvents :many
+SELECT * FROM task_events
+WHERE task_id = $1 AND user_id = $2
+ORDER BY id DESC
+LIMIT $3 OFFSET $4;
+
+-- name: GetLatestUndoableTaskEvent :one
+SELECT * FROM task_events
+WHERE task_id = $1 AND user_id = $2 AND undone_at IS NULL AND reverts_event_id IS NULL
+ORDER BY id DESC
+LIMIT 1;
+
+-- name: MarkTaskEventUndone :exec
+UPDATE task_events SET undone_at = now()
+WHERE id = $1;
@@ -1,5 +1,5 @@
 -- name: CreateTask :one
-INSERT INTO tasks (title, description, status, priority, due_date, user_id, project_id)
+INSERT INTO tasks (title, description, status, priority, due_date, user_id, project_id, position, status_category, estimate_seconds, tags, recurrence)
 VALUES (
   sqlc.arg('title'),
   sqlc.narg('description'),
@@ -7,37 +7,295 @@ VALUES (
   COALESCE(sqlc.narg('priority'), 1),
   sqlc.narg('due_date'),
   sqlc.arg('user_id'),
-  sqlc.narg('project_id')
+  sqlc.narg('project_id'),
+  sqlc.arg('position'),
+  sqlc.arg('status_category'),
+  sqlc.narg('estimate_seconds'),
+  COALESCE(sqlc.narg('tags')::text[], '{}'),
+  sqlc.narg('recurrence')
 )
 RETURNING *;
 
 -- name: GetTask :one
-SELECT * FROM tasks WHERE id = sqlc.arg('id') AND user_id = sqlc.arg('user_id');
+SELECT * FROM tasks
+WHERE id = sqlc.arg('id') AND user_id = sqlc.arg('user_id') AND deleted_at IS NULL;
+
+-- name: GetTaskForUpdate :one
+SELECT * FROM tasks
+WHERE id = sqlc.arg('id') AND user_id = sqlc.arg('user_id') AND deleted_at IS NULL
+FOR UPDATE;
+
+-- name: LockTask :one
+SELECT * FROM tasks
+WHERE id = sqlc.arg('id') AND user_id = sqlc.arg('user_id')
+FOR UPDATE;
 
 -- name: ListTasks :many
 SELECT * FROM tasks
 WHERE user_id = sqlc.arg('user_id')
+  AND deleted_at IS NULL
   AND (sqlc.narg('status')::text IS NULL OR status = sqlc.narg('status')::text)
   AND (sqlc.narg('due_before')::timestamptz IS NULL OR due_date <= sqlc.narg('due_before')::timestamptz)
-ORDER BY created_at DESC
+ORDER BY
+  CASE WHEN sqlc.arg('sort')::text = 'position' THEN position END,
+  created_at DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: FilterTasks :many
+-- Every condition is optional: a NULL argument (or a false inbox) skips it.
+SELECT * FROM tasks
+WHERE user_id = sqlc.arg('user_id')
+  AND deleted_at IS NULL
+  AND (sqlc.narg('statuses')::text[] IS NULL OR status = ANY(sqlc.narg('statuses')::text[]))
+  AND (sqlc.narg('categories')::text[] IS NULL OR status_category = ANY(sqlc.narg('categories')::text[]))
+  AND (sqlc.narg('priority_min')::int IS NULL OR priority >= sqlc.narg('priority_min')::int)
+  AND (sqlc.narg('priority_max')::int IS NULL OR priority <= sqlc.narg('priority_max')::int)
+  AND (sqlc.narg('project_ids')::bigint[] IS NULL OR project_id = ANY(sqlc.narg('project_ids')::bigint[]))
+  AND (NOT sqlc.arg('inbox')::bool OR project_id IS NULL)
+  AND (sqlc.narg('tags')::text[] IS NULL OR tags @> sqlc.narg('tags')::text[])
+  AND (sqlc.narg('due_after')::timestamptz IS NULL OR due_date >= sqlc.narg('due_after')::timestamptz)
+  AND (sqlc.narg('due_before')::timestamptz IS NULL OR due_date < sqlc.narg('due_before')::timestamptz)
+  AND (sqlc.narg('has_due_date')::bool IS NULL OR (due_date IS NOT NULL) = sqlc.narg('has_due_date')::bool)
+ORDER BY
+  CASE WHEN sqlc.arg('sort')::text = 'due_date' THEN due_date END,
+  CASE WHEN sqlc.arg('sort')::text = 'priority' THEN priority END DESC,
+  CASE WHEN sqlc.arg('sort')::text = 'position' THEN position END,
+  created_at DESC
 LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
 
 -- name: ListTasksByProject :many
 SELECT * FROM tasks
-WHERE user_id = sqlc.arg('user_id') AND project_id = sqlc.arg('project_id')
-ORDER BY created_at DESC;
+WHERE user_id = sqlc.arg('user_id') AND project_id = sqlc.arg('project_id') AND deleted_at IS NULL
+ORDER BY
+  CASE WHEN sqlc.arg('sort')::text = 'position' THEN position END,
+  created_at DESC;
+
+-- name: ListCollectionTasks :many
+-- ListCollectionTasks returns the live tasks of a project, or of the inbox
+-- when project_id is NULL, in the order they were created.
+SELECT * FROM tasks
+WHERE user_id = sqlc.arg('user_id') AND project_id IS NOT DISTINCT FROM sqlc.narg('project_id') AND deleted_at IS NULL
+ORDER BY id;
+
+-- name: CountExportTasks :one
+-- CountExportTasks counts the live tasks an export with these filters would
+-- hold. With no project_ids and inbox false every task is counted.
+SELECT count(*) FROM tasks
+WHERE user_id = sqlc.arg('user_id')
+  AND deleted_at IS NULL
+  AND (
+    (sqlc.narg('project_ids')::bigint[] IS NULL AND NOT sqlc.arg('inbox')::bool)
+    OR project_id = ANY(sqlc.narg('project_ids')::bigint[])
+    OR (sqlc.arg('inbox')::bool AND project_id IS NULL)
+  );
+
+-- name: ListExportTasks :many
+-- ListExportTasks pages through the live tasks of an export, inbox first
+-- and then by project, each in the order they were created. Pass the
+-- project (0 for the inbox) and id of the last task of the previous page.
+SELECT * FROM tasks
+WHERE user_id = sqlc.arg('user_id')
+  AND deleted_at IS NULL
+  AND (
+    (sqlc.narg('project_ids')::bigint[] IS NULL AND NOT sqlc.arg('inbox')::bool)
+    OR project_id = ANY(sqlc.narg('project_ids')::bigint[])
+    OR (sqlc.arg('inbox')::bool AND project_id IS NULL)
+  )
+  AND (COALESCE(project_id, 0), id) > (sqlc.arg('after_project')::bigint, sqlc.arg('after_id')::bigint)
+ORDER BY COALESCE(project_id, 0), id
+LIMIT sqlc.arg('limit');
 
 -- name: UpdateTask :one
+-- UpdateTask leaves a nullable field alone when NULL is passed for it; the
+-- clear_* flags set it to NULL instead.
 UPDATE tasks
 SET
   title       = COALESCE(sqlc.narg('title'), title),
-  description = COALESCE(sqlc.narg('description'), description),
+  description = CASE WHEN sqlc.arg('clear_description')::bool THEN NULL
+                     ELSE COALESCE(sqlc.narg('description'), description) END,
   status      = COALESCE(sqlc.narg('status'), status),
+  status_category = COALESCE(sqlc.narg('status_category'), status_category),
   priority    = COALESCE(sqlc.narg('priority'), priority),
-  due_date    = COALESCE(sqlc.narg('due_date'), due_date),
-  project_id  = COALESCE(sqlc.narg('project_id'), project_id)
-WHERE id = sqlc.arg('id') AND user_id = sqlc.arg('user_id')
+  due_date    = CASE WHEN sqlc.arg('clear_due_date')::bool THEN NULL
+                     ELSE COALESCE(sqlc.narg('due_date'), due_date) END,
+  estimate_seconds = CASE WHEN sqlc.arg('clear_estimate')::bool THEN NULL
+                          ELSE COALESCE(sqlc.narg('estimate_seconds'), estimate_seconds) END,
+  tags        = COALESCE(sqlc.narg('tags')::text[], tags),
+  -- An empty recurrence clears it
+  recurrence  = NULLIF(COALESCE(sqlc.narg('recurrence'), recurrence), ''),
+  project_id  = CASE WHEN sqlc.arg('clear_project')::bool THEN NULL
+                     ELSE COALESCE(sqlc.narg('project_id'), project_id) END,
+  -- Sections belong to a project, so moving to another project clears it
+  section_id  = CASE
+    WHEN sqlc.arg('clear_project')::bool THEN NULL
+    WHEN project_id IS DISTINCT FROM COALESCE(sqlc.narg('project_id'), project_id) THEN NULL
+    ELSE section_id
+  END
+WHERE id = sqlc.arg('id') AND user_id = sqlc.arg('user_id') AND deleted_at IS NULL
+RETURNING *;
+
+-- name: SetTaskFields :one
+UPDATE tasks
+SET
+  title       = sqlc.arg('title'),
+  description = sqlc.narg('description'),
+  status      = sqlc.arg('status'),
+  status_category = sqlc.arg('status_category'),
+  priority    = sqlc.arg('priority'),
+  due_date    = sqlc.narg('due_date'),
+  estimate_seconds = sqlc.narg('estimate_seconds'),
+  tags        = sqlc.arg('tags'),
+  recurrence  = sqlc.narg('recurrence'),
+  project_id  = sqlc.narg('project_id'),
+  section_id  = sqlc.narg('section_id')
+WHERE id = sqlc.arg('id') AND user_id = sqlc.arg('user_id') AND deleted_at IS NULL
+RETURNING *;
+
+-- name: MoveTask :one
+UPDATE tasks
+SET
+  project_id = sqlc.narg('project_id'),
+  section_id = sqlc.narg('section_id'),
+  position   = sqlc.arg('position'),
+  status     = sqlc.arg('status'),
+  status_category = sqlc.arg('status_category')
+WHERE id = sqlc.arg('id') AND user_id = sqlc.arg('user_id') AND deleted_at IS NULL
+RETURNING *;
+
+-- name: SetTaskPosition :exec
+UPDATE tasks SET position = sqlc.arg('position')
+WHERE id = sqlc.arg('id') AND user_id = sqlc.arg('user_id');
+
+-- name: LockTaskPositions :exec
+SELECT pg_advisory_xact_lock(hashtextextended(
+  'task_positions:' || sqlc.arg('user_id')::bigint || ':' || COALESCE(sqlc.narg('project_id')::bigint, 0), 0
+));
+
+-- name: GetLastTaskPosition :one
+SELECT position FROM tasks
+WHERE user_id = sqlc.arg('user_id')
+  AND project_id IS NOT DISTINCT FROM sqlc.narg('project_id')
+  AND deleted_at IS NULL
+  AND id <> sqlc.arg('exclude_id')
+ORDER BY position DESC
+LIMIT 1;
+
+-- name: GetNextTaskPosition :one
+SELECT position FROM tasks
+WHERE user_id = sqlc.arg('user_id')
+  AND project_id IS NOT DISTINCT FROM sqlc.narg('project_id')
+  AND deleted_at IS NULL
+  AND id <> sqlc.arg('exclude_id')
+  AND position > sqlc.arg('position')
+ORDER BY position
+LIMIT 1;
+
+-- name: GetPrevTaskPosition :one
+SELECT position FROM tasks
+WHERE user_id = sqlc.arg('user_id')
+  AND project_id IS NOT DISTINCT FROM sqlc.narg('project_id')
+  AND deleted_at IS NULL
+  AND id <> sqlc.arg('exclude_id')
+  AND position < sqlc.arg('position')
+ORDER BY position DESC
+LIMIT 1;
+
+-- name: ListTaskIDsByPosition :many
+SELECT id FROM tasks
+WHERE user_id = sqlc.arg('user_id')
+  AND project_id IS NOT DISTINCT FROM sqlc.narg('project_id')
+  AND deleted_at IS NULL
+  AND id <> sqlc.arg('exclude_id')
+ORDER BY position, id;
+
+-- name: ListProjectTaskStatuses :many
+-- Includes trashed tasks, which keep their status when restored.
+SELECT DISTINCT status FROM tasks
+WHERE project_id = $1
+ORDER BY status;
+
+-- name: RemapProjectTaskStatus :many
+UPDATE tasks
+SET status = sqlc.arg('to_status'), status_category = sqlc.arg('status_category')
+WHERE project_id = sqlc.arg('project_id') AND status = sqlc.arg('from_status')
+RETURNING *;
+
+-- name: SyncProjectTaskCategories :many
+UPDATE tasks t
+SET status_category = s.category
+FROM project_statuses s
+WHERE t.project_id = $1
+  AND s.project_id = t.project_id
+  AND s.key = t.status
+  AND t.status_category <> s.category
+RETURNING t.id;
+
+-- name: RefreshTaskTimeSpent :exec
+UPDATE tasks
+SET time_spent_seconds = (
+  SELECT COALESCE(SUM(EXTRACT(EPOCH FROM e.ended_at - e.started_at)), 0)::bigint
+  FROM time_entries e
+  WHERE e.task_id = tasks.id AND e.ended_at IS NOT NULL
+)
+WHERE id = $1;
+
+-- name: RefreshTaskChecklist :one
+-- Also bumps updated_at through the tasks trigger, so checklist edits show
+-- up as task changes.
+UPDATE tasks
+SET
+  checklist_total   = (SELECT count(*) FROM checklist_items c WHERE c.task_id = tasks.id),
+  checklist_checked = (SELECT count(*) FROM checklist_items c WHERE c.task_id = tasks.id AND c.checked)
+WHERE id = $1
 RETURNING *;
 
--- name: DeleteTask :exec
-DELETE FROM tasks WHERE id = sqlc.arg('id') AND user_id = sqlc.arg('user_id');
+-- name: DeleteTask :one
+UPDATE tasks SET deleted_at = now()
+WHERE id = sqlc.arg('id') AND user_id = sqlc.arg('user_id') AND deleted_at IS NULL
+RETURNING *;
+
+-- name: GetTrashedTask :one
+SELECT * FROM tasks
+WHERE id = sqlc.arg('id') AND user_id = sqlc.arg('user_id') AND deleted_at IS NOT NULL;
+
+-- name: ListTrashedTasks :many
+SELECT * FROM tasks
+WHERE user_id = sqlc.arg('user_id') AND deleted_at IS NOT NULL
+ORDER BY deleted_at DESC;
+
+-- name: RestoreTask :one
+UPDATE tasks SET deleted_at = NULL
+WHERE id = sqlc.arg('id') AND user_id = sqlc.arg('user_id') AND deleted_at IS NOT NULL
+RETURNING *;
+
+-- name: PurgeTask :execrows
+DELETE FROM tasks
+WHERE id = sqlc.arg('id') AND user_id = sqlc.arg('user_id') AND deleted_at IS NOT NULL;
+
+-- name: TrashProjectTasks :many
+UPDATE tasks SET deleted_at = sqlc.arg('deleted_at')
+WHERE project_id = sqlc.arg('project_id') AND user_id = sqlc.arg('user_id') AND deleted_at IS NULL
+RETURNING *;
+
+-- name: RestoreProjectTasks :many
+UPDATE tasks SET deleted_at = NULL
+WHERE project_id = sqlc.arg('project_id') AND user_id = sqlc.arg('user_id') AND deleted_at = sqlc.arg('deleted_at')
+RETURNING *;
+
+-- name: PurgeProjectTasks :exec
+DELETE FROM tasks
+WHERE project_id = sqlc.arg('project_id') AND user_id = sqlc.arg('user_id') AND deleted_at IS NOT NULL;
+
+-- name: EmptyTaskTrash :execrows
+DELETE FROM tasks WHERE user_id = sqlc.arg('user_id') AND deleted_at IS NOT NULL;
+
+-- name: ListExpiredTrashUsers :many
+-- ListExpiredTrashUsers returns the users with tasks or projects trashed
+-- before a time, for purging them one user at a time.
+SELECT user_id FROM tasks WHERE deleted_at IS NOT NULL AND deleted_at < sqlc.arg('before')
+UNION
+SELECT user_id FROM projects WHERE deleted_at IS NOT NULL AND deleted_at < sqlc.arg('before');
+
+-- name: PurgeExpiredTasks :execrows
+DELETE FROM tasks
+WHERE user_id = sqlc.arg('user_id') AND deleted_at IS NOT NULL AND deleted_at < sqlc.arg('before');
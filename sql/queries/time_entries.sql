@@ -0,0 +1,87 @@
+-- name: CreateTimeEntry :one
+INSERT INTO time_entries (task_id, user_id, started_at, ended_at, note)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: StartTimeEntry :one
+INSERT INTO time_entries (task_id, user_id, started_at, note)
+VALUES ($1, $2, now(), $3)
+RETURNING *;
+
+-- name: GetRunningTimeEntry :one
+SELECT * FROM time_entries
+WHERE user_id = $1 AND ended_at IS NULL
+FOR UPDATE;
+
+-- name: StopTimeEntry :one
+UPDATE time_entries
+SET ended_at = GREATEST(now(), started_at), updated_at = now()
+WHERE id = $1 AND ended_at IS NULL
+RETURNING *;
+
+-- name: GetTimeEntry :one
+SELECT * FROM time_entries
+WHERE id = $1 AND task_id = $2 AND user_id = $3
+FOR UPDATE;
+
+-- name: ListTimeEntries :many
+SELECT * FROM time_entries
+WHERE task_id = $1 AND user_id = $2
+ORDER BY started_at DESC, id DESC;
+
+-- name: UpdateTimeEntry :one
+UPDATE time_entries
+SET started_at = $1, ended_at = $2, note = $3, updated_at = now()
+WHERE id = $4
+RETURNING *;
+
+-- name: DeleteTimeEntry :one
+DELETE FROM time_entries
+WHERE id = $1 AND task_id = $2 AND user_id = $3
+RETURNING *;
+
+-- name: StopTaskTimeEntries :execrows
+UPDATE time_entries
+SET ended_at = GREATEST(now(), started_at), updated_at = now()
+WHERE task_id = $1 AND ended_at IS NULL;
+
+-- name: ListStaleTimerUsers :many
+-- ListStaleTimerUsers returns the users with timers that have run longer
+-- than max_seconds.
+SELECT DISTINCT user_id FROM time_entries
+WHERE ended_at IS NULL
+  AND started_at < now() - sqlc.arg('max_seconds')::bigint * interval '1 second';
+
+-- name: StopStaleTimeEntries :many
+-- Ends the user's timers that have run longer than max_seconds, crediting
+-- exactly max_seconds to each.
+UPDATE time_entries
+SET
+  ended_at     = started_at + sqlc.arg('max_seconds')::bigint * interval '1 second',
+  auto_stopped = true,
+  updated_at   = now()
+WHERE user_id = sqlc.arg('user_id')
+  AND ended_at IS NULL
+  AND started_at < now() - sqlc.arg('max_seconds')::bigint * interval '1 second'
+RETURNING *;
+
+-- name: TimeReport :many
+-- Finished time per project, user and day (in the given time zone) for
+-- entries that started in [from, to).
+SELECT
+  t.project_id,
+  p.name AS project_name,
+  e.user_id,
+  (e.started_at AT TIME ZONE sqlc.arg('tz')::text)::date AS day,
+  COALESCE(SUM(EXTRACT(EPOCH FROM e.ended_at - e.started_at)), 0)::bigint AS seconds,
+  COUNT(*) AS entries
+FROM time_entries e
+JOIN tasks t ON t.id = e.task_id
+LEFT JOIN projects p ON p.id = t.project_id
+WHERE t.user_id = sqlc.arg('user_id')
+  AND e.ended_at IS NOT NULL
+  AND e.started_at >= sqlc.arg('from')
+  AND e.started_at < sqlc.arg('to')
+  AND (sqlc.narg('project_id')::bigint IS NULL OR t.project_id = sqlc.narg('project_id')::bigint)
+GROUP BY t.project_id, p.name, e.user_id, day
+ORDER BY day, t.project_id, e.user_id;
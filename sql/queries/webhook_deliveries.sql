@@ -0,0 +1,85 @@
+-- name: CreateWebhookDelivery :one
+INSERT INTO webhook_deliveries (webhook_id, event_type, resource_type, payload)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: RedeliverWebhookDelivery :one
+-- RedeliverWebhookDelivery queues a copy of a delivery, which keeps its
+-- original event and payload.
+INSERT INTO webhook_deliveries (webhook_id, event_type, resource_type, payload)
+SELECT webhook_id, event_type, resource_type, payload
+FROM webhook_deliveries
+WHERE id = $1 AND webhook_id = $2
+RETURNING *;
+
+-- name: ClaimWebhookDeliveries :many
+-- ClaimWebhookDeliveries picks due deliveries of active webhooks and moves
+-- them out of reach until lease_until, so that other workers skip them while
+-- they are sent. A worker that dies mid-send leaves them to be retried once
+-- the lease runs out.
+UPDATE webhook_deliveries
+SET next_attempt_at = sqlc.arg('lease_until')
+WHERE id IN (
+  SELECT d.id
+  FROM webhook_deliveries d
+  JOIN webhooks w ON w.id = d.webhook_id
+  WHERE d.status = 'pending' AND d.next_attempt_at <= now() AND w.active
+  ORDER BY d.next_attempt_at
+  LIMIT sqlc.arg('limit')
+  FOR UPDATE OF d SKIP LOCKED
+)
+RETURNING *;
+
+-- name: RecordWebhookAttempt :exec
+INSERT INTO webhook_attempts (delivery_id, status_code, error, duration_ms)
+VALUES ($1, $2, $3, $4);
+
+-- name: FinishWebhookAttempt :one
+-- FinishWebhookAttempt stores the outcome of an attempt. A delivery that is
+-- still pending is retried at next_attempt_at.
+UPDATE webhook_deliveries
+SET
+  status           = sqlc.arg('status'),
+  attempts         = attempts + 1,
+  last_status_code = sqlc.arg('last_status_code'),
+  last_error       = sqlc.arg('last_error'),
+  next_attempt_at  = sqlc.arg('next_attempt_at'),
+  completed_at     = CASE WHEN sqlc.arg('status')::text = 'pending' THEN NULL ELSE now() END
+WHERE id = sqlc.arg('id')
+RETURNING *;
+
+-- name: GetWebhookDelivery :one
+SELECT * FROM webhook_deliveries
+WHERE id = $1 AND webhook_id = $2;
+
+-- name: ListWebhookDeliveries :many
+-- ListWebhookDeliveries returns a webhook's deliveries, newest first,
+-- optionally only those with a status or older than a delivery.
+SELECT * FROM webhook_deliveries
+WHERE webhook_id = sqlc.arg('webhook_id')
+  AND (sqlc.narg('status')::text IS NULL OR status = sqlc.narg('status'))
+  AND (sqlc.narg('before_id')::bigint IS NULL OR id < sqlc.narg('before_id'))
+ORDER BY id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: ListWebhookAttempts :many
+SELECT * FROM webhook_attempts
+WHERE delivery_id = $1
+ORDER BY attempted_at, id;
+
+-- name: DeleteExpiredWebhookDeliveries :execrows
+DELETE FROM webhook_deliveries
+WHERE completed_at < $1;
+
+-- name: EnqueueWebhookEvent :execrows
+-- EnqueueWebhookEvent queues an outbox event for every active webhook of the
+-- user that wants it. Webhooks that already have the event are skipped, so
+-- publishing it again is harmless.
+INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, resource_type, payload)
+SELECT w.id, sqlc.arg('event_id')::uuid, sqlc.arg('event_type')::text, sqlc.arg('resource_type')::text, sqlc.arg('payload')::jsonb
+FROM webhooks w
+WHERE w.user_id = sqlc.arg('user_id')
+  AND w.active
+  AND (w.project_id IS NULL OR w.project_id = sqlc.narg('project_id'))
+  AND (cardinality(w.event_types) = 0 OR sqlc.arg('event_type') = ANY (w.event_types))
+ON CONFLICT (webhook_id, event_id) DO NOTHING;
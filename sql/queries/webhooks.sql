@@ -0,0 +1,61 @@
+-- name: CreateWebhook :one
+INSERT INTO webhooks (user_id, project_id, url, secret, event_types)
+VALUES (sqlc.arg('user_id'), sqlc.arg('project_id'), sqlc.arg('url'), sqlc.arg('secret'), sqlc.arg('event_types'))
+RETURNING *;
+
+-- name: GetWebhook :one
+SELECT * FROM webhooks
+WHERE id = $1 AND user_id = $2;
+
+-- name: GetWebhookByID :one
+SELECT * FROM webhooks
+WHERE id = $1;
+
+-- name: ListWebhooks :many
+SELECT * FROM webhooks
+WHERE user_id = $1
+ORDER BY id;
+
+-- name: SetWebhookFields :one
+-- SetWebhookFields replaces a webhook's settings. Turning a webhook back on
+-- forgets its failures.
+UPDATE webhooks
+SET
+  url           = sqlc.arg('url'),
+  project_id    = sqlc.arg('project_id'),
+  event_types   = sqlc.arg('event_types'),
+  failure_count = CASE WHEN sqlc.arg('active')::boolean AND NOT active THEN 0 ELSE failure_count END,
+  disabled_at   = CASE WHEN sqlc.arg('active')::boolean THEN NULL ELSE disabled_at END,
+  active        = sqlc.arg('active'),
+  updated_at    = now()
+WHERE id = sqlc.arg('id') AND user_id = sqlc.arg('user_id')
+RETURNING *;
+
+-- name: SetWebhookSecret :one
+UPDATE webhooks
+SET secret = $1, updated_at = now()
+WHERE id = $2 AND user_id = $3
+RETURNING *;
+
+-- name: DeleteWebhook :execrows
+DELETE FROM webhooks
+WHERE id = $1 AND user_id = $2;
+
+-- name: ResetWebhookFailures :exec
+UPDATE webhooks
+SET failure_count = 0
+WHERE id = $1 AND failure_count > 0;
+
+-- name: CountWebhookFailure :one
+-- CountWebhookFailure records a failed attempt and turns the webhook off
+-- once it has failed max_failures times in a row.
+UPDATE webhooks
+SET
+  failure_count = failure_count + 1,
+  active        = active AND failure_count + 1 < sqlc.arg('max_failures'),
+  disabled_at   = CASE
+    WHEN active AND failure_count + 1 >= sqlc.arg('max_failures') THEN now()
+    ELSE disabled_at
+  END
+WHERE id = sqlc.arg('id')
+RETURNING *;